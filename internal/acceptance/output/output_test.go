@@ -0,0 +1,119 @@
+package acceptance_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var pathToMain string
+
+func TestOutput(t *testing.T) {
+	SetDefaultEventuallyTimeout(time.Minute)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "output")
+}
+
+var _ = BeforeSuite(func() {
+	var err error
+	pathToMain, err = gexec.Build("github.com/pivotal-cf/kiln")
+	Expect(err).NotTo(HaveOccurred())
+})
+
+var _ = AfterSuite(func() {
+	gexec.CleanupBuildArtifacts()
+})
+
+var _ = Describe("--output json", func() {
+	const (
+		kilnfile = `---
+releases:
+  - name: banana
+`
+		kilnfileLock = `---
+releases:
+  - name: banana
+    version: 1.2.3
+    sha1: some-sha1
+    remote_source: bosh.io
+    remote_path: https://bosh.io/d/github.com/cloudfoundry/banana-release?v=1.2.3
+stemcell_criteria:
+  os: some-os
+  version: "4.5.6"
+`
+	)
+
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "kiln-output")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(filepath.Join(tmpDir, "Kilnfile"), []byte(kilnfile), 0o644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(tmpDir, "Kilnfile.lock"), []byte(kilnfileLock), 0o644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	run := func(args ...string) *gexec.Session {
+		cmd := exec.Command(pathToMain, args...)
+		cmd.Dir = tmpDir
+		session, err := gexec.Start(cmd, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(session).Should(gexec.Exit())
+		return session
+	}
+
+	When("the command succeeds", func() {
+		It("writes the report to stdout and the logs to stderr", func() {
+			session := run("--output", "json", "remove-release", "--kilnfile", filepath.Join(tmpDir, "Kilnfile"), "--name", "banana")
+
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(session.Out.Contents()).To(MatchJSON(`{
+				"command": "remove-release",
+				"success": true,
+				"changes": [{"kind": "release", "name": "banana", "from": "1.2.3"}]
+			}`))
+			Expect(session.Err).To(gbytes.Say("Removed banana"))
+		})
+	})
+
+	When("the command fails", func() {
+		It("writes the report to stdout and exits with status 1", func() {
+			session := run("--output", "json", "remove-release", "--kilnfile", filepath.Join(tmpDir, "Kilnfile"), "--name", "lemon")
+
+			Expect(session.ExitCode()).To(Equal(1))
+			Expect(session.Out.Contents()).To(MatchJSON(`{
+				"command": "remove-release",
+				"success": false,
+				"errors": [{"code": "error", "message": "no release named \"lemon\" exists in your Kilnfile or Kilnfile.lock"}]
+			}`))
+			Expect(session.Err).To(gbytes.Say(`no release named "lemon" exists`))
+		})
+	})
+
+	When("the command does not support --output json", func() {
+		It("writes an error report without running the command", func() {
+			session := run("--output", "json", "version")
+
+			Expect(session.ExitCode()).To(Equal(1))
+			Expect(session.Out.Contents()).To(MatchJSON(`{
+				"command": "version",
+				"success": false,
+				"errors": [{"code": "error", "message": "--output json is not supported by the version command"}]
+			}`))
+			Expect(session.Err).To(gbytes.Say("--output json is not supported by the version command"))
+		})
+	})
+})
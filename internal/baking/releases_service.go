@@ -4,13 +4,15 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sync"
 
 	"github.com/pivotal-cf/kiln/internal/builder"
 )
 
 type ReleasesService struct {
-	logger logger
-	reader partReader
+	logger      logger
+	reader      partReader
+	concurrency int
 }
 
 func NewReleasesService(logger logger, reader partReader) ReleasesService {
@@ -20,17 +22,29 @@ func NewReleasesService(logger logger, reader partReader) ReleasesService {
 	}
 }
 
+// WithConcurrency sets the number of release tarballs read at the same time. Without it tarballs are
+// read one at a time.
+func (s ReleasesService) WithConcurrency(concurrency int) ReleasesService {
+	s.concurrency = concurrency
+	return s
+}
+
 func (s ReleasesService) FromDirectories(directories []string) (map[string]interface{}, error) {
 	s.logger.Println("Reading release manifests...")
 
-	var releases []builder.Part
+	var tarballPaths []string
 	for _, directory := range directories {
-		newReleases, err := s.ReleasesInDirectory(directory)
+		paths, err := tarballsInDirectory(directory)
 		if err != nil {
 			return nil, err
 		}
 
-		releases = append(releases, newReleases...)
+		tarballPaths = append(tarballPaths, paths...)
+	}
+
+	releases, err := s.readReleases(tarballPaths)
+	if err != nil {
+		return nil, err
 	}
 
 	manifests := map[string]interface{}{}
@@ -42,6 +56,15 @@ func (s ReleasesService) FromDirectories(directories []string) (map[string]inter
 }
 
 func (s ReleasesService) ReleasesInDirectory(directoryPath string) ([]builder.Part, error) {
+	tarballPaths, err := tarballsInDirectory(directoryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.readReleases(tarballPaths)
+}
+
+func tarballsInDirectory(directoryPath string) ([]string, error) {
 	var tarballPaths []string
 
 	err := filepath.Walk(directoryPath, func(path string, _ os.FileInfo, err error) error {
@@ -55,19 +78,54 @@ func (s ReleasesService) ReleasesInDirectory(directoryPath string) ([]builder.Pa
 
 		return nil
 	})
-	if err != nil {
-		return nil, err
+
+	return tarballPaths, err
+}
+
+// readReleases reads the tarballs with up to s.concurrency workers. The releases are returned in the
+// order of tarballPaths and the returned error is the error of the first tarball that could not be read.
+func (s ReleasesService) readReleases(tarballPaths []string) ([]builder.Part, error) {
+	workerCount := s.concurrency
+	if workerCount < 1 {
+		workerCount = 1
+	}
+	if workerCount > len(tarballPaths) {
+		workerCount = len(tarballPaths)
 	}
 
+	type readResult struct {
+		part builder.Part
+		err  error
+	}
+
+	results := make([]readResult, len(tarballPaths))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workerCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				part, err := s.reader.Read(tarballPaths[index])
+				results[index] = readResult{part: part, err: err}
+			}
+		}()
+	}
+	for index := range tarballPaths {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
 	var releases []builder.Part
-	for _, tarballPath := range tarballPaths {
-		rel, err := s.reader.Read(tarballPath)
-		if err != nil {
-			return nil, err
+	for _, result := range results {
+		if result.err != nil {
+			return nil, result.err
 		}
 
-		releases = append(releases, rel)
+		releases = append(releases, result.part)
 	}
 
-	return releases, err
+	return releases, nil
 }
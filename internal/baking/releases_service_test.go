@@ -1,9 +1,17 @@
 package baking_test
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"strings"
+	"testing"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -82,6 +90,36 @@ var _ = Describe("ReleasesService", func() {
 			Expect(reader.ReadArgsForCall(1)).To(Equal(filepath.Join(tempDir, "some-release.tar.gz")))
 		})
 
+		Context("when tarballs are read concurrently", func() {
+			BeforeEach(func() {
+				service = service.WithConcurrency(4)
+			})
+
+			It("returns every release", func() {
+				reader.ReadStub = func(path string) (builder.Part, error) {
+					name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".tgz"), ".tar.gz")
+					return builder.Part{Name: name, Metadata: builder.ReleaseManifest{Name: name, File: filepath.Base(path)}}, nil
+				}
+
+				releases, err := service.FromDirectories([]string{tempDir})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(releases).To(Equal(map[string]interface{}{
+					"other-release": builder.ReleaseManifest{Name: "other-release", File: "other-release.tgz"},
+					"some-release":  builder.ReleaseManifest{Name: "some-release", File: "some-release.tar.gz"},
+				}))
+				Expect(reader.ReadCallCount()).To(Equal(2))
+			})
+
+			It("returns the error of the first tarball that could not be read", func() {
+				reader.ReadStub = func(path string) (builder.Part, error) {
+					return builder.Part{}, fmt.Errorf("failed to read %s", filepath.Base(path))
+				}
+
+				_, err := service.FromDirectories([]string{tempDir})
+				Expect(err).To(MatchError("failed to read other-release.tgz"))
+			})
+		})
+
 		Context("failure cases", func() {
 			Context("when there is a directory that does not exist", func() {
 				It("returns an error", func() {
@@ -188,3 +226,69 @@ var _ = Describe("ReleasesService", func() {
 		})
 	})
 })
+
+func BenchmarkReleasesService_FromDirectories(b *testing.B) {
+	const (
+		releaseCount = 8
+		packageSize  = 16 << 20
+	)
+	releasesDirectory := b.TempDir()
+	var totalSize int64
+	for i := 0; i < releaseCount; i++ {
+		tarballPath := filepath.Join(releasesDirectory, fmt.Sprintf("release-%d.tgz", i))
+		if err := writeBenchmarkReleaseTarball(tarballPath, fmt.Sprintf("name: release-%d\nversion: 1.2.3\n", i), packageSize); err != nil {
+			b.Fatal(err)
+		}
+		info, err := os.Stat(tarballPath)
+		if err != nil {
+			b.Fatal(err)
+		}
+		totalSize += info.Size()
+	}
+
+	for _, concurrency := range []int{1, 4} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			// the zero value reader does not cache so every iteration reads the tarballs
+			service := NewReleasesService(log.New(io.Discard, "", 0), builder.ReleaseManifestReader{}).WithConcurrency(concurrency)
+			b.SetBytes(totalSize)
+			for i := 0; i < b.N; i++ {
+				if _, err := service.FromDirectories([]string{releasesDirectory}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func writeBenchmarkReleaseTarball(tarballPath, releaseManifest string, packageSize int64) error {
+	f, err := os.Create(tarballPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	gw, err := gzip.NewWriterLevel(f, gzip.BestSpeed)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(gw)
+	if err := tw.WriteHeader(&tar.Header{Name: "./release.MF", Size: int64(len(releaseManifest)), Mode: 0o644}); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(tw, releaseManifest); err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: "./packages/some-package.tgz", Size: packageSize, Mode: 0o644}); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(tw, rand.New(rand.NewSource(1)), packageSize); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -96,7 +96,8 @@ func (ss StemcellService) FromKilnfile(kilnfilePath string) (map[string]interfac
 	}
 
 	stemcellCriteria := struct {
-		Metadata stemcellMetadata `yaml:"stemcell_criteria"`
+		Metadata  stemcellMetadata   `yaml:"stemcell_criteria"`
+		Stemcells []stemcellMetadata `yaml:"stemcells"`
 	}{}
 
 	lockFileContent, err := io.ReadAll(kilnfileLock)
@@ -109,11 +110,23 @@ func (ss StemcellService) FromKilnfile(kilnfilePath string) (map[string]interfac
 		return nil, err
 	}
 
-	stemcell := stemcellCriteria.Metadata
+	if len(stemcellCriteria.Stemcells) == 0 {
+		stemcell := stemcellCriteria.Metadata
 
-	stemcellManifest := map[string]interface{}{
-		stemcell.OperatingSystem: stemcellCriteria.Metadata,
+		stemcellManifest := map[string]interface{}{
+			stemcell.OperatingSystem: stemcellCriteria.Metadata,
+		}
+
+		return stemcellManifest, err
+	}
+
+	stemcellManifests := make(map[string]interface{}, len(stemcellCriteria.Stemcells))
+	for _, stemcell := range stemcellCriteria.Stemcells {
+		if _, ok := stemcellManifests[stemcell.OperatingSystem]; ok {
+			return nil, fmt.Errorf("more than one stemcell was found for OS '%s' in %s", stemcell.OperatingSystem, kilnfileLockBasename)
+		}
+		stemcellManifests[stemcell.OperatingSystem] = stemcell
 	}
 
-	return stemcellManifest, err
+	return stemcellManifests, nil
 }
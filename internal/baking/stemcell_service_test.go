@@ -7,6 +7,7 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
 
 	. "github.com/pivotal-cf/kiln/internal/baking"
 	"github.com/pivotal-cf/kiln/internal/baking/fakes"
@@ -156,4 +157,47 @@ var _ = Describe("StemcellService", func() {
 			})
 		})
 	})
+
+	Describe("FromKilnfile", func() {
+		var (
+			kilnfilePath string
+			service      StemcellService
+		)
+
+		BeforeEach(func() {
+			service = NewStemcellService(&fakes.Logger{}, &fakes.PartReader{})
+
+			tempDir, err := os.MkdirTemp("", "")
+			Expect(err).NotTo(HaveOccurred())
+			kilnfilePath = filepath.Join(tempDir, "Kilnfile")
+		})
+
+		AfterEach(func() {
+			_ = os.RemoveAll(filepath.Dir(kilnfilePath))
+		})
+
+		It("reads the stemcell criteria from the Kilnfile.lock", func() {
+			Expect(os.WriteFile(kilnfilePath+".lock", []byte("stemcell_criteria: {os: ubuntu-jammy, version: \"1.123\"}\n"), 0o644)).To(Succeed())
+
+			stemcells, err := service.FromKilnfile(kilnfilePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stemcells).To(HaveLen(1))
+			Expect(yaml.Marshal(stemcells["ubuntu-jammy"])).To(MatchYAML("{os: ubuntu-jammy, version: \"1.123\"}"))
+		})
+
+		Context("when the Kilnfile.lock has a list of stemcells", func() {
+			It("reads every stemcell", func() {
+				Expect(os.WriteFile(kilnfilePath+".lock", []byte(`stemcells:
+- {os: ubuntu-jammy, version: "1.123"}
+- {os: windows2019, version: "2019.60"}
+`), 0o644)).To(Succeed())
+
+				stemcells, err := service.FromKilnfile(kilnfilePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(stemcells).To(HaveLen(2))
+				Expect(yaml.Marshal(stemcells["ubuntu-jammy"])).To(MatchYAML("{os: ubuntu-jammy, version: \"1.123\"}"))
+				Expect(yaml.Marshal(stemcells["windows2019"])).To(MatchYAML("{os: windows2019, version: \"2019.60\"}"))
+			})
+		})
+	})
 })
@@ -3,8 +3,9 @@ package fakes
 
 import (
 	"io"
-	"io/fs"
+	"os"
 	"sync"
+	"time"
 )
 
 type Zipper struct {
@@ -20,12 +21,12 @@ type Zipper struct {
 	addReturnsOnCall map[int]struct {
 		result1 error
 	}
-	AddWithModeStub        func(string, io.Reader, fs.FileMode) error
+	AddWithModeStub        func(string, io.Reader, os.FileMode) error
 	addWithModeMutex       sync.RWMutex
 	addWithModeArgsForCall []struct {
 		arg1 string
 		arg2 io.Reader
-		arg3 fs.FileMode
+		arg3 os.FileMode
 	}
 	addWithModeReturns struct {
 		result1 error
@@ -54,6 +55,11 @@ type Zipper struct {
 	createFolderReturnsOnCall map[int]struct {
 		result1 error
 	}
+	SetModifiedStub        func(time.Time)
+	setModifiedMutex       sync.RWMutex
+	setModifiedArgsForCall []struct {
+		arg1 time.Time
+	}
 	SetWriterStub        func(io.Writer)
 	setWriterMutex       sync.RWMutex
 	setWriterArgsForCall []struct {
@@ -125,13 +131,13 @@ func (fake *Zipper) AddReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *Zipper) AddWithMode(arg1 string, arg2 io.Reader, arg3 fs.FileMode) error {
+func (fake *Zipper) AddWithMode(arg1 string, arg2 io.Reader, arg3 os.FileMode) error {
 	fake.addWithModeMutex.Lock()
 	ret, specificReturn := fake.addWithModeReturnsOnCall[len(fake.addWithModeArgsForCall)]
 	fake.addWithModeArgsForCall = append(fake.addWithModeArgsForCall, struct {
 		arg1 string
 		arg2 io.Reader
-		arg3 fs.FileMode
+		arg3 os.FileMode
 	}{arg1, arg2, arg3})
 	stub := fake.AddWithModeStub
 	fakeReturns := fake.addWithModeReturns
@@ -152,13 +158,13 @@ func (fake *Zipper) AddWithModeCallCount() int {
 	return len(fake.addWithModeArgsForCall)
 }
 
-func (fake *Zipper) AddWithModeCalls(stub func(string, io.Reader, fs.FileMode) error) {
+func (fake *Zipper) AddWithModeCalls(stub func(string, io.Reader, os.FileMode) error) {
 	fake.addWithModeMutex.Lock()
 	defer fake.addWithModeMutex.Unlock()
 	fake.AddWithModeStub = stub
 }
 
-func (fake *Zipper) AddWithModeArgsForCall(i int) (string, io.Reader, fs.FileMode) {
+func (fake *Zipper) AddWithModeArgsForCall(i int) (string, io.Reader, os.FileMode) {
 	fake.addWithModeMutex.RLock()
 	defer fake.addWithModeMutex.RUnlock()
 	argsForCall := fake.addWithModeArgsForCall[i]
@@ -302,6 +308,38 @@ func (fake *Zipper) CreateFolderReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *Zipper) SetModified(arg1 time.Time) {
+	fake.setModifiedMutex.Lock()
+	fake.setModifiedArgsForCall = append(fake.setModifiedArgsForCall, struct {
+		arg1 time.Time
+	}{arg1})
+	stub := fake.SetModifiedStub
+	fake.recordInvocation("SetModified", []interface{}{arg1})
+	fake.setModifiedMutex.Unlock()
+	if stub != nil {
+		fake.SetModifiedStub(arg1)
+	}
+}
+
+func (fake *Zipper) SetModifiedCallCount() int {
+	fake.setModifiedMutex.RLock()
+	defer fake.setModifiedMutex.RUnlock()
+	return len(fake.setModifiedArgsForCall)
+}
+
+func (fake *Zipper) SetModifiedCalls(stub func(time.Time)) {
+	fake.setModifiedMutex.Lock()
+	defer fake.setModifiedMutex.Unlock()
+	fake.SetModifiedStub = stub
+}
+
+func (fake *Zipper) SetModifiedArgsForCall(i int) time.Time {
+	fake.setModifiedMutex.RLock()
+	defer fake.setModifiedMutex.RUnlock()
+	argsForCall := fake.setModifiedArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *Zipper) SetWriter(arg1 io.Writer) {
 	fake.setWriterMutex.Lock()
 	fake.setWriterArgsForCall = append(fake.setWriterArgsForCall, struct {
@@ -345,6 +383,8 @@ func (fake *Zipper) Invocations() map[string][][]interface{} {
 	defer fake.closeMutex.RUnlock()
 	fake.createFolderMutex.RLock()
 	defer fake.createFolderMutex.RUnlock()
+	fake.setModifiedMutex.RLock()
+	defer fake.setModifiedMutex.RUnlock()
 	fake.setWriterMutex.RLock()
 	defer fake.setWriterMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
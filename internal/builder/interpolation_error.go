@@ -0,0 +1,240 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// InterpolationError is returned by the Interpolator when a template expression in a file fails.
+// It names the file and line of the expression, the expression and includes a snippet of the file.
+type InterpolationError struct {
+	File   string
+	Line   int
+	Column int // zero when the column is not known
+
+	Expression string // the failing expression without the $( ) delimiters
+	Snippet    string
+
+	// Stage is "parsing" or "rendering".
+	Stage string
+	Err   error
+}
+
+func (e *InterpolationError) Error() string {
+	var message strings.Builder
+	_, _ = fmt.Fprintf(&message, "failed when %s a template: %s:%d", e.Stage, e.File, e.Line)
+	if e.Column > 0 {
+		_, _ = fmt.Fprintf(&message, ":%d", e.Column)
+	}
+	message.WriteString(": ")
+	if e.Expression != "" {
+		message.WriteString(e.Expression + ": ")
+	}
+	message.WriteString(e.Err.Error())
+	if e.Snippet != "" {
+		message.WriteString("\n" + e.Snippet)
+	}
+	return message.String()
+}
+
+func (e *InterpolationError) Unwrap() error { return e.Err }
+
+// templateSource is the file a template was read from. When line is zero the template is the contents
+// of the file. Otherwise the template was re-marshalled from the part starting at line so expressions
+// are looked up in the file to find their position.
+type templateSource struct {
+	path string
+	line int
+}
+
+var (
+	parseErrorLocation = regexp.MustCompile(`^template: (.+?):(\d+): `)
+	execErrorLocation  = regexp.MustCompile(`^template: (.+?):(\d+):(\d+): executing ".*?" at <.*?>: `)
+	errorCallingHelper = regexp.MustCompile(`^error calling \w+: `)
+)
+
+// sourceMappedError returns an InterpolationError for err when the failing expression can be
+// located in a file. Otherwise it returns nil.
+func sourceMappedError(input InterpolateInput, name string, templateYAML []byte, source *templateSource, stage string, err error) *InterpolationError {
+	var interpolationError *InterpolationError
+	if errors.As(err, &interpolationError) {
+		// the error was already mapped in a nested template
+		return interpolationError
+	}
+
+	templateName, line, column, cause := templateErrorLocation(input, name, err)
+	if line == 0 || (templateName == name && source == nil) {
+		return nil
+	}
+
+	mapped := &InterpolationError{Stage: stage, Err: cause, Line: line}
+
+	var contents string
+	if templateName == name {
+		contents = string(templateYAML)
+		mapped.File = source.path
+	} else {
+		// the error is in a partial which is parsed as it is in its file
+		partial, ok := partialWithFile(input.Partials, templateName)
+		if !ok {
+			return nil
+		}
+		contents = string(partial.Template)
+		mapped.File = templateName
+	}
+
+	expression, expressionColumn := expressionAt(lineAt(contents, line), column)
+	if templateName == name && source.line > 0 {
+		file, readErr := os.ReadFile(source.path)
+		if readErr != nil {
+			return nil
+		}
+		contents = string(file)
+		mapped.Line, expressionColumn = findExpression(contents, expression, source.line)
+	}
+
+	mapped.Column = expressionColumn + 1
+	mapped.Expression = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(expression, "$("), ")"))
+	mapped.Snippet = snippet(contents, mapped.Line)
+
+	return mapped
+}
+
+// templateErrorLocation returns the name of the template, the line and column (-1 when not known)
+// of the innermost template error in err and the error that caused it.
+func templateErrorLocation(input InterpolateInput, name string, err error) (string, int, int, error) {
+	var execError template.ExecError
+	if errors.As(err, &execError) {
+		// partials are executed with ExecuteTemplate from the include helper so the innermost error
+		// of the templates parsed together is the most precise
+		for {
+			var inner template.ExecError
+			if !errors.As(errors.Unwrap(execError.Err), &inner) {
+				break
+			}
+			if _, ok := partialWithFile(input.Partials, inner.Name); inner.Name != name && !ok {
+				break
+			}
+			execError = inner
+		}
+
+		match := execErrorLocation.FindStringSubmatch(execError.Err.Error())
+		if match == nil {
+			return "", 0, 0, err
+		}
+		line, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		cause := errors.Unwrap(execError.Err)
+		if cause == nil {
+			cause = errors.New(strings.TrimPrefix(execError.Err.Error(), match[0]))
+		} else if helper := errorCallingHelper.FindString(strings.TrimPrefix(execError.Err.Error(), match[0])); helper != "" {
+			cause = fmt.Errorf("%s%w", helper, cause)
+		}
+		return match[1], line, column, cause
+	}
+
+	message := err.Error()
+	match := parseErrorLocation.FindStringSubmatch(message)
+	if match == nil {
+		return "", 0, 0, err
+	}
+	line, _ := strconv.Atoi(match[2])
+	return match[1], line, -1, errors.New(strings.TrimPrefix(message, match[0]))
+}
+
+func partialWithFile(partials map[string]Partial, file string) (Partial, bool) {
+	for _, partial := range partials {
+		if partial.File == file {
+			return partial, true
+		}
+	}
+	return Partial{}, false
+}
+
+func lineAt(contents string, line int) string {
+	lines := strings.Split(contents, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return lines[line-1]
+}
+
+// expressionAt returns the template expression in line containing column, or the first expression
+// in line when column is negative, and the index it starts at. The index is -1 when there is none.
+func expressionAt(line string, column int) (string, int) {
+	start := strings.Index(line, "$(")
+	if column >= 0 && column < len(line) {
+		if i := strings.LastIndex(line[:column+1], "$("); i >= 0 {
+			start = i
+		}
+	}
+	if start < 0 {
+		return "", -1
+	}
+	quote := rune(0)
+	for i, c := range line[start+2:] {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '`'):
+			quote = c
+		case quote == 0 && c == ')':
+			return line[start : start+2+i+1], start
+		}
+	}
+	return line[start:], start
+}
+
+// findExpression returns the line and column of expression in contents looking from fromLine first.
+// The line is fromLine and the column is -1 when the expression is not found.
+func findExpression(contents, expression string, fromLine int) (int, int) {
+	if expression == "" {
+		return fromLine, -1
+	}
+	lines := strings.Split(contents, "\n")
+	search := func(from int) (int, int, bool) {
+		for i := from; i < len(lines); i++ {
+			if column := strings.Index(lines[i], expression); column >= 0 {
+				return i + 1, column, true
+			}
+		}
+		return 0, 0, false
+	}
+	if line, column, ok := search(fromLine - 1); ok {
+		return line, column
+	}
+	if line, column, ok := search(0); ok {
+		return line, column
+	}
+	return fromLine, -1
+}
+
+// snippet returns the line in contents with the lines around it.
+func snippet(contents string, line int) string {
+	lines := strings.Split(strings.TrimSuffix(contents, "\n"), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	first, last := line-1, line+1
+	if first < 1 {
+		first = 1
+	}
+	if last > len(lines) {
+		last = len(lines)
+	}
+	width := len(strconv.Itoa(last))
+	var out strings.Builder
+	for n := first; n <= last; n++ {
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		_, _ = fmt.Fprintf(&out, "%s %*d | %s\n", marker, width, n, lines[n-1])
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
 
 	yamlConverter "github.com/ghodss/yaml"
+	"github.com/masterminds/sprig"
 	"gopkg.in/yaml.v2"
 )
 
@@ -29,6 +31,10 @@ const (
 	// of the tile function during interpolation. When it is not set, the function returns
 	// an error.
 	TileNameVariable = "tile_name"
+
+	// maxIncludeDepth limits how deeply partials may include other partials so
+	// a partial including itself fails instead of overflowing the stack.
+	maxIncludeDepth = 100
 )
 
 type Interpolator struct{}
@@ -46,16 +52,28 @@ type InterpolateInput struct {
 	Jobs               map[string]interface{}
 	PropertyBlueprints map[string]interface{}
 	RuntimeConfigs     map[string]interface{}
+	Partials           map[string]Partial
 	StubReleases       bool
 	MetadataGitSHA     func() (string, error)
 }
 
+// Partial is a reusable template fragment rendered with the include and partial helpers.
+type Partial struct {
+	// File is the path of the partial and is used as the template name so errors report it.
+	File     string
+	Template []byte
+}
+
 func NewInterpolator() Interpolator {
 	return Interpolator{}
 }
 
 func (i Interpolator) Interpolate(input InterpolateInput, name string, templateYAML []byte) ([]byte, error) {
-	interpolatedYAML, err := i.interpolate(input, name, templateYAML)
+	var source *templateSource
+	if name != "" {
+		source = &templateSource{path: name}
+	}
+	interpolatedYAML, err := i.interpolate(input, name, templateYAML, source)
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +94,20 @@ func (i Interpolator) functions(input InterpolateInput) template.FuncMap {
 		return i.interpolateValueIntoYAML(input, "", input.Version)
 	}
 
-	return template.FuncMap{
+	// the kiln helpers replace sprig functions with the same name
+	functions := sprig.TxtFuncMap()
+	// metadata should only depend on the tile sources and flags, so the functions that read the
+	// environment, the clock or DNS, or that generate random values, keys or passwords are removed
+	for _, name := range []string{
+		"env", "expandenv", "getHostByName",
+		"now", "ago", "date", "dateInZone", "date_in_zone", "dateModify", "date_modify", "htmlDate", "htmlDateInZone",
+		"randAlpha", "randAlphaNum", "randAscii", "randNumeric", "shuffle", "uuidv4",
+		"genPrivateKey", "genCA", "genSelfSignedCert", "genSignedCert", "derivePassword", "encryptAES",
+	} {
+		delete(functions, name)
+	}
+
+	helpers := template.FuncMap{
 		"bosh_variable": func(key string) (string, error) {
 			if input.BOSHVariables == nil {
 				return "", errors.New("--bosh-variables-directory must be specified")
@@ -240,38 +271,165 @@ func (i Interpolator) functions(input InterpolateInput) template.FuncMap {
 
 			return string(output), nil
 		},
+		"releases_matching": func(regex string) (string, error) {
+			if input.ReleaseManifests == nil {
+				return "", errors.New("missing ReleaseManifests")
+			}
+			re, err := regexp.Compile(regex)
+			if err != nil {
+				return "", err
+			}
+
+			var names []string
+			for name := range input.ReleaseManifests {
+				if re.MatchString(name) {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+
+			releases := make([]interface{}, 0, len(names))
+			for _, name := range names {
+				releases = append(releases, input.ReleaseManifests[name])
+			}
+			return i.interpolateValueIntoYAML(input, regex, releases)
+		},
+		"toYaml": func(value interface{}) (string, error) {
+			out, err := yaml.Marshal(value)
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSuffix(string(out), "\n"), nil
+		},
+		"fromYaml": func(in string) (interface{}, error) {
+			// converting through JSON gives maps with string keys which the sprig functions expect
+			out, err := yamlConverter.YAMLToJSON([]byte(in))
+			if err != nil {
+				return nil, fmt.Errorf("could not YAML unmarshal %q: %s", in, err)
+			}
+			var value interface{}
+			err = json.Unmarshal(out, &value)
+			if err != nil {
+				return nil, fmt.Errorf("could not YAML unmarshal %q: %s", in, err) // NOTE: this cannot happen because out was converted from YAML
+			}
+			return value, nil
+		},
 		"tile": tileFunc(input.Variables),
+
+		// include and partial are replaced in interpolate where the partial templates are parsed
+		"include": func(string, ...interface{}) (string, error) {
+			return "", errors.New("include is not available")
+		},
+		"partial": func(string, ...interface{}) (string, error) {
+			return "", errors.New("partial is not available")
+		},
+	}
+	for name, fn := range helpers {
+		functions[name] = fn
 	}
+	return functions
 }
 
-func (i Interpolator) interpolate(input InterpolateInput, name string, templateYAML []byte) ([]byte, error) {
-	t, err := template.New(name).
+// partialFunctions returns the include and partial helpers. The include helper renders a partial as text
+// to be used with indent or nindent, and the partial helper renders it inline like the other helpers.
+func (i Interpolator) partialFunctions(input InterpolateInput, t *template.Template) template.FuncMap {
+	depth := 0
+	include := func(name string, data ...interface{}) (string, error) {
+		partial, ok := input.Partials[name]
+		if !ok {
+			return "", fmt.Errorf("could not find partial with name '%s'", name)
+		}
+		if depth >= maxIncludeDepth {
+			return "", fmt.Errorf("partial '%s' is included more than %d times recursively", name, maxIncludeDepth)
+		}
+		depth++
+		defer func() { depth-- }()
+
+		var dot interface{} = input.Variables
+		if len(data) > 0 {
+			dot = data[0]
+		}
+		var buffer bytes.Buffer
+		err := t.ExecuteTemplate(&buffer, partial.File, dot)
+		if err != nil {
+			return "", err
+		}
+		return buffer.String(), nil
+	}
+	return template.FuncMap{
+		"include": include,
+		"partial": func(name string, data ...interface{}) (string, error) {
+			out, err := include(name, data...)
+			if err != nil {
+				return "", err
+			}
+			inlinedYAML, err := i.yamlMarshalOneLine([]byte(out))
+			if err != nil {
+				return "", fmt.Errorf("partial '%s' is not valid YAML: %s", name, err)
+			}
+			return string(inlinedYAML), nil
+		},
+	}
+}
+
+func (i Interpolator) interpolate(input InterpolateInput, name string, templateYAML []byte, source *templateSource) ([]byte, error) {
+	t := template.New(name).
 		Funcs(i.functions(input)).
 		Delims("$(", ")").
-		Option("missingkey=error").
-		Parse(string(templateYAML))
+		Option("missingkey=error")
+	t.Funcs(i.partialFunctions(input, t))
+
+	for _, partialName := range sortedPartialNames(input.Partials) {
+		partial := input.Partials[partialName]
+		_, err := t.New(partial.File).Parse(string(partial.Template))
+		if err != nil {
+			return nil, fmt.Errorf("failed when parsing a %w", err)
+		}
+	}
+
+	t, err := t.Parse(string(templateYAML))
 	if err != nil {
+		if mapped := sourceMappedError(input, name, templateYAML, source, "parsing", err); mapped != nil {
+			return nil, mapped
+		}
 		return nil, fmt.Errorf("failed when parsing a %w", err)
 	}
 
 	var buffer bytes.Buffer
 	err = t.Execute(&buffer, input.Variables)
 	if err != nil {
+		if mapped := sourceMappedError(input, name, templateYAML, source, "rendering", err); mapped != nil {
+			return nil, mapped
+		}
 		return nil, fmt.Errorf("failed when rendering a %w", err)
 	}
 
 	return buffer.Bytes(), nil
 }
 
+func sortedPartialNames(partials map[string]Partial) []string {
+	names := make([]string, 0, len(partials))
+	for name := range partials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (i Interpolator) interpolateValueIntoYAML(input InterpolateInput, name string, val interface{}) (string, error) {
 	initialYAML, err := yaml.Marshal(val)
 	if err != nil {
 		return "", err // should never happen
 	}
 
-	interpolatedYAML, err := i.interpolate(input, name, initialYAML)
+	var source *templateSource
+	if metadata, ok := val.(MetadataSource); ok && metadata.Path != "" {
+		source = &templateSource{path: metadata.Path, line: metadata.Line}
+	}
+
+	interpolatedYAML, err := i.interpolate(input, name, initialYAML, source)
 	if err != nil {
-		return "", fmt.Errorf("unable to interpolate value: %s", err)
+		return "", fmt.Errorf("unable to interpolate value: %w", err)
 	}
 
 	inlinedYAML, err := i.yamlMarshalOneLine(interpolatedYAML)
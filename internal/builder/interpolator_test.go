@@ -2,7 +2,12 @@ package builder_test
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
@@ -388,6 +393,29 @@ some_runtime_configs:
 		})
 	})
 
+	Context("when the release has a SHA256 sum", func() {
+		It("does not add the sum to the metadata", func() {
+			input.ReleaseManifests = map[string]interface{}{
+				"some-release": builder.ReleaseManifest{
+					Name:    "some-release",
+					Version: "1.2.3",
+					File:    "some-release-1.2.3.tgz",
+					SHA1:    "123abc",
+					SHA256:  "456def",
+				},
+			}
+			interpolator := builder.NewInterpolator()
+			interpolatedYAML, err := interpolator.Interpolate(input, "", []byte(`releases: [$(release "some-release")]`))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(interpolatedYAML).To(HelpfullyMatchYAML(`releases:
+- name: some-release
+  file: some-release-1.2.3.tgz
+  sha1: 123abc
+  version: 1.2.3`))
+		})
+	})
+
 	Context("when release tgz file does not exist and stub releases is true", func() {
 		It("creates stub values for file, sha1, and version", func() {
 			interpolator := builder.NewInterpolator()
@@ -403,6 +431,258 @@ some_runtime_configs:
 		})
 	})
 
+	Context("when sprig functions are used", func() {
+		It("interpolates them", func() {
+			interpolatedYAML, err := interpolator.Interpolate(input, "", []byte(`label: $( index . "some-variable" | upper | quote )`))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(interpolatedYAML).To(HelpfullyMatchYAML(`label: SOME-VALUE`))
+		})
+
+		It("does not read the environment", func() {
+			_, err := interpolator.Interpolate(input, "", []byte(`home: $( env "HOME" )`))
+
+			Expect(err).To(MatchError(ContainSubstring(`function "env" not defined`)))
+		})
+
+		It("does not have functions that make the metadata change between bakes", func() {
+			for _, expression := range []string{
+				`$( now )`,
+				`$( ago 0 )`,
+				`$( date "2006-01-02" 0 )`,
+				`$( dateInZone "2006-01-02" 0 "UTC" )`,
+				`$( date_in_zone "2006-01-02" 0 "UTC" )`,
+				`$( dateModify "1h" 0 )`,
+				`$( date_modify "1h" 0 )`,
+				`$( htmlDate 0 )`,
+				`$( htmlDateInZone 0 "UTC" )`,
+				`$( randAlpha 8 )`,
+				`$( randAlphaNum 8 )`,
+				`$( randAscii 8 )`,
+				`$( randNumeric 8 )`,
+				`$( shuffle "banana" )`,
+				`$( uuidv4 )`,
+				`$( genPrivateKey "rsa" )`,
+				`$( genCA "ca" 365 )`,
+				`$( genSelfSignedCert "cn" nil nil 365 )`,
+				`$( genSignedCert "cn" nil nil 365 "ca" )`,
+				`$( derivePassword 1 "long" "password" "user" "example.com" )`,
+				`$( encryptAES "password" "plaintext" )`,
+				`$( getHostByName "example.com" )`,
+			} {
+				name := strings.Fields(strings.TrimPrefix(expression, "$( "))[0]
+				_, err := interpolator.Interpolate(input, "", []byte("value: "+expression))
+				Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf(`function %q not defined`, name))), expression)
+			}
+		})
+	})
+
+	Context("when toYaml and fromYaml are used", func() {
+		It("converts between values and YAML", func() {
+			interpolatedYAML, err := interpolator.Interpolate(input, "", []byte(`
+$( $size := fromYaml "{instances: 3}" )size: $( $size.instances )
+network:
+$( fromYaml "{name: some-network, azs: [a, b]}" | toYaml | indent 2 )
+`))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(interpolatedYAML).To(HelpfullyMatchYAML(`
+size: 3
+network:
+  name: some-network
+  azs: [a, b]
+`))
+		})
+	})
+
+	Context("when partials are provided", func() {
+		BeforeEach(func() {
+			input.Partials = map[string]builder.Partial{
+				"network": {
+					File:     "partials/network.yml",
+					Template: []byte("name: $( index . \"some-variable\" )\nazs: [a, b]\n"),
+				},
+				"greeting": {
+					File:     "partials/greeting.yml",
+					Template: []byte(`hello $( .name )`),
+				},
+			}
+		})
+
+		It("renders them with include and partial", func() {
+			interpolatedYAML, err := interpolator.Interpolate(input, "", []byte(`
+inlined: $( partial "network" )
+indented:
+$( include "network" . | indent 2 )
+greeting: $( dict "name" "world" | include "greeting" | quote )
+`))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(interpolatedYAML).To(HelpfullyMatchYAML(`
+inlined:
+  name: some-value
+  azs: [a, b]
+indented:
+  name: some-value
+  azs: [a, b]
+greeting: hello world
+`))
+		})
+
+		It("allows partials inside forms", func() {
+			input.FormTypes = map[string]interface{}{
+				"some-form": builder.Metadata{
+					"name":       "some-form",
+					"properties": `$( partial "network" )`,
+				},
+			}
+			interpolatedYAML, err := interpolator.Interpolate(input, "", []byte(`forms: [$( form "some-form" )]`))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(interpolatedYAML).To(HelpfullyMatchYAML(`
+forms:
+- name: some-form
+  properties:
+    name: some-value
+    azs: [a, b]
+`))
+		})
+
+		It("reports the partial file when it fails to parse", func() {
+			input.Partials["broken"] = builder.Partial{File: "partials/broken.yml", Template: []byte("name: x\nvalue: $( nope )\n")}
+
+			_, err := interpolator.Interpolate(input, "", []byte(`value: $( partial "network" )`))
+
+			Expect(err).To(MatchError(ContainSubstring(`partials/broken.yml:2: function "nope" not defined`)))
+		})
+
+		It("returns an error when the partial does not exist", func() {
+			_, err := interpolator.Interpolate(input, "", []byte(`value: $( partial "missing" )`))
+
+			Expect(err).To(MatchError(ContainSubstring("could not find partial with name 'missing'")))
+		})
+
+		It("returns an error when a partial includes itself", func() {
+			input.Partials["loop"] = builder.Partial{File: "partials/loop.yml", Template: []byte(`$( include "loop" . )`)}
+
+			_, err := interpolator.Interpolate(input, "", []byte(`value: $( partial "loop" )`))
+
+			Expect(err).To(MatchError(ContainSubstring("partial 'loop' is included more than 100 times recursively")))
+		})
+	})
+
+	Context("when releases_matching is used", func() {
+		BeforeEach(func() {
+			input.ReleaseManifests = map[string]interface{}{
+				"some-release":  builder.ReleaseManifest{Name: "some-release", Version: "1.2.3", File: "some-release-1.2.3.tgz", SHA1: "some-release-sha"},
+				"some-other":    builder.ReleaseManifest{Name: "some-other", Version: "2.3.4", File: "some-other-2.3.4.tgz", SHA1: "some-other-sha"},
+				"other-release": builder.ReleaseManifest{Name: "other-release", Version: "3.4.5", File: "other-release-3.4.5.tgz", SHA1: "other-release-sha"},
+			}
+		})
+
+		It("returns the releases with matching names sorted by name", func() {
+			interpolatedYAML, err := interpolator.Interpolate(input, "", []byte(`releases: $( releases_matching "^some-" )`))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(interpolatedYAML).To(HelpfullyMatchYAML(`
+releases:
+- name: some-other
+  file: some-other-2.3.4.tgz
+  sha1: some-other-sha
+  version: 2.3.4
+- name: some-release
+  file: some-release-1.2.3.tgz
+  sha1: some-release-sha
+  version: 1.2.3
+`))
+		})
+
+		It("returns an error with the template line when the regex is invalid", func() {
+			_, err := interpolator.Interpolate(input, "base.yml", []byte("name: x\nreleases: $( releases_matching \"(\" )"))
+
+			Expect(err).To(MatchError(And(ContainSubstring("base.yml:2:"), ContainSubstring("missing closing )"))))
+		})
+	})
+
+	Context("when a template expression fails", func() {
+		var tempDir string
+
+		BeforeEach(func() {
+			var err error
+			tempDir, err = os.MkdirTemp("", "interpolator")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(tempDir)).To(Succeed())
+		})
+
+		It("reports the position in the metadata file", func() {
+			_, err := interpolator.Interpolate(input, "base.yml", []byte("name: some-name\nlabel: $( variable \"some-missing-variable\" )\nversion: 1.2.3\n"))
+
+			var interpolationError *builder.InterpolationError
+			Expect(errors.As(err, &interpolationError)).To(BeTrue())
+			Expect(interpolationError.File).To(Equal("base.yml"))
+			Expect(interpolationError.Line).To(Equal(2))
+			Expect(interpolationError.Column).To(Equal(8))
+			Expect(interpolationError.Expression).To(Equal(`variable "some-missing-variable"`))
+			Expect(err).To(MatchError(`failed when rendering a template: base.yml:2:8: variable "some-missing-variable": error calling variable: could not find variable with key 'some-missing-variable'
+  1 | name: some-name
+> 2 | label: $( variable "some-missing-variable" )
+  3 | version: 1.2.3`))
+		})
+
+		It("reports the position of parse errors in the metadata file", func() {
+			_, err := interpolator.Interpolate(input, "base.yml", []byte("name: some-name\nlabel: $( nope )\n"))
+
+			Expect(err).To(MatchError(ContainSubstring(`failed when parsing a template: base.yml:2:8: nope: function "nope" not defined`)))
+		})
+
+		It("reports the position in the part file the metadata was read from", func() {
+			partPath := filepath.Join(tempDir, "ha_proxy.yml")
+			Expect(os.WriteFile(partPath, []byte(`---
+- name: some-property
+  type: string
+- name: some-other-property
+  type: string
+  label: $( form "some-missing-form" )
+`), 0o644)).To(Succeed())
+			input.PropertyBlueprints = map[string]interface{}{
+				"some-other-property": builder.MetadataSource{
+					Path: partPath,
+					Line: 4,
+					Metadata: map[interface{}]interface{}{
+						"name":  "some-other-property",
+						"type":  "string",
+						"label": `$( form "some-missing-form" )`,
+					},
+				},
+			}
+
+			_, err := interpolator.Interpolate(input, "base.yml", []byte("properties:\n- $( property \"some-other-property\" )\n"))
+
+			var interpolationError *builder.InterpolationError
+			Expect(errors.As(err, &interpolationError)).To(BeTrue())
+			Expect(interpolationError.File).To(Equal(partPath))
+			Expect(interpolationError.Line).To(Equal(6))
+			Expect(interpolationError.Column).To(Equal(10))
+			Expect(interpolationError.Expression).To(Equal(`form "some-missing-form"`))
+			Expect(interpolationError.Snippet).To(Equal(`  5 |   type: string
+> 6 |   label: $( form "some-missing-form" )`))
+			Expect(err).To(MatchError(ContainSubstring("could not find form with key 'some-missing-form'")))
+		})
+
+		It("reports the position in a partial", func() {
+			input.Partials = map[string]builder.Partial{
+				"network": {File: "partials/network.yml", Template: []byte("name: some-network\nazs: $( variable \"some-missing-azs\" )\n")},
+			}
+
+			_, err := interpolator.Interpolate(input, "base.yml", []byte("network: $( partial \"network\" )\n"))
+
+			Expect(err).To(MatchError(ContainSubstring(`failed when rendering a template: partials/network.yml:2:6: variable "some-missing-azs": error calling variable: could not find variable with key 'some-missing-azs'`)))
+		})
+	})
+
 	Context("failure cases", func() {
 		Context("when the requested form name is not found", func() {
 			It("returns an error", func() {
@@ -7,9 +7,11 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
 
 	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
 )
 
 type MetadataPartsDirectoryReader struct {
@@ -21,6 +23,22 @@ type Part struct {
 	File     string
 	Name     string
 	Metadata interface{}
+
+	// Path and Line locate a part read by a MetadataPartsDirectoryReader in its file.
+	Path string
+	Line int
+}
+
+// MetadataSource is the metadata of a part along with the file and line it was read from. It marshals
+// to YAML as the metadata, and the Interpolator uses the file and line to report errors in the part file.
+type MetadataSource struct {
+	Path     string
+	Line     int
+	Metadata interface{}
+}
+
+func (source MetadataSource) MarshalYAML() (interface{}, error) {
+	return source.Metadata, nil
 }
 
 func NewMetadataPartsDirectoryReader() MetadataPartsDirectoryReader {
@@ -61,7 +79,7 @@ func (r MetadataPartsDirectoryReader) ParseMetadataTemplates(directories []strin
 
 	manifests := map[string]interface{}{}
 	for _, rel := range releases {
-		manifests[rel.Name] = rel.Metadata
+		manifests[rel.Name] = MetadataSource{Path: rel.Path, Line: rel.Line, Metadata: rel.Metadata}
 	}
 
 	return manifests, nil
@@ -108,12 +126,17 @@ func (r MetadataPartsDirectoryReader) readMetadataRecursivelyFromDir(p string, v
 			data = buf.Bytes()
 		}
 
+		// the nodes are only used for the line numbers of the parts
+		var document yamlv3.Node
+		_ = yamlv3.Unmarshal(data, &document)
+		node := documentContent(&document)
+
 		var vars interface{}
 		if r.topLevelKey != "" {
 			var fileVars map[string]interface{}
 			err = yaml.Unmarshal(data, &fileVars)
 			if err != nil {
-				return fmt.Errorf("cannot unmarshal '%s': %s", filePath, err)
+				return yamlError(filePath, err)
 			}
 
 			var ok bool
@@ -121,16 +144,17 @@ func (r MetadataPartsDirectoryReader) readMetadataRecursivelyFromDir(p string, v
 			if !ok {
 				return fmt.Errorf("not a %s file: %q", r.topLevelKey, filePath)
 			}
+			node = mappingValue(node, r.topLevelKey)
 		} else {
 			err = yaml.Unmarshal(data, &vars)
 			if err != nil {
-				return fmt.Errorf("cannot unmarshal '%s': %s", filePath, err)
+				return yamlError(filePath, err)
 			}
 		}
 
-		parts, err = r.readMetadataIntoParts(path.Base(filePath), vars, parts)
+		parts, err = r.readMetadataIntoParts(filePath, vars, node, parts)
 		if err != nil {
-			return fmt.Errorf("file '%s' with top-level key '%s' has an invalid format: %s", filePath, r.topLevelKey, err)
+			return err
 		}
 
 		return nil
@@ -139,30 +163,35 @@ func (r MetadataPartsDirectoryReader) readMetadataRecursivelyFromDir(p string, v
 	return parts, err
 }
 
-func (r MetadataPartsDirectoryReader) readMetadataIntoParts(fileName string, vars interface{}, parts []Part) ([]Part, error) {
+func (r MetadataPartsDirectoryReader) readMetadataIntoParts(filePath string, vars interface{}, node *yamlv3.Node, parts []Part) ([]Part, error) {
+	fileName := path.Base(filePath)
 	switch v := vars.(type) {
 	case []interface{}:
-		for _, item := range v {
+		for index, item := range v {
+			itemNode := sequenceItem(node, index)
+
 			i, ok := item.(map[interface{}]interface{})
 			if !ok {
-				return []Part{}, fmt.Errorf("metadata item '%v' must be a map", item)
+				return []Part{}, r.invalidFormatError(filePath, itemNode, fmt.Errorf("metadata item '%v' must be a map", item))
 			}
 
 			part, err := r.buildPartFromMetadata(i, fileName)
 			if err != nil {
-				return []Part{}, err
+				return []Part{}, r.invalidFormatError(filePath, itemNode, err)
 			}
+			part.Path, part.Line = filePath, nodeLine(itemNode)
 
 			parts = append(parts, part)
 		}
 	case map[interface{}]interface{}:
 		part, err := r.buildPartFromMetadata(v, fileName)
 		if err != nil {
-			return []Part{}, err
+			return []Part{}, r.invalidFormatError(filePath, node, err)
 		}
+		part.Path, part.Line = filePath, nodeLine(node)
 		parts = append(parts, part)
 	default:
-		return []Part{}, fmt.Errorf("expected either slice or map value")
+		return []Part{}, r.invalidFormatError(filePath, node, fmt.Errorf("expected either slice or map value"))
 	}
 
 	return parts, nil
@@ -181,6 +210,61 @@ func (r MetadataPartsDirectoryReader) buildPartFromMetadata(metadata map[interfa
 	return Part{File: legacyFilename, Name: name, Metadata: metadata}, nil
 }
 
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): `)
+
+// yamlError adds the file and line to YAML syntax errors.
+func yamlError(filePath string, err error) error {
+	if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+		return fmt.Errorf("%s:%s: cannot unmarshal '%s': %s", filePath, match[1], filePath, err)
+	}
+	return fmt.Errorf("cannot unmarshal '%s': %s", filePath, err)
+}
+
+// invalidFormatError reports err at the line and column of node when it is known.
+func (r MetadataPartsDirectoryReader) invalidFormatError(filePath string, node *yamlv3.Node, err error) error {
+	position := filePath
+	if node != nil {
+		position = fmt.Sprintf("%s:%d:%d", filePath, node.Line, node.Column)
+	}
+	if r.topLevelKey == "" {
+		return fmt.Errorf("%s: file has an invalid format: %w", position, err)
+	}
+	return fmt.Errorf("%s: file with top-level key '%s' has an invalid format: %w", position, r.topLevelKey, err)
+}
+
+func nodeLine(node *yamlv3.Node) int {
+	if node == nil {
+		return 0
+	}
+	return node.Line
+}
+
+func documentContent(document *yamlv3.Node) *yamlv3.Node {
+	if document.Kind != yamlv3.DocumentNode || len(document.Content) == 0 {
+		return nil
+	}
+	return document.Content[0]
+}
+
+func mappingValue(node *yamlv3.Node, key string) *yamlv3.Node {
+	if node == nil || node.Kind != yamlv3.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func sequenceItem(node *yamlv3.Node, index int) *yamlv3.Node {
+	if node == nil || node.Kind != yamlv3.SequenceNode || index >= len(node.Content) {
+		return nil
+	}
+	return node.Content[index]
+}
+
 func (r MetadataPartsDirectoryReader) orderWithOrderFromFile(path string, parts []Part) ([]Part, error) {
 	orderPath := filepath.Join(path, "_order.yml")
 	f, err := os.Open(orderPath)
@@ -6,6 +6,8 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf-experimental/gomegamatchers"
+	"gopkg.in/yaml.v2"
 
 	"github.com/pivotal-cf/kiln/internal/builder"
 )
@@ -59,6 +61,8 @@ type: password
 						"name": "variable-1",
 						"type": "certificate",
 					},
+					Path: filepath.Join(tempDir, "vars-file-1.yml"),
+					Line: 2,
 				},
 				{
 					File: "vars-file-1.yml",
@@ -67,6 +71,8 @@ type: password
 						"name": "variable-2",
 						"type": "user",
 					},
+					Path: filepath.Join(tempDir, "vars-file-1.yml"),
+					Line: 4,
 				},
 				{
 					File: "vars-file-2.yml",
@@ -75,6 +81,8 @@ type: password
 						"name": "variable-3",
 						"type": "password",
 					},
+					Path: filepath.Join(tempDir, "vars-file-2.yml"),
+					Line: 2,
 				},
 			}))
 		})
@@ -107,6 +115,16 @@ type: password
 			})
 		})
 
+		Context("when a yaml file has a syntax error", func() {
+			It("returns an error with the file and line", func() {
+				err := os.WriteFile(filepath.Join(tempDir, "not-valid-yaml.yml"), []byte("---\n- name: some-name\n  type: string\n  - oops: : \n"), 0o755)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = reader.Read(tempDir)
+				Expect(err).To(MatchError(HavePrefix(filepath.Join(tempDir, "not-valid-yaml.yml") + ":3: cannot unmarshal")))
+			})
+		})
+
 		Context("when file contains an array item without a name", func() {
 			BeforeEach(func() {
 				err := os.WriteFile(filepath.Join(tempDir, "vars-file-1.yml"), []byte(`[{foo: bar}]`), 0o755)
@@ -134,6 +152,45 @@ type: password
 		})
 	})
 
+	Describe("ParseMetadataTemplates", func() {
+		It("returns the metadata with the file and line of each part", func() {
+			err := os.WriteFile(filepath.Join(tempDir, "vars-file-1.yml"), []byte(`---
+variables:
+- name: variable-1
+  type: certificate
+- name: variable-2
+  type: $( tile )
+`), 0o755)
+			Expect(err).ToNot(HaveOccurred())
+
+			reader = builder.NewMetadataPartsDirectoryReaderWithTopLevelKey("variables")
+			metadata, err := reader.ParseMetadataTemplates([]string{tempDir}, map[string]interface{}{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata).To(Equal(map[string]interface{}{
+				"variable-1": builder.MetadataSource{
+					Path: filepath.Join(tempDir, "vars-file-1.yml"),
+					Line: 3,
+					Metadata: map[interface{}]interface{}{
+						"name": "variable-1",
+						"type": "certificate",
+					},
+				},
+				"variable-2": builder.MetadataSource{
+					Path: filepath.Join(tempDir, "vars-file-1.yml"),
+					Line: 5,
+					Metadata: map[interface{}]interface{}{
+						"name": "variable-2",
+						"type": "$( tile )",
+					},
+				},
+			}))
+
+			out, err := yaml.Marshal(metadata["variable-1"])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(HelpfullyMatchYAML("{name: variable-1, type: certificate}"))
+		})
+	})
+
 	Context("when a top-level key is specified", func() {
 		BeforeEach(func() {
 			err := os.WriteFile(filepath.Join(tempDir, "_order.yml"), []byte(`---
@@ -175,6 +232,8 @@ variables:
 							"name": "variable-1",
 							"type": "certificate",
 						},
+						Path: filepath.Join(tempDir, "vars-file-1.yml"),
+						Line: 3,
 					},
 					{
 						File: "vars-file-1.yml",
@@ -183,6 +242,8 @@ variables:
 							"name": "variable-2",
 							"type": "user",
 						},
+						Path: filepath.Join(tempDir, "vars-file-1.yml"),
+						Line: 5,
 					},
 					{
 						File: "vars-file-2.yml",
@@ -191,6 +252,8 @@ variables:
 							"name": "variable-3",
 							"type": "password",
 						},
+						Path: filepath.Join(tempDir, "vars-file-2.yml"),
+						Line: 3,
 					},
 				}))
 			})
@@ -233,6 +296,11 @@ variables:
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("must be a map"))
 			})
+
+			It("returns an error with the line and column of the item", func() {
+				_, err := reader.Read(tempDir)
+				Expect(err).To(MatchError(HavePrefix(filepath.Join(tempDir, "vars-file-1.yml") + ":1:13: file with top-level key 'variables' has an invalid format")))
+			})
 		})
 
 		Context("when variable file contains an array item without a name", func() {
@@ -277,6 +345,8 @@ variables:
 							"name": "variable-3",
 							"type": "password",
 						},
+						Path: filepath.Join(tempDir, "vars-file-2.yml"),
+						Line: 3,
 					},
 					{
 						File: "vars-file-1.yml",
@@ -285,6 +355,8 @@ variables:
 							"name": "variable-2",
 							"type": "user",
 						},
+						Path: filepath.Join(tempDir, "vars-file-1.yml"),
+						Line: 5,
 					},
 					{
 						File: "vars-file-1.yml",
@@ -293,6 +365,8 @@ variables:
 							"name": "variable-1",
 							"type": "certificate",
 						},
+						Path: filepath.Join(tempDir, "vars-file-1.yml"),
+						Line: 3,
 					},
 				},
 				))
@@ -4,10 +4,13 @@ import (
 	"archive/tar"
 	"compress/gzip"
 	"crypto/sha1"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/osfs"
@@ -20,6 +23,7 @@ type ReleaseManifest struct {
 	Version         string
 	File            string
 	SHA1            string
+	SHA256          string `yaml:"-"` // used to verify releases; tile metadata only has the SHA1 sum
 	StemcellOS      string `yaml:"-"`
 	StemcellVersion string `yaml:"-"`
 }
@@ -36,11 +40,26 @@ type compiledPackage struct {
 }
 
 type ReleaseManifestReader struct {
-	fs billy.Filesystem
+	fs    billy.Filesystem
+	cache *releaseManifestCache
 }
 
+// NewReleaseManifestReader returns a reader that caches what it reads from each tarball. A tarball is
+// read again when its size or modification time changes, so fetch and bake in the same process only
+// read each release once.
 func NewReleaseManifestReader(fs billy.Filesystem) ReleaseManifestReader {
-	return ReleaseManifestReader{fs: fs}
+	return ReleaseManifestReader{fs: fs, cache: &releaseManifestCache{parts: make(map[releaseManifestCacheKey]Part)}}
+}
+
+type releaseManifestCacheKey struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+type releaseManifestCache struct {
+	mu    sync.Mutex
+	parts map[releaseManifestCacheKey]Part
 }
 
 func (r ReleaseManifestReader) Read(releaseTarball string) (Part, error) {
@@ -48,46 +67,48 @@ func (r ReleaseManifestReader) Read(releaseTarball string) (Part, error) {
 		r.fs = osfs.New("")
 	}
 
-	file, err := r.fs.Open(releaseTarball)
+	if r.cache == nil {
+		return r.read(releaseTarball)
+	}
+
+	info, err := r.fs.Stat(releaseTarball)
 	if err != nil {
 		return Part{}, err
 	}
-	defer closeAndIgnoreError(file)
+	key := releaseManifestCacheKey{path: releaseTarball, size: info.Size(), modTime: info.ModTime()}
 
-	// TODO: use component.ReadReleaseManifest
-	// we could not do it yet due to a circular package reference where we import builder in the local release source
+	r.cache.mu.Lock()
+	part, ok := r.cache.parts[key]
+	r.cache.mu.Unlock()
+	if ok {
+		return part, nil
+	}
 
-	gr, err := gzip.NewReader(file)
+	part, err = r.read(releaseTarball)
 	if err != nil {
 		return Part{}, err
 	}
-	defer closeAndIgnoreError(gr)
 
-	tr := tar.NewReader(gr)
+	r.cache.mu.Lock()
+	r.cache.parts[key] = part
+	r.cache.mu.Unlock()
 
-	var header *tar.Header
-	for {
-		header, err = tr.Next()
-		if err != nil {
-			if err == io.EOF {
-				return Part{}, fmt.Errorf("could not find release.MF in %q", releaseTarball)
-			}
-
-			return Part{}, fmt.Errorf("error while reading %q: %s", releaseTarball, err)
-		}
-
-		if filepath.Base(header.Name) == "release.MF" {
-			break
-		}
-	}
+	return part, nil
+}
 
-	var inputReleaseManifest inputReleaseManifest
-	inputReleaseManifestContents, err := io.ReadAll(tr)
+func (r ReleaseManifestReader) read(releaseTarball string) (Part, error) {
+	file, err := r.fs.Open(releaseTarball)
 	if err != nil {
-		return Part{}, err // NOTE: cannot replicate this error scenario in a test
+		return Part{}, err
 	}
+	defer closeAndIgnoreError(file)
+
+	// The tarball is read once: every byte read to find release.MF is also hashed
+	// and the rest of the tarball is hashed after the manifest is parsed.
+	sha1Hash, sha256Hash := sha1.New(), sha256.New()
+	tarball := io.TeeReader(file, io.MultiWriter(sha1Hash, sha256Hash))
 
-	err = yaml.Unmarshal(inputReleaseManifestContents, &inputReleaseManifest)
+	inputReleaseManifest, err := readInputReleaseManifest(tarball, releaseTarball)
 	if err != nil {
 		return Part{}, err
 	}
@@ -112,18 +133,13 @@ func (r ReleaseManifestReader) Read(releaseTarball string) (Part, error) {
 		StemcellVersion: stemcellVersion,
 	}
 
-	_, err = file.Seek(0, 0)
+	_, err = io.Copy(io.Discard, tarball)
 	if err != nil {
 		return Part{}, err // NOTE: cannot replicate this error scenario in a test
 	}
 
-	hash := sha1.New()
-	_, err = io.Copy(hash, file)
-	if err != nil {
-		return Part{}, err // NOTE: cannot replicate this error scenario in a test
-	}
-
-	outputReleaseManifest.SHA1 = fmt.Sprintf("%x", hash.Sum(nil))
+	outputReleaseManifest.SHA1 = fmt.Sprintf("%x", sha1Hash.Sum(nil))
+	outputReleaseManifest.SHA256 = fmt.Sprintf("%x", sha256Hash.Sum(nil))
 
 	return Part{
 		File:     releaseTarball,
@@ -131,3 +147,46 @@ func (r ReleaseManifestReader) Read(releaseTarball string) (Part, error) {
 		Metadata: outputReleaseManifest,
 	}, nil
 }
+
+// readInputReleaseManifest reads the tarball until it finds release.MF. The rest of the tarball is not read.
+func readInputReleaseManifest(tarball io.Reader, releaseTarball string) (inputReleaseManifest, error) {
+	// TODO: use component.ReadReleaseManifest
+	// we could not do it yet due to a circular package reference where we import builder in the local release source
+
+	gr, err := gzip.NewReader(tarball)
+	if err != nil {
+		return inputReleaseManifest{}, err
+	}
+	defer closeAndIgnoreError(gr)
+
+	tr := tar.NewReader(gr)
+
+	var header *tar.Header
+	for {
+		header, err = tr.Next()
+		if err != nil {
+			if err == io.EOF {
+				return inputReleaseManifest{}, fmt.Errorf("could not find release.MF in %q", releaseTarball)
+			}
+
+			return inputReleaseManifest{}, fmt.Errorf("error while reading %q: %s", releaseTarball, err)
+		}
+
+		if filepath.Base(header.Name) == "release.MF" {
+			break
+		}
+	}
+
+	var manifest inputReleaseManifest
+	inputReleaseManifestContents, err := io.ReadAll(tr)
+	if err != nil {
+		return inputReleaseManifest{}, err // NOTE: cannot replicate this error scenario in a test
+	}
+
+	err = yaml.Unmarshal(inputReleaseManifestContents, &manifest)
+	if err != nil {
+		return inputReleaseManifest{}, err
+	}
+
+	return manifest, nil
+}
@@ -6,10 +6,13 @@ import (
 	"bytes"
 	"compress/gzip"
 	"crypto/sha1"
+	"crypto/sha256"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"testing"
 	"time"
 
 	"github.com/go-git/go-billy/v5/osfs"
@@ -20,7 +23,7 @@ import (
 	"github.com/pivotal-cf/kiln/internal/builder"
 )
 
-func createReleaseTarball(releaseMetadata string) (*os.File, string) {
+func createReleaseTarball(releaseMetadata string) (*os.File, string, string) {
 	tarball, err := os.CreateTemp("", "kiln")
 	Expect(err).NotTo(HaveOccurred())
 
@@ -55,29 +58,31 @@ func createReleaseTarball(releaseMetadata string) (*os.File, string) {
 	file, err = os.Open(tarball.Name())
 	Expect(err).NotTo(HaveOccurred())
 
-	hash := sha1.New()
-	_, err = io.Copy(hash, file)
+	sha1Hash, sha256Hash := sha1.New(), sha256.New()
+	_, err = io.Copy(io.MultiWriter(sha1Hash, sha256Hash), file)
 	Expect(err).NotTo(HaveOccurred())
 
-	releaseSHA1 := fmt.Sprintf("%x", hash.Sum(nil))
+	releaseSHA1 := fmt.Sprintf("%x", sha1Hash.Sum(nil))
+	releaseSHA256 := fmt.Sprintf("%x", sha256Hash.Sum(nil))
 
 	err = file.Close()
 	Expect(err).NotTo(HaveOccurred())
 
-	return tarball, releaseSHA1
+	return tarball, releaseSHA1, releaseSHA256
 }
 
 var _ = Describe("ReleaseManifestReader", func() {
 	var (
-		reader      builder.ReleaseManifestReader
-		releaseSHA1 string
-		tarball     *os.File
-		err         error
+		reader        builder.ReleaseManifestReader
+		releaseSHA1   string
+		releaseSHA256 string
+		tarball       *os.File
+		err           error
 	)
 
 	BeforeEach(func() {
 		reader = builder.NewReleaseManifestReader(osfs.New(""))
-		tarball, releaseSHA1 = createReleaseTarball(`
+		tarball, releaseSHA1, releaseSHA256 = createReleaseTarball(`
 name: release
 version: 1.2.3
 compiled_packages:
@@ -104,15 +109,35 @@ compiled_packages:
 					Version:         "1.2.3",
 					File:            filepath.Base(tarball.Name()),
 					SHA1:            releaseSHA1,
+					SHA256:          releaseSHA256,
 					StemcellOS:      "ubuntu-xenial",
 					StemcellVersion: "170.25",
 				},
 			}))
 		})
 
+		It("reads a tarball again only when it changes", func() {
+			first, err := reader.Read(tarball.Name())
+			Expect(err).NotTo(HaveOccurred())
+
+			second, err := reader.Read(tarball.Name())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(second).To(Equal(first))
+
+			var changedTarball bytes.Buffer
+			Expect(writeReleaseTarball(&changedTarball, "name: release\nversion: 1.2.4\n", 0)).To(Succeed())
+			Expect(os.WriteFile(tarball.Name(), changedTarball.Bytes(), 0o644)).To(Succeed())
+			Expect(os.Chtimes(tarball.Name(), time.Now(), time.Now().Add(time.Minute))).To(Succeed())
+
+			changed, err := reader.Read(tarball.Name())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed.Metadata.(builder.ReleaseManifest).Version).To(Equal("1.2.4"))
+			Expect(changed.Metadata.(builder.ReleaseManifest).SHA1).To(Equal(fmt.Sprintf("%x", sha1.Sum(changedTarball.Bytes()))))
+		})
+
 		Context("when the release is not pre-compiled", func() {
 			BeforeEach(func() {
-				tarball, releaseSHA1 = createReleaseTarball(`
+				tarball, releaseSHA1, releaseSHA256 = createReleaseTarball(`
 name: release
 version: 1.2.3
 `)
@@ -130,6 +155,7 @@ version: 1.2.3
 						Version:         "1.2.3",
 						File:            filepath.Base(tarball.Name()),
 						SHA1:            releaseSHA1,
+						SHA256:          releaseSHA256,
 						StemcellOS:      "",
 						StemcellVersion: "",
 					},
@@ -137,6 +163,33 @@ version: 1.2.3
 			})
 		})
 
+		Context("when the tarball has packages after the release manifest", func() {
+			BeforeEach(func() {
+				tarball, err = os.CreateTemp("", "kiln")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(writeReleaseTarball(tarball, "name: release\nversion: 1.2.3\n", 1<<20)).To(Succeed())
+				Expect(tarball.Close()).To(Succeed())
+
+				contents, err := os.ReadFile(tarball.Name())
+				Expect(err).NotTo(HaveOccurred())
+				releaseSHA1 = fmt.Sprintf("%x", sha1.Sum(contents))
+				releaseSHA256 = fmt.Sprintf("%x", sha256.Sum256(contents))
+			})
+
+			It("hashes the whole tarball", func() {
+				var releaseManifest builder.Part
+				releaseManifest, err = reader.Read(tarball.Name())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(releaseManifest.Metadata).To(Equal(builder.ReleaseManifest{
+					Name:    "release",
+					Version: "1.2.3",
+					File:    filepath.Base(tarball.Name()),
+					SHA1:    releaseSHA1,
+					SHA256:  releaseSHA256,
+				}))
+			})
+		})
+
 		Context("failure cases", func() {
 			Context("when the tarball cannot be opened", func() {
 				It("returns an error", func() {
@@ -283,7 +336,7 @@ version: 1.2.3
 
 		Context("when the release has a malformed stemcell string", func() {
 			BeforeEach(func() {
-				tarball, releaseSHA1 = createReleaseTarball(`
+				tarball, releaseSHA1, releaseSHA256 = createReleaseTarball(`
 name: release
 version: 1.2.3
 compiled_packages:
@@ -300,3 +353,68 @@ compiled_packages:
 		})
 	})
 })
+
+func BenchmarkReleaseManifestReader_Read(b *testing.B) {
+	const packageSize = 64 << 20
+	tarballPath := filepath.Join(b.TempDir(), "release.tgz")
+	tarball, err := os.Create(tarballPath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := writeReleaseTarball(tarball, "name: release\nversion: 1.2.3\n", packageSize); err != nil {
+		b.Fatal(err)
+	}
+	if err := tarball.Close(); err != nil {
+		b.Fatal(err)
+	}
+	info, err := os.Stat(tarballPath)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bm := range []struct {
+		name   string
+		reader builder.ReleaseManifestReader
+	}{
+		{name: "uncached", reader: builder.ReleaseManifestReader{}},
+		{name: "cached", reader: builder.NewReleaseManifestReader(osfs.New(""))},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(info.Size())
+			for i := 0; i < b.N; i++ {
+				if _, err := bm.reader.Read(tarballPath); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// writeReleaseTarball writes a release tarball with a release.MF followed by a package of random bytes.
+// Like real BOSH release packages the package does not compress.
+func writeReleaseTarball(w io.Writer, releaseManifest string, packageSize int64) error {
+	gw, err := gzip.NewWriterLevel(w, gzip.BestSpeed)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(gw)
+
+	if err := tw.WriteHeader(&tar.Header{Name: "./release.MF", Size: int64(len(releaseManifest)), Mode: 0o644}); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(tw, releaseManifest); err != nil {
+		return err
+	}
+
+	if err := tw.WriteHeader(&tar.Header{Name: "./packages/some-package.tgz", Size: packageSize, Mode: 0o644}); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(tw, rand.New(rand.NewSource(1)), packageSize); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
@@ -0,0 +1,43 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SourceDateEpochVariable is the environment variable used to set the modification time of reproducible builds.
+// See https://reproducible-builds.org/docs/source-date-epoch/
+const SourceDateEpochVariable = "SOURCE_DATE_EPOCH"
+
+// SourceDateEpoch returns the modification time for the entries of a reproducible tile. It is read
+// from SOURCE_DATE_EPOCH when it is set and otherwise is the commit time of HEAD in repositoryDirectory.
+func SourceDateEpoch(repositoryDirectory string) (time.Time, error) {
+	if value, ok := os.LookupEnv(SourceDateEpochVariable); ok && value != "" {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s must be a number of seconds since the unix epoch: %w", SourceDateEpochVariable, err)
+		}
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		return time.Time{}, fmt.Errorf("could not get the commit time (set %s instead): %w", SourceDateEpochVariable, err)
+	}
+	var out bytes.Buffer
+	gitLog := exec.Command("git", "log", "-1", "--format=%ct", "HEAD")
+	gitLog.Dir = repositoryDirectory
+	gitLog.Stdout = &out
+	if err := gitLog.Run(); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get the HEAD commit time (set %s instead): %w", SourceDateEpochVariable, err)
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(out.String()), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse the HEAD commit time: %w", err)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
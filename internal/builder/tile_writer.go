@@ -6,7 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strings"
+	"sort"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -32,6 +33,7 @@ type zipper interface {
 	Add(path string, file io.Reader) error
 	AddWithMode(path string, file io.Reader, mode os.FileMode) error
 	CreateFolder(path string) error
+	SetModified(modified time.Time)
 	Close() error
 }
 
@@ -51,6 +53,30 @@ type WriteInput struct {
 	MigrationDirectories []string
 	ReleaseDirectories   []string
 	EmbedPaths           []string
+
+	// ReleaseFiles are the names of the release tarballs in ReleaseDirectories that
+	// are added to the tile. Every tarball is added when it is empty.
+	ReleaseFiles []string
+
+	// Reproducible sorts the tile entries by path and gives each entry the same
+	// modification time and a normalized file mode, so the same inputs always
+	// produce the same tile.
+	Reproducible bool
+
+	// ModifiedTime is the modification time of every entry when Reproducible is set.
+	// Times before 1980 (the earliest time a zip file can record) are raised to 1980.
+	ModifiedTime time.Time
+}
+
+// zipEpoch is the earliest modification time that can be stored in a zip file.
+var zipEpoch = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// tileEntry is a file or folder in the tile. Files are opened when they are added to the zip.
+type tileEntry struct {
+	path   string
+	mode   os.FileMode // zero when the file system mode should not be recorded
+	folder bool
+	open   func() (io.ReadCloser, error)
 }
 
 type tileMetadata struct {
@@ -72,135 +98,205 @@ func (w TileWriter) Write(generatedMetadataContents []byte, input WriteInput) er
 
 	w.zipper.SetWriter(f)
 
-	err = w.addToZipper(filepath.Join("metadata", "metadata.yml"), bytes.NewBuffer(generatedMetadataContents), input.OutputFile)
+	err = w.write(generatedMetadataContents, input)
 	if err != nil {
 		w.removeOutputFile(input.OutputFile)
 		return err
 	}
 
-	err = w.addMigrations(input.MigrationDirectories, input.OutputFile)
+	return nil
+}
+
+func (w TileWriter) write(generatedMetadataContents []byte, input WriteInput) error {
+	entries := []tileEntry{{
+		path: filepath.Join("metadata", "metadata.yml"),
+		open: contentsOpener(generatedMetadataContents),
+	}}
+
+	migrations, err := w.migrationEntries(input.MigrationDirectories)
 	if err != nil {
-		w.removeOutputFile(input.OutputFile)
 		return err
 	}
+	entries = append(entries, migrations...)
 
+	var releases []tileEntry
 	if input.StubReleases {
-		err = w.addStubReleases(generatedMetadataContents, input.OutputFile)
+		releases, err = stubReleaseEntries(generatedMetadataContents)
 	} else {
-		err = w.addReleases(input.ReleaseDirectories, input.OutputFile)
+		releases, err = w.releaseEntries(input.ReleaseDirectories, input.ReleaseFiles)
 	}
 	if err != nil {
-		w.removeOutputFile(input.OutputFile)
 		return err
 	}
+	entries = append(entries, releases...)
 
-	err = w.addEmbeddedPaths(input.EmbedPaths, input.OutputFile)
+	embedded, err := w.embeddedEntries(input.EmbedPaths)
 	if err != nil {
-		w.removeOutputFile(input.OutputFile)
 		return err
 	}
+	entries = append(entries, embedded...)
 
-	err = w.zipper.Close()
-	if err != nil {
-		w.removeOutputFile(input.OutputFile)
-		return err
+	if input.Reproducible {
+		entries = reproducibleEntries(entries)
+		modified := input.ModifiedTime.UTC()
+		if modified.Before(zipEpoch) {
+			modified = zipEpoch
+		}
+		w.zipper.SetModified(modified)
 	}
 
-	return nil
-}
-
-func (w TileWriter) addReleases(releasesDirs []string, outputFile string) error {
-	for _, releasesDirectory := range releasesDirs {
-		err := w.addReleaseTarballs(releasesDirectory, outputFile)
+	for _, entry := range entries {
+		err := w.addEntry(entry, input.OutputFile)
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return w.zipper.Close()
 }
 
-func (w TileWriter) addStubReleases(generatedMetadataContents []byte, outputFile string) error {
-	var metadata tileMetadata
-	err := yaml.Unmarshal(generatedMetadataContents, &metadata)
+// reproducibleEntries sorts the entries by path, uses forward slashes in paths, and replaces file system
+// modes with 0644, or 0755 for executable files, so tiles do not depend on the host that built them.
+func reproducibleEntries(entries []tileEntry) []tileEntry {
+	normalized := make([]tileEntry, len(entries))
+	for i, entry := range entries {
+		entry.path = filepath.ToSlash(entry.path)
+		if !entry.folder {
+			if entry.mode&0o111 != 0 {
+				entry.mode = 0o755
+			} else {
+				entry.mode = 0o644
+			}
+		}
+		normalized[i] = entry
+	}
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return normalized[i].path < normalized[j].path
+	})
+	return normalized
+}
+
+func (w TileWriter) addEntry(entry tileEntry, outputFile string) error {
+	if entry.folder {
+		w.logger.Printf("Creating empty migrations folder in %s...", outputFile)
+		return w.zipper.CreateFolder(entry.path)
+	}
+
+	file, err := entry.open()
 	if err != nil {
 		return err
 	}
-	for _, release := range metadata.Releases {
-		path := filepath.Join("releases", release.File)
-		contents := io.NopCloser(strings.NewReader(""))
-		err = w.addToZipper(path, contents, outputFile)
-		if err != nil {
-			return err
-		}
+	defer closeAndIgnoreError(file)
+
+	w.logger.Printf("Adding %s to %s...", filepath.ToSlash(entry.path), outputFile)
+
+	if entry.mode != 0 {
+		return w.zipper.AddWithMode(entry.path, file, entry.mode)
 	}
-	return nil
+	return w.zipper.Add(entry.path, file)
 }
 
-func (w TileWriter) addReleaseTarballs(releasesDir string, outputFile string) error {
-	return w.filesystem.Walk(releasesDir, func(filePath string, info os.FileInfo, err error) error {
-		isTarball, _ := regexp.MatchString("tgz$|tar.gz$", filePath)
-		if !isTarball {
-			return nil
-		}
+func contentsOpener(contents []byte) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewBuffer(contents)), nil
+	}
+}
 
-		if err != nil {
-			return err
-		}
+func (w TileWriter) fileOpener(filePath string) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return w.filesystem.Open(filePath)
+	}
+}
 
-		if info.IsDir() {
-			return nil
-		}
+func (w TileWriter) releaseEntries(releasesDirs, releaseFiles []string) ([]tileEntry, error) {
+	included := make(map[string]bool, len(releaseFiles))
+	for _, name := range releaseFiles {
+		included[name] = true
+	}
+	var entries []tileEntry
+	for _, releasesDirectory := range releasesDirs {
+		err := w.filesystem.Walk(releasesDirectory, func(filePath string, info os.FileInfo, err error) error {
+			isTarball, _ := regexp.MatchString("tgz$|tar.gz$", filePath)
+			if !isTarball {
+				return nil
+			}
 
-		file, err := w.filesystem.Open(filePath)
-		if err != nil {
-			return err
-		}
-		defer closeAndIgnoreError(file)
+			if err != nil {
+				return err
+			}
 
-		return w.addToZipper(filepath.Join("releases", filepath.Base(filePath)), file, outputFile)
-	})
-}
+			if info.IsDir() {
+				return nil
+			}
 
-func (w TileWriter) addEmbeddedPaths(embedPaths []string, outputFile string) error {
-	for _, embedPath := range embedPaths {
-		err := w.addEmbeddedPath(embedPath, outputFile)
+			if len(included) > 0 && !included[filepath.Base(filePath)] {
+				return nil
+			}
+
+			entries = append(entries, tileEntry{
+				path: filepath.Join("releases", filepath.Base(filePath)),
+				open: w.fileOpener(filePath),
+			})
+			return nil
+		})
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	return nil
+	return entries, nil
 }
 
-func (w TileWriter) addEmbeddedPath(pathToEmbed, outputFile string) error {
-	return w.filesystem.Walk(pathToEmbed, func(filePath string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+func stubReleaseEntries(generatedMetadataContents []byte) ([]tileEntry, error) {
+	var metadata tileMetadata
+	err := yaml.Unmarshal(generatedMetadataContents, &metadata)
+	if err != nil {
+		return nil, err
+	}
+	var entries []tileEntry
+	for _, release := range metadata.Releases {
+		entries = append(entries, tileEntry{
+			path: filepath.Join("releases", release.File),
+			open: contentsOpener(nil),
+		})
+	}
+	return entries, nil
+}
 
-		if info.IsDir() {
-			return nil
-		}
+func (w TileWriter) embeddedEntries(embedPaths []string) ([]tileEntry, error) {
+	var entries []tileEntry
+	for _, pathToEmbed := range embedPaths {
+		err := w.filesystem.Walk(pathToEmbed, func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
 
-		file, err := w.filesystem.Open(filePath)
-		if err != nil {
-			return err
-		}
-		defer closeAndIgnoreError(file)
+			if info.IsDir() {
+				return nil
+			}
+
+			relativePath, err := filepath.Rel(pathToEmbed, filePath)
+			if err != nil {
+				return err // not tested
+			}
 
-		relativePath, err := filepath.Rel(pathToEmbed, filePath)
+			entries = append(entries, tileEntry{
+				path: filepath.Join("embed", filepath.Join(filepath.Base(pathToEmbed), relativePath)),
+				mode: info.Mode(),
+				open: w.fileOpener(filePath),
+			})
+			return nil
+		})
 		if err != nil {
-			return err // not tested
+			return nil, err
 		}
+	}
 
-		entryPath := filepath.Join("embed", filepath.Join(filepath.Base(pathToEmbed), relativePath))
-		return w.addToZipperWithMode(entryPath, file, info.Mode(), outputFile)
-	})
+	return entries, nil
 }
 
-func (w TileWriter) addMigrations(migrationsDir []string, outputFile string) error {
-	var found bool
+func (w TileWriter) migrationEntries(migrationsDir []string) ([]tileEntry, error) {
+	var entries []tileEntry
 
 	for _, migrationDir := range migrationsDir {
 		err := w.filesystem.Walk(migrationDir, func(filePath string, info os.FileInfo, err error) error {
@@ -220,47 +316,22 @@ func (w TileWriter) addMigrations(migrationsDir []string, outputFile string) err
 				return nil
 			}
 
-			found = true
-
-			file, err := w.filesystem.Open(filePath)
-			if err != nil {
-				return err
-			}
-			defer closeAndIgnoreError(file)
-
-			return w.addToZipper(filepath.Join("migrations", "v1", filepath.Base(filePath)), file, outputFile)
+			entries = append(entries, tileEntry{
+				path: filepath.Join("migrations", "v1", filepath.Base(filePath)),
+				open: w.fileOpener(filePath),
+			})
+			return nil
 		})
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	if !found {
-		return w.addEmptyMigrationsDirectory(outputFile)
+	if len(entries) == 0 {
+		entries = append(entries, tileEntry{path: filepath.Join("migrations", "v1"), folder: true})
 	}
 
-	return nil
-}
-
-func (w TileWriter) addToZipper(path string, contents io.Reader, outputFile string) error {
-	w.logger.Printf("Adding %s to %s...", path, outputFile)
-
-	return w.zipper.Add(path, contents)
-}
-
-func (w TileWriter) addToZipperWithMode(path string, contents io.Reader, mode os.FileMode, outputFile string) error {
-	w.logger.Printf("Adding %s to %s...", path, outputFile)
-
-	return w.zipper.AddWithMode(path, contents, mode)
-}
-
-func (w TileWriter) addEmptyMigrationsDirectory(outputFile string) error {
-	w.logger.Printf("Creating empty migrations folder in %s...", outputFile)
-	err := w.zipper.CreateFolder(filepath.Join("migrations", "v1"))
-	if err != nil {
-		return err
-	}
-	return nil
+	return entries, nil
 }
 
 func (w TileWriter) removeOutputFile(path string) {
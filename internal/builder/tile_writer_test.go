@@ -1,12 +1,14 @@
 package builder_test
 
 import (
+	"archive/zip"
 	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
@@ -314,6 +316,27 @@ releases:
 					Expect(zipper.CreateFolderArgsForCall(0)).To(Equal(filepath.Join("migrations", "v1")))
 				})
 			})
+
+			Context("and release files are provided", func() {
+				It("only adds those releases", func() {
+					input := builder.WriteInput{
+						ReleaseDirectories:   []string{"/some/path/releases"},
+						ReleaseFiles:         []string{"release-2.tgz"},
+						MigrationDirectories: []string{},
+						OutputFile:           "some-output-dir/cool-product-file-1.2.3-build.4.pivotal",
+					}
+
+					err := tileWriter.Write([]byte("generated-metadata-contents"), input)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(logger.PrintfCall.Receives.LogLines).To(Equal([]string{
+						fmt.Sprintf("Building %s...", outputFile),
+						fmt.Sprintf("Adding metadata/metadata.yml to %s...", outputFile),
+						fmt.Sprintf("Creating empty migrations folder in %s...", outputFile),
+						fmt.Sprintf("Adding releases/release-2.tgz to %s...", outputFile),
+					}))
+				})
+			})
 		})
 
 		Context("when a file to embed is provided", func() {
@@ -447,6 +470,102 @@ releases:
 			})
 		})
 
+		Context("when the tile is reproducible", func() {
+			var (
+				releaseModes  map[string]os.FileMode
+				walkReversed  bool
+				modifiedTimes []time.Time
+			)
+
+			BeforeEach(func() {
+				releaseModes = map[string]os.FileMode{"release-1.tgz": 0o600, "release-2.tgz": 0o600, "bin": 0o700}
+				walkReversed = false
+
+				filesystem.WalkStub = func(root string, walkFn filepath.WalkFunc) error {
+					names := []string{"release-1.tgz", "release-2.tgz"}
+					if root == "/some/path/to-embed" {
+						names = []string{"bin", "README.md"}
+					}
+					if walkReversed {
+						names[0], names[1] = names[1], names[0]
+					}
+					for _, name := range names {
+						info := &fakes.FileInfo{}
+						info.ModeReturns(releaseModes[name])
+						_ = walkFn(filepath.Join(root, name), info, nil)
+					}
+					return nil
+				}
+				filesystem.OpenStub = func(path string) (io.ReadCloser, error) {
+					return NewBuffer(bytes.NewBufferString(path)), nil
+				}
+
+				modifiedTimes = []time.Time{
+					time.Date(2021, time.November, 3, 21, 31, 14, 0, time.UTC),
+					time.Date(2021, time.November, 3, 14, 31, 14, 0, time.FixedZone("PDT", -7*60*60)),
+				}
+			})
+
+			writeTile := func(modified time.Time) []byte {
+				var output bytes.Buffer
+				filesystem.CreateReturns(NewBuffer(&output), nil)
+				realZipper := builder.NewZipper()
+				err := builder.NewTileWriter(filesystem, &realZipper, logger).Write([]byte("generated-metadata-contents"), builder.WriteInput{
+					ReleaseDirectories: []string{"/some/path/releases"},
+					EmbedPaths:         []string{"/some/path/to-embed"},
+					OutputFile:         outputFile,
+					Reproducible:       true,
+					ModifiedTime:       modified,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				return output.Bytes()
+			}
+
+			It("writes byte-identical tiles", func() {
+				first := writeTile(modifiedTimes[0])
+
+				walkReversed = true
+				releaseModes = map[string]os.FileMode{"release-1.tgz": 0o644, "release-2.tgz": 0o664, "bin": 0o755}
+				second := writeTile(modifiedTimes[1])
+
+				Expect(second).To(Equal(first))
+			})
+
+			It("sorts entries and normalizes their modes and times", func() {
+				tile := writeTile(modifiedTimes[0])
+
+				reader, err := zip.NewReader(bytes.NewReader(tile), int64(len(tile)))
+				Expect(err).NotTo(HaveOccurred())
+
+				var names []string
+				for _, file := range reader.File {
+					names = append(names, file.Name)
+					Expect(file.Modified.Equal(modifiedTimes[0])).To(BeTrue())
+					if file.Name == "embed/to-embed/bin" {
+						Expect(file.Mode()).To(Equal(os.FileMode(0o755)))
+					} else if !file.Mode().IsDir() {
+						Expect(file.Mode()).To(Equal(os.FileMode(0o644)))
+					}
+				}
+				Expect(names).To(Equal([]string{
+					"embed/to-embed/README.md",
+					"embed/to-embed/bin",
+					"metadata/metadata.yml",
+					"migrations/v1/",
+					"releases/release-1.tgz",
+					"releases/release-2.tgz",
+				}))
+			})
+
+			It("does not use times before 1980", func() {
+				tile := writeTile(time.Unix(0, 0))
+
+				reader, err := zip.NewReader(bytes.NewReader(tile), int64(len(tile)))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(reader.File[0].Modified.Year()).To(Equal(1980))
+			})
+		})
+
 		Context("failure cases", func() {
 			Context("when creating the zip file fails", func() {
 				BeforeEach(func() {
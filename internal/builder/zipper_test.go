@@ -116,6 +116,26 @@ var _ = Describe("Zipper", func() {
 			Expect(reader.File[0].FileHeader.Modified).To(BeTemporally("~", time.Now(), time.Minute))
 		})
 
+		It("uses the time set with SetModified", func() {
+			modified := time.Date(2021, time.November, 3, 21, 31, 14, 0, time.UTC)
+
+			zipper := builder.NewZipper()
+			zipper.SetWriter(tileFile)
+			zipper.SetModified(modified)
+
+			err := zipper.Add("some/path/to/file.txt", strings.NewReader("file contents"))
+			Expect(err).NotTo(HaveOccurred())
+
+			err = zipper.Close()
+			Expect(err).NotTo(HaveOccurred())
+
+			reader, err := zip.OpenReader(pathToTile)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(reader.File).To(HaveLen(1))
+			Expect(reader.File[0].FileHeader.Modified.Equal(modified)).To(BeTrue())
+		})
+
 		Context("failure cases", func() {
 			Context("when the file cannot be copied", func() {
 				It("returns an error", func() {
@@ -0,0 +1,290 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/pivotal-cf/jhanda"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pivotal-cf/kiln/internal/commands/flags"
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+type AddRelease struct {
+	Options struct {
+		flags.Standard
+
+		Name              string `short:"n" long:"name"               required:"true" description:"name of the release to add"`
+		GitHubRepository  string `          long:"github-repository"                  description:"GitHub repository with the BOSH release source code"`
+		VersionConstraint string `          long:"version-constraint"                 description:"semantic version constraint for the release; without it any version is allowed"`
+		ReleasesDir       string `short:"rd" long:"releases-directory" default:"releases" description:"path to a directory to download releases into"`
+		WithoutDownload   bool   `          long:"without-download"                   description:"lock the release without downloading it"`
+		MetadataPath      string `short:"m" long:"metadata"                           description:"path to a metadata file; a release template expression for the release is added to its releases list"`
+	}
+	multiReleaseSourceProvider MultiReleaseSourceProvider
+	filesystem                 billy.Filesystem
+	logger                     *log.Logger
+	report                     *Report
+}
+
+func NewAddRelease(logger *log.Logger, filesystem billy.Filesystem, multiReleaseSourceProvider MultiReleaseSourceProvider) AddRelease {
+	return AddRelease{
+		logger:                     logger,
+		multiReleaseSourceProvider: multiReleaseSourceProvider,
+		filesystem:                 filesystem,
+	}
+}
+
+// WithReport configures the Report the added and downloaded release are added to.
+func (a AddRelease) WithReport(report *Report) AddRelease {
+	a.report = report
+	return a
+}
+
+func (a AddRelease) Execute(args []string) error {
+	_, err := flags.LoadFlagsWithDefaults(&a.Options, args, a.filesystem.Stat)
+	if err != nil {
+		return err
+	}
+
+	kilnfile, kilnfileLock, err := a.Options.Standard.LoadKilnfiles(a.filesystem, nil)
+	if err != nil {
+		return withErrorCode(ErrorCodeKilnfile, fmt.Errorf("error loading Kilnfiles: %w", err))
+	}
+
+	name := a.Options.Name
+	if _, err := kilnfile.BOSHReleaseTarballSpecification(name); err == nil {
+		return fmt.Errorf("release %q is already in the Kilnfile", name)
+	}
+	if _, err := kilnfileLock.FindBOSHReleaseWithName(name); err == nil {
+		return fmt.Errorf("release %q is already in the Kilnfile.lock", name)
+	}
+
+	spec := cargo.BOSHReleaseTarballSpecification{
+		Name:             name,
+		Version:          a.Options.VersionConstraint,
+		GitHubRepository: a.Options.GitHubRepository,
+	}
+	if _, err := spec.VersionConstraints(); err != nil {
+		return err
+	}
+
+	stemcell, err := kilnfileLock.StemcellForRelease(spec)
+	if err != nil {
+		return err
+	}
+	query := spec
+	query.StemcellOS = stemcell.OS
+	query.StemcellVersion = stemcell.Version
+
+	kilnfile.Releases = append(kilnfile.Releases, spec)
+	releaseSource := a.multiReleaseSourceProvider(kilnfile, false)
+
+	a.logger.Println("Searching for the release...")
+
+	remoteRelease, err := releaseSource.FindReleaseVersion(query, !a.Options.WithoutDownload)
+	if err != nil {
+		if component.IsErrNotFound(err) {
+			return fmt.Errorf("couldn't find %q %s in any release source", name, a.Options.VersionConstraint)
+		}
+		return fmt.Errorf("error finding the release %q: %w", name, err)
+	}
+
+	signatures, publicKey, err := releaseSignatureReader(releaseSource, remoteRelease.RemoteSource)
+	if err != nil {
+		return err
+	}
+
+	releaseLock := cargo.BOSHReleaseTarballLock{
+		Name:         name,
+		Version:      remoteRelease.Version,
+		SHA1:         remoteRelease.SHA1,
+		SHA256:       remoteRelease.SHA256,
+		RemoteSource: remoteRelease.RemoteSource,
+		RemotePath:   remoteRelease.RemotePath,
+	}
+	if !a.Options.WithoutDownload {
+		if a.Options.ReleasesDir == "" {
+			// the default is cleared when the directory does not exist yet
+			a.Options.ReleasesDir = "releases"
+		}
+		if err := a.filesystem.MkdirAll(a.Options.ReleasesDir, 0o777); err != nil {
+			return err
+		}
+		localRelease, err := releaseSource.DownloadRelease(a.Options.ReleasesDir, remoteRelease)
+		if err != nil {
+			return withErrorCode(ErrorCodeDownloadFailed, fmt.Errorf("error downloading the release %q: %w", name, err))
+		}
+		a.report.AddDownload(localRelease)
+		releaseLock.SHA1 = localRelease.Lock.SHA1
+		releaseLock.SHA256 = localRelease.Lock.SHA256
+	}
+	releaseLock.Signature, err = releaseSignature(signatures, publicKey, releaseLock)
+	if err != nil {
+		return err
+	}
+	releaseLock.Provenance = releaseProvenance(a.logger, releaseSource, releaseLock)
+
+	err = editKilnfileReleases(a.filesystem, a.Options.Kilnfile, func(releases *yaml.Node) error {
+		var specNode yaml.Node
+		if err := specNode.Encode(spec); err != nil {
+			return err
+		}
+		releases.Content = append(releases.Content, &specNode)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	kilnfileLock.Releases = append(kilnfileLock.Releases, releaseLock)
+	err = a.Options.Standard.SaveKilnfileLock(a.filesystem, kilnfileLock)
+	if err != nil {
+		return err
+	}
+	a.report.AddReleaseChange(cargo.BOSHReleaseTarballLock{}, releaseLock)
+
+	if a.Options.MetadataPath != "" {
+		err = editFile(a.filesystem, a.Options.MetadataPath, func(metadata []byte) ([]byte, error) {
+			return addMetadataReleaseExpression(metadata, name), nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	a.logger.Printf("Added %s %s. DON'T FORGET TO MAKE A COMMIT AND PR\n", name, releaseLock.Version)
+	return nil
+}
+
+func (a AddRelease) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Adds a BOSH release to the Kilnfile, locks the newest version allowed by --version-constraint in Kilnfile.lock, and downloads it. With --metadata, a release template expression for the release is added to the releases list of a metadata file.",
+		ShortDescription: "adds a release to the Kilnfile and Kilnfile.lock",
+		Flags:            a.Options,
+	}
+}
+
+// editKilnfileReleases calls edit with the releases sequence of the Kilnfile and writes the result.
+// The Kilnfile is edited without interpolation so variables and comments are preserved.
+func editKilnfileReleases(fs billy.Filesystem, kilnfilePath string, edit func(releases *yaml.Node) error) error {
+	return editFile(fs, kilnfilePath, func(buf []byte) ([]byte, error) {
+		var document yaml.Node
+		if err := yaml.Unmarshal(buf, &document); err != nil {
+			return nil, fmt.Errorf("failed to parse Kilnfile: %w", err)
+		}
+		if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+			document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+		}
+		kilnfile := document.Content[0]
+		if kilnfile.Kind != yaml.MappingNode {
+			return nil, errors.New("failed to parse Kilnfile: expected a mapping")
+		}
+		var releases *yaml.Node
+		for i := 0; i+1 < len(kilnfile.Content); i += 2 {
+			if kilnfile.Content[i].Value == "releases" {
+				releases = kilnfile.Content[i+1]
+				break
+			}
+		}
+		if releases == nil {
+			releases = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			kilnfile.Content = append(kilnfile.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "releases"}, releases)
+		}
+		if releases.Kind != yaml.SequenceNode {
+			// an empty value like "releases:" is parsed as a null scalar
+			*releases = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		}
+		releases.Style = 0
+		if err := edit(releases); err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		encoder := yaml.NewEncoder(&out)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&document); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	})
+}
+
+func editFile(fs billy.Filesystem, path string, edit func([]byte) ([]byte, error)) error {
+	f, err := fs.Open(path)
+	if err != nil {
+		return err
+	}
+	buf, err := io.ReadAll(f)
+	closeAndIgnoreError(f)
+	if err != nil {
+		return err
+	}
+	buf, err = edit(buf)
+	if err != nil {
+		return err
+	}
+	out, err := fs.Create(path)
+	if err != nil {
+		return err
+	}
+	defer closeAndIgnoreError(out)
+	_, err = out.Write(buf)
+	return err
+}
+
+// metadataReleasesKey matches the top level releases key of a metadata file and an optional inline empty list.
+var metadataReleasesKey = regexp.MustCompile(`^releases:\s*(\[\s*\])?\s*$`)
+
+// addMetadataReleaseExpression appends a release template expression to the releases list of a metadata
+// file. The metadata is edited as text because it may contain template expressions that are not valid YAML.
+func addMetadataReleaseExpression(metadata []byte, name string) []byte {
+	lines := strings.Split(string(metadata), "\n")
+	keyIndex := -1
+	for i, line := range lines {
+		if metadataReleasesKey.MatchString(line) {
+			keyIndex = i
+			break
+		}
+	}
+	if keyIndex < 0 {
+		content := strings.TrimRight(string(metadata), "\n")
+		if content != "" {
+			content += "\n"
+		}
+		return []byte(content + "releases:\n- " + metadataReleaseExpression(name) + "\n")
+	}
+
+	lines[keyIndex] = "releases:"
+	prefix, last := "- ", keyIndex
+	for i := keyIndex + 1; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "-") {
+			break
+		}
+		if trimmed := strings.TrimLeft(line, " "); last == keyIndex && strings.HasPrefix(trimmed, "- ") {
+			prefix = line[:len(line)-len(trimmed)] + "- "
+		}
+		last = i
+	}
+	entry := prefix + metadataReleaseExpression(name)
+	lines = append(lines[:last+1], append([]string{entry}, lines[last+1:]...)...)
+	return []byte(strings.Join(lines, "\n"))
+}
+
+func metadataReleaseExpression(name string) string {
+	return fmt.Sprintf("$( release %q )", name)
+}
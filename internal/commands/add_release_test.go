@@ -0,0 +1,228 @@
+package commands_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	"github.com/pivotal-cf/kiln/internal/commands/fakes"
+	"github.com/pivotal-cf/kiln/internal/component"
+	componentFakes "github.com/pivotal-cf/kiln/internal/component/fakes"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+const addReleaseTestKilnfile = `---
+# release sources are configured for the whole tile
+release_sources:
+  - type: bosh.io
+  - type: s3
+    bucket: some-bucket
+    access_key_id: $(variable "aws_access_key_id")
+stemcell_criteria:
+  os: alpine
+  version: "*"
+releases:
+  - name: apple
+`
+
+func TestAddRelease_Execute(t *testing.T) {
+	setup := func(t *testing.T) (billy.Filesystem, *componentFakes.MultiReleaseSource, *fakes.MultiReleaseSourceProvider) {
+		t.Helper()
+		fs := memfs.New()
+		if err := util.WriteFile(fs, "Kilnfile", []byte(addReleaseTestKilnfile), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := fsWriteYAML(fs, "Kilnfile.lock", cargo.KilnfileLock{
+			Stemcell: cargo.Stemcell{OS: "alpine", Version: "1.1"},
+			Releases: []cargo.BOSHReleaseTarballLock{{Name: "apple", Version: "1.0.0", RemoteSource: "bosh.io"}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		releaseSource := new(componentFakes.MultiReleaseSource)
+		releaseSource.FindReleaseVersionReturns(cargo.BOSHReleaseTarballLock{
+			Name: "banana", Version: "1.2.3", RemoteSource: "bosh.io", RemotePath: "https://bosh.io/d/github.com/cloudfoundry/banana-release?v=1.2.3",
+		}, nil)
+		releaseSource.DownloadReleaseReturns(component.Local{
+			Lock:      cargo.BOSHReleaseTarballLock{Name: "banana", Version: "1.2.3", SHA1: "some-sha1", SHA256: "some-sha256"},
+			LocalPath: "releases/banana-1.2.3.tgz",
+		}, nil)
+		releaseSource.FindByIDReturns(nil, errors.New("no provenance"))
+		provider := new(fakes.MultiReleaseSourceProvider)
+		provider.Returns(releaseSource)
+		return fs, releaseSource, provider
+	}
+
+	t.Run("it adds, locks, and downloads the release", func(t *testing.T) {
+		please := NewWithT(t)
+		fs, releaseSource, provider := setup(t)
+
+		report := commands.NewReport("add-release")
+		err := commands.NewAddRelease(log.New(io.Discard, "", 0), fs, provider.Spy).WithReport(report).Execute([]string{
+			"--name", "banana",
+			"--github-repository", "https://github.com/cloudfoundry/banana-release",
+			"--version-constraint", "~1.2",
+			"--variable", "aws_access_key_id=some-secret",
+		})
+		please.Expect(err).NotTo(HaveOccurred())
+
+		kilnfile, _ := provider.ArgsForCall(0)
+		please.Expect(kilnfile.Releases).To(ContainElement(cargo.BOSHReleaseTarballSpecification{
+			Name: "banana", Version: "~1.2", GitHubRepository: "https://github.com/cloudfoundry/banana-release",
+		}))
+		please.Expect(kilnfile.ReleaseSources[1].AccessKeyId).To(Equal("some-secret"))
+
+		spec, noDownload := releaseSource.FindReleaseVersionArgsForCall(0)
+		please.Expect(spec).To(Equal(cargo.BOSHReleaseTarballSpecification{
+			Name: "banana", Version: "~1.2", GitHubRepository: "https://github.com/cloudfoundry/banana-release",
+			StemcellOS: "alpine", StemcellVersion: "1.1",
+		}))
+		please.Expect(noDownload).To(BeTrue())
+
+		releasesDir, _ := releaseSource.DownloadReleaseArgsForCall(0)
+		please.Expect(releasesDir).To(Equal("releases"))
+
+		kilnfileContents, err := util.ReadFile(fs, "Kilnfile")
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(string(kilnfileContents)).To(ContainSubstring("# release sources are configured for the whole tile"))
+		please.Expect(string(kilnfileContents)).To(ContainSubstring(`access_key_id: $(variable "aws_access_key_id")`))
+		please.Expect(string(kilnfileContents)).To(ContainSubstring(`releases:
+  - name: apple
+  - name: banana
+    version: ~1.2
+    github_repository: https://github.com/cloudfoundry/banana-release
+`))
+
+		var lock cargo.KilnfileLock
+		please.Expect(fsReadYAML(fs, "Kilnfile.lock", &lock)).To(Succeed())
+		please.Expect(lock.Releases).To(ContainElement(cargo.BOSHReleaseTarballLock{
+			Name: "banana", Version: "1.2.3", SHA1: "some-sha1", SHA256: "some-sha256",
+			RemoteSource: "bosh.io", RemotePath: "https://bosh.io/d/github.com/cloudfoundry/banana-release?v=1.2.3",
+		}))
+
+		please.Expect(report.Changes).To(Equal([]commands.ReportChange{
+			{Kind: "release", Name: "banana", To: "1.2.3", RemoteSource: "bosh.io", RemotePath: "https://bosh.io/d/github.com/cloudfoundry/banana-release?v=1.2.3"},
+		}))
+		please.Expect(report.Downloads).To(HaveLen(1))
+	})
+
+	t.Run("without download", func(t *testing.T) {
+		please := NewWithT(t)
+		fs, releaseSource, provider := setup(t)
+
+		err := commands.NewAddRelease(log.New(io.Discard, "", 0), fs, provider.Spy).Execute([]string{"--name", "banana", "--without-download", "--variable", "aws_access_key_id=x"})
+		please.Expect(err).NotTo(HaveOccurred())
+
+		_, noDownload := releaseSource.FindReleaseVersionArgsForCall(0)
+		please.Expect(noDownload).To(BeFalse())
+		please.Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(0))
+	})
+
+	t.Run("when the release source has a signature_public_key", func(t *testing.T) {
+		please := NewWithT(t)
+		fs, releaseSource, provider := setup(t)
+
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		please.Expect(err).NotTo(HaveOccurred())
+		der, err := x509.MarshalPKIXPublicKey(publicKey)
+		please.Expect(err).NotTo(HaveOccurred())
+
+		sourceDir := t.TempDir()
+		releaseSource.FindByIDReturns(component.NewDirectoryReleaseSource(cargo.ReleaseSourceConfig{
+			Type:               component.ReleaseSourceTypeDirectory,
+			ID:                 "signed",
+			Path:               sourceDir,
+			PathTemplate:       "{{.Name}}-{{.Version}}.tgz",
+			SignaturePublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		}, log.New(io.Discard, "", 0)), nil)
+		releaseSource.FindReleaseVersionReturns(cargo.BOSHReleaseTarballLock{
+			Name: "banana", Version: "1.2.3", RemoteSource: "signed", RemotePath: "banana-1.2.3.tgz",
+		}, nil)
+		digest := sha256.Sum256([]byte("some release contents"))
+		releaseSource.DownloadReleaseReturns(component.Local{
+			Lock:      cargo.BOSHReleaseTarballLock{Name: "banana", Version: "1.2.3", SHA1: "some-sha1", SHA256: hex.EncodeToString(digest[:])},
+			LocalPath: "releases/banana-1.2.3.tgz",
+		}, nil)
+
+		err = commands.NewAddRelease(log.New(io.Discard, "", 0), fs, provider.Spy).Execute([]string{"--name", "banana", "--variable", "aws_access_key_id=x"})
+		please.Expect(err).To(MatchError(ContainSubstring("failed to read the signature of banana 1.2.3")))
+
+		signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, digest[:]))
+		please.Expect(os.WriteFile(filepath.Join(sourceDir, "banana-1.2.3.tgz.sig"), []byte(signature), 0o644)).To(Succeed())
+
+		err = commands.NewAddRelease(log.New(io.Discard, "", 0), fs, provider.Spy).Execute([]string{"--name", "banana", "--variable", "aws_access_key_id=x"})
+		please.Expect(err).NotTo(HaveOccurred())
+
+		var lock cargo.KilnfileLock
+		please.Expect(fsReadYAML(fs, "Kilnfile.lock", &lock)).To(Succeed())
+		banana, err := lock.FindBOSHReleaseWithName("banana")
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(banana.Signature).To(Equal(signature))
+	})
+
+	t.Run("with a metadata file", func(t *testing.T) {
+		please := NewWithT(t)
+		fs, _, provider := setup(t)
+		please.Expect(util.WriteFile(fs, "base.yml", []byte(`---
+name: fruit
+releases:
+  - $( release "apple" )
+
+job_types:
+  - $( instance_group "tree" )
+`), 0o644)).To(Succeed())
+
+		err := commands.NewAddRelease(log.New(io.Discard, "", 0), fs, provider.Spy).Execute([]string{"--name", "banana", "--metadata", "base.yml", "--variable", "aws_access_key_id=x"})
+		please.Expect(err).NotTo(HaveOccurred())
+
+		metadata, err := util.ReadFile(fs, "base.yml")
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(string(metadata)).To(Equal(`---
+name: fruit
+releases:
+  - $( release "apple" )
+  - $( release "banana" )
+
+job_types:
+  - $( instance_group "tree" )
+`))
+	})
+
+	t.Run("when the release is already in the Kilnfile", func(t *testing.T) {
+		please := NewWithT(t)
+		fs, _, provider := setup(t)
+
+		err := commands.NewAddRelease(log.New(io.Discard, "", 0), fs, provider.Spy).Execute([]string{"--name", "apple", "--variable", "aws_access_key_id=x"})
+		please.Expect(err).To(MatchError(`release "apple" is already in the Kilnfile`))
+	})
+
+	t.Run("when the release is not found", func(t *testing.T) {
+		please := NewWithT(t)
+		fs, releaseSource, provider := setup(t)
+		releaseSource.FindReleaseVersionReturns(cargo.BOSHReleaseTarballLock{}, component.ErrNotFound)
+
+		var output bytes.Buffer
+		err := commands.NewAddRelease(log.New(&output, "", 0), fs, provider.Spy).Execute([]string{"--name", "banana", "--version-constraint", "~9", "--variable", "aws_access_key_id=x"})
+		please.Expect(err).To(MatchError(`couldn't find "banana" ~9 in any release source`))
+
+		kilnfileContents, err := util.ReadFile(fs, "Kilnfile")
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(string(kilnfileContents)).To(Equal(addReleaseTestKilnfile))
+	})
+}
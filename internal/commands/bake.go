@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -9,6 +11,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-billy/v5"
 	"github.com/pivotal-cf/jhanda"
@@ -16,6 +19,7 @@ import (
 	"github.com/pivotal-cf/kiln/internal/builder"
 	"github.com/pivotal-cf/kiln/internal/commands/flags"
 	"github.com/pivotal-cf/kiln/internal/helper"
+	"github.com/pivotal-cf/kiln/pkg/proofing"
 )
 
 //counterfeiter:generate -o ./fakes/interpolator.go --fake-name Interpolator . interpolator
@@ -153,30 +157,40 @@ type Bake struct {
 	icon     iconService
 	metadata metadataService
 
-	fetcher jhanda.Command
-	Options struct {
+	fetcher      jhanda.Command
+	watchContext context.Context
+	Options      struct {
 		flags.Standard
 		flags.FetchBakeOptions
 
-		Metadata                 string   `short:"m"   long:"metadata"                   default:"base.yml"         description:"path to the metadata file"`
-		ReleaseDirectories       []string `short:"rd"  long:"releases-directory"         default:"releases"         description:"path to a directory containing release tarballs"`
-		FormDirectories          []string `short:"f"   long:"forms-directory"            default:"forms"            description:"path to a directory containing forms"`
-		IconPath                 string   `short:"i"   long:"icon"                       default:"icon.png"         description:"path to icon file"`
-		InstanceGroupDirectories []string `short:"ig"  long:"instance-groups-directory"  default:"instance_groups"  description:"path to a directory containing instance groups"`
-		JobDirectories           []string `short:"j"   long:"jobs-directory"             default:"jobs"             description:"path to a directory containing jobs"`
-		MigrationDirectories     []string `short:"md"  long:"migrations-directory"       default:"migrations"       description:"path to a directory containing migrations"`
-		PropertyDirectories      []string `short:"pd"  long:"properties-directory"       default:"properties"       description:"path to a directory containing property blueprints"`
-		RuntimeConfigDirectories []string `short:"rcd" long:"runtime-configs-directory"  default:"runtime_configs"  description:"path to a directory containing runtime configs"`
-		BOSHVariableDirectories  []string `short:"vd"  long:"bosh-variables-directory"   default:"bosh_variables"   description:"path to a directory containing BOSH variables"`
-		StemcellTarball          string   `short:"st"  long:"stemcell-tarball"                                      description:"deprecated -- path to a stemcell tarball  (NOTE: mutually exclusive with --kilnfile)"`
-		StemcellsDirectories     []string `short:"sd"  long:"stemcells-directory"                                   description:"path to a directory containing stemcells  (NOTE: mutually exclusive with --kilnfile or --stemcell-tarball)"`
-		EmbedPaths               []string `short:"e"   long:"embed"                                                 description:"path to files to include in the tile /embed directory"`
-		OutputFile               string   `short:"o"   long:"output-file"                                           description:"path to where the tile will be output"`
-		MetadataOnly             bool     `short:"mo"  long:"metadata-only"                                         description:"don't build a tile, output the metadata to stdout"`
-		Sha256                   bool     `            long:"sha256"                                                description:"calculates a SHA256 checksum of the output file"`
-		StubReleases             bool     `short:"sr"  long:"stub-releases"                                         description:"skips importing release tarballs into the tile"`
-		Version                  string   `short:"v"   long:"version"                                               description:"version of the tile"`
-		SkipFetchReleases        []string `short:"sfr" long:"skip-fetch-directories"        description:"skips the automatic release fetch the specified release directories"`
+		Metadata                 string        `short:"m"   long:"metadata"                   default:"base.yml"         description:"path to the metadata file"`
+		ReleaseDirectories       []string      `short:"rd"  long:"releases-directory"         default:"releases"         description:"path to a directory containing release tarballs"`
+		FormDirectories          []string      `short:"f"   long:"forms-directory"            default:"forms"            description:"path to a directory containing forms"`
+		IconPath                 string        `short:"i"   long:"icon"                       default:"icon.png"         description:"path to icon file"`
+		InstanceGroupDirectories []string      `short:"ig"  long:"instance-groups-directory"  default:"instance_groups"  description:"path to a directory containing instance groups"`
+		JobDirectories           []string      `short:"j"   long:"jobs-directory"             default:"jobs"             description:"path to a directory containing jobs"`
+		MigrationDirectories     []string      `short:"md"  long:"migrations-directory"       default:"migrations"       description:"path to a directory containing migrations"`
+		PropertyDirectories      []string      `short:"pd"  long:"properties-directory"       default:"properties"       description:"path to a directory containing property blueprints"`
+		RuntimeConfigDirectories []string      `short:"rcd" long:"runtime-configs-directory"  default:"runtime_configs"  description:"path to a directory containing runtime configs"`
+		BOSHVariableDirectories  []string      `short:"vd"  long:"bosh-variables-directory"   default:"bosh_variables"   description:"path to a directory containing BOSH variables"`
+		PartialDirectories       []string      `            long:"partials-directory"        default:"partials"         description:"path to a directory containing template partials for the include and partial helpers"`
+		StemcellTarball          string        `short:"st"  long:"stemcell-tarball"                                      description:"deprecated -- path to a stemcell tarball  (NOTE: mutually exclusive with --kilnfile)"`
+		StemcellsDirectories     []string      `short:"sd"  long:"stemcells-directory"                                   description:"path to a directory containing stemcells  (NOTE: mutually exclusive with --kilnfile or --stemcell-tarball)"`
+		EmbedPaths               []string      `short:"e"   long:"embed"                                                 description:"path to files to include in the tile /embed directory"`
+		OutputFile               string        `short:"o"   long:"output-file"                                           description:"path to where the tile will be output"`
+		MetadataOnly             bool          `short:"mo"  long:"metadata-only"                                         description:"don't build a tile, output the metadata to stdout"`
+		Sha256                   bool          `            long:"sha256"                                                description:"calculates a SHA256 checksum of the output file"`
+		Lint                     bool          `            long:"lint"                                                  description:"checks the property and release references in job manifests and runtime configs"`
+		Reproducible             bool          `            long:"reproducible"                                          description:"builds a byte-identical tile from the same inputs; entry times are taken from SOURCE_DATE_EPOCH or the HEAD commit"`
+		StubReleases             bool          `short:"sr"  long:"stub-releases"                                         description:"skips importing release tarballs into the tile"`
+		Version                  string        `short:"v"   long:"version"                                               description:"version of the tile"`
+		SkipFetchReleases        []string      `short:"sfr" long:"skip-fetch-directories"        description:"skips the automatic release fetch the specified release directories"`
+		Watch                    bool          `            long:"watch"                                                 description:"watches the metadata, template and variables files, printing a diff of the interpolated metadata on each change (releases are not fetched)"`
+		WatchInterval            time.Duration `            long:"watch-interval"            default:"500ms"            description:"how often to check for changes when using --watch"`
+		Variants                 []string      `            long:"variant"                                               description:"name of a tile variant declared in the Kilnfile to bake (may be repeated)"`
+		AllVariants              bool          `            long:"all-variants"                                          description:"bakes every tile variant declared in the Kilnfile, parsing the releases once"`
+		Config                   string        `            long:"config"                    default:"bake.yml"         description:"path to a bake configuration file; flags that are passed override its values"`
+		PrintConfig              bool          `            long:"print-config"                                          description:"prints the configuration resolved from the bake configuration file, flags and defaults without baking"`
 	}
 }
 
@@ -206,9 +220,17 @@ func NewBakeWithInterfaces(interpolator interpolator, tileWriter tileWriter, out
 	}
 }
 
+// WithWatchContext configures the context that stops bake --watch. Without it bake --watch runs until the process exits.
+func (b Bake) WithWatchContext(ctx context.Context) Bake {
+	b.watchContext = ctx
+	return b
+}
+
 func shouldGenerateTileFileName(b *Bake, args []string) bool {
 	return b.Options.OutputFile == "" &&
 		!b.Options.MetadataOnly &&
+		!b.Options.Watch &&
+		!b.bakingVariants() &&
 		!flags.IsSet("o", "output-file", args)
 }
 
@@ -216,8 +238,9 @@ func shouldReadVersionFile(b *Bake, args []string) bool {
 	return b.Options.Version == "" && !flags.IsSet("v", "version", args)
 }
 
-func shouldNotUseDefaultKilnfileFlag(args []string) bool {
-	return (flags.IsSet("st", "stemcell-tarball", args) || flags.IsSet("sd", "stemcells-directory", args)) &&
+func shouldNotUseDefaultKilnfileFlag(b *Bake, args []string) bool {
+	// the stemcell options are only set by their flags or the bake configuration file
+	return (b.Options.StemcellTarball != "" || len(b.Options.StemcellsDirectories) > 0) &&
 		!flags.IsSet("kf", "kilnfile", args)
 }
 
@@ -246,15 +269,26 @@ func (b *Bake) loadFlags(args []string) error {
 		return err
 	}
 
+	if b.Options.Config != "" {
+		config, err := b.readConfiguration()
+		if err != nil {
+			return err
+		}
+		b.applyConfiguration(config, args)
+	}
+
 	// setup default creds
 	if !b.Options.MetadataOnly {
 		added, err := addDefaultCredentials(b)
 		if err != nil {
 			return err
 		}
-		if added {
+		// the hints are not printed with --print-config so the printed configuration is valid YAML
+		switch {
+		case b.Options.PrintConfig:
+		case added:
 			b.outLogger.Println("Setting default credentials from ~/.kiln/credentials.yml. (hint: --variable-file overrides this default. --variable overrides both.)")
-		} else {
+		default:
 			b.outLogger.Println("Warning: No credentials file found at ~/.kiln/credentials.yml. (hint: create this file to set default credentials. see --help for more info.)")
 		}
 	}
@@ -293,7 +327,7 @@ func (b *Bake) loadFlags(args []string) error {
 		}
 	}
 
-	if shouldNotUseDefaultKilnfileFlag(args) {
+	if shouldNotUseDefaultKilnfileFlag(b, args) {
 		b.Options.Standard.Kilnfile = ""
 	}
 
@@ -342,7 +376,11 @@ func (b Bake) Execute(args []string) error {
 		return err
 	}
 
-	if !b.Options.StubReleases {
+	if b.Options.PrintConfig {
+		return b.printConfiguration()
+	}
+
+	if !b.Options.StubReleases && !b.Options.Watch {
 	fetch:
 		// TODO update to take the union of release dirs into account
 		for _, dir := range b.Options.ReleaseDirectories {
@@ -393,92 +431,96 @@ func (b Bake) Execute(args []string) error {
 		return errors.New("--output-file cannot be provided when using --metadata-only")
 	}
 
+	if b.Options.OutputFile != "" && b.Options.Watch {
+		return errors.New("--output-file cannot be provided when using --watch")
+	}
+
+	if b.Options.AllVariants && len(b.Options.Variants) > 0 {
+		return errors.New("--variant cannot be provided when using --all-variants")
+	}
+
+	if b.bakingVariants() {
+		switch {
+		case b.Options.OutputFile != "":
+			return errors.New("--output-file cannot be provided when baking variants (set output_file on the variant in the Kilnfile)")
+		case b.Options.Watch:
+			return errors.New("--watch cannot be provided when baking variants")
+		case b.Options.MetadataOnly && (b.Options.AllVariants || len(b.Options.Variants) > 1):
+			return errors.New("--metadata-only can only be used with a single --variant")
+		}
+	}
+
 	// TODO: Remove check after deprecation of --stemcell-tarball
 	if b.Options.StemcellTarball != "" {
 		b.errLogger.Println("warning: --stemcell-tarball is being deprecated in favor of --stemcells-directory")
 	}
 
-	templateVariables, err := b.templateVariables.FromPathsAndPairs(b.Options.VariableFiles, b.Options.Variables)
+	var parts metadataParts
+
+	err = b.readTemplateVariables(&parts)
 	if err != nil {
-		return fmt.Errorf("failed to parse template variables: %s", err)
+		return err
 	}
 
-	releaseManifests, err := b.releases.FromDirectories(b.Options.ReleaseDirectories)
+	parts.releaseManifests, err = b.releases.FromDirectories(b.Options.ReleaseDirectories)
 	if err != nil {
 		return fmt.Errorf("failed to parse releases: %s", err)
 	}
 
-	var stemcellManifests map[string]interface{}
-	var stemcellManifest interface{}
 	if b.Options.StemcellTarball != "" {
 		// TODO remove when stemcell tarball is deprecated
-		stemcellManifest, err = b.stemcell.FromTarball(b.Options.StemcellTarball)
+		parts.stemcellManifest, err = b.stemcell.FromTarball(b.Options.StemcellTarball)
 	} else if b.Options.Kilnfile != "" {
-		stemcellManifests, err = b.stemcell.FromKilnfile(b.Options.Kilnfile)
+		parts.stemcellManifests, err = b.stemcell.FromKilnfile(b.Options.Kilnfile)
 	} else if len(b.Options.StemcellsDirectories) > 0 {
-		stemcellManifests, err = b.stemcell.FromDirectories(b.Options.StemcellsDirectories)
+		parts.stemcellManifests, err = b.stemcell.FromDirectories(b.Options.StemcellsDirectories)
 	}
 	if err != nil {
 		return fmt.Errorf("failed to parse stemcell: %s", err)
 	}
 
-	boshVariables, err := b.boshVariables.ParseMetadataTemplates(b.Options.BOSHVariableDirectories, templateVariables)
+	parts.icon, err = b.icon.Encode(b.Options.IconPath)
 	if err != nil {
-		return fmt.Errorf("failed to parse bosh variables: %s", err)
+		return fmt.Errorf("failed to encode icon: %s", err)
 	}
 
-	forms, err := b.forms.ParseMetadataTemplates(b.Options.FormDirectories, templateVariables)
-	if err != nil {
-		return fmt.Errorf("failed to parse forms: %s", err)
+	if b.bakingVariants() {
+		return b.bakeVariants(parts)
 	}
 
-	instanceGroups, err := b.instanceGroups.ParseMetadataTemplates(b.Options.InstanceGroupDirectories, templateVariables)
+	err = b.readTemplates(&parts)
 	if err != nil {
-		return fmt.Errorf("failed to parse instance groups: %s", err)
+		return err
 	}
 
-	jobs, err := b.jobs.ParseMetadataTemplates(b.Options.JobDirectories, templateVariables)
-	if err != nil {
-		return fmt.Errorf("failed to parse jobs: %s", err)
+	if b.Options.Watch {
+		return b.watch(&parts)
 	}
 
-	propertyBlueprints, err := b.properties.ParseMetadataTemplates(b.Options.PropertyDirectories, templateVariables)
-	if err != nil {
-		return fmt.Errorf("failed to parse properties: %s", err)
-	}
+	return b.bakeTile(parts, nil)
+}
 
-	runtimeConfigs, err := b.runtimeConfigs.ParseMetadataTemplates(b.Options.RuntimeConfigDirectories, templateVariables)
-	if err != nil {
-		return fmt.Errorf("failed to parse runtime configs: %s", err)
+// readTemplates reads the metadata, the partials and parses the metadata part templates.
+func (b Bake) readTemplates(parts *metadataParts) error {
+	for _, parser := range b.metadataPartParsers(parts) {
+		err := parser.parse()
+		if err != nil {
+			return err
+		}
 	}
 
-	icon, err := b.icon.Encode(b.Options.IconPath)
+	err := b.readPartials(parts)
 	if err != nil {
-		return fmt.Errorf("failed to encode icon: %s", err)
+		return err
 	}
 
-	metadata, err := b.metadata.Read(b.Options.Metadata)
-	if err != nil {
-		return fmt.Errorf("failed to read metadata: %s", err)
-	}
+	return b.readMetadata(parts)
+}
 
-	input := builder.InterpolateInput{
-		Version:            b.Options.Version,
-		Variables:          templateVariables,
-		BOSHVariables:      boshVariables,
-		ReleaseManifests:   releaseManifests,
-		StemcellManifests:  stemcellManifests,
-		StemcellManifest:   stemcellManifest, // TODO Remove when --stemcell-tarball is deprecated
-		FormTypes:          forms,
-		IconImage:          icon,
-		InstanceGroups:     instanceGroups,
-		Jobs:               jobs,
-		PropertyBlueprints: propertyBlueprints,
-		RuntimeConfigs:     runtimeConfigs,
-		StubReleases:       b.Options.StubReleases,
-		MetadataGitSHA:     builder.GitMetadataSHA(filepath.Dir(b.Options.Kilnfile), b.Options.MetadataOnly || b.Options.StubReleases),
-	}
-	interpolatedMetadata, err := b.interpolator.Interpolate(input, b.Options.Metadata, metadata)
+// bakeTile interpolates the metadata and writes the tile or, with --metadata-only, prints the metadata.
+// When releaseFiles is not empty only those release tarballs are added to the tile.
+func (b Bake) bakeTile(parts metadataParts, releaseFiles []string) error {
+	interpolatedMetadata, err := b.interpolateMetadata(parts)
 	if err != nil {
 		return err
 	}
@@ -488,13 +530,23 @@ func (b Bake) Execute(args []string) error {
 		return nil
 	}
 
-	err = b.tileWriter.Write(interpolatedMetadata, builder.WriteInput{
+	writeInput := builder.WriteInput{
 		OutputFile:           b.Options.OutputFile,
 		StubReleases:         b.Options.StubReleases,
 		MigrationDirectories: b.Options.MigrationDirectories,
 		ReleaseDirectories:   b.Options.ReleaseDirectories,
+		ReleaseFiles:         releaseFiles,
 		EmbedPaths:           b.Options.EmbedPaths,
-	})
+	}
+	if b.Options.Reproducible {
+		writeInput.Reproducible = true
+		writeInput.ModifiedTime, err = builder.SourceDateEpoch(filepath.Dir(b.Options.Kilnfile))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = b.tileWriter.Write(interpolatedMetadata, writeInput)
 	if err != nil {
 		return err
 	}
@@ -509,6 +561,160 @@ func (b Bake) Execute(args []string) error {
 	return nil
 }
 
+// metadataParts are the inputs interpolated into the metadata template.
+type metadataParts struct {
+	templateVariables map[string]interface{}
+	releaseManifests  map[string]interface{}
+	stemcellManifests map[string]interface{}
+	stemcellManifest  interface{}
+	boshVariables     map[string]interface{}
+	forms             map[string]interface{}
+	instanceGroups    map[string]interface{}
+	jobs              map[string]interface{}
+	properties        map[string]interface{}
+	runtimeConfigs    map[string]interface{}
+	partials          map[string]builder.Partial
+	icon              string
+	metadata          []byte
+}
+
+// metadataPartParser parses the templates in a set of directories into one of the metadata parts.
+type metadataPartParser struct {
+	directories []string
+	parse       func() error
+}
+
+func (b Bake) metadataPartParsers(parts *metadataParts) []metadataPartParser {
+	parser := func(name string, service metadataTemplatesParser, directories []string, part *map[string]interface{}) metadataPartParser {
+		return metadataPartParser{
+			directories: directories,
+			parse: func() error {
+				parsed, err := service.ParseMetadataTemplates(directories, parts.templateVariables)
+				if err != nil {
+					return fmt.Errorf("failed to parse %s: %s", name, err)
+				}
+				*part = parsed
+				return nil
+			},
+		}
+	}
+	return []metadataPartParser{
+		parser("bosh variables", b.boshVariables, b.Options.BOSHVariableDirectories, &parts.boshVariables),
+		parser("forms", b.forms, b.Options.FormDirectories, &parts.forms),
+		parser("instance groups", b.instanceGroups, b.Options.InstanceGroupDirectories, &parts.instanceGroups),
+		parser("jobs", b.jobs, b.Options.JobDirectories, &parts.jobs),
+		parser("properties", b.properties, b.Options.PropertyDirectories, &parts.properties),
+		parser("runtime configs", b.runtimeConfigs, b.Options.RuntimeConfigDirectories, &parts.runtimeConfigs),
+	}
+}
+
+func (b Bake) readTemplateVariables(parts *metadataParts) error {
+	templateVariables, err := b.templateVariables.FromPathsAndPairs(b.Options.VariableFiles, b.Options.Variables)
+	if err != nil {
+		return fmt.Errorf("failed to parse template variables: %s", err)
+	}
+	parts.templateVariables = templateVariables
+	return nil
+}
+
+func (b Bake) readMetadata(parts *metadataParts) error {
+	metadata, err := b.metadata.Read(b.Options.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata: %s", err)
+	}
+	parts.metadata = metadata
+	return nil
+}
+
+// readPartials reads the template partials. A partial is named by its path in the partials directory
+// without the extension, so partials/networks/az.yml is included with $( include "networks/az" ).
+func (b Bake) readPartials(parts *metadataParts) error {
+	partials := make(map[string]builder.Partial)
+	for _, directory := range b.Options.PartialDirectories {
+		err := b.readPartialsDirectory(partials, directory, "")
+		if err != nil {
+			return fmt.Errorf("failed to read partials: %s", err)
+		}
+	}
+	if len(partials) == 0 {
+		partials = nil
+	}
+	parts.partials = partials
+	return nil
+}
+
+func (b Bake) readPartialsDirectory(partials map[string]builder.Partial, directory, prefix string) error {
+	infos, err := b.fs.ReadDir(directory)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		filePath := filepath.Join(directory, info.Name())
+		if info.IsDir() {
+			err := b.readPartialsDirectory(partials, filePath, prefix+info.Name()+"/")
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		extension := filepath.Ext(info.Name())
+		if extension != ".yml" && extension != ".yaml" {
+			continue
+		}
+		name := prefix + strings.TrimSuffix(info.Name(), extension)
+		if existing, ok := partials[name]; ok {
+			return fmt.Errorf("partial %q is defined in both %s and %s", name, existing.File, filePath)
+		}
+		file, err := b.fs.Open(filePath)
+		if err != nil {
+			return err
+		}
+		contents, err := io.ReadAll(file)
+		closeAndIgnoreError(file)
+		if err != nil {
+			return err
+		}
+		partials[name] = builder.Partial{File: filePath, Template: contents}
+	}
+	return nil
+}
+
+func (b Bake) interpolateMetadata(parts metadataParts) ([]byte, error) {
+	input := builder.InterpolateInput{
+		Version:            b.Options.Version,
+		Variables:          parts.templateVariables,
+		BOSHVariables:      parts.boshVariables,
+		ReleaseManifests:   parts.releaseManifests,
+		StemcellManifests:  parts.stemcellManifests,
+		StemcellManifest:   parts.stemcellManifest, // TODO Remove when --stemcell-tarball is deprecated
+		FormTypes:          parts.forms,
+		IconImage:          parts.icon,
+		InstanceGroups:     parts.instanceGroups,
+		Jobs:               parts.jobs,
+		PropertyBlueprints: parts.properties,
+		RuntimeConfigs:     parts.runtimeConfigs,
+		Partials:           parts.partials,
+		StubReleases:       b.Options.StubReleases,
+		MetadataGitSHA:     builder.GitMetadataSHA(filepath.Dir(b.Options.Kilnfile), b.Options.MetadataOnly || b.Options.StubReleases || b.Options.Watch),
+	}
+	interpolatedMetadata, err := b.interpolator.Interpolate(input, b.Options.Metadata, parts.metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.Options.Lint {
+		productTemplate, err := proofing.Parse(bytes.NewReader(interpolatedMetadata))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse interpolated metadata: %w", err)
+		}
+		if err := productTemplate.Lint(); err != nil {
+			return nil, fmt.Errorf("interpolated metadata has invalid references:\n%w", err)
+		}
+	}
+
+	return interpolatedMetadata, nil
+}
+
 func (b Bake) Usage() jhanda.Usage {
 	return jhanda.Usage{
 		Description:      "Bakes tile metadata, stemcell, releases, and migrations into a format that can be consumed by OpsManager.",
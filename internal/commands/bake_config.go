@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/kiln/internal/commands/flags"
+)
+
+// bakeConfiguration is the bake configuration file (bake.yml). Its values replace the flag defaults,
+// and flags that are passed explicitly replace its values. Paths are relative to the directory
+// containing the file.
+type bakeConfiguration struct {
+	Metadata string `yaml:"metadata,omitempty"`
+	Icon     string `yaml:"icon,omitempty"`
+
+	ReleaseDirectories       []string `yaml:"releases_directories,omitempty"`
+	BOSHVariableDirectories  []string `yaml:"bosh_variables_directories,omitempty"`
+	FormDirectories          []string `yaml:"forms_directories,omitempty"`
+	InstanceGroupDirectories []string `yaml:"instance_groups_directories,omitempty"`
+	JobDirectories           []string `yaml:"jobs_directories,omitempty"`
+	MigrationDirectories     []string `yaml:"migrations_directories,omitempty"`
+	PartialDirectories       []string `yaml:"partials_directories,omitempty"`
+	PropertyDirectories      []string `yaml:"properties_directories,omitempty"`
+	RuntimeConfigDirectories []string `yaml:"runtime_configs_directories,omitempty"`
+	EmbedPaths               []string `yaml:"embed_paths,omitempty"`
+
+	VariablesFiles []string `yaml:"variables_files,omitempty"`
+
+	OutputFile string `yaml:"output_file,omitempty"`
+
+	// StemcellsDirectories and StemcellTarball select where the stemcell criteria are read
+	// from. When neither is set the criteria are read from the Kilnfile.lock.
+	StemcellsDirectories []string `yaml:"stemcells_directories,omitempty"`
+	StemcellTarball      string   `yaml:"stemcell_tarball,omitempty"`
+}
+
+func (b Bake) readConfiguration() (bakeConfiguration, error) {
+	file, err := b.fs.Open(b.Options.Config)
+	if err != nil {
+		return bakeConfiguration{}, fmt.Errorf("failed to open bake configuration: %w", err)
+	}
+	defer closeAndIgnoreError(file)
+	buf, err := io.ReadAll(file)
+	if err != nil {
+		return bakeConfiguration{}, fmt.Errorf("failed to read bake configuration: %w", err)
+	}
+	var config bakeConfiguration
+	err = yaml.UnmarshalStrict(buf, &config)
+	if err != nil {
+		return bakeConfiguration{}, fmt.Errorf("failed to parse bake configuration %s: %w", b.Options.Config, err)
+	}
+	return config, nil
+}
+
+// applyConfiguration sets the options from the configuration file unless the flag is in args.
+func (b *Bake) applyConfiguration(config bakeConfiguration, args []string) {
+	configDirectory := filepath.Dir(b.Options.Config)
+	resolve := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(configDirectory, p)
+	}
+	setPath := func(option *string, value, short, long string) {
+		if value == "" || flags.IsSet(short, long, args) {
+			return
+		}
+		*option = resolve(value)
+	}
+	setPaths := func(option *[]string, values []string, short, long string) {
+		if len(values) == 0 || flags.IsSet(short, long, args) {
+			return
+		}
+		resolved := make([]string, 0, len(values))
+		for _, p := range values {
+			resolved = append(resolved, resolve(p))
+		}
+		*option = resolved
+	}
+
+	setPath(&b.Options.Metadata, config.Metadata, "m", "metadata")
+	setPath(&b.Options.IconPath, config.Icon, "i", "icon")
+	setPaths(&b.Options.ReleaseDirectories, config.ReleaseDirectories, "rd", "releases-directory")
+	setPaths(&b.Options.BOSHVariableDirectories, config.BOSHVariableDirectories, "vd", "bosh-variables-directory")
+	setPaths(&b.Options.FormDirectories, config.FormDirectories, "f", "forms-directory")
+	setPaths(&b.Options.InstanceGroupDirectories, config.InstanceGroupDirectories, "ig", "instance-groups-directory")
+	setPaths(&b.Options.JobDirectories, config.JobDirectories, "j", "jobs-directory")
+	setPaths(&b.Options.MigrationDirectories, config.MigrationDirectories, "md", "migrations-directory")
+	setPaths(&b.Options.PartialDirectories, config.PartialDirectories, "", "partials-directory")
+	setPaths(&b.Options.PropertyDirectories, config.PropertyDirectories, "pd", "properties-directory")
+	setPaths(&b.Options.RuntimeConfigDirectories, config.RuntimeConfigDirectories, "rcd", "runtime-configs-directory")
+	setPaths(&b.Options.EmbedPaths, config.EmbedPaths, "e", "embed")
+	setPaths(&b.Options.VariableFiles, config.VariablesFiles, "vf", "variables-file")
+	setPaths(&b.Options.StemcellsDirectories, config.StemcellsDirectories, "sd", "stemcells-directory")
+	setPath(&b.Options.StemcellTarball, config.StemcellTarball, "st", "stemcell-tarball")
+
+	// the metadata is printed and variants name their own tiles so the output file does not apply
+	if !b.Options.MetadataOnly && !b.Options.Watch && !b.bakingVariants() {
+		setPath(&b.Options.OutputFile, config.OutputFile, "o", "output-file")
+	}
+}
+
+// resolvedConfiguration returns the configuration after the configuration file, flags and defaults are applied.
+func (b Bake) resolvedConfiguration() bakeConfiguration {
+	return bakeConfiguration{
+		Metadata:                 b.Options.Metadata,
+		Icon:                     b.Options.IconPath,
+		ReleaseDirectories:       b.Options.ReleaseDirectories,
+		BOSHVariableDirectories:  b.Options.BOSHVariableDirectories,
+		FormDirectories:          b.Options.FormDirectories,
+		InstanceGroupDirectories: b.Options.InstanceGroupDirectories,
+		JobDirectories:           b.Options.JobDirectories,
+		MigrationDirectories:     b.Options.MigrationDirectories,
+		PartialDirectories:       b.Options.PartialDirectories,
+		PropertyDirectories:      b.Options.PropertyDirectories,
+		RuntimeConfigDirectories: b.Options.RuntimeConfigDirectories,
+		EmbedPaths:               b.Options.EmbedPaths,
+		VariablesFiles:           b.Options.VariableFiles,
+		OutputFile:               b.Options.OutputFile,
+		StemcellsDirectories:     b.Options.StemcellsDirectories,
+		StemcellTarball:          b.Options.StemcellTarball,
+	}
+}
+
+func (b Bake) printConfiguration() error {
+	buf, err := yaml.Marshal(b.resolvedConfiguration())
+	if err != nil {
+		return err // untestable
+	}
+	b.outLogger.Printf("%s", buf)
+	return nil
+}
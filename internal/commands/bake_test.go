@@ -1,14 +1,19 @@
 package commands_test
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/go-git/go-billy/v5/osfs"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 	. "github.com/pivotal-cf-experimental/gomegamatchers"
 	"github.com/pivotal-cf/jhanda"
 	"github.com/pivotal-cf/kiln/internal/builder"
@@ -88,7 +93,12 @@ var _ = Describe("Bake", func() {
 		fileVersion := "some-version"
 		fakeVersionInfo.SizeReturns(int64(len(fileVersion)))
 		fakeVersionInfo.NameReturns("version")
-		fakeFilesystem.StatReturns(fakeVersionInfo, nil)
+		fakeFilesystem.StatStub = func(name string) (os.FileInfo, error) {
+			if filepath.Base(name) == "bake.yml" {
+				return nil, os.ErrNotExist
+			}
+			return fakeVersionInfo, nil
+		}
 		result1 := &fakes.File{}
 		result1.ReadReturns(0, nil)
 		fakeFilesystem.OpenReturns(result1, nil)
@@ -355,6 +365,9 @@ var _ = Describe("Bake", func() {
 				"/home/.kiln/credentials.yml",
 				"--variable", "some-variable=some-variable-value",
 				"--download-threads", "5",
+				"--parallel-downloads", "4",
+				"--download-retries", "3",
+				"--download-retry-delay", "2s",
 				"--no-confirm",
 				"--releases-directory",
 				otherReleasesDirectory,
@@ -367,6 +380,9 @@ var _ = Describe("Bake", func() {
 				"/home/.kiln/credentials.yml",
 				"--variable", "some-variable=some-variable-value",
 				"--download-threads", "5",
+				"--parallel-downloads", "4",
+				"--download-retries", "3",
+				"--download-retry-delay", "2s",
 				"--no-confirm",
 				"--releases-directory",
 				someReleasesDirectory,
@@ -392,6 +408,426 @@ var _ = Describe("Bake", func() {
 				Expect(fakeFetcher.ExecuteCallCount()).To(Equal(0))
 			})
 		})
+		Context("when --lint is specified", func() {
+			It("returns an error for invalid references", func() {
+				fakeInterpolator.InterpolateReturns([]byte(`---
+name: some-product
+job_types:
+  - name: some-job
+    manifest: |
+      some_property: (( .properties.some_missing_property.value ))
+`), nil)
+
+				err := bake.Execute([]string{
+					"--metadata", "some-metadata",
+					"--output-file", "some-output-dir/some-product-file-1.2.3-build.4",
+					"--lint",
+				})
+				Expect(err).To(MatchError(ContainSubstring(`job_types[0].manifest: ".properties.some_missing_property.value" references undeclared property blueprint "some_missing_property"`)))
+				Expect(fakeTileWriter.WriteCallCount()).To(Equal(0))
+			})
+
+			It("builds the tile when references are valid", func() {
+				fakeInterpolator.InterpolateReturns([]byte(`---
+name: some-product
+property_blueprints:
+  - {name: some_property, type: string, configurable: true, default: some-value}
+job_types:
+  - name: some-job
+    manifest: |
+      some_property: (( .properties.some_property.value ))
+`), nil)
+
+				err := bake.Execute([]string{
+					"--metadata", "some-metadata",
+					"--output-file", "some-output-dir/some-product-file-1.2.3-build.4",
+					"--lint",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeTileWriter.WriteCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when --reproducible is specified", func() {
+			var previousSourceDateEpoch string
+
+			BeforeEach(func() {
+				previousSourceDateEpoch = os.Getenv("SOURCE_DATE_EPOCH")
+				Expect(os.Setenv("SOURCE_DATE_EPOCH", "1635975074")).To(Succeed())
+			})
+
+			AfterEach(func() {
+				Expect(os.Setenv("SOURCE_DATE_EPOCH", previousSourceDateEpoch)).To(Succeed())
+			})
+
+			It("writes the tile with the time from SOURCE_DATE_EPOCH", func() {
+				err := bake.Execute([]string{
+					"--metadata", "some-metadata",
+					"--output-file", "some-output-dir/some-product-file-1.2.3-build.4",
+					"--reproducible",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeTileWriter.WriteCallCount()).To(Equal(1))
+				_, writeInput := fakeTileWriter.WriteArgsForCall(0)
+				Expect(writeInput.Reproducible).To(BeTrue())
+				Expect(writeInput.ModifiedTime).To(Equal(time.Unix(1635975074, 0).UTC()))
+			})
+
+			It("returns an error when SOURCE_DATE_EPOCH is not a number", func() {
+				Expect(os.Setenv("SOURCE_DATE_EPOCH", "yesterday")).To(Succeed())
+
+				err := bake.Execute([]string{
+					"--metadata", "some-metadata",
+					"--output-file", "some-output-dir/some-product-file-1.2.3-build.4",
+					"--reproducible",
+				})
+				Expect(err).To(MatchError(ContainSubstring("SOURCE_DATE_EPOCH must be a number of seconds since the unix epoch")))
+				Expect(fakeTileWriter.WriteCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when --watch is specified", func() {
+			var (
+				outBuffer, errBuffer *gbytes.Buffer
+				ctx                  context.Context
+				cancel               context.CancelFunc
+				done                 chan error
+			)
+
+			BeforeEach(func() {
+				Expect(os.MkdirAll(filepath.Join(tmpDir, "forms"), 0o755)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(tmpDir, "jobs"), 0o755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(tmpDir, "base.yml"), []byte("name: $( variable \"name\" )\n"), 0o644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(tmpDir, "forms", "form.yml"), []byte("name: some-form\n"), 0o644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(tmpDir, "jobs", "job.yml"), []byte("name: some-job\n"), 0o644)).To(Succeed())
+
+				outBuffer, errBuffer = gbytes.NewBuffer(), gbytes.NewBuffer()
+				ctx, cancel = context.WithCancel(context.Background())
+				done = make(chan error, 1)
+
+				fakeFormsService.ParseMetadataTemplatesStub = func([]string, map[string]interface{}) (map[string]interface{}, error) {
+					form, err := os.ReadFile(filepath.Join(tmpDir, "forms", "form.yml"))
+					if err != nil {
+						return nil, err
+					}
+					if bytes.Contains(form, []byte("broken")) {
+						return nil, errors.New("some template error")
+					}
+					return map[string]interface{}{"some-form": string(bytes.TrimSpace(form))}, nil
+				}
+				fakeInterpolator.InterpolateStub = func(input builder.InterpolateInput, _ string, _ []byte) ([]byte, error) {
+					return []byte(fmt.Sprintf("form: %s\nversion: %s\n", input.FormTypes["some-form"], input.Version)), nil
+				}
+
+				bake = commands.NewBakeWithInterfaces(fakeInterpolator, fakeTileWriter, log.New(outBuffer, "", 0), log.New(errBuffer, "", 0), fakeTemplateVariablesService, fakeBOSHVariablesService, fakeReleasesService, fakeStemcellService, fakeFormsService, fakeInstanceGroupsService, fakeJobsService, fakePropertiesService, fakeRuntimeConfigsService, fakeIconService, fakeMetadataService, fakeChecksummer, fakeFetcher, osfs.New(tmpDir), fakeHomeDirFunc).WithWatchContext(ctx)
+
+				go func() {
+					done <- bake.Execute([]string{
+						"--metadata", "base.yml",
+						"--forms-directory", "forms",
+						"--instance-groups-directory", "jobs",
+						"--jobs-directory", "jobs",
+						"--version", "1.2.3",
+						"--watch",
+						"--watch-interval", "10ms",
+					})
+				}()
+				Eventually(outBuffer).Should(gbytes.Say("form: name: some-form\nversion: 1.2.3\n"))
+			})
+
+			AfterEach(func() {
+				cancel()
+				Eventually(done).Should(Receive(BeNil()))
+			})
+
+			It("prints a diff of the metadata after re-parsing only the changed directory", func() {
+				Expect(os.WriteFile(filepath.Join(tmpDir, "forms", "form.yml"), []byte("name: some-other-form\n"), 0o644)).To(Succeed())
+
+				Eventually(outBuffer).Should(gbytes.Say(`(?s)--- previous metadata\n\+\+\+ metadata\n.*-form: name: some-form\n\+form: name: some-other-form\n`))
+
+				Expect(fakeFormsService.ParseMetadataTemplatesCallCount()).To(Equal(2))
+				Expect(fakeJobsService.ParseMetadataTemplatesCallCount()).To(Equal(1))
+				Expect(fakeInterpolator.InterpolateCallCount()).To(Equal(2))
+				Expect(fakeReleasesService.FromDirectoriesCallCount()).To(Equal(1))
+				Expect(fakeFetcher.ExecuteCallCount()).To(Equal(0))
+				Expect(fakeTileWriter.WriteCallCount()).To(Equal(0))
+			})
+
+			It("waits for a file to stop changing before parsing it", func() {
+				form, err := os.OpenFile(filepath.Join(tmpDir, "forms", "form.yml"), os.O_WRONLY|os.O_TRUNC, 0o644)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = form.WriteString("name: some-")
+				Expect(err).NotTo(HaveOccurred())
+				time.Sleep(time.Millisecond)
+				_, err = form.WriteString("other-form\n")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(form.Close()).To(Succeed())
+
+				Eventually(outBuffer).Should(gbytes.Say(`\+form: name: some-other-form\n`))
+				Expect(string(outBuffer.Contents())).NotTo(ContainSubstring("+form: name: some-\n"))
+				Expect(fakeFormsService.ParseMetadataTemplatesCallCount()).To(Equal(2))
+			})
+
+			It("prints template errors and keeps watching", func() {
+				Expect(os.WriteFile(filepath.Join(tmpDir, "forms", "form.yml"), []byte("name: broken\n"), 0o644)).To(Succeed())
+				Eventually(errBuffer).Should(gbytes.Say("error: failed to parse forms: some template error"))
+
+				Expect(os.WriteFile(filepath.Join(tmpDir, "forms", "form.yml"), []byte("name: fixed\n"), 0o644)).To(Succeed())
+				Eventually(outBuffer).Should(gbytes.Say(`\+form: name: fixed\n`))
+				Expect(fakeInterpolator.InterpolateCallCount()).To(Equal(2))
+			})
+
+			It("prints that nothing changed when only the metadata file changed without affecting the output", func() {
+				Expect(os.WriteFile(filepath.Join(tmpDir, "base.yml"), []byte("name: $( variable \"other-name\" )\n"), 0o644)).To(Succeed())
+
+				Eventually(outBuffer).Should(gbytes.Say("No changes to metadata"))
+				Expect(fakeMetadataService.ReadCallCount()).To(Equal(2))
+				Expect(fakeFormsService.ParseMetadataTemplatesCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when --partials-directory is specified", func() {
+			BeforeEach(func() {
+				Expect(os.MkdirAll(filepath.Join(tmpDir, "partials", "networks"), 0o755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(tmpDir, "partials", "label.yml"), []byte("label: some-label\n"), 0o644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(tmpDir, "partials", "networks", "az.yaml"), []byte("azs: [a, b]\n"), 0o644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(tmpDir, "partials", "README.md"), []byte("not a partial\n"), 0o644)).To(Succeed())
+
+				bake = commands.NewBakeWithInterfaces(fakeInterpolator, fakeTileWriter, fakeLogger, fakeLogger, fakeTemplateVariablesService, fakeBOSHVariablesService, fakeReleasesService, fakeStemcellService, fakeFormsService, fakeInstanceGroupsService, fakeJobsService, fakePropertiesService, fakeRuntimeConfigsService, fakeIconService, fakeMetadataService, fakeChecksummer, fakeFetcher, osfs.New(tmpDir), fakeHomeDirFunc)
+			})
+
+			It("passes the partials to the interpolator", func() {
+				err := bake.Execute([]string{
+					"--metadata", "some-metadata",
+					"--partials-directory", "partials",
+					"--version", "1.2.3",
+					"--metadata-only",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeInterpolator.InterpolateCallCount()).To(Equal(1))
+				input, _, _ := fakeInterpolator.InterpolateArgsForCall(0)
+				Expect(input.Partials).To(Equal(map[string]builder.Partial{
+					"label":       {File: filepath.Join("partials", "label.yml"), Template: []byte("label: some-label\n")},
+					"networks/az": {File: filepath.Join("partials", "networks", "az.yaml"), Template: []byte("azs: [a, b]\n")},
+				}))
+			})
+
+			It("returns an error when the partials directory does not exist", func() {
+				err := bake.Execute([]string{
+					"--metadata", "some-metadata",
+					"--partials-directory", "missing-partials",
+					"--version", "1.2.3",
+					"--metadata-only",
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to read partials")))
+			})
+		})
+
+		Context("when a bake configuration file exists", func() {
+			var outBuffer *gbytes.Buffer
+
+			BeforeEach(func() {
+				Expect(os.WriteFile(filepath.Join(tmpDir, "bake.yml"), []byte(`---
+metadata: some-metadata
+forms_directories: [forms, more_forms]
+instance_groups_directories: [instance_groups]
+variables_files: [variables.yml]
+output_file: some-tile.pivotal
+stemcells_directories: [stemcells]
+`), 0o644)).To(Succeed())
+
+				outBuffer = gbytes.NewBuffer()
+				bake = commands.NewBakeWithInterfaces(fakeInterpolator, fakeTileWriter, log.New(outBuffer, "", 0), fakeLogger, fakeTemplateVariablesService, fakeBOSHVariablesService, fakeReleasesService, fakeStemcellService, fakeFormsService, fakeInstanceGroupsService, fakeJobsService, fakePropertiesService, fakeRuntimeConfigsService, fakeIconService, fakeMetadataService, fakeChecksummer, fakeFetcher, osfs.New(tmpDir), fakeHomeDirFunc)
+			})
+
+			It("bakes the tile with its values", func() {
+				err := bake.Execute([]string{"--version", "1.2.3"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeMetadataService.ReadArgsForCall(0)).To(Equal("some-metadata"))
+				forms, _ := fakeFormsService.ParseMetadataTemplatesArgsForCall(0)
+				Expect(forms).To(Equal([]string{"forms", "more_forms"}))
+				variablesFiles, _ := fakeTemplateVariablesService.FromPathsAndPairsArgsForCall(0)
+				Expect(variablesFiles).To(Equal([]string{"variables.yml"}))
+
+				Expect(fakeStemcellService.FromDirectoriesCallCount()).To(Equal(1))
+				Expect(fakeStemcellService.FromDirectoriesArgsForCall(0)).To(Equal([]string{"stemcells"}))
+
+				Expect(fakeTileWriter.WriteCallCount()).To(Equal(1))
+				_, input := fakeTileWriter.WriteArgsForCall(0)
+				Expect(input.OutputFile).To(Equal("some-tile.pivotal"))
+			})
+
+			It("uses the flags that are passed instead", func() {
+				err := bake.Execute([]string{
+					"--version", "1.2.3",
+					"--forms-directory", "other_forms",
+					"--output-file", "other-tile.pivotal",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				forms, _ := fakeFormsService.ParseMetadataTemplatesArgsForCall(0)
+				Expect(forms).To(Equal([]string{"other_forms"}))
+				instanceGroups, _ := fakeInstanceGroupsService.ParseMetadataTemplatesArgsForCall(0)
+				Expect(instanceGroups).To(Equal([]string{"instance_groups"}))
+
+				_, input := fakeTileWriter.WriteArgsForCall(0)
+				Expect(input.OutputFile).To(Equal("other-tile.pivotal"))
+			})
+
+			It("resolves paths relative to the configuration file", func() {
+				Expect(os.MkdirAll(filepath.Join(tmpDir, "ci"), 0o755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(tmpDir, "ci", "small.yml"), []byte("metadata: small.yml\n"), 0o644)).To(Succeed())
+
+				err := bake.Execute([]string{"--config", "ci/small.yml", "--metadata-only"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeMetadataService.ReadArgsForCall(0)).To(Equal(filepath.Join("ci", "small.yml")))
+			})
+
+			It("prints the resolved configuration without baking", func() {
+				err := bake.Execute([]string{
+					"--version", "1.2.3",
+					"--forms-directory", "other_forms",
+					"--print-config",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				var config map[string]interface{}
+				Expect(yaml.Unmarshal(outBuffer.Contents(), &config)).To(Succeed())
+				Expect(config).To(Equal(map[string]interface{}{
+					"metadata":                    "some-metadata",
+					"forms_directories":           []interface{}{"other_forms"},
+					"instance_groups_directories": []interface{}{"instance_groups"},
+					"variables_files":             []interface{}{"variables.yml"},
+					"output_file":                 "some-tile.pivotal",
+					"stemcells_directories":       []interface{}{"stemcells"},
+				}))
+
+				Expect(fakeFetcher.ExecuteCallCount()).To(Equal(0))
+				Expect(fakeTileWriter.WriteCallCount()).To(Equal(0))
+			})
+
+			It("returns an error when the configuration file has an unknown field", func() {
+				Expect(os.WriteFile(filepath.Join(tmpDir, "bake.yml"), []byte("form_directories: [forms]\n"), 0o644)).To(Succeed())
+
+				err := bake.Execute([]string{"--version", "1.2.3"})
+				Expect(err).To(MatchError(ContainSubstring("failed to parse bake configuration bake.yml")))
+			})
+		})
+
+		Context("when --all-variants is specified", func() {
+			BeforeEach(func() {
+				Expect(os.WriteFile(filepath.Join(tmpDir, "Kilnfile"), []byte(`---
+variants:
+  - name: ert
+  - name: srt
+    output_file: small/srt.pivotal
+    variables_files: [variables/srt.yml]
+    variables:
+      small_footprint: true
+    instance_groups_directories: [srt/instance_groups]
+    releases: [some-release-2]
+`), 0o644)).To(Succeed())
+
+				bake = commands.NewBakeWithInterfaces(fakeInterpolator, fakeTileWriter, fakeLogger, fakeLogger, fakeTemplateVariablesService, fakeBOSHVariablesService, fakeReleasesService, fakeStemcellService, fakeFormsService, fakeInstanceGroupsService, fakeJobsService, fakePropertiesService, fakeRuntimeConfigsService, fakeIconService, fakeMetadataService, fakeChecksummer, fakeFetcher, osfs.New(tmpDir), fakeHomeDirFunc)
+			})
+
+			It("bakes every variant reading the releases once", func() {
+				err := bake.Execute([]string{
+					"--metadata", "some-metadata",
+					"--instance-groups-directory", "some-instance-groups-directory",
+					"--variables-file", "some-variables-file",
+					"--version", "1.2.3",
+					"--all-variants",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeReleasesService.FromDirectoriesCallCount()).To(Equal(1))
+				Expect(fakeStemcellService.FromKilnfileCallCount()).To(Equal(1))
+				Expect(fakeIconService.EncodeCallCount()).To(Equal(1))
+
+				Expect(fakeTemplateVariablesService.FromPathsAndPairsCallCount()).To(Equal(3))
+				variablesFiles, _ := fakeTemplateVariablesService.FromPathsAndPairsArgsForCall(2)
+				Expect(variablesFiles).To(Equal([]string{"some-variables-file", filepath.Join("variables", "srt.yml")}))
+
+				Expect(fakeInstanceGroupsService.ParseMetadataTemplatesCallCount()).To(Equal(2))
+				ertInstanceGroups, _ := fakeInstanceGroupsService.ParseMetadataTemplatesArgsForCall(0)
+				Expect(ertInstanceGroups).To(Equal([]string{"some-instance-groups-directory"}))
+				srtInstanceGroups, _ := fakeInstanceGroupsService.ParseMetadataTemplatesArgsForCall(1)
+				Expect(srtInstanceGroups).To(Equal([]string{filepath.Join("srt", "instance_groups")}))
+
+				Expect(fakeInterpolator.InterpolateCallCount()).To(Equal(2))
+				ertInput, _, _ := fakeInterpolator.InterpolateArgsForCall(0)
+				Expect(ertInput.Variables).To(HaveKeyWithValue("tile_name", "ert"))
+				Expect(ertInput.ReleaseManifests).To(HaveLen(2))
+				srtInput, _, _ := fakeInterpolator.InterpolateArgsForCall(1)
+				Expect(srtInput.Variables).To(HaveKeyWithValue("tile_name", "srt"))
+				Expect(srtInput.Variables).To(HaveKeyWithValue("small_footprint", true))
+				Expect(srtInput.ReleaseManifests).To(Equal(map[string]interface{}{
+					"some-release-2": builder.ReleaseManifest{
+						Name:    "some-release-2",
+						Version: "2.3.4",
+						File:    "release2.tar.gz",
+					},
+				}))
+
+				Expect(fakeTileWriter.WriteCallCount()).To(Equal(2))
+				_, ertWriteInput := fakeTileWriter.WriteArgsForCall(0)
+				Expect(ertWriteInput.OutputFile).To(Equal("tile-ert-1.2.3.pivotal"))
+				Expect(ertWriteInput.ReleaseFiles).To(BeEmpty())
+				_, srtWriteInput := fakeTileWriter.WriteArgsForCall(1)
+				Expect(srtWriteInput.OutputFile).To(Equal(filepath.Join("small", "srt.pivotal")))
+				Expect(srtWriteInput.ReleaseFiles).To(Equal([]string{"release2.tar.gz"}))
+			})
+
+			It("keeps the tile_name set in the variables", func() {
+				fakeTemplateVariablesService.FromPathsAndPairsReturns(map[string]interface{}{"tile_name": "ERT"}, nil)
+
+				err := bake.Execute([]string{
+					"--metadata", "some-metadata",
+					"--version", "1.2.3",
+					"--variant", "ert",
+					"--metadata-only",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeInterpolator.InterpolateCallCount()).To(Equal(1))
+				input, _, _ := fakeInterpolator.InterpolateArgsForCall(0)
+				Expect(input.Variables).To(HaveKeyWithValue("tile_name", "ERT"))
+				Expect(fakeTileWriter.WriteCallCount()).To(Equal(0))
+			})
+
+			It("returns an error when a variant does not exist", func() {
+				err := bake.Execute([]string{
+					"--metadata", "some-metadata",
+					"--variant", "banana",
+				})
+				Expect(err).To(MatchError(ContainSubstring(`tile variant with name "banana"`)))
+			})
+
+			It("returns an error when the output file is provided", func() {
+				err := bake.Execute([]string{
+					"--metadata", "some-metadata",
+					"--output-file", "some-tile.pivotal",
+					"--all-variants",
+				})
+				Expect(err).To(MatchError(ContainSubstring("--output-file cannot be provided when baking variants")))
+			})
+
+			It("returns an error when a variant release is not in the releases directories", func() {
+				fakeReleasesService.FromDirectoriesReturns(map[string]interface{}{}, nil)
+
+				err := bake.Execute([]string{
+					"--metadata", "some-metadata",
+					"--variant", "srt",
+				})
+				Expect(err).To(MatchError(`failed to bake variant "srt": release "some-release-2" is not in the releases directories`))
+			})
+		})
+
 		Context("when the --sha256 flag is not specified", func() {
 			It("does not calculate a checksum", func() {
 				err := bake.Execute([]string{
@@ -530,6 +966,9 @@ var _ = Describe("Bake", func() {
 					"--variables-file",
 					"/home/.kiln/credentials.yml",
 					"--download-threads", "0",
+					"--parallel-downloads", "4",
+					"--download-retries", "3",
+					"--download-retry-delay", "2s",
 					"--no-confirm",
 					"--releases-directory",
 					someReleasesDirectory,
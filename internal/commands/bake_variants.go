@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/pivotal-cf/kiln/internal/builder"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+func (b Bake) bakingVariants() bool {
+	return b.Options.AllVariants || len(b.Options.Variants) > 0
+}
+
+// bakeVariants bakes the tile variants declared in the Kilnfile. The releases, stemcells and icon in parts
+// are read once and shared; the templates are read again for each variant with its overrides applied.
+func (b Bake) bakeVariants(parts metadataParts) error {
+	variants, err := b.selectedVariants(parts.templateVariables)
+	if err != nil {
+		return err
+	}
+
+	for _, variant := range variants {
+		variantBake := b.withVariant(variant)
+		if !b.Options.MetadataOnly {
+			b.errLogger.Printf("Baking variant %q into %s...\n", variant.Name, variantBake.Options.OutputFile)
+		}
+		err := variantBake.bakeVariant(parts, variant)
+		if err != nil {
+			return fmt.Errorf("failed to bake variant %q: %w", variant.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (b Bake) bakeVariant(parts metadataParts, variant cargo.TileVariant) error {
+	err := b.readTemplateVariables(&parts)
+	if err != nil {
+		return err
+	}
+	variables := make(map[string]interface{}, len(parts.templateVariables)+len(variant.Variables)+1)
+	for name, value := range parts.templateVariables {
+		variables[name] = value
+	}
+	for name, value := range variant.Variables {
+		variables[name] = value
+	}
+	if _, ok := variables[builder.TileNameVariable]; !ok {
+		variables[builder.TileNameVariable] = variant.Name
+	}
+	parts.templateVariables = variables
+
+	var releaseFiles []string
+	parts.releaseManifests, releaseFiles, err = variantReleases(parts.releaseManifests, variant.Releases)
+	if err != nil {
+		return err
+	}
+
+	err = b.readTemplates(&parts)
+	if err != nil {
+		return err
+	}
+
+	return b.bakeTile(parts, releaseFiles)
+}
+
+func (b Bake) selectedVariants(templateVariables map[string]interface{}) ([]cargo.TileVariant, error) {
+	if b.Options.Kilnfile == "" {
+		return nil, errors.New("baking variants requires a Kilnfile")
+	}
+	kilnfileFile, err := b.fs.Open(b.Options.Kilnfile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Kilnfile: %w", err)
+	}
+	defer closeAndIgnoreError(kilnfileFile)
+	kilnfile, err := cargo.InterpolateAndParseKilnfile(kilnfileFile, templateVariables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Kilnfile: %w", err)
+	}
+
+	if b.Options.AllVariants {
+		if len(kilnfile.Variants) == 0 {
+			return nil, fmt.Errorf("%s does not declare any variants", b.Options.Kilnfile)
+		}
+		return kilnfile.Variants, nil
+	}
+
+	variants := make([]cargo.TileVariant, 0, len(b.Options.Variants))
+	for _, name := range b.Options.Variants {
+		variant, err := kilnfile.FindVariant(name)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, variant)
+	}
+	return variants, nil
+}
+
+// withVariant returns a copy of b with the variant's overrides applied to the options.
+// Paths in the variant are relative to the directory containing the Kilnfile.
+func (b Bake) withVariant(variant cargo.TileVariant) Bake {
+	kilnfileDirectory := filepath.Dir(b.Options.Kilnfile)
+	resolve := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(kilnfileDirectory, p)
+	}
+	override := func(option *[]string, paths []string) {
+		if len(paths) == 0 {
+			return
+		}
+		resolved := make([]string, 0, len(paths))
+		for _, p := range paths {
+			resolved = append(resolved, resolve(p))
+		}
+		*option = resolved
+	}
+
+	if variant.Metadata != "" {
+		b.Options.Metadata = resolve(variant.Metadata)
+	}
+
+	variableFiles := append([]string{}, b.Options.VariableFiles...)
+	for _, p := range variant.VariablesFiles {
+		variableFiles = append(variableFiles, resolve(p))
+	}
+	b.Options.VariableFiles = variableFiles
+
+	override(&b.Options.BOSHVariableDirectories, variant.BOSHVariableDirectories)
+	override(&b.Options.FormDirectories, variant.FormDirectories)
+	override(&b.Options.InstanceGroupDirectories, variant.InstanceGroupDirectories)
+	override(&b.Options.JobDirectories, variant.JobDirectories)
+	override(&b.Options.MigrationDirectories, variant.MigrationDirectories)
+	override(&b.Options.PartialDirectories, variant.PartialDirectories)
+	override(&b.Options.PropertyDirectories, variant.PropertyDirectories)
+	override(&b.Options.RuntimeConfigDirectories, variant.RuntimeConfigDirectories)
+	override(&b.Options.EmbedPaths, variant.EmbedPaths)
+
+	switch {
+	case b.Options.MetadataOnly:
+	case variant.OutputFile != "":
+		b.Options.OutputFile = resolve(variant.OutputFile)
+	case b.Options.Version != "":
+		b.Options.OutputFile = "tile-" + variant.Name + "-" + b.Options.Version + ".pivotal"
+	default:
+		b.Options.OutputFile = "tile-" + variant.Name + ".pivotal"
+	}
+
+	return b
+}
+
+// variantReleases returns the release manifests with the names and the files of the release tarballs.
+// When names is empty every release is returned and the files are nil so the tile includes every tarball.
+func variantReleases(releaseManifests map[string]interface{}, names []string) (map[string]interface{}, []string, error) {
+	if len(names) == 0 {
+		return releaseManifests, nil, nil
+	}
+	manifests := make(map[string]interface{}, len(names))
+	files := make([]string, 0, len(names))
+	for _, name := range names {
+		manifest, ok := releaseManifests[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("release %q is not in the releases directories", name)
+		}
+		manifests[name] = manifest
+		if releaseManifest, ok := manifest.(builder.ReleaseManifest); ok {
+			files = append(files, releaseManifest.File)
+		}
+	}
+	return manifests, files, nil
+}
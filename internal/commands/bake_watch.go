@@ -0,0 +1,205 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// watch re-interpolates the metadata whenever the metadata file, a variables file or a template or partials
+// directory changes. Only the templates in changed directories are parsed again; releases, stemcells and the icon are read once.
+// The interpolated metadata is printed first and then a diff is printed after each change. A change is only
+// picked up once the watched files have not changed for one interval so files that are still being written
+// are not parsed. Errors are printed and watching continues so they can be fixed without restarting.
+func (b Bake) watch(parts *metadataParts) error {
+	ctx := b.watchContext
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	interval := b.Options.WatchInterval
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+
+	parsers := b.metadataPartParsers(parts)
+
+	watched := make([]watchedPaths, 0, len(parsers)+3)
+	watched = append(watched,
+		watchedPaths{paths: b.Options.VariableFiles},
+		watchedPaths{paths: []string{b.Options.Metadata}},
+		watchedPaths{paths: b.Options.PartialDirectories},
+	)
+	for _, parser := range parsers {
+		watched = append(watched, watchedPaths{paths: parser.directories})
+	}
+	for i := range watched {
+		watched[i].fingerprint = b.fingerprint(watched[i].paths)
+	}
+	const (
+		variablesIndex = iota
+		metadataIndex
+		partialsIndex
+		firstParserIndex
+	)
+
+	var previousMetadata []byte
+	interpolate := func() {
+		interpolatedMetadata, err := b.interpolateMetadata(*parts)
+		if err != nil {
+			b.errLogger.Printf("error: %s\n", err)
+			return
+		}
+		if previousMetadata == nil {
+			b.outLogger.Printf("%s", interpolatedMetadata)
+		} else {
+			b.printMetadataDiff(previousMetadata, interpolatedMetadata)
+		}
+		previousMetadata = interpolatedMetadata
+	}
+
+	b.errLogger.Println("Watching for changes...")
+	interpolate()
+
+	// parsers that failed are parsed again on the next change even when their directories did not change
+	failed := make([]bool, len(parsers))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		changed, settling := false, false
+		for i := range watched {
+			fingerprint := b.fingerprint(watched[i].paths)
+			if fingerprint != watched[i].fingerprint {
+				watched[i].changed = true
+				watched[i].fingerprint = fingerprint
+				settling = true
+			}
+			changed = changed || watched[i].changed
+		}
+		if !changed || settling {
+			continue
+		}
+
+		var errs errorList
+		if watched[variablesIndex].changed {
+			if err := b.readTemplateVariables(parts); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if watched[metadataIndex].changed {
+			if err := b.readMetadata(parts); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if watched[partialsIndex].changed {
+			if err := b.readPartials(parts); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		for i, parser := range parsers {
+			if !watched[variablesIndex].changed && !watched[firstParserIndex+i].changed && !failed[i] {
+				continue
+			}
+			err := parser.parse()
+			failed[i] = err != nil
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		for i := range watched {
+			watched[i].changed = false
+		}
+		if len(errs) > 0 {
+			b.errLogger.Printf("error: %s\n", errs)
+			continue
+		}
+
+		interpolate()
+	}
+}
+
+type watchedPaths struct {
+	paths       []string
+	fingerprint string
+	changed     bool
+}
+
+func (b Bake) printMetadataDiff(previous, current []byte) {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(previous)),
+		B:        difflib.SplitLines(string(current)),
+		FromFile: "previous metadata",
+		ToFile:   "metadata",
+		Context:  3,
+	})
+	if err != nil {
+		b.errLogger.Printf("error: failed to diff metadata: %s\n", err)
+		return
+	}
+	if diff == "" {
+		b.outLogger.Println("No changes to metadata")
+		return
+	}
+	b.outLogger.Print(diff)
+}
+
+// fingerprint hashes the names and contents of the files in paths. Directories are walked recursively
+// and paths that can not be read are hashed with their error so appearing and disappearing files are changes.
+func (b Bake) fingerprint(paths []string) string {
+	h := sha1.New()
+	for _, p := range paths {
+		b.hashPath(h, p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (b Bake) hashPath(h hash.Hash, p string) {
+	_, _ = fmt.Fprintf(h, "%s\x00", p)
+	info, err := b.fs.Stat(p)
+	if err != nil {
+		_, _ = fmt.Fprintf(h, "%s\x00", err)
+		return
+	}
+	if !info.IsDir() {
+		file, err := b.fs.Open(p)
+		if err != nil {
+			_, _ = fmt.Fprintf(h, "%s\x00", err)
+			return
+		}
+		defer closeAndIgnoreError(file)
+		_, _ = io.Copy(h, file)
+		return
+	}
+	infos, err := b.fs.ReadDir(p)
+	if err != nil {
+		_, _ = fmt.Fprintf(h, "%s\x00", err)
+		return
+	}
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), ".") {
+			// skip editor swap and backup files
+			continue
+		}
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.hashPath(h, filepath.Join(p, name))
+	}
+}
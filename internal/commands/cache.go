@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pivotal-cf/jhanda"
+
+	"github.com/pivotal-cf/kiln/internal/commands/flags"
+	"github.com/pivotal-cf/kiln/internal/component"
+)
+
+type Cache struct {
+	Logger  *log.Logger
+	HomeDir flags.HomeDirFunc
+	report  *Report
+
+	Options struct {
+		CacheDirectory string        `long:"cache-directory" description:"path to the release cache (defaults to ~/.kiln/cache)"`
+		OlderThan      time.Duration `long:"older-than"      description:"prune removes releases not used within this duration (for example 720h)"`
+	}
+}
+
+func NewCache(logger *log.Logger) *Cache {
+	return &Cache{
+		Logger:  logger,
+		HomeDir: os.UserHomeDir,
+	}
+}
+
+// WithReport configures the Report the listed, removed, or corrupted releases are set as the result of.
+func (cmd *Cache) WithReport(report *Report) *Cache {
+	cmd.report = report
+	return cmd
+}
+
+func (cmd *Cache) Execute(args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected a subcommand: list, prune, or verify")
+	}
+	subcommand := args[0]
+
+	_, err := jhanda.Parse(&cmd.Options, args[1:])
+	if err != nil {
+		return err
+	}
+
+	cache, err := cmd.releaseCache()
+	if err != nil {
+		return err
+	}
+
+	switch subcommand {
+	case "list":
+		releases, err := cache.List()
+		if err != nil {
+			return err
+		}
+		cmd.printReleases(releases)
+		cmd.setResult(releases)
+		return nil
+	case "prune":
+		if cmd.Options.OlderThan <= 0 {
+			return errors.New("prune requires a positive --older-than duration")
+		}
+		removed, err := cache.Prune(time.Now().Add(-cmd.Options.OlderThan))
+		cmd.printReleases(removed)
+		cmd.setResult(removed)
+		if err != nil {
+			return err
+		}
+		cmd.Logger.Printf("removed %d releases from %s", len(removed), cache.Directory)
+		return nil
+	case "verify":
+		corrupted, err := cache.Verify()
+		if err != nil {
+			return err
+		}
+		cmd.setResult(corrupted)
+		if len(corrupted) > 0 {
+			cmd.printReleases(corrupted)
+			return fmt.Errorf("found %d corrupted releases in %s; remove their directories and fetch them again", len(corrupted), cache.Directory)
+		}
+		cmd.Logger.Printf("all releases in %s match their SHA1 sums", cache.Directory)
+		return nil
+	default:
+		return fmt.Errorf("unknown cache subcommand %q: expected list, prune, or verify", subcommand)
+	}
+}
+
+func (cmd *Cache) releaseCache() (component.ReleaseCache, error) {
+	if cmd.Options.CacheDirectory != "" {
+		return component.NewReleaseCache(cmd.Options.CacheDirectory), nil
+	}
+	home, err := cmd.HomeDir()
+	if err != nil {
+		return component.ReleaseCache{}, fmt.Errorf("failed to find the release cache: %w", err)
+	}
+	return component.NewReleaseCache(component.DefaultReleaseCacheDirectory(home)), nil
+}
+
+func (cmd *Cache) printReleases(releases []component.CachedRelease) {
+	w := tabwriter.NewWriter(cmd.Logger.Writer(), 0, 4, 2, ' ', 0)
+	for _, rel := range releases {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", rel.SHA1, rel.Size, rel.LastUsed.Format(time.RFC3339), filepath.Base(rel.Path))
+	}
+	_ = w.Flush()
+}
+
+func (cmd *Cache) setResult(releases []component.CachedRelease) {
+	if releases == nil {
+		releases = make([]component.CachedRelease, 0)
+	}
+	cmd.report.SetResult(releases)
+}
+
+func (cmd *Cache) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Manages the release cache shared by fetch and bake. Run \"kiln cache list\" to show cached releases, \"kiln cache prune --older-than 720h\" to remove releases that have not been used recently, and \"kiln cache verify\" to check cached releases against their SHA1 sums.",
+		ShortDescription: "lists, prunes, or verifies cached releases",
+		Flags:            cmd.Options,
+	}
+}
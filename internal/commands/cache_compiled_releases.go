@@ -51,6 +51,7 @@ type CacheCompiledReleases struct {
 
 	Logger *log.Logger
 	FS     billy.Filesystem
+	Report *Report
 
 	ReleaseSourceAndCache func(kilnfile cargo.Kilnfile, targetID string) (ReleaseStorage, error)
 	OpsManager            func(om.ClientConfiguration) (OpsManagerReleaseCacheSource, error)
@@ -88,6 +89,12 @@ func (cmd *CacheCompiledReleases) WithLogger(logger *log.Logger) *CacheCompiledR
 	return cmd
 }
 
+// WithReport configures the Report the cached releases are added to.
+func (cmd *CacheCompiledReleases) WithReport(report *Report) *CacheCompiledReleases {
+	cmd.Report = report
+	return cmd
+}
+
 func (cmd *CacheCompiledReleases) Execute(args []string) error {
 	_, err := flags.LoadFlagsWithDefaults(&cmd.Options, args, cmd.FS.Stat)
 	if err != nil {
@@ -96,20 +103,29 @@ func (cmd *CacheCompiledReleases) Execute(args []string) error {
 
 	kilnfile, lock, err := cmd.Options.LoadKilnfiles(cmd.FS, nil)
 	if err != nil {
-		return fmt.Errorf("failed to load kilnfiles: %w", err)
+		return withErrorCode(ErrorCodeKilnfile, fmt.Errorf("failed to load kilnfiles: %w", err))
 	}
 
-	omAPI, deploymentName, stagedStemcellOS, stagedStemcellVersion, err := cmd.fetchProductDeploymentData()
+	omAPI, deploymentName, stagedStemcells, err := cmd.fetchProductDeploymentData()
 	if err != nil {
 		return err
 	}
 
-	if stagedStemcellOS != lock.Stemcell.OS || stagedStemcellVersion != lock.Stemcell.Version {
-		return fmt.Errorf(
-			"staged stemcell (%s %s) and lock stemcell (%s %s) do not match",
-			stagedStemcellOS, stagedStemcellVersion,
-			lock.Stemcell.OS, lock.Stemcell.Version,
-		)
+	for _, lockStemcell := range lock.AllStemcells() {
+		stagedStemcell, found := findStagedStemcell(stagedStemcells, lockStemcell.OS)
+		if !found {
+			return withErrorCode(ErrorCodeStemcellMismatch, fmt.Errorf(
+				"lock stemcell (%s %s) is not staged",
+				lockStemcell.OS, lockStemcell.Version,
+			))
+		}
+		if stagedStemcell.Version != lockStemcell.Version {
+			return withErrorCode(ErrorCodeStemcellMismatch, fmt.Errorf(
+				"staged stemcell (%s %s) and lock stemcell (%s %s) do not match",
+				stagedStemcell.OS, stagedStemcell.Version,
+				lockStemcell.OS, lockStemcell.Version,
+			))
+		}
 	}
 
 	releaseStore, err := cmd.ReleaseSourceAndCache(kilnfile, cmd.Options.UploadTargetID)
@@ -122,11 +138,20 @@ func (cmd *CacheCompiledReleases) Execute(args []string) error {
 		releasesUpdatedFromCache = false
 	)
 	for _, rel := range lock.Releases {
+		spec, err := kilnfile.BOSHReleaseTarballSpecification(rel.Name)
+		if err != nil {
+			spec = cargo.BOSHReleaseTarballSpecification{Name: rel.Name}
+		}
+		stemcell, err := lock.StemcellForRelease(spec)
+		if err != nil {
+			return err
+		}
+
 		remote, err := releaseStore.GetMatchedRelease(cargo.BOSHReleaseTarballSpecification{
 			Name:            rel.Name,
 			Version:         rel.Version,
-			StemcellOS:      lock.Stemcell.OS,
-			StemcellVersion: lock.Stemcell.Version,
+			StemcellOS:      stemcell.OS,
+			StemcellVersion: stemcell.Version,
 		})
 		if err != nil {
 			if !component.IsErrNotFound(err) {
@@ -135,26 +160,26 @@ func (cmd *CacheCompiledReleases) Execute(args []string) error {
 			releasesToExport = append(releasesToExport, cargo.BOSHReleaseTarballLock{
 				Name:            rel.Name,
 				Version:         rel.Version,
-				StemcellOS:      lock.Stemcell.OS,
-				StemcellVersion: lock.Stemcell.Version,
+				StemcellOS:      stemcell.OS,
+				StemcellVersion: stemcell.Version,
 			})
 			continue
 		}
 
 		cmd.Logger.Printf("found %s/%s in %s\n", rel.Name, rel.Version, remote.RemoteSource)
 
-		sum, err := cmd.downloadAndComputeSHA(releaseStore, remote)
+		remote.SHA1, remote.SHA256, err = cmd.downloadAndComputeSHA(releaseStore, remote)
 		if err != nil {
 			cmd.Logger.Printf("unable to get hash sum for %s", remote.ReleaseSlug())
 			continue
 		}
-		remote.SHA1 = sum
 
 		releasesUpdatedFromCache = true
 		err = updateLock(lock, remote, cmd.Options.UploadTargetID)
 		if err != nil {
 			return fmt.Errorf("failed to update lock file: %w", err)
 		}
+		cmd.Report.AddReleaseChange(rel, remote)
 	}
 
 	switch len(releasesToExport) {
@@ -199,7 +224,7 @@ func (cmd *CacheCompiledReleases) Execute(args []string) error {
 
 	for _, rel := range releasesToExport {
 		releaseSlug := rel.ReleaseSlug()
-		stemcellSlug := boshdir.NewOSVersionSlug(stagedStemcellOS, stagedStemcellVersion)
+		stemcellSlug := boshdir.NewOSVersionSlug(rel.StemcellOS, rel.StemcellVersion)
 
 		hasRelease, err := hasRequiredCompiledPackages(bosh, rel.ReleaseSlug(), stemcellSlug)
 		if err != nil {
@@ -222,6 +247,7 @@ func (cmd *CacheCompiledReleases) Execute(args []string) error {
 		if err != nil {
 			return fmt.Errorf("failed to lock release %s: %w", rel.Name, err)
 		}
+		cmd.Report.AddReleaseChange(rel, newRemote)
 	}
 
 	err = cmd.Options.Standard.SaveKilnfileLock(cmd.FS, lock)
@@ -265,20 +291,20 @@ func hasRequiredCompiledPackages(d boshdir.Director, releaseSlug boshdir.Release
 	return false, nil
 }
 
-func (cmd *CacheCompiledReleases) fetchProductDeploymentData() (_ OpsManagerReleaseCacheSource, deploymentName, stemcellOS, stemcellVersion string, _ error) {
+func (cmd *CacheCompiledReleases) fetchProductDeploymentData() (_ OpsManagerReleaseCacheSource, deploymentName string, stemcells []cargo.Stemcell, _ error) {
 	omAPI, err := cmd.OpsManager(cmd.Options.ClientConfiguration)
 	if err != nil {
-		return nil, "", "", "", err
+		return nil, "", nil, err
 	}
 
 	stagedProduct, err := omAPI.GetStagedProductByName(cmd.Options.Name)
 	if err != nil {
-		return nil, "", "", "", err
+		return nil, "", nil, err
 	}
 
 	stagedManifest, err := omAPI.GetStagedProductManifest(stagedProduct.Product.GUID)
 	if err != nil {
-		return nil, "", "", "", err
+		return nil, "", nil, err
 	}
 
 	var manifest struct {
@@ -290,15 +316,27 @@ func (cmd *CacheCompiledReleases) fetchProductDeploymentData() (_ OpsManagerRele
 	}
 
 	if err := yaml.Unmarshal([]byte(stagedManifest), &manifest); err != nil {
-		return nil, "", "", "", err
+		return nil, "", nil, err
 	}
 
 	if len(manifest.Stemcells) == 0 {
-		return nil, "", "", "", errors.New("manifest stemcell not set")
+		return nil, "", nil, errors.New("manifest stemcell not set")
+	}
+	for _, stemcell := range manifest.Stemcells {
+		stemcells = append(stemcells, cargo.Stemcell{OS: stemcell.OS, Version: stemcell.Version})
 	}
-	stagedStemcell := manifest.Stemcells[0]
 
-	return omAPI, manifest.Name, stagedStemcell.OS, stagedStemcell.Version, nil
+	return omAPI, manifest.Name, stemcells, nil
+}
+
+// findStagedStemcell returns the staged stemcell with the operating system.
+func findStagedStemcell(staged []cargo.Stemcell, os string) (cargo.Stemcell, bool) {
+	for _, stemcell := range staged {
+		if stemcell.OS == os {
+			return stemcell, true
+		}
+	}
+	return cargo.Stemcell{}, false
 }
 
 func (cmd *CacheCompiledReleases) cacheRelease(bosh boshdir.Director, rc ReleaseStorage, deployment boshdir.Deployment, releaseSlug boshdir.ReleaseSlug, stemcellSlug boshdir.OSVersionSlug) (cargo.BOSHReleaseTarballLock, error) {
@@ -309,7 +347,7 @@ func (cmd *CacheCompiledReleases) cacheRelease(bosh boshdir.Director, rc Release
 	}
 
 	cmd.Logger.Printf("\tdownloading %s\n", releaseSlug)
-	releaseFilePath, _, sha1sum, err := cmd.saveReleaseLocally(bosh, cmd.Options.ReleasesDir, releaseSlug, stemcellSlug, result)
+	releaseFilePath, sha256sum, sha1sum, err := cmd.saveReleaseLocally(bosh, cmd.Options.ReleasesDir, releaseSlug, stemcellSlug, result)
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
 	}
@@ -326,6 +364,7 @@ func (cmd *CacheCompiledReleases) cacheRelease(bosh boshdir.Director, rc Release
 	}
 
 	remoteRelease.SHA1 = sha1sum
+	remoteRelease.SHA256 = sha256sum
 
 	return remoteRelease, nil
 }
@@ -336,9 +375,9 @@ func updateLock(lock cargo.KilnfileLock, release cargo.BOSHReleaseTarballLock, t
 			continue
 		}
 
-		checksum := release.SHA1
+		checksum, sha256Checksum := release.SHA1, release.SHA256
 		if releaseLock.RemoteSource == targetID {
-			checksum = releaseLock.SHA1
+			checksum, sha256Checksum = releaseLock.SHA1, releaseLock.SHA256
 		}
 
 		lock.Releases[index] = cargo.BOSHReleaseTarballLock{
@@ -347,6 +386,7 @@ func updateLock(lock cargo.KilnfileLock, release cargo.BOSHReleaseTarballLock, t
 			RemoteSource: release.RemoteSource,
 			RemotePath:   release.RemotePath,
 			SHA1:         checksum,
+			SHA256:       sha256Checksum,
 		}
 		return nil
 	}
@@ -393,14 +433,14 @@ func (cmd *CacheCompiledReleases) saveReleaseLocally(director boshdir.Director,
 	return filePath, sha256sumString, sha1sumString, nil
 }
 
-func (cmd *CacheCompiledReleases) downloadAndComputeSHA(cache component.ReleaseSource, remote cargo.BOSHReleaseTarballLock) (string, error) {
+func (cmd *CacheCompiledReleases) downloadAndComputeSHA(cache component.ReleaseSource, remote cargo.BOSHReleaseTarballLock) (string, string, error) {
 	if remote.SHA1 != "" {
-		return remote.SHA1, nil
+		return remote.SHA1, remote.SHA256, nil
 	}
 
 	tmpdir, err := os.MkdirTemp("/tmp", "kiln")
 	if err != nil {
-		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+		return "", "", fmt.Errorf("failed to create temporary directory: %w", err)
 	}
 	defer func() {
 		err = os.RemoveAll(tmpdir)
@@ -411,10 +451,10 @@ func (cmd *CacheCompiledReleases) downloadAndComputeSHA(cache component.ReleaseS
 
 	comp, err := cache.DownloadRelease(tmpdir, remote)
 	if err != nil {
-		return "", fmt.Errorf("failed to download release: %s", err)
+		return "", "", fmt.Errorf("failed to download release: %s", err)
 	}
 
-	return comp.Lock.SHA1, nil
+	return comp.Lock.SHA1, comp.Lock.SHA256, nil
 }
 
 func (cmd *CacheCompiledReleases) Usage() jhanda.Usage {
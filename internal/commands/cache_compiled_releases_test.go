@@ -299,10 +299,11 @@ func TestCacheCompiledReleases_Execute_when_one_release_is_cached_another_is_alr
 		}, nil
 	})
 	test.releaseStorage.DownloadReleaseReturns(component.Local{}, fmt.Errorf("SO MUCH NOTHING"))
+	report := commands.NewReport("cache-compiled-releases")
 
 	// run
 
-	err := test.cmd.Execute([]string{
+	err := test.cmd.WithReport(report).Execute([]string{
 		"--upload-target-id", "cached-compiled-releases",
 	})
 
@@ -325,12 +326,19 @@ func TestCacheCompiledReleases_Execute_when_one_release_is_cached_another_is_alr
 
 	please.Expect(uploadedRelease.String()).To(Equal(releaseInBlobstore))
 
+	please.Expect(report.Changes).To(ConsistOf(
+		commands.ReportChange{Kind: "release", Name: "orange", From: "1.0.0", To: "1.0.0", RemoteSource: "cached-compiled-releases", RemotePath: "orange-1.0.0-alpine-9.0.0"},
+		commands.ReportChange{Kind: "release", Name: "banana", From: "2.0.0", To: "2.0.0", RemoteSource: "cached-compiled-releases", RemotePath: "banana-2.0.0-alpine-9.0.0"},
+		commands.ReportChange{Kind: "release", Name: "lemon", From: "3.0.0", To: "3.0.0", RemoteSource: "cached-compiled-releases", RemotePath: "lemon-3.0.0-alpine-9.0.0"},
+	))
+
 	var updatedKilnfile cargo.KilnfileLock
 	please.Expect(fsReadYAML(test.cmd.FS, "Kilnfile.lock", &updatedKilnfile)).NotTo(HaveOccurred())
 	please.Expect(updatedKilnfile.Releases).To(ContainElement(cargo.BOSHReleaseTarballLock{
 		Name:         "lemon",
 		Version:      "3.0.0",
 		SHA1:         "012ed191f1d07c14bbcbbc0423d0de1c56757348",
+		SHA256:       "75a7261038c7380cd867216d2878376c64d905d7e4fcb6c4f2714a3f78ae10f2",
 		RemoteSource: "cached-compiled-releases",
 		RemotePath:   "lemon-3.0.0-alpine-9.0.0",
 	}))
@@ -601,8 +609,121 @@ func TestCacheCompiledReleases_Execute_staged_and_lock_stemcells_are_not_the_sam
 	please.Expect(test.releaseStorage.GetMatchedReleaseCallCount()).To(Equal(0))
 	please.Expect(test.bosh.DownloadResourceUncheckedCallCount()).To(Equal(0))
 	please.Expect(err).To(MatchError(Equal("staged stemcell (alpine 9.0.1) and lock stemcell (alpine 9.0.0) do not match")))
+	please.Expect(commands.ErrorCode(err)).To(Equal(commands.ErrorCodeStemcellMismatch))
 
 	var updatedLock cargo.KilnfileLock
 	please.Expect(fsReadYAML(test.cmd.FS, "Kilnfile.lock", &updatedLock)).NotTo(HaveOccurred())
 	please.Expect(updatedLock).To(Equal(initialLock))
 }
+
+func TestCacheCompiledReleases_Execute_when_the_lock_has_more_than_one_stemcell(t *testing.T) {
+	please := NewWithT(t)
+
+	// setup
+
+	test := newCacheCompiledReleasesTestData(t, cargo.Kilnfile{
+		ReleaseSources: []cargo.ReleaseSourceConfig{
+			{
+				ID: "compiled-releases",
+			},
+		},
+		Releases: []cargo.BOSHReleaseTarballSpecification{
+			{Name: "orange"},
+			{Name: "banana", StemcellOS: "windows2019"},
+		},
+	}, cargo.KilnfileLock{
+		Releases: []cargo.BOSHReleaseTarballLock{
+			{
+				Name:         "orange",
+				Version:      "1.0.0",
+				RemoteSource: "compiled-releases",
+				RemotePath:   "orange-1.0.0-alpine-9.0.0",
+				SHA1:         "fake-checksum",
+			},
+			{
+				Name:         "banana",
+				Version:      "2.0.0",
+				RemoteSource: "compiled-releases",
+				RemotePath:   "banana-2.0.0-windows2019-2019.50",
+				SHA1:         "fake-checksum",
+			},
+		},
+		Stemcells: []cargo.Stemcell{
+			{OS: "alpine", Version: "9.0.0"},
+			{OS: "windows2019", Version: "2019.50"},
+		},
+	}, "9.0.0")
+	test.opsManager.GetStagedProductManifestReturns(`{"name": "cf-some-id", "stemcells": [{"os": "alpine", "version": "9.0.0"}, {"os": "windows2019", "version": "2019.50"}]}`, nil)
+	test.releaseStorage.GetMatchedReleaseCalls(func(spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+		lock := spec.Lock()
+		lock.RemoteSource = "compiled-releases"
+		lock.RemotePath = fmt.Sprintf("%s-%s-%s-%s", spec.Name, spec.Version, spec.StemcellOS, spec.StemcellVersion)
+		lock.SHA1 = "fake-checksum"
+		return lock, nil
+	})
+
+	// run
+
+	err := test.cmd.Execute([]string{
+		"--upload-target-id", "compiled-releases",
+	})
+
+	// check
+
+	please.Expect(err).NotTo(HaveOccurred())
+	please.Expect(test.releaseStorage.GetMatchedReleaseCallCount()).To(Equal(2))
+	please.Expect(test.releaseStorage.GetMatchedReleaseArgsForCall(0)).To(Equal(cargo.BOSHReleaseTarballSpecification{
+		Name: "orange", Version: "1.0.0", StemcellOS: "alpine", StemcellVersion: "9.0.0",
+	}))
+	please.Expect(test.releaseStorage.GetMatchedReleaseArgsForCall(1)).To(Equal(cargo.BOSHReleaseTarballSpecification{
+		Name: "banana", Version: "2.0.0", StemcellOS: "windows2019", StemcellVersion: "2019.50",
+	}))
+}
+
+func TestCacheCompiledReleases_Execute_when_a_staged_stemcell_does_not_match_one_of_the_lock_stemcells(t *testing.T) {
+	please := NewWithT(t)
+
+	// setup
+
+	test := newCacheCompiledReleasesTestData(t, cargo.Kilnfile{}, cargo.KilnfileLock{
+		Stemcells: []cargo.Stemcell{
+			{OS: "alpine", Version: "9.0.0"},
+			{OS: "windows2019", Version: "2019.50"},
+		},
+	}, "9.0.0")
+	test.opsManager.GetStagedProductManifestReturns(`{"name": "cf-some-id", "stemcells": [{"os": "alpine", "version": "9.0.0"}, {"os": "windows2019", "version": "2019.51"}]}`, nil)
+
+	// run
+
+	err := test.cmd.Execute([]string{
+		"--upload-target-id", "compiled-releases",
+	})
+
+	// check
+
+	please.Expect(err).To(MatchError(Equal("staged stemcell (windows2019 2019.51) and lock stemcell (windows2019 2019.50) do not match")))
+}
+
+func TestCacheCompiledReleases_Execute_when_a_lock_stemcell_is_not_staged(t *testing.T) {
+	please := NewWithT(t)
+
+	// setup
+
+	test := newCacheCompiledReleasesTestData(t, cargo.Kilnfile{}, cargo.KilnfileLock{
+		Stemcells: []cargo.Stemcell{
+			{OS: "alpine", Version: "9.0.0"},
+			{OS: "windows2019", Version: "2019.50"},
+		},
+	}, "9.0.0")
+	test.opsManager.GetStagedProductManifestReturns(`{"name": "cf-some-id", "stemcells": [{"os": "alpine", "version": "9.0.0"}]}`, nil)
+
+	// run
+
+	err := test.cmd.Execute([]string{
+		"--upload-target-id", "compiled-releases",
+	})
+
+	// check
+
+	please.Expect(err).To(MatchError(Equal("lock stemcell (windows2019 2019.50) is not staged")))
+}
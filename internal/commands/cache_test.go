@@ -0,0 +1,108 @@
+package commands_test
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	"github.com/pivotal-cf/kiln/internal/component"
+)
+
+func TestCache_Execute(t *testing.T) {
+	const (
+		contents = "some release contents"
+		sum      = "641c0d1e5d0578ededf8cd76f5d8399e867f6f6c"
+	)
+
+	setup := func(t *testing.T) (*commands.Cache, *bytes.Buffer, component.ReleaseCache) {
+		t.Helper()
+		home := t.TempDir()
+		releasesDir := t.TempDir()
+		releasePath := filepath.Join(releasesDir, "banana-1.2.3.tgz")
+		if err := os.WriteFile(releasePath, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cache := component.NewReleaseCache(component.DefaultReleaseCacheDirectory(home))
+		if err := cache.Add(releasePath, sum); err != nil {
+			t.Fatal(err)
+		}
+
+		var output bytes.Buffer
+		cmd := commands.NewCache(log.New(&output, "", 0))
+		cmd.HomeDir = func() (string, error) { return home, nil }
+		return cmd, &output, cache
+	}
+
+	t.Run("list", func(t *testing.T) {
+		please := NewWithT(t)
+		cmd, output, _ := setup(t)
+
+		report := commands.NewReport("cache")
+		please.Expect(cmd.WithReport(report).Execute([]string{"list"})).To(Succeed())
+		please.Expect(output.String()).To(ContainSubstring(sum))
+		please.Expect(output.String()).To(ContainSubstring("banana-1.2.3.tgz"))
+
+		releases, ok := report.Result.([]component.CachedRelease)
+		please.Expect(ok).To(BeTrue())
+		please.Expect(releases).To(HaveLen(1))
+		please.Expect(releases[0].SHA1).To(Equal(sum))
+		please.Expect(filepath.Base(releases[0].Path)).To(Equal("banana-1.2.3.tgz"))
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		please := NewWithT(t)
+		cmd, output, cache := setup(t)
+
+		old := time.Now().Add(-48 * time.Hour)
+		please.Expect(os.Chtimes(filepath.Join(cache.Directory, sum, "banana-1.2.3.tgz"), old, old)).To(Succeed())
+
+		please.Expect(cmd.Execute([]string{"prune", "--older-than", "24h"})).To(Succeed())
+		please.Expect(output.String()).To(ContainSubstring("removed 1 releases"))
+		please.Expect(cache.List()).To(BeEmpty())
+	})
+
+	t.Run("prune without older-than", func(t *testing.T) {
+		please := NewWithT(t)
+		cmd, _, cache := setup(t)
+
+		please.Expect(cmd.Execute([]string{"prune"})).To(MatchError(ContainSubstring("--older-than")))
+		please.Expect(cache.List()).To(HaveLen(1))
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		please := NewWithT(t)
+		cmd, output, cache := setup(t)
+
+		please.Expect(cmd.Execute([]string{"verify"})).To(Succeed())
+
+		please.Expect(os.WriteFile(filepath.Join(cache.Directory, sum, "banana-1.2.3.tgz"), []byte("corrupted"), 0o644)).To(Succeed())
+		output.Reset()
+
+		please.Expect(cmd.Execute([]string{"verify"})).To(MatchError(ContainSubstring("found 1 corrupted releases")))
+		please.Expect(output.String()).To(ContainSubstring(sum))
+	})
+
+	t.Run("cache directory flag", func(t *testing.T) {
+		please := NewWithT(t)
+		cmd, output, _ := setup(t)
+
+		report := commands.NewReport("cache")
+		please.Expect(cmd.WithReport(report).Execute([]string{"list", "--cache-directory", t.TempDir()})).To(Succeed())
+		please.Expect(output.String()).To(BeEmpty())
+		please.Expect(report.Result).To(Equal([]component.CachedRelease{}))
+	})
+
+	t.Run("unknown subcommand", func(t *testing.T) {
+		please := NewWithT(t)
+		cmd, _, _ := setup(t)
+
+		please.Expect(cmd.Execute([]string{"explode"})).To(MatchError(ContainSubstring("unknown cache subcommand")))
+		please.Expect(cmd.Execute(nil)).To(MatchError(ContainSubstring("expected a subcommand")))
+	})
+}
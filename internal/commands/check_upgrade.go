@@ -0,0 +1,213 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/pivotal-cf/jhanda"
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/kiln/pkg/proofing"
+	"github.com/pivotal-cf/kiln/pkg/proofing/upgrade"
+	"github.com/pivotal-cf/kiln/pkg/tile"
+)
+
+type CheckUpgrade struct {
+	outLogger *log.Logger
+	report    *Report
+
+	Options struct {
+		Stable       string `long:"stable"        required:"true" description:"tile (.pivotal file), metadata file, or git revision of the published tile"`
+		Candidate    string `long:"candidate"     required:"true" description:"tile (.pivotal file), metadata file, or git revision of the new tile"`
+		MetadataPath string `long:"metadata-path"                 description:"path of a baked metadata file in the git repository (required for git revisions)"`
+		Repository   string `long:"repository"    default:"."     description:"path to the git repository (used for git revisions)"`
+		Allowlist    string `long:"allowlist"                     description:"path to a YAML file listing acknowledged breaking changes"`
+	}
+}
+
+func NewCheckUpgrade(outLogger *log.Logger) *CheckUpgrade {
+	return &CheckUpgrade{
+		outLogger: outLogger,
+	}
+}
+
+// WithReport configures the Report the breaking changes are set as the result of.
+func (cmd *CheckUpgrade) WithReport(report *Report) *CheckUpgrade {
+	cmd.report = report
+	return cmd
+}
+
+// CheckUpgradeResult is the "--output json" result of check-upgrade. The JSON field
+// names should not change.
+type CheckUpgradeResult struct {
+	// BreakingChanges are the messages of the breaking changes that are not
+	// acknowledged, by category.
+	BreakingChanges map[string][]string `json:"breaking_changes"`
+
+	Acknowledged []string `json:"acknowledged"`
+
+	// UnmatchedAllowlistEntries are the allowlist changes that do not match a breaking change.
+	UnmatchedAllowlistEntries []string `json:"unmatched_allowlist_entries"`
+}
+
+// AcknowledgedBreakingChange is an entry of the check-upgrade allowlist. Change must
+// match the breaking change message printed by check-upgrade.
+type AcknowledgedBreakingChange struct {
+	Change string `yaml:"change"`
+	Reason string `yaml:"reason,omitempty"`
+}
+
+func (cmd *CheckUpgrade) Execute(args []string) error {
+	_, err := jhanda.Parse(&cmd.Options, args)
+	if err != nil {
+		return err
+	}
+
+	var allowlist []AcknowledgedBreakingChange
+	if cmd.Options.Allowlist != "" {
+		buf, err := os.ReadFile(cmd.Options.Allowlist)
+		if err != nil {
+			return fmt.Errorf("failed to read allowlist: %w", err)
+		}
+		if err := yaml.Unmarshal(buf, &allowlist); err != nil {
+			return fmt.Errorf("failed to parse allowlist: %w", err)
+		}
+	}
+
+	stable, err := cmd.readProductTemplate(cmd.Options.Stable)
+	if err != nil {
+		return fmt.Errorf("failed to read stable metadata: %w", err)
+	}
+	candidate, err := cmd.readProductTemplate(cmd.Options.Candidate)
+	if err != nil {
+		return fmt.Errorf("failed to read candidate metadata: %w", err)
+	}
+
+	var (
+		breakingChanges = make(map[string][]string)
+		acknowledged    = make([]string, 0)
+		unmatched       = make([]string, 0)
+		used            = make(map[string]bool)
+		count           int
+	)
+	for _, breakingChange := range upgrade.ListBreakingChanges(stable, candidate) {
+		message := breakingChange.Error()
+		if isAcknowledged(allowlist, message) {
+			acknowledged = append(acknowledged, message)
+			used[message] = true
+			continue
+		}
+		category := upgrade.Category(breakingChange)
+		breakingChanges[category] = append(breakingChanges[category], message)
+		count++
+	}
+
+	categories := make([]string, 0, len(breakingChanges))
+	for category := range breakingChanges {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		cmd.outLogger.Printf("%s:\n", category)
+		for _, message := range breakingChanges[category] {
+			cmd.outLogger.Printf("  %s\n", message)
+		}
+	}
+	if len(acknowledged) > 0 {
+		cmd.outLogger.Println("acknowledged:")
+		for _, message := range acknowledged {
+			cmd.outLogger.Printf("  %s\n", message)
+		}
+	}
+	for _, entry := range allowlist {
+		if !used[entry.Change] {
+			unmatched = append(unmatched, entry.Change)
+			cmd.outLogger.Printf("Warning: allowlist entry does not match a breaking change: %s\n", entry.Change)
+		}
+	}
+	cmd.report.SetResult(CheckUpgradeResult{
+		BreakingChanges:           breakingChanges,
+		Acknowledged:              acknowledged,
+		UnmatchedAllowlistEntries: unmatched,
+	})
+
+	if count > 0 {
+		return fmt.Errorf("found %d breaking change(s)", count)
+	}
+	cmd.outLogger.Println("No breaking changes found")
+	return nil
+}
+
+func isAcknowledged(allowlist []AcknowledgedBreakingChange, message string) bool {
+	for _, entry := range allowlist {
+		if entry.Change == message {
+			return true
+		}
+	}
+	return false
+}
+
+// readProductTemplate reads a tile, a metadata file, or the metadata file at a git revision.
+func (cmd *CheckUpgrade) readProductTemplate(source string) (proofing.ProductTemplate, error) {
+	var (
+		metadata []byte
+		err      error
+	)
+	if info, statErr := os.Stat(source); statErr == nil && !info.IsDir() {
+		if filepath.Ext(source) == ".yml" || filepath.Ext(source) == ".yaml" {
+			metadata, err = os.ReadFile(source)
+		} else {
+			metadata, err = tile.ReadMetadataFromFile(source)
+		}
+	} else {
+		metadata, err = cmd.readMetadataAtRevision(source)
+	}
+	if err != nil {
+		return proofing.ProductTemplate{}, err
+	}
+	return proofing.Parse(bytes.NewReader(metadata))
+}
+
+func (cmd *CheckUpgrade) readMetadataAtRevision(revision string) ([]byte, error) {
+	if cmd.Options.MetadataPath == "" {
+		return nil, errors.New("--metadata-path is required when comparing git revisions")
+	}
+	repository, err := git.PlainOpenWithOptions(cmd.Options.Repository, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a file and the git repository could not be opened: %w", revision, err)
+	}
+	hash, err := repository.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve git revision %q: %w", revision, err)
+	}
+	commit, err := repository.CommitObject(*hash)
+	if err != nil {
+		return nil, err
+	}
+	file, err := commit.File(filepath.ToSlash(cmd.Options.MetadataPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s at %s: %w", cmd.Options.MetadataPath, revision, err)
+	}
+	r, err := file.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer closeAndIgnoreError(r)
+	return io.ReadAll(r)
+}
+
+func (cmd *CheckUpgrade) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Lists the changes in a candidate tile that would break upgrades from a stable tile. It exits with an error when there are breaking changes that are not in the allowlist.",
+		ShortDescription: "checks a tile for changes that break upgrades",
+		Flags:            cmd.Options,
+	}
+}
@@ -0,0 +1,161 @@
+package commands_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+)
+
+const (
+	checkUpgradeStableMetadata = `---
+name: banana
+product_version: 1.0.0
+property_blueprints:
+  - {name: color, type: string, configurable: true, default: yellow}
+  - {name: size, type: integer, configurable: true}
+post_deploy_errands:
+  - {name: smoke_tests}
+`
+	checkUpgradeCandidateMetadata = `---
+name: banana
+product_version: 1.1.0
+property_blueprints:
+  - {name: color, type: string, configurable: true, default: yellow}
+`
+)
+
+func TestCheckUpgrade_Execute(t *testing.T) {
+	t.Run("metadata files with breaking changes", func(t *testing.T) {
+		please := NewWithT(t)
+
+		dir := t.TempDir()
+		stablePath := writeCheckUpgradeTestFile(t, dir, "stable.yml", checkUpgradeStableMetadata)
+		candidatePath := writeCheckUpgradeTestFile(t, dir, "candidate.yml", checkUpgradeCandidateMetadata)
+
+		var output bytes.Buffer
+		report := commands.NewReport("check-upgrade")
+		err := commands.NewCheckUpgrade(log.New(&output, "", 0)).WithReport(report).Execute([]string{"--stable", stablePath, "--candidate", candidatePath})
+		please.Expect(err).To(MatchError("found 2 breaking change(s)"))
+		please.Expect(output.String()).To(Equal(`errand:
+  breaking change for errand with name "smoke_tests": removed
+property_blueprint:
+  breaking change for property with name "size": removed or renamed configurable property
+`))
+
+		result, err := json.Marshal(report.Result)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(result).To(MatchJSON(`{
+			"breaking_changes": {
+				"errand": ["breaking change for errand with name \"smoke_tests\": removed"],
+				"property_blueprint": ["breaking change for property with name \"size\": removed or renamed configurable property"]
+			},
+			"acknowledged": [],
+			"unmatched_allowlist_entries": []
+		}`))
+	})
+
+	t.Run("tiles without breaking changes", func(t *testing.T) {
+		please := NewWithT(t)
+
+		dir := t.TempDir()
+		stablePath := writeDiffTestTile(t, dir, "stable.pivotal", checkUpgradeCandidateMetadata)
+		candidatePath := writeDiffTestTile(t, dir, "candidate.pivotal", checkUpgradeCandidateMetadata)
+
+		var output bytes.Buffer
+		err := commands.NewCheckUpgrade(log.New(&output, "", 0)).Execute([]string{"--stable", stablePath, "--candidate", candidatePath})
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(output.String()).To(Equal("No breaking changes found\n"))
+	})
+
+	t.Run("acknowledged breaking changes", func(t *testing.T) {
+		please := NewWithT(t)
+
+		dir := t.TempDir()
+		stablePath := writeCheckUpgradeTestFile(t, dir, "stable.yml", checkUpgradeStableMetadata)
+		candidatePath := writeCheckUpgradeTestFile(t, dir, "candidate.yml", checkUpgradeCandidateMetadata)
+		allowlistPath := writeCheckUpgradeTestFile(t, dir, "allowlist.yml", `---
+- change: 'breaking change for errand with name "smoke_tests": removed'
+  reason: smoke tests moved to a separate tile
+- change: 'breaking change for property with name "size": removed or renamed configurable property'
+- change: 'breaking change for property with name "shape": removed or renamed configurable property'
+`)
+
+		var output bytes.Buffer
+		report := commands.NewReport("check-upgrade")
+		err := commands.NewCheckUpgrade(log.New(&output, "", 0)).WithReport(report).Execute([]string{"--stable", stablePath, "--candidate", candidatePath, "--allowlist", allowlistPath})
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(report.Result).To(Equal(commands.CheckUpgradeResult{
+			BreakingChanges: map[string][]string{},
+			Acknowledged: []string{
+				`breaking change for property with name "size": removed or renamed configurable property`,
+				`breaking change for errand with name "smoke_tests": removed`,
+			},
+			UnmatchedAllowlistEntries: []string{`breaking change for property with name "shape": removed or renamed configurable property`},
+		}))
+		please.Expect(output.String()).To(ContainSubstring("acknowledged:\n  breaking change for property with name \"size\""))
+		please.Expect(output.String()).To(ContainSubstring(`Warning: allowlist entry does not match a breaking change: breaking change for property with name "shape"`))
+		please.Expect(output.String()).To(ContainSubstring("No breaking changes found"))
+	})
+
+	t.Run("git revisions", func(t *testing.T) {
+		please := NewWithT(t)
+
+		dir := t.TempDir()
+		repo, err := git.PlainInit(dir, false)
+		please.Expect(err).NotTo(HaveOccurred())
+		commitCheckUpgradeTestMetadata(t, repo, dir, checkUpgradeStableMetadata)
+		commitCheckUpgradeTestMetadata(t, repo, dir, checkUpgradeCandidateMetadata)
+
+		var output bytes.Buffer
+		err = commands.NewCheckUpgrade(log.New(&output, "", 0)).Execute([]string{
+			"--repository", dir, "--metadata-path", "metadata/banana.yml",
+			"--stable", "HEAD~1", "--candidate", "HEAD",
+		})
+		please.Expect(err).To(MatchError("found 2 breaking change(s)"))
+	})
+
+	t.Run("git revisions without a metadata path", func(t *testing.T) {
+		please := NewWithT(t)
+
+		err := commands.NewCheckUpgrade(log.New(&bytes.Buffer{}, "", 0)).Execute([]string{"--stable", "HEAD~1", "--candidate", "HEAD"})
+		please.Expect(err).To(MatchError(ContainSubstring("--metadata-path is required")))
+	})
+}
+
+func writeCheckUpgradeTestFile(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func commitCheckUpgradeTestMetadata(t *testing.T, repo *git.Repository, dir, metadata string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, "metadata"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeCheckUpgradeTestFile(t, filepath.Join(dir, "metadata"), "banana.yml", metadata)
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("metadata/banana.yml"); err != nil {
+		t.Fatal(err)
+	}
+	signature := &object.Signature{Name: "releen", Email: "releen@example.com", When: time.Unix(1635975074, 0)}
+	if _, err := wt.Commit("update metadata", &git.CommitOptions{Author: signature, Committer: signature}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,312 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/pivotal-cf/jhanda"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+	"github.com/pivotal-cf/kiln/pkg/history"
+	"github.com/pivotal-cf/kiln/pkg/proofing"
+	"github.com/pivotal-cf/kiln/pkg/tile"
+)
+
+type Diff struct {
+	outLogger *log.Logger
+	report    *Report
+
+	Options struct {
+		Kilnfile   string `short:"kf" long:"kilnfile"   default:"Kilnfile" description:"path to the Kilnfile in the repository (used for git revisions)"`
+		Repository string `short:"r"  long:"repository" default:"."        description:"path to the git repository (used for git revisions)"`
+	}
+}
+
+func NewDiff(outLogger *log.Logger) *Diff {
+	return &Diff{
+		outLogger: outLogger,
+	}
+}
+
+// WithReport configures the Report the differences are set as the result of.
+func (cmd *Diff) WithReport(report *Report) *Diff {
+	cmd.report = report
+	return cmd
+}
+
+// TileDiff is the result of kiln diff. The JSON field names are part of the
+// "--output json" result and should not change.
+type TileDiff struct {
+	Releases  []ReleaseDiff  `json:"releases"`
+	Stemcells []StemcellDiff `json:"stemcells"`
+
+	// The following are only set when both arguments are tiles; a Kilnfile.lock does
+	// not have them.
+	PropertyBlueprints *NameDiff `json:"property_blueprints,omitempty"`
+	JobTypes           *NameDiff `json:"job_types,omitempty"`
+	FormTypes          *NameDiff `json:"form_types,omitempty"`
+	RuntimeConfigs     *NameDiff `json:"runtime_configs,omitempty"`
+}
+
+// ReleaseDiff is a release bump. From is empty for added releases and To is empty
+// for removed releases.
+type ReleaseDiff struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// StemcellDiff is a stemcell change. From is empty for added stemcells and To is
+// empty for removed stemcells.
+type StemcellDiff struct {
+	OS   string `json:"os"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type NameDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+func (diff TileDiff) IsEmpty() bool {
+	return len(diff.Releases) == 0 && len(diff.Stemcells) == 0 &&
+		diff.PropertyBlueprints.isEmpty() && diff.JobTypes.isEmpty() &&
+		diff.FormTypes.isEmpty() && diff.RuntimeConfigs.isEmpty()
+}
+
+func (diff *NameDiff) isEmpty() bool {
+	return diff == nil || (len(diff.Added) == 0 && len(diff.Removed) == 0)
+}
+
+func (cmd *Diff) Execute(args []string) error {
+	argsAfterFlags, err := jhanda.Parse(&cmd.Options, args)
+	if err != nil {
+		return err
+	}
+	if len(argsAfterFlags) != 2 {
+		return errors.New("expected two arguments: <tile-or-git-revision> <tile-or-git-revision>")
+	}
+	var repository *git.Repository
+	snapshots := make([]tileSnapshot, 0, 2)
+	for _, arg := range argsAfterFlags {
+		var snapshot tileSnapshot
+		if info, statErr := os.Stat(arg); statErr == nil && !info.IsDir() {
+			snapshot, err = readTileSnapshot(arg)
+		} else {
+			if repository == nil {
+				repository, err = git.PlainOpenWithOptions(cmd.Options.Repository, &git.PlainOpenOptions{DetectDotGit: true})
+				if err != nil {
+					return fmt.Errorf("%q is not a tile file and the git repository could not be opened: %w", arg, err)
+				}
+			}
+			snapshot, err = readRevisionSnapshot(repository, arg, cmd.Options.Kilnfile)
+		}
+		if err != nil {
+			return err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	diff := calculateTileDiff(snapshots[0], snapshots[1])
+
+	cmd.printText(diff)
+	cmd.report.SetResult(diff)
+	return nil
+}
+
+func (cmd *Diff) printText(diff TileDiff) {
+	if diff.IsEmpty() {
+		cmd.outLogger.Println("No differences")
+		return
+	}
+	if len(diff.Releases) > 0 {
+		cmd.outLogger.Println("Releases:")
+		for _, r := range diff.Releases {
+			switch {
+			case r.From == "":
+				cmd.outLogger.Printf("  added %s %s\n", r.Name, r.To)
+			case r.To == "":
+				cmd.outLogger.Printf("  removed %s %s\n", r.Name, r.From)
+			default:
+				cmd.outLogger.Printf("  bumped %s from %s to %s\n", r.Name, r.From, r.To)
+			}
+		}
+	}
+	if len(diff.Stemcells) > 0 {
+		cmd.outLogger.Println("Stemcells:")
+		for _, s := range diff.Stemcells {
+			switch {
+			case s.From == "":
+				cmd.outLogger.Printf("  added %s %s\n", s.OS, s.To)
+			case s.To == "":
+				cmd.outLogger.Printf("  removed %s %s\n", s.OS, s.From)
+			default:
+				cmd.outLogger.Printf("  changed %s from %s to %s\n", s.OS, s.From, s.To)
+			}
+		}
+	}
+	cmd.printNameDiff("Property blueprints", diff.PropertyBlueprints)
+	cmd.printNameDiff("Job types", diff.JobTypes)
+	cmd.printNameDiff("Form types", diff.FormTypes)
+	cmd.printNameDiff("Runtime configs", diff.RuntimeConfigs)
+}
+
+func (cmd *Diff) printNameDiff(title string, diff *NameDiff) {
+	if diff.isEmpty() {
+		return
+	}
+	cmd.outLogger.Printf("%s:\n", title)
+	for _, name := range diff.Added {
+		cmd.outLogger.Printf("  + %s\n", name)
+	}
+	for _, name := range diff.Removed {
+		cmd.outLogger.Printf("  - %s\n", name)
+	}
+}
+
+func (cmd *Diff) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Prints the release, stemcell, and metadata differences between two tiles (.pivotal files) or two git revisions of the Kilnfile.lock",
+		ShortDescription: "prints the differences between two tiles or Kilnfile.lock revisions",
+		Flags:            cmd.Options,
+	}
+}
+
+// tileSnapshot is the part of a tile or Kilnfile.lock that is compared. The metadata
+// name lists are only set for tiles.
+type tileSnapshot struct {
+	releases  []cargo.BOSHReleaseTarballLock
+	stemcells []cargo.Stemcell
+
+	hasMetadata                                             bool
+	propertyBlueprints, jobTypes, formTypes, runtimeConfigs []string
+}
+
+func readTileSnapshot(tilePath string) (tileSnapshot, error) {
+	metadata, err := tile.ReadMetadataFromFile(tilePath)
+	if err != nil {
+		return tileSnapshot{}, fmt.Errorf("failed to read metadata from %s: %w", tilePath, err)
+	}
+	productTemplate, err := proofing.Parse(bytes.NewReader(metadata))
+	if err != nil {
+		return tileSnapshot{}, fmt.Errorf("failed to parse metadata from %s: %w", tilePath, err)
+	}
+
+	snapshot := tileSnapshot{hasMetadata: true}
+	for _, release := range productTemplate.Releases {
+		snapshot.releases = append(snapshot.releases, cargo.BOSHReleaseTarballLock{Name: release.Name, Version: release.Version})
+	}
+	if criteria := productTemplate.StemcellCriteria; criteria.OS != "" {
+		snapshot.stemcells = append(snapshot.stemcells, cargo.Stemcell{OS: criteria.OS, Version: criteria.Version})
+	}
+	for _, blueprint := range productTemplate.PropertyBlueprints {
+		snapshot.propertyBlueprints = append(snapshot.propertyBlueprints, blueprint.PropertyName())
+	}
+	for _, jobType := range productTemplate.JobTypes {
+		snapshot.jobTypes = append(snapshot.jobTypes, jobType.Name)
+	}
+	for _, formType := range productTemplate.FormTypes {
+		snapshot.formTypes = append(snapshot.formTypes, formType.Name)
+	}
+	for _, runtimeConfig := range productTemplate.RuntimeConfigs {
+		snapshot.runtimeConfigs = append(snapshot.runtimeConfigs, runtimeConfig.Name)
+	}
+	return snapshot, nil
+}
+
+func readRevisionSnapshot(repository *git.Repository, revision, kilnfilePath string) (tileSnapshot, error) {
+	hash, err := repository.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return tileSnapshot{}, fmt.Errorf("failed to resolve git revision %q: %w", revision, err)
+	}
+	_, lock, err := history.Kilnfile(repository.Storer, *hash, kilnfilePath)
+	if err != nil {
+		return tileSnapshot{}, fmt.Errorf("failed to read the Kilnfile.lock at %s: %w", revision, err)
+	}
+	return tileSnapshot{
+		releases:  lock.Releases,
+		stemcells: lock.AllStemcells(),
+	}, nil
+}
+
+func calculateTileDiff(from, to tileSnapshot) TileDiff {
+	diff := TileDiff{
+		Releases:  make([]ReleaseDiff, 0),
+		Stemcells: make([]StemcellDiff, 0),
+	}
+
+	for _, bump := range cargo.CalculateBumps(to.releases, from.releases) {
+		diff.Releases = append(diff.Releases, ReleaseDiff{Name: bump.Name, From: bump.FromVersion, To: bump.ToVersion})
+	}
+	for _, release := range from.releases {
+		if !containsRelease(to.releases, release.Name) {
+			diff.Releases = append(diff.Releases, ReleaseDiff{Name: release.Name, From: release.Version})
+		}
+	}
+
+	previousStemcells := make(map[string]string, len(from.stemcells))
+	for _, stemcell := range from.stemcells {
+		previousStemcells[stemcell.OS] = stemcell.Version
+	}
+	for _, stemcell := range to.stemcells {
+		previous, found := previousStemcells[stemcell.OS]
+		delete(previousStemcells, stemcell.OS)
+		if found && previous == stemcell.Version {
+			continue
+		}
+		diff.Stemcells = append(diff.Stemcells, StemcellDiff{OS: stemcell.OS, From: previous, To: stemcell.Version})
+	}
+	for _, stemcell := range from.stemcells {
+		if version, removed := previousStemcells[stemcell.OS]; removed {
+			diff.Stemcells = append(diff.Stemcells, StemcellDiff{OS: stemcell.OS, From: version})
+		}
+	}
+
+	if from.hasMetadata && to.hasMetadata {
+		diff.PropertyBlueprints = calculateNameDiff(from.propertyBlueprints, to.propertyBlueprints)
+		diff.JobTypes = calculateNameDiff(from.jobTypes, to.jobTypes)
+		diff.FormTypes = calculateNameDiff(from.formTypes, to.formTypes)
+		diff.RuntimeConfigs = calculateNameDiff(from.runtimeConfigs, to.runtimeConfigs)
+	}
+
+	return diff
+}
+
+func containsRelease(releases []cargo.BOSHReleaseTarballLock, name string) bool {
+	for _, release := range releases {
+		if release.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func calculateNameDiff(from, to []string) *NameDiff {
+	diff := &NameDiff{Added: make([]string, 0), Removed: make([]string, 0)}
+	fromSet := make(map[string]struct{}, len(from))
+	for _, name := range from {
+		fromSet[name] = struct{}{}
+	}
+	toSet := make(map[string]struct{}, len(to))
+	for _, name := range to {
+		toSet[name] = struct{}{}
+		if _, found := fromSet[name]; !found {
+			diff.Added = append(diff.Added, name)
+		}
+	}
+	for _, name := range from {
+		if _, found := toSet[name]; !found {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	return diff
+}
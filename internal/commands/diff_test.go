@@ -0,0 +1,190 @@
+package commands_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+func TestDiff_Execute(t *testing.T) {
+	t.Run("tiles", func(t *testing.T) {
+		please := NewWithT(t)
+
+		dir := t.TempDir()
+		fromTile := writeDiffTestTile(t, dir, "from.pivotal", `---
+releases:
+  - {name: banana, version: 1.0.0}
+  - {name: lemon, version: 2.0.0}
+  - {name: orange, version: 3.0.0}
+stemcell_criteria: {os: ubuntu-jammy, version: "1.10"}
+property_blueprints:
+  - {name: color, type: string}
+  - {name: size, type: integer}
+job_types:
+  - {name: peeler}
+form_types:
+  - {name: colors}
+runtime_configs:
+  - {name: dns}
+`)
+		toTile := writeDiffTestTile(t, dir, "to.pivotal", `---
+releases:
+  - {name: banana, version: 1.1.0}
+  - {name: lemon, version: 2.0.0}
+  - {name: pear, version: 1.0.0}
+stemcell_criteria: {os: ubuntu-jammy, version: "1.12"}
+property_blueprints:
+  - {name: color, type: string}
+  - {name: ripeness, type: string}
+job_types:
+  - {name: peeler}
+  - {name: juicer}
+form_types:
+  - {name: colors}
+runtime_configs: []
+`)
+
+		var output bytes.Buffer
+		report := commands.NewReport("diff")
+		err := commands.NewDiff(log.New(&output, "", 0)).WithReport(report).Execute([]string{fromTile, toTile})
+		please.Expect(err).NotTo(HaveOccurred())
+
+		result, err := json.Marshal(report.Result)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(result).To(MatchJSON(`{
+			"releases": [
+				{"name": "banana", "from": "1.0.0", "to": "1.1.0"},
+				{"name": "pear", "from": "", "to": "1.0.0"},
+				{"name": "orange", "from": "3.0.0", "to": ""}
+			],
+			"stemcells": [
+				{"os": "ubuntu-jammy", "from": "1.10", "to": "1.12"}
+			],
+			"property_blueprints": {"added": ["ripeness"], "removed": ["size"]},
+			"job_types": {"added": ["juicer"], "removed": []},
+			"form_types": {"added": [], "removed": []},
+			"runtime_configs": {"added": [], "removed": ["dns"]}
+		}`))
+		please.Expect(output.String()).To(Equal(`Releases:
+  bumped banana from 1.0.0 to 1.1.0
+  added pear 1.0.0
+  removed orange 3.0.0
+Stemcells:
+  changed ubuntu-jammy from 1.10 to 1.12
+Property blueprints:
+  + ripeness
+  - size
+Job types:
+  + juicer
+Runtime configs:
+  - dns
+`))
+	})
+
+	t.Run("git revisions", func(t *testing.T) {
+		please := NewWithT(t)
+
+		dir := t.TempDir()
+		repo, err := git.PlainInit(dir, false)
+		please.Expect(err).NotTo(HaveOccurred())
+
+		commitDiffTestLock(t, repo, dir, cargo.KilnfileLock{
+			Releases: []cargo.BOSHReleaseTarballLock{{Name: "banana", Version: "1.0.0"}},
+			Stemcell: cargo.Stemcell{OS: "ubuntu-jammy", Version: "1.10"},
+		})
+		commitDiffTestLock(t, repo, dir, cargo.KilnfileLock{
+			Releases: []cargo.BOSHReleaseTarballLock{{Name: "banana", Version: "1.0.1"}},
+			Stemcells: []cargo.Stemcell{
+				{OS: "ubuntu-jammy", Version: "1.10"},
+				{OS: "windows2019", Version: "2019.50"},
+			},
+		})
+
+		report := commands.NewReport("diff")
+		diff := commands.NewDiff(log.New(io.Discard, "", 0)).WithReport(report)
+		err = diff.Execute([]string{"--repository", dir, "HEAD~1", "HEAD"})
+		please.Expect(err).NotTo(HaveOccurred())
+
+		please.Expect(report.Result).To(Equal(commands.TileDiff{
+			Releases:  []commands.ReleaseDiff{{Name: "banana", From: "1.0.0", To: "1.0.1"}},
+			Stemcells: []commands.StemcellDiff{{OS: "windows2019", To: "2019.50"}},
+		}))
+	})
+
+	t.Run("no differences", func(t *testing.T) {
+		please := NewWithT(t)
+
+		dir := t.TempDir()
+		tilePath := writeDiffTestTile(t, dir, "tile.pivotal", "releases: [{name: banana, version: 1.0.0}]\n")
+
+		var output bytes.Buffer
+		err := commands.NewDiff(log.New(&output, "", 0)).Execute([]string{tilePath, tilePath})
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(output.String()).To(Equal("No differences\n"))
+	})
+
+	t.Run("wrong number of arguments", func(t *testing.T) {
+		please := NewWithT(t)
+
+		err := commands.NewDiff(log.New(&bytes.Buffer{}, "", 0)).Execute([]string{"HEAD"})
+		please.Expect(err).To(MatchError(ContainSubstring("expected two arguments")))
+	})
+}
+
+func writeDiffTestTile(t *testing.T, dir, name, metadata string) string {
+	t.Helper()
+	tilePath := filepath.Join(dir, name)
+	f, err := os.Create(tilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeAndIgnoreError(f)
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("metadata/metadata.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(metadata)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return tilePath
+}
+
+func commitDiffTestLock(t *testing.T, repo *git.Repository, dir string, lock cargo.KilnfileLock) {
+	t.Helper()
+	buf, err := yaml.Marshal(lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Kilnfile.lock"), buf, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Add("Kilnfile.lock"); err != nil {
+		t.Fatal(err)
+	}
+	signature := &object.Signature{Name: "releen", Email: "releen@example.com", When: time.Unix(1635975074, 0)}
+	if _, err := wt.Commit("update lock", &git.CommitOptions{Author: signature, Committer: signature}); err != nil {
+		t.Fatal(err)
+	}
+}
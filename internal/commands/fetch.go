@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sync"
+	"time"
 
+	"github.com/go-git/go-billy/v5/osfs"
 	"github.com/pivotal-cf/jhanda"
 
 	"github.com/pivotal-cf/kiln/internal/commands/flags"
@@ -27,6 +30,8 @@ type Fetch struct {
 
 	multiReleaseSourceProvider MultiReleaseSourceProvider
 	localReleaseDirectory      LocalReleaseDirectory
+	releaseCache               ReleaseCache
+	report                     *Report
 	Options                    FetchOptions
 }
 
@@ -41,6 +46,26 @@ func NewFetch(logger *log.Logger, multiReleaseSourceProvider MultiReleaseSourceP
 	}
 }
 
+// WithReleaseCache configures a cache shared between tiles. Releases are linked from
+// the cache instead of being downloaded when their SHA1 sum matches the Kilnfile.lock.
+func (f Fetch) WithReleaseCache(cache ReleaseCache) Fetch {
+	f.releaseCache = cache
+	return f
+}
+
+// WithReport configures the Report downloaded releases are added to.
+func (f Fetch) WithReport(report *Report) Fetch {
+	f.report = report
+	return f
+}
+
+//counterfeiter:generate -o ./fakes/release_cache.go --fake-name ReleaseCache . ReleaseCache
+type ReleaseCache interface {
+	Link(sha1, releasesDir string) (string, error)
+	Add(localPath, sha1 string) error
+	Remove(sha1 string) error
+}
+
 //counterfeiter:generate -o ./fakes/local_release_directory.go --fake-name LocalReleaseDirectory . LocalReleaseDirectory
 type LocalReleaseDirectory interface {
 	GetLocalReleases(releasesDir string) ([]component.Local, error)
@@ -53,7 +78,21 @@ func (f Fetch) Execute(args []string) error {
 		return err
 	}
 
-	_, missingReleases, extraReleases := partition(kilnfileLock.Releases, availableLocalReleaseSet)
+	availableReleases, missingReleases, extraReleases := partition(kilnfileLock.Releases, availableLocalReleaseSet)
+
+	for _, local := range availableReleases {
+		rl, _ := kilnfileLock.FindBOSHReleaseWithName(local.Lock.Name)
+		err := verifyRelease(kilnfile, rl, local)
+		if err == nil {
+			continue
+		}
+		f.logger.Printf("Release %s %s failed verification and will be downloaded again: %s", rl.Name, rl.Version, err)
+		err = os.Remove(local.LocalPath)
+		if err != nil {
+			return fmt.Errorf("error deleting release file %q that failed verification: %w", local.LocalPath, err) // untested
+		}
+		missingReleases = append(missingReleases, rl)
+	}
 
 	err = f.localReleaseDirectory.DeleteExtraReleases(extraReleases, f.Options.NoConfirm)
 	if err != nil {
@@ -63,7 +102,10 @@ func (f Fetch) Execute(args []string) error {
 	if len(missingReleases) > 0 {
 		f.logger.Printf("Found %d missing releases to download", len(missingReleases))
 
-		_, err := f.downloadMissingReleases(kilnfile, missingReleases)
+		downloaded, err := f.downloadMissingReleases(kilnfile, missingReleases)
+		for _, local := range downloaded {
+			f.report.AddDownload(local)
+		}
 		if err != nil {
 			return err
 		}
@@ -99,7 +141,7 @@ func (f *Fetch) setup(args []string) (cargo.Kilnfile, cargo.KilnfileLock, []comp
 
 	kilnfile, kilnfileLock, err := f.Options.LoadKilnfiles(nil, nil)
 	if err != nil {
-		return cargo.Kilnfile{}, cargo.KilnfileLock{}, nil, err
+		return cargo.Kilnfile{}, cargo.KilnfileLock{}, nil, withErrorCode(ErrorCodeKilnfile, err)
 	}
 
 	f.logger.Printf("Gathering releases...")
@@ -114,34 +156,163 @@ func (f *Fetch) setup(args []string) (cargo.Kilnfile, cargo.KilnfileLock, []comp
 func (f Fetch) downloadMissingReleases(kilnfile cargo.Kilnfile, releaseLocks []cargo.BOSHReleaseTarballLock) ([]component.Local, error) {
 	releaseSource := f.multiReleaseSourceProvider(kilnfile, f.Options.AllowOnlyPublishableReleases)
 
-	var downloaded []component.Local
+	workerCount := f.Options.ParallelDownloads
+	if workerCount < 1 {
+		workerCount = 1
+	}
+	if workerCount > len(releaseLocks) {
+		workerCount = len(releaseLocks)
+	}
 
-	for _, rl := range releaseLocks {
-		remoteRelease := cargo.BOSHReleaseTarballLock{
-			Name:         rl.Name,
-			Version:      rl.Version,
-			RemotePath:   rl.RemotePath,
-			RemoteSource: rl.RemoteSource,
+	type downloadResult struct {
+		local component.Local
+		err   error
+	}
+
+	results := make([]downloadResult, len(releaseLocks))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workerCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				local, err := f.downloadRelease(kilnfile, releaseSource, releaseLocks[index])
+				results[index] = downloadResult{local: local, err: err}
+			}
+		}()
+	}
+	for index := range releaseLocks {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	var (
+		downloaded []component.Local
+		failures   errorList
+	)
+	for index, result := range results {
+		if result.err != nil {
+			rl := releaseLocks[index]
+			failures = append(failures, fmt.Errorf("- %s %s from %s: %w", rl.Name, rl.Version, rl.RemoteSource, result.err))
+			continue
 		}
+		downloaded = append(downloaded, result.local)
+	}
+
+	if len(failures) > 0 {
+		return downloaded, fmt.Errorf("failed to download %d of %d releases:\n%w", len(failures), len(releaseLocks), failures)
+	}
+
+	return downloaded, nil
+}
 
-		local, err := releaseSource.DownloadRelease(f.Options.ReleasesDir, remoteRelease)
+// downloadRelease retries failed downloads with an exponential backoff. Release sources
+// keep partially downloaded files so each retry resumes where the previous attempt stopped.
+func (f Fetch) downloadRelease(kilnfile cargo.Kilnfile, releaseSource component.MultiReleaseSource, rl cargo.BOSHReleaseTarballLock) (component.Local, error) {
+	remoteRelease := cargo.BOSHReleaseTarballLock{
+		Name:         rl.Name,
+		Version:      rl.Version,
+		RemotePath:   rl.RemotePath,
+		RemoteSource: rl.RemoteSource,
+	}
+
+	useCache := f.releaseCache != nil && !f.Options.NoReleaseCache && rl.SHA1 != ""
+	if useCache {
+		localPath, err := f.releaseCache.Link(rl.SHA1, f.Options.ReleasesDir)
+		if err == nil {
+			err = f.verifyCachedRelease(localPath, rl.SHA1)
+		}
+		if err == nil {
+			err = verifyRelease(kilnfile, rl, component.Local{LocalPath: localPath})
+		}
+		if err == nil {
+			f.logger.Printf("Using cached %s %s", rl.Name, rl.Version)
+			return component.Local{Lock: remoteRelease.WithSHA1(rl.SHA1).WithSHA256(rl.SHA256), LocalPath: localPath}, nil
+		}
+		if localPath != "" {
+			_ = os.Remove(localPath)
+		}
+		if !component.IsErrNotFound(err) {
+			f.logger.Printf("Warning: failed to use cached %s %s: %s", rl.Name, rl.Version, err)
+		}
+	}
+
+	var (
+		local component.Local
+		err   error
+		delay = f.Options.DownloadRetryDelay
+	)
+	for attempt := 0; ; attempt++ {
+		local, err = releaseSource.DownloadRelease(f.Options.ReleasesDir, remoteRelease)
+		if err == nil {
+			break
+		}
+		if attempt >= f.Options.DownloadRetries {
+			return component.Local{}, withErrorCode(ErrorCodeDownloadFailed, fmt.Errorf("download failed: %w", err))
+		}
+		f.logger.Printf("Download of %s %s failed (attempt %d of %d), retrying in %s: %s", rl.Name, rl.Version, attempt+1, f.Options.DownloadRetries+1, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+
+	if local.Lock.SHA1 != rl.SHA1 {
+		err = os.Remove(local.LocalPath)
 		if err != nil {
-			return nil, fmt.Errorf("download failed: %w", err)
+			return component.Local{}, fmt.Errorf("error deleting bad release file %q: %w", local.LocalPath, err) // untested
 		}
 
-		if local.Lock.SHA1 != rl.SHA1 {
-			err = os.Remove(local.LocalPath)
-			if err != nil {
-				return nil, fmt.Errorf("error deleting bad release file %q: %w", local.LocalPath, err) // untested
-			}
+		return component.Local{}, withErrorCode(ErrorCodeChecksumMismatch, fmt.Errorf("downloaded release %q had an incorrect SHA1 - expected %q, got %q", local.LocalPath, rl.SHA1, local.Lock.SHA1))
+	}
+
+	err = verifyRelease(kilnfile, rl, local)
+	if err != nil {
+		_ = os.Remove(local.LocalPath)
+		return component.Local{}, withErrorCode(ErrorCodeVerificationFailed, fmt.Errorf("downloaded release %q failed verification: %w", local.LocalPath, err))
+	}
 
-			return nil, fmt.Errorf("downloaded release %q had an incorrect SHA1 - expected %q, got %q", local.LocalPath, rl.SHA1, local.Lock.SHA1)
+	if useCache {
+		err = f.releaseCache.Add(local.LocalPath, local.Lock.SHA1)
+		if err != nil {
+			f.logger.Printf("Warning: failed to add %s %s to the release cache: %s", rl.Name, rl.Version, err)
 		}
+	}
 
-		downloaded = append(downloaded, local)
+	return local, nil
+}
+
+// verifyCachedRelease checks the linked release against the SHA1 sum it was cached under.
+// A corrupted cache entry is removed so it is downloaded and cached again.
+func (f Fetch) verifyCachedRelease(localPath, sha1 string) error {
+	sum, err := component.CalculateSum(localPath, osfs.New(""))
+	if err != nil {
+		return fmt.Errorf("failed to calculate the SHA1 sum of %q: %w", localPath, err)
 	}
+	if sum == sha1 {
+		return nil
+	}
+	if err := f.releaseCache.Remove(sha1); err != nil {
+		f.logger.Printf("Warning: failed to remove corrupted release %s from the release cache: %s", sha1, err)
+	}
+	return fmt.Errorf("cached release has an incorrect SHA1 - expected %q, got %q", sha1, sum)
+}
 
-	return downloaded, nil
+// sha256Matches returns false only when both locks have a SHA256 sum and the sums differ.
+// Kilnfile.lock files written before SHA256 sums were recorded do not have them.
+func sha256Matches(local, lock cargo.BOSHReleaseTarballLock) bool {
+	return local.SHA256 == "" || lock.SHA256 == "" || local.SHA256 == lock.SHA256
+}
+
+// verifyRelease checks the SHA256 sum and signature of a release when the Kilnfile.lock
+// or the release source requires it.
+func verifyRelease(kilnfile cargo.Kilnfile, rl cargo.BOSHReleaseTarballLock, local component.Local) error {
+	source, _ := component.FindReleaseSourceConfig(kilnfile, rl.RemoteSource)
+	if !component.ReleaseNeedsVerification(rl, source) {
+		return nil
+	}
+	return component.VerifyLocalRelease(local, rl, source)
 }
 
 func (f Fetch) Usage() jhanda.Usage {
@@ -159,7 +330,7 @@ func partition(releaseLocks []cargo.BOSHReleaseTarballLock, localReleases []comp
 nextRelease:
 	for _, rel := range localReleases {
 		for j, lock := range missing {
-			if rel.Lock.Name == lock.Name && rel.Lock.Version == lock.Version && rel.Lock.SHA1 == lock.SHA1 {
+			if rel.Lock.Name == lock.Name && rel.Lock.Version == lock.Version && rel.Lock.SHA1 == lock.SHA1 && sha256Matches(rel.Lock, lock) {
 				intersection = append(intersection, rel)
 				missing = append(missing[:j], missing[j+1:]...)
 				continue nextRelease
@@ -1,11 +1,14 @@
 package commands_test
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/pivotal-cf/jhanda"
 	"gopkg.in/yaml.v2"
@@ -35,10 +38,12 @@ var _ = Describe("Fetch", func() {
 		fakeReleaseSources          *componentFakes.MultiReleaseSource
 		releaseSourceList           component.ReleaseSourceList
 		fakeLocalReleaseDirectory   *commandsFakes.LocalReleaseDirectory
+		fakeReleaseCache            *commandsFakes.ReleaseCache
 		multiReleaseSourceProvider  commands.MultiReleaseSourceProvider
 
 		fetchExecuteArgs []string
 		fetchExecuteErr  error
+		report           *commands.Report
 	)
 
 	const (
@@ -77,6 +82,8 @@ stemcell_criteria:
 `
 
 			fakeLocalReleaseDirectory = new(commandsFakes.LocalReleaseDirectory)
+			fakeReleaseCache = nil
+			report = commands.NewReport("fetch")
 
 			fakeS3CompiledReleaseSource = new(componentFakes.ReleaseSource)
 			fakeS3CompiledReleaseSource.ConfigurationReturns(cargo.ReleaseSourceConfig{
@@ -117,9 +124,13 @@ stemcell_criteria:
 
 			err := os.WriteFile(someKilnfileLockPath, []byte(lockContents), 0o644)
 			Expect(err).NotTo(HaveOccurred())
-			fetch = commands.NewFetch(logger, multiReleaseSourceProvider, fakeLocalReleaseDirectory)
+			fetch = commands.NewFetch(logger, multiReleaseSourceProvider, fakeLocalReleaseDirectory).WithReport(report)
+			if fakeReleaseCache != nil {
+				fetch = fetch.WithReleaseCache(fakeReleaseCache)
+			}
 
 			fetchExecuteErr = fetch.Execute(fetchExecuteArgs)
+			report.Finish(fetchExecuteErr)
 		})
 
 		When("a local compiled release exists", func() {
@@ -396,6 +407,110 @@ stemcell_criteria:
 				Expect(object).To(Equal(missingReleaseS3Built))
 			})
 
+			It("adds the downloaded releases to the report", func() {
+				Expect(report.Success).To(BeTrue())
+				Expect(report.Downloads).To(ConsistOf(
+					commands.ReportDownload{Name: "some-missing-release-on-s3-compiled", Version: "4.5.6", SHA1: "correct-sha", LocalPath: "local-path-1"},
+					commands.ReportDownload{Name: "some-missing-release-on-boshio", Version: "5.6.7", SHA1: "correct-sha", LocalPath: "local-path-2"},
+					commands.ReportDownload{Name: "some-missing-release-on-s3-built", Version: "8.9.0", SHA1: "correct-sha", LocalPath: "local-path-3"},
+				))
+			})
+
+			When("a release cache is configured", func() {
+				var (
+					cachedPath string
+					cachedSHA1 string
+				)
+
+				BeforeEach(func() {
+					cachedPath = filepath.Join(someReleasesDirectory, "cached.tgz")
+					Expect(os.WriteFile(cachedPath, []byte("cached release"), 0o644)).To(Succeed())
+					sum := sha1.Sum([]byte("cached release"))
+					cachedSHA1 = hex.EncodeToString(sum[:])
+
+					lockContents = strings.Replace(lockContents,
+						"remote_path: "+missingReleaseS3CompiledPath+"\n  sha1: correct-sha",
+						"remote_path: "+missingReleaseS3CompiledPath+"\n  sha1: "+cachedSHA1, 1)
+					fakeS3CompiledReleaseSource.DownloadReleaseReturns(component.Local{
+						Lock: missingReleaseS3CompiledID.Lock().WithSHA1(cachedSHA1), LocalPath: "local-path-1",
+					}, nil)
+
+					fakeReleaseCache = new(commandsFakes.ReleaseCache)
+					fakeReleaseCache.LinkCalls(func(sum, _ string) (string, error) {
+						if sum == cachedSHA1 {
+							return cachedPath, nil
+						}
+						return "", component.ErrNotFound
+					})
+				})
+
+				It("links the cached release instead of downloading it", func() {
+					Expect(fetchExecuteErr).NotTo(HaveOccurred())
+
+					Expect(fakeReleaseCache.LinkCallCount()).To(Equal(3))
+					for i := 0; i < fakeReleaseCache.LinkCallCount(); i++ {
+						_, releasesDir := fakeReleaseCache.LinkArgsForCall(i)
+						Expect(releasesDir).To(Equal(someReleasesDirectory))
+					}
+
+					Expect(fakeS3CompiledReleaseSource.DownloadReleaseCallCount()).To(Equal(0))
+					Expect(fakeBoshIOReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+					Expect(fakeS3BuiltReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+					Expect(fakeReleaseCache.RemoveCallCount()).To(Equal(0))
+				})
+
+				When("the cached release does not match its SHA1 sum", func() {
+					BeforeEach(func() {
+						Expect(os.WriteFile(cachedPath, []byte("corrupted release"), 0o644)).To(Succeed())
+					})
+
+					It("removes it from the cache and downloads the release", func() {
+						Expect(fetchExecuteErr).NotTo(HaveOccurred())
+
+						Expect(fakeReleaseCache.RemoveCallCount()).To(Equal(1))
+						Expect(fakeReleaseCache.RemoveArgsForCall(0)).To(Equal(cachedSHA1))
+						Expect(cachedPath).NotTo(BeAnExistingFile())
+
+						Expect(fakeS3CompiledReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+						Expect(fakeReleaseCache.AddCallCount()).To(Equal(3))
+					})
+				})
+
+				It("adds downloaded releases to the cache", func() {
+					Expect(fetchExecuteErr).NotTo(HaveOccurred())
+
+					Expect(fakeReleaseCache.AddCallCount()).To(Equal(2))
+					_, sha1 := fakeReleaseCache.AddArgsForCall(0)
+					Expect(sha1).To(Equal("correct-sha"))
+				})
+
+				When("the cache fails", func() {
+					BeforeEach(func() {
+						fakeReleaseCache.LinkReturns("", errors.New("lemon"))
+						fakeReleaseCache.AddReturns(errors.New("banana"))
+					})
+
+					It("downloads the releases", func() {
+						Expect(fetchExecuteErr).NotTo(HaveOccurred())
+						Expect(fakeS3CompiledReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+						Expect(fakeBoshIOReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+						Expect(fakeS3BuiltReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+					})
+				})
+
+				When("the cache is disabled", func() {
+					BeforeEach(func() {
+						fetchExecuteArgs = append(fetchExecuteArgs, "--no-release-cache")
+					})
+
+					It("does not use the cache", func() {
+						Expect(fetchExecuteErr).NotTo(HaveOccurred())
+						Expect(fakeReleaseCache.LinkCallCount()).To(Equal(0))
+						Expect(fakeReleaseCache.AddCallCount()).To(Equal(0))
+					})
+				})
+			})
+
 			Context("when download fails", func() {
 				var wrappedErr error
 
@@ -405,6 +520,7 @@ stemcell_criteria:
 						component.Local{},
 						wrappedErr,
 					)
+					fetchExecuteArgs = append(fetchExecuteArgs, "--download-retry-delay", "1ms")
 				})
 
 				It("returns an error", func() {
@@ -412,6 +528,65 @@ stemcell_criteria:
 					Expect(fetchExecuteErr).To(MatchError(ContainSubstring("download failed")))
 					Expect(errors.Is(fetchExecuteErr, wrappedErr)).To(BeTrue())
 				})
+
+				It("retries the download", func() {
+					Expect(fakeS3CompiledReleaseSource.DownloadReleaseCallCount()).To(Equal(4))
+				})
+
+				It("still downloads the other releases", func() {
+					Expect(fakeBoshIOReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+					Expect(fakeS3BuiltReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+				})
+
+				It("reports the failed download", func() {
+					Expect(report.Success).To(BeFalse())
+					Expect(report.Downloads).To(HaveLen(2))
+					Expect(report.Errors).To(HaveLen(1))
+					Expect(report.Errors[0].Code).To(Equal(commands.ErrorCodeDownloadFailed))
+					Expect(report.Errors[0].Message).To(ContainSubstring("some-missing-release-on-s3-compiled 4.5.6"))
+				})
+
+				When("other downloads fail too", func() {
+					var otherErr error
+
+					BeforeEach(func() {
+						otherErr = errors.New("boom")
+						fakeBoshIOReleaseSource.DownloadReleaseReturns(component.Local{}, otherErr)
+					})
+
+					It("reports every failed release", func() {
+						Expect(fetchExecuteErr).To(MatchError(ContainSubstring("failed to download 2 of 3 releases")))
+						Expect(fetchExecuteErr).To(MatchError(ContainSubstring("some-missing-release-on-s3-compiled 4.5.6")))
+						Expect(fetchExecuteErr).To(MatchError(ContainSubstring("some-missing-release-on-boshio 5.6.7")))
+						Expect(errors.Is(fetchExecuteErr, wrappedErr)).To(BeTrue())
+						Expect(errors.Is(fetchExecuteErr, otherErr)).To(BeTrue())
+					})
+				})
+
+				When("the download succeeds after a retry", func() {
+					BeforeEach(func() {
+						fakeS3CompiledReleaseSource.DownloadReleaseReturnsOnCall(0, component.Local{}, wrappedErr)
+						fakeS3CompiledReleaseSource.DownloadReleaseReturnsOnCall(1, component.Local{
+							Lock: missingReleaseS3CompiledID.Lock().WithSHA1("correct-sha"), LocalPath: "local-path-1",
+						}, nil)
+					})
+
+					It("does not return an error", func() {
+						Expect(fetchExecuteErr).NotTo(HaveOccurred())
+						Expect(fakeS3CompiledReleaseSource.DownloadReleaseCallCount()).To(Equal(2))
+					})
+				})
+
+				When("retries are disabled", func() {
+					BeforeEach(func() {
+						fetchExecuteArgs = append(fetchExecuteArgs, "--download-retries", "0")
+					})
+
+					It("attempts the download once", func() {
+						Expect(fetchExecuteErr).To(HaveOccurred())
+						Expect(fakeS3CompiledReleaseSource.DownloadReleaseCallCount()).To(Equal(1))
+					})
+				})
 			})
 
 			Context("when the downloaded release has the wrong sha1", func() {
@@ -437,6 +612,12 @@ stemcell_criteria:
 					Expect(fetchExecuteErr).To(MatchError(ContainSubstring(`"wrong-sha"`)))
 				})
 
+				It("reports the checksum mismatch", func() {
+					Expect(report.Success).To(BeFalse())
+					Expect(report.Errors).To(HaveLen(1))
+					Expect(report.Errors[0].Code).To(Equal(commands.ErrorCodeChecksumMismatch))
+				})
+
 				It("deletes the release file from disk", func() {
 					_, err := os.Stat(badReleasePath)
 					Expect(err).To(HaveOccurred())
@@ -629,3 +810,95 @@ release_sources:
 		})
 	})
 })
+
+var _ = Describe("Fetch with a directory release source", func() {
+	var (
+		tmpDir, releasesDir, kilnfilePath string
+
+		kilnfile cargo.Kilnfile
+		lock     cargo.BOSHReleaseTarballLock
+		fetch    commands.Fetch
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "fetch-directory-test")
+		Expect(err).NotTo(HaveOccurred())
+		sourceDir := filepath.Join(tmpDir, "shared-releases")
+		releasesDir = filepath.Join(tmpDir, "releases")
+		kilnfilePath = filepath.Join(tmpDir, "Kilnfile")
+
+		kilnfile = cargo.Kilnfile{
+			ReleaseSources: []cargo.ReleaseSourceConfig{
+				{Type: component.ReleaseSourceTypeDirectory, ID: "nfs", Path: sourceDir, PathTemplate: "{{.Name}}/{{.Name}}-{{.Version}}.tgz"},
+			},
+			Releases: []cargo.BOSHReleaseTarballSpecification{{Name: "mango"}},
+		}
+		source := component.NewReleaseSourceRepo(kilnfile, log.New(GinkgoWriter, "", 0))
+		uploader, err := source.FindReleaseUploader("nfs")
+		Expect(err).NotTo(HaveOccurred())
+		lock, err = uploader.UploadRelease(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "1.2.3"}, strings.NewReader("some release contents"))
+		Expect(err).NotTo(HaveOccurred())
+
+		fetch = commands.NewFetch(log.New(GinkgoWriter, "", 0), func(kilnfile cargo.Kilnfile, _ bool) component.MultiReleaseSource {
+			return component.NewReleaseSourceRepo(kilnfile, log.New(GinkgoWriter, "", 0))
+		}, new(commandsFakes.LocalReleaseDirectory))
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	JustBeforeEach(func() {
+		kilnfileBuf, err := yaml.Marshal(kilnfile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(kilnfilePath, kilnfileBuf, 0o644)).To(Succeed())
+		lockBuf, err := yaml.Marshal(cargo.KilnfileLock{Releases: []cargo.BOSHReleaseTarballLock{lock}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(kilnfilePath+".lock", lockBuf, 0o644)).To(Succeed())
+	})
+
+	It("fetches releases without any network access", func() {
+		err := fetch.Execute([]string{"--kilnfile", kilnfilePath, "--releases-directory", releasesDir})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.ReadFile(filepath.Join(releasesDir, "mango-1.2.3.tgz"))).To(BeEquivalentTo("some release contents"))
+	})
+
+	When("the Kilnfile.lock has a SHA256 sum", func() {
+		When("the sum matches", func() {
+			BeforeEach(func() {
+				lock.SHA256 = "ca6838db82f1df4b7456c468b3035aee107a19caaafb65e2b5e68f037d8bb6cc"
+			})
+
+			It("fetches the release", func() {
+				err := fetch.Execute([]string{"--kilnfile", kilnfilePath, "--releases-directory", releasesDir})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(filepath.Join(releasesDir, "mango-1.2.3.tgz")).To(BeARegularFile())
+			})
+		})
+
+		When("the sum does not match", func() {
+			BeforeEach(func() {
+				lock.SHA256 = "0000000000000000000000000000000000000000000000000000000000000000"
+			})
+
+			It("refuses the release", func() {
+				err := fetch.Execute([]string{"--kilnfile", kilnfilePath, "--releases-directory", releasesDir, "--download-retries", "0"})
+				Expect(err).To(MatchError(ContainSubstring("SHA256 mismatch")))
+				Expect(filepath.Join(releasesDir, "mango-1.2.3.tgz")).NotTo(BeAnExistingFile())
+			})
+		})
+	})
+
+	When("the release source requires signatures", func() {
+		BeforeEach(func() {
+			kilnfile.ReleaseSources[0].SignaturePublicKey = "-----BEGIN PUBLIC KEY-----\nMFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAE\n-----END PUBLIC KEY-----\n"
+		})
+
+		It("refuses an unsigned release", func() {
+			err := fetch.Execute([]string{"--kilnfile", kilnfilePath, "--releases-directory", releasesDir, "--download-retries", "0"})
+			Expect(err).To(MatchError(ContainSubstring("requires a signature")))
+			Expect(filepath.Join(releasesDir, "mango-1.2.3.tgz")).NotTo(BeAnExistingFile())
+		})
+	})
+})
@@ -15,6 +15,7 @@ import (
 type FindReleaseVersion struct {
 	outLogger   *log.Logger
 	mrsProvider MultiReleaseSourceProvider
+	report      *Report
 
 	Options struct {
 		flags.Standard
@@ -37,6 +38,12 @@ func NewFindReleaseVersion(outLogger *log.Logger, multiReleaseSourceProvider Mul
 	}
 }
 
+// WithReport configures the Report the release version is set as the result of.
+func (cmd *FindReleaseVersion) WithReport(report *Report) *FindReleaseVersion {
+	cmd.report = report
+	return cmd
+}
+
 func (cmd *FindReleaseVersion) Execute(args []string) error {
 	kilnfile, kilnfileLock, err := cmd.setup(args)
 	if err != nil {
@@ -49,20 +56,27 @@ func (cmd *FindReleaseVersion) Execute(args []string) error {
 		return err
 	}
 
-	spec.StemcellOS = kilnfileLock.Stemcell.OS
-	spec.StemcellVersion = kilnfileLock.Stemcell.Version
+	stemcell, err := kilnfileLock.StemcellForRelease(spec)
+	if err != nil {
+		return err
+	}
+	spec.StemcellOS = stemcell.OS
+	spec.StemcellVersion = stemcell.Version
 
 	releaseRemote, err := releaseSource.FindReleaseVersion(spec, cmd.Options.NoDownload)
 	if err != nil {
 		return err
 	}
 
-	releaseVersionJson, _ := json.Marshal(releaseVersionOutput{
+	output := releaseVersionOutput{
 		Version:    releaseRemote.Version,
 		RemotePath: releaseRemote.RemotePath,
 		Source:     releaseRemote.RemoteSource,
 		SHA:        releaseRemote.SHA1,
-	})
+	}
+	cmd.report.SetResult(output)
+
+	releaseVersionJson, _ := json.Marshal(output)
 	cmd.outLogger.Println(string(releaseVersionJson))
 	return err
 }
@@ -83,8 +97,7 @@ func (cmd *FindReleaseVersion) setup(args []string) (cargo.Kilnfile, cargo.Kilnf
 
 	kilnfile, kilnfileLock, err := cmd.Options.LoadKilnfiles(nil, nil)
 	if err != nil {
-		fmt.Println(err)
-		return cargo.Kilnfile{}, cargo.KilnfileLock{}, err
+		return cargo.Kilnfile{}, cargo.KilnfileLock{}, withErrorCode(ErrorCodeKilnfile, err)
 	}
 
 	return kilnfile, kilnfileLock, nil
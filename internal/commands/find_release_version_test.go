@@ -1,6 +1,7 @@
 package commands_test
 
 import (
+	"encoding/json"
 	"log"
 	"os"
 	"path/filepath"
@@ -27,6 +28,7 @@ var _ = Describe("Find the release version", func() {
 		executeErr       error
 		releaseName      string
 		someKilnfilePath string
+		report           *commands.Report
 	)
 
 	Describe("Execute", func() {
@@ -69,7 +71,8 @@ releases:
 			multiReleaseSourceProvider := func(kilnfile cargo.Kilnfile, allowOnlyPublishable bool) component.MultiReleaseSource {
 				return fakeReleasesSource
 			}
-			findReleaseVersion = commands.NewFindReleaseVersion(logger, multiReleaseSourceProvider)
+			report = commands.NewReport("find-release-version")
+			findReleaseVersion = commands.NewFindReleaseVersion(logger, multiReleaseSourceProvider).WithReport(report)
 
 			logger.Printf("releaseName is: %s", releaseName)
 			executeErr = findReleaseVersion.Execute(fetchExecuteArgs)
@@ -113,6 +116,12 @@ releases:
 						Expect((&writer).String()).To(ContainSubstring("\"source\":\"bosh.io\""))
 						Expect((&writer).String()).To(ContainSubstring("\"sha\":\"some-sha\""))
 					})
+
+					It("sets the release as the report result", func() {
+						result, err := json.Marshal(report.Result)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(result).To(MatchJSON(`{"version": "74.12.5", "remote_path": "remote_url", "source": "bosh.io", "sha": "some-sha"}`))
+					})
 				})
 			})
 		})
@@ -26,9 +26,12 @@ type FindStemcellVersion struct {
 
 	Options struct {
 		flags.Standard
+
+		OS string `long:"os" description:"operating system of the stemcell (required when the Kilnfile has more than one stemcell)"`
 	}
 
-	FS billy.Filesystem
+	FS     billy.Filesystem
+	report *Report
 }
 
 type stemcellVersionOutput struct {
@@ -45,18 +48,32 @@ func NewFindStemcellVersion(outLogger *log.Logger, pivnetService *pivnet.Service
 	}
 }
 
+// WithReport configures the Report the stemcell version is set as the result of.
+func (cmd FindStemcellVersion) WithReport(report *Report) FindStemcellVersion {
+	cmd.report = report
+	return cmd
+}
+
 func (cmd FindStemcellVersion) Execute(args []string) error {
 	kilnfile, err := cmd.setup(args)
 	if err != nil {
 		return err
 	}
 
-	productSlug, err := kilnfile.Stemcell.ProductSlug()
+	stemcell := kilnfile.Stemcell
+	if len(kilnfile.Stemcells) > 0 || cmd.Options.OS != "" {
+		stemcell, err = kilnfile.FindStemcell(cmd.Options.OS)
+		if err != nil {
+			return err
+		}
+	}
+
+	productSlug, err := stemcell.ProductSlug()
 	if err != nil {
 		return err
 	}
 
-	if kilnfile.Stemcell.Version == "" {
+	if stemcell.Version == "" {
 		return fmt.Errorf(ErrStemcellMajorVersionMustBeValid)
 	}
 
@@ -66,7 +83,7 @@ func (cmd FindStemcellVersion) Execute(args []string) error {
 		return err
 	}
 
-	c, err := semver.NewConstraint(kilnfile.Stemcell.Version)
+	c, err := semver.NewConstraint(stemcell.Version)
 	if err != nil {
 		return err
 	}
@@ -76,11 +93,14 @@ func (cmd FindStemcellVersion) Execute(args []string) error {
 		return err
 	}
 
-	stemcellVersionJson, err := json.Marshal(stemcellVersionOutput{
+	output := stemcellVersionOutput{
 		Version:    v,
 		Source:     "Tanzunet",
 		RemotePath: TanzuNetRemotePath,
-	})
+	}
+	cmd.report.SetResult(output)
+
+	stemcellVersionJson, err := json.Marshal(output)
 	if err != nil {
 		return err
 	}
@@ -103,7 +123,7 @@ func (cmd *FindStemcellVersion) setup(args []string) (cargo.Kilnfile, error) {
 
 	kilnfile, _, err := cmd.Options.Standard.LoadKilnfiles(cmd.FS, nil)
 	if err != nil {
-		return cargo.Kilnfile{}, fmt.Errorf("error loading Kilnfiles: %w", err)
+		return cargo.Kilnfile{}, withErrorCode(ErrorCodeKilnfile, fmt.Errorf("error loading Kilnfiles: %w", err))
 	}
 
 	return kilnfile, nil
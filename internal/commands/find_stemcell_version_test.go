@@ -1,6 +1,7 @@
 package commands_test
 
 import (
+	"encoding/json"
 	"log"
 	"net/http"
 	"os"
@@ -32,6 +33,7 @@ var _ = Describe("Find the stemcell version", func() {
 		serverMock           *fakes.RoundTripper
 		simpleRequest        *http.Request
 		requestErr           error
+		report               *commands.Report
 	)
 
 	Describe("Execute", func() {
@@ -89,7 +91,8 @@ stemcell_criteria:
 			err = os.WriteFile(someKilnfileLockPath, []byte(lockContents), 0o644)
 			Expect(err).NotTo(HaveOccurred())
 
-			findStemcellVersion = commands.NewFindStemcellVersion(logger, pivnetService)
+			report = commands.NewReport("find-stemcell-version")
+			findStemcellVersion = commands.NewFindStemcellVersion(logger, pivnetService).WithReport(report)
 
 			fetchExecuteArgs = []string{
 				"--kilnfile", someKilnfilePath,
@@ -179,6 +182,12 @@ stemcell_criteria:
 					Expect((&writer).String()).To(ContainSubstring("\"remote_path\":\"network.pivotal.io\""))
 					Expect((&writer).String()).To(ContainSubstring("\"source\":\"Tanzunet\""))
 				})
+
+				It("sets the stemcell as the report result", func() {
+					result, err := json.Marshal(report.Result)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(MatchJSON(`{"version": "456.118", "source": "Tanzunet", "remote_path": "network.pivotal.io"}`))
+				})
 			})
 		})
 	})
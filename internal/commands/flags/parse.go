@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/osfs"
@@ -43,9 +44,13 @@ type Standard struct {
 }
 
 type FetchBakeOptions struct {
-	DownloadThreads              int  `short:"dt" long:"download-threads" description:"number of parallel threads to download parts from S3"`
-	NoConfirm                    bool `short:"n" long:"no-confirm" default:"true" description:"non-interactive mode, will delete extra releases in releases dir without prompting"`
-	AllowOnlyPublishableReleases bool `long:"allow-only-publishable-releases" default:"false" description:"include releases that would not be shipped with the tile (development builds)"`
+	DownloadThreads              int           `short:"dt" long:"download-threads" description:"number of parallel threads to download parts from S3, GCS, or Azure"`
+	ParallelDownloads            int           `long:"parallel-downloads" default:"4" description:"number of releases to download at the same time"`
+	DownloadRetries              int           `long:"download-retries" default:"3" description:"number of times to retry a failed release download"`
+	DownloadRetryDelay           time.Duration `long:"download-retry-delay" default:"2s" description:"delay before the first download retry; it doubles after each attempt"`
+	NoConfirm                    bool          `short:"n" long:"no-confirm" default:"true" description:"non-interactive mode, will delete extra releases in releases dir without prompting"`
+	AllowOnlyPublishableReleases bool          `long:"allow-only-publishable-releases" default:"false" description:"include releases that would not be shipped with the tile (development builds)"`
+	NoReleaseCache               bool          `long:"no-release-cache" description:"do not use or populate the shared release cache in ~/.kiln/cache"`
 }
 
 // LoadKilnfiles parses and interpolates the Kilnfile and parsed the Kilnfile.lock.
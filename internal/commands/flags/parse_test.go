@@ -2,6 +2,7 @@ package flags_test
 
 import (
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -20,6 +21,9 @@ var _ = Describe("ToStrings", func() {
 				flags.Standard{Kilnfile: "kilnfile1", VariableFiles: []string{"variables-files-1", "variables-files-2"}, Variables: []string{"variables-1", "variables-2"}},
 				flags.FetchBakeOptions{
 					DownloadThreads:              0,
+					ParallelDownloads:            4,
+					DownloadRetries:              3,
+					DownloadRetryDelay:           2 * time.Second,
 					NoConfirm:                    true,
 					AllowOnlyPublishableReleases: false,
 				},
@@ -33,6 +37,9 @@ var _ = Describe("ToStrings", func() {
 				"--variables-file variables-files-2 " +
 				"--variable variables-1 --variable variables-2 " +
 				"--download-threads 0 " +
+				"--parallel-downloads 4 " +
+				"--download-retries 3 " +
+				"--download-retry-delay 2s " +
 				"--no-confirm " +
 				"--releases-directory releases-dir"
 
@@ -49,6 +56,9 @@ var _ = Describe("ToStrings", func() {
 				flags.Standard{Kilnfile: "kilnfile1", VariableFiles: []string{"variables-files-1", "variables-files-2"}, Variables: []string{"variables-1", "variables-2"}},
 				flags.FetchBakeOptions{
 					DownloadThreads:              0,
+					ParallelDownloads:            4,
+					DownloadRetries:              3,
+					DownloadRetryDelay:           2 * time.Second,
 					NoConfirm:                    false,
 					AllowOnlyPublishableReleases: false,
 				},
@@ -62,6 +72,9 @@ var _ = Describe("ToStrings", func() {
 				"--variables-file variables-files-2 " +
 				"--variable variables-1 --variable variables-2 " +
 				"--download-threads 0 " +
+				"--parallel-downloads 4 " +
+				"--download-retries 3 " +
+				"--download-retry-delay 2s " +
 				"--releases-directory releases-dir"
 
 			Expect(jhandaArguments).To(Equal(strings.Split(expectedJhandaArguments, " ")))
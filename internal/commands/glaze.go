@@ -46,6 +46,13 @@ func (cmd *Glaze) Usage() jhanda.Usage {
 
 func pinVersions(kf cargo.Kilnfile, kl cargo.KilnfileLock) (cargo.Kilnfile, error) {
 	kf.Stemcell.Version = kl.Stemcell.Version
+	for i, stemcell := range kf.Stemcells {
+		l, err := kl.FindStemcell(stemcell.OS)
+		if err != nil {
+			return cargo.Kilnfile{}, err
+		}
+		kf.Stemcells[i].Version = l.Version
+	}
 	for releaseIndex, release := range kf.Releases {
 		l, err := kl.FindBOSHReleaseWithName(release.Name)
 		if err != nil {
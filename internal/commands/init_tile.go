@@ -0,0 +1,216 @@
+package commands
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/pivotal-cf/jhanda"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+)
+
+//go:embed init_template
+var initTemplate embed.FS
+
+const initTemplateRoot = "init_template"
+
+// initReleaseSources are the release_sources entries kiln init writes to the Kilnfile. Credentials are
+// Kilnfile template variables so they can be passed with --variables-file.
+var initReleaseSources = map[string]string{
+	component.ReleaseSourceTypeBOSHIO: `  - type: bosh.io
+`,
+	component.ReleaseSourceTypeGithub: `  - type: github
+    org: my-github-org
+    github_token: $(variable "github_token")
+`,
+	component.ReleaseSourceTypeS3: `  - type: s3
+    bucket: my-releases-bucket
+    region: us-west-1
+    access_key_id: $(variable "aws_access_key_id")
+    secret_access_key: $(variable "aws_secret_access_key")
+    path_template: "{{.Name}}/{{.Name}}-{{.Version}}.tgz"
+`,
+	component.ReleaseSourceTypeArtifactory: `  - type: artifactory
+    artifactory_host: https://artifactory.example.com
+    repo: my-releases-repo
+    username: $(variable "artifactory_username")
+    password: $(variable "artifactory_password")
+    path_template: "{{.Name}}/{{.Name}}-{{.Version}}.tgz"
+`,
+	component.ReleaseSourceTypeOCI: `  - type: oci
+    registry: registry.example.com
+    repo: my-releases
+    username: $(variable "registry_username")
+    password: $(variable "registry_password")
+`,
+	component.ReleaseSourceTypeDirectory: `  - type: directory
+    path: /mnt/releases
+    path_template: "{{.Name}}-{{.Version}}.tgz"
+`,
+	component.ReleaseSourceTypeGCS: `  - type: gcs
+    bucket: my-releases-bucket
+    path_template: "{{.Name}}/{{.Name}}-{{.Version}}.tgz"
+    service_account_key: $(variable "gcs_service_account_key")
+`,
+	component.ReleaseSourceTypeAzure: `  - type: azure
+    container: my-releases
+    account_name: myreleases
+    path_template: "{{.Name}}/{{.Name}}-{{.Version}}.tgz"
+    account_key: $(variable "azure_account_key")
+`,
+}
+
+var initTileName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+type Init struct {
+	FS        billy.Filesystem
+	outLogger *log.Logger
+	report    *Report
+
+	Options struct {
+		Name            string `short:"n" long:"name"             required:"true"        description:"name of the tile (the metadata name and Kilnfile slug)"`
+		Directory       string `short:"d" long:"directory"                               description:"directory to create the tile source in (defaults to the tile name)"`
+		Release         string `short:"r" long:"release"                                 description:"name of the BOSH release the tile deploys (defaults to the tile name)"`
+		ReleaseSource   string `          long:"release-source"   default:"bosh.io"      description:"release source type for the Kilnfile: bosh.io, github, s3, artifactory, oci, directory, gcs, or azure"`
+		StemcellOS      string `          long:"stemcell-os"      default:"ubuntu-jammy" description:"stemcell operating system"`
+		StemcellVersion string `          long:"stemcell-version" default:"1.1"          description:"stemcell version for the Kilnfile.lock"`
+	}
+}
+
+func NewInit(fs billy.Filesystem, outLogger *log.Logger) *Init {
+	return &Init{
+		FS:        fs,
+		outLogger: outLogger,
+	}
+}
+
+// WithReport configures the Report the created tile source is set as the result of.
+func (cmd *Init) WithReport(report *Report) *Init {
+	cmd.report = report
+	return cmd
+}
+
+// InitResult is the "--output json" result of init. The JSON field names should not change.
+type InitResult struct {
+	Name      string   `json:"name"`
+	Directory string   `json:"directory"`
+	Files     []string `json:"files"`
+}
+
+type initTemplateData struct {
+	Name, Label, Release, ReleaseSource string
+	StemcellOS, StemcellVersion         string
+}
+
+func (cmd *Init) Execute(args []string) error {
+	_, err := jhanda.Parse(&cmd.Options, args)
+	if err != nil {
+		return err
+	}
+	if !initTileName.MatchString(cmd.Options.Name) {
+		return fmt.Errorf("invalid tile name %q: use lower case letters, numbers, dashes, and underscores", cmd.Options.Name)
+	}
+	releaseSource, ok := initReleaseSources[cmd.Options.ReleaseSource]
+	if !ok {
+		types := make([]string, 0, len(initReleaseSources))
+		for t := range initReleaseSources {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		return fmt.Errorf("unknown release source type %q: expected one of %s", cmd.Options.ReleaseSource, strings.Join(types, ", "))
+	}
+	if cmd.Options.Directory == "" {
+		cmd.Options.Directory = cmd.Options.Name
+	}
+	if cmd.Options.Release == "" {
+		cmd.Options.Release = cmd.Options.Name
+	}
+
+	if entries, err := cmd.FS.ReadDir(cmd.Options.Directory); err == nil && len(entries) > 0 {
+		return fmt.Errorf("directory %q is not empty", cmd.Options.Directory)
+	}
+
+	data := initTemplateData{
+		Name:            cmd.Options.Name,
+		Label:           initLabel(cmd.Options.Name),
+		Release:         cmd.Options.Release,
+		ReleaseSource:   releaseSource,
+		StemcellOS:      cmd.Options.StemcellOS,
+		StemcellVersion: cmd.Options.StemcellVersion,
+	}
+
+	var files []string
+	err = fs.WalkDir(initTemplate, initTemplateRoot, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		contents, err := initTemplate.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		outputPath := path.Join(cmd.Options.Directory, strings.TrimPrefix(filePath, initTemplateRoot+"/"))
+		if strings.HasSuffix(outputPath, ".tmpl") {
+			outputPath = strings.TrimSuffix(outputPath, ".tmpl")
+			contents, err = renderInitTemplate(filePath, contents, data)
+			if err != nil {
+				return err
+			}
+		}
+		if err := cmd.FS.MkdirAll(path.Dir(outputPath), 0o755); err != nil {
+			return err
+		}
+		files = append(files, outputPath)
+		return util.WriteFile(cmd.FS, outputPath, contents, 0o644)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write tile source: %w", err)
+	}
+
+	cmd.report.SetResult(InitResult{Name: cmd.Options.Name, Directory: cmd.Options.Directory, Files: files})
+
+	cmd.outLogger.Printf("Created tile source for %q in %s\n", cmd.Options.Name, cmd.Options.Directory)
+	cmd.outLogger.Println("Next steps:")
+	cmd.outLogger.Printf("  cd %s\n", cmd.Options.Directory)
+	cmd.outLogger.Println("  kiln bake --stub-releases")
+	cmd.outLogger.Printf("  kiln add-release --name %s\n", cmd.Options.Release)
+	return nil
+}
+
+func renderInitTemplate(name string, contents []byte, data initTemplateData) ([]byte, error) {
+	tmpl, err := template.New(path.Base(name)).Option("missingkey=error").Parse(string(contents))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// initLabel makes a label like "My Tile" from a tile name like "my-tile".
+func initLabel(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == '_' })
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+func (cmd *Init) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Creates the source tree of a new tile: a base.yml, Kilnfile, Kilnfile.lock, version file, forms, properties, instance groups, jobs, migrations, and manifest test skeletons. The result bakes with --stub-releases.",
+		ShortDescription: "creates the source tree of a new tile",
+		Flags:            cmd.Options,
+	}
+}
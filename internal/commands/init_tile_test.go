@@ -0,0 +1,113 @@
+package commands_test
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+func TestInit_Execute(t *testing.T) {
+	t.Run("tile source", func(t *testing.T) {
+		please := NewWithT(t)
+
+		fs := memfs.New()
+		var output bytes.Buffer
+		err := commands.NewInit(fs, log.New(&output, "", 0)).Execute([]string{"--name", "my-tile", "--release", "my-release"})
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(output.String()).To(ContainSubstring(`Created tile source for "my-tile" in my-tile`))
+
+		for _, name := range []string{
+			"base.yml",
+			"icon.png",
+			"version",
+			"forms/configuration.yml",
+			"properties/example_property.yml",
+			"instance_groups/server.yml",
+			"jobs/server.yml",
+			"migrations/README.md",
+			"test/manifest/config.json",
+			"test/manifest/manifest_suite_test.go",
+			"test/manifest/server_test.go",
+		} {
+			_, err := fs.Stat("my-tile/" + name)
+			please.Expect(err).NotTo(HaveOccurred(), name)
+		}
+
+		baseYML, err := util.ReadFile(fs, "my-tile/base.yml")
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(string(baseYML)).To(ContainSubstring("name: my-tile\nlabel: My Tile\n"))
+		please.Expect(string(baseYML)).To(ContainSubstring(`$( release "my-release" )`))
+
+		var kilnfile cargo.Kilnfile
+		please.Expect(fsReadYAML(fs, "my-tile/Kilnfile", &kilnfile)).To(Succeed())
+		please.Expect(kilnfile.Slug).To(Equal("my-tile"))
+		please.Expect(kilnfile.ReleaseSources).To(Equal([]cargo.ReleaseSourceConfig{{Type: "bosh.io"}}))
+		please.Expect(kilnfile.Releases).To(BeEmpty())
+
+		var lock cargo.KilnfileLock
+		please.Expect(fsReadYAML(fs, "my-tile/Kilnfile.lock", &lock)).To(Succeed())
+		please.Expect(lock.Releases).To(BeEmpty())
+		please.Expect(lock.Stemcell).To(Equal(cargo.Stemcell{OS: "ubuntu-jammy", Version: "1.1"}))
+	})
+
+	t.Run("valid Kilnfile", func(t *testing.T) {
+		please := NewWithT(t)
+
+		fs := memfs.New()
+		err := commands.NewInit(fs, log.New(&bytes.Buffer{}, "", 0)).Execute([]string{"--name", "my-tile"})
+		please.Expect(err).NotTo(HaveOccurred())
+
+		err = commands.NewValidate(fs).Execute([]string{"--kilnfile", "my-tile/Kilnfile"})
+		please.Expect(err).NotTo(HaveOccurred())
+	})
+
+	t.Run("report", func(t *testing.T) {
+		please := NewWithT(t)
+
+		report := commands.NewReport("init")
+		err := commands.NewInit(memfs.New(), log.New(&bytes.Buffer{}, "", 0)).WithReport(report).Execute([]string{"--name", "my-tile", "--directory", "tile"})
+		please.Expect(err).NotTo(HaveOccurred())
+
+		result, ok := report.Result.(commands.InitResult)
+		please.Expect(ok).To(BeTrue())
+		please.Expect(result.Name).To(Equal("my-tile"))
+		please.Expect(result.Directory).To(Equal("tile"))
+		please.Expect(result.Files).To(ContainElements("tile/Kilnfile", "tile/Kilnfile.lock", "tile/base.yml"))
+	})
+
+	t.Run("release source", func(t *testing.T) {
+		please := NewWithT(t)
+
+		fs := memfs.New()
+		err := commands.NewInit(fs, log.New(&bytes.Buffer{}, "", 0)).Execute([]string{"--name", "my-tile", "--directory", "tile", "--release-source", "github"})
+		please.Expect(err).NotTo(HaveOccurred())
+
+		kilnfile, err := util.ReadFile(fs, "tile/Kilnfile")
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(string(kilnfile)).To(ContainSubstring("  - type: github\n    org: my-github-org\n"))
+	})
+
+	t.Run("unknown release source", func(t *testing.T) {
+		please := NewWithT(t)
+
+		err := commands.NewInit(memfs.New(), log.New(&bytes.Buffer{}, "", 0)).Execute([]string{"--name", "my-tile", "--release-source", "ftp"})
+		please.Expect(err).To(MatchError(ContainSubstring(`unknown release source type "ftp"`)))
+	})
+
+	t.Run("directory is not empty", func(t *testing.T) {
+		please := NewWithT(t)
+
+		fs := memfs.New()
+		please.Expect(util.WriteFile(fs, "my-tile/base.yml", []byte("name: banana"), 0o644)).To(Succeed())
+
+		err := commands.NewInit(fs, log.New(&bytes.Buffer{}, "", 0)).Execute([]string{"--name", "my-tile"})
+		please.Expect(err).To(MatchError(`directory "my-tile" is not empty`))
+	})
+}
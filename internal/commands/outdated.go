@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/pivotal-cf/jhanda"
+
+	"github.com/pivotal-cf/kiln/internal/commands/flags"
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+type Outdated struct {
+	outLogger   *log.Logger
+	mrsProvider MultiReleaseSourceProvider
+	report      *Report
+
+	Options struct {
+		flags.Standard
+		Parallelism int `long:"parallelism" default:"8" description:"number of releases to look up at the same time"`
+	}
+}
+
+// OutdatedRelease is a row of the outdated table. The JSON field names are part of
+// the "--output json" result and should not change.
+type OutdatedRelease struct {
+	Name    string `json:"name"`
+	Current string `json:"current"`
+
+	// Allowed is the highest version satisfying the version constraint in the Kilnfile.
+	Allowed       string `json:"allowed"`
+	AllowedSource string `json:"allowed_source"`
+
+	// Latest is the highest version ignoring the version constraint in the Kilnfile.
+	Latest       string `json:"latest"`
+	LatestSource string `json:"latest_source"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// IsOutdated returns true when a newer version is allowed by the Kilnfile.
+func (release OutdatedRelease) IsOutdated() bool {
+	return release.Allowed != "" && release.Allowed != release.Current
+}
+
+func NewOutdated(outLogger *log.Logger, multiReleaseSourceProvider MultiReleaseSourceProvider) *Outdated {
+	return &Outdated{
+		outLogger:   outLogger,
+		mrsProvider: multiReleaseSourceProvider,
+	}
+}
+
+// WithReport configures the Report the releases are set as the result of.
+func (cmd *Outdated) WithReport(report *Report) *Outdated {
+	cmd.report = report
+	return cmd
+}
+
+func (cmd *Outdated) Execute(args []string) error {
+	argsAfterFlags, err := flags.LoadFlagsWithDefaults(&cmd.Options, args, nil)
+	if err != nil {
+		return err
+	}
+	if len(argsAfterFlags) != 0 {
+		return fmt.Errorf("unexpected arguments: %v", argsAfterFlags)
+	}
+
+	kilnfile, kilnfileLock, err := cmd.Options.LoadKilnfiles(nil, nil)
+	if err != nil {
+		return err
+	}
+
+	releases := cmd.findVersions(cmd.mrsProvider(kilnfile, false), kilnfile, kilnfileLock)
+
+	cmd.printTable(releases)
+	cmd.report.SetResult(releases)
+
+	failed := 0
+	for _, release := range releases {
+		if release.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to find versions for %d release(s)", failed)
+	}
+	return nil
+}
+
+// findVersions looks up the allowed and latest versions of every release in the Kilnfile.
+// The results are in the same order as the Kilnfile releases.
+func (cmd *Outdated) findVersions(releaseSource component.MultiReleaseSource, kilnfile cargo.Kilnfile, kilnfileLock cargo.KilnfileLock) []OutdatedRelease {
+	parallelism := cmd.Options.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	results := make([]OutdatedRelease, len(kilnfile.Releases))
+	semaphore := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, spec := range kilnfile.Releases {
+		result := OutdatedRelease{Name: spec.Name}
+		if lock, err := kilnfileLock.FindBOSHReleaseWithName(spec.Name); err == nil {
+			result.Current = lock.Version
+		}
+
+		stemcell, err := kilnfileLock.StemcellForRelease(spec)
+		if err != nil {
+			result.Error = err.Error()
+			results[i] = result
+			continue
+		}
+		spec.StemcellOS = stemcell.OS
+		spec.StemcellVersion = stemcell.Version
+
+		wg.Add(1)
+		go func(i int, spec cargo.BOSHReleaseTarballSpecification, result OutdatedRelease) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			results[i] = findOutdatedRelease(releaseSource, spec, result)
+		}(i, spec, result)
+	}
+	wg.Wait()
+	return results
+}
+
+func findOutdatedRelease(releaseSource component.MultiReleaseSource, spec cargo.BOSHReleaseTarballSpecification, result OutdatedRelease) OutdatedRelease {
+	allowed, err := releaseSource.FindReleaseVersion(spec, true)
+	if err != nil && !component.IsErrNotFound(err) {
+		result.Error = err.Error()
+		return result
+	}
+	result.Allowed, result.AllowedSource = allowed.Version, allowed.RemoteSource
+
+	spec.Version = ""
+	latest, err := releaseSource.FindReleaseVersion(spec, true)
+	if err != nil && !component.IsErrNotFound(err) {
+		result.Error = err.Error()
+		return result
+	}
+	result.Latest, result.LatestSource = latest.Version, latest.RemoteSource
+
+	return result
+}
+
+func (cmd *Outdated) printTable(releases []OutdatedRelease) {
+	w := tabwriter.NewWriter(cmd.outLogger.Writer(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "RELEASE\tCURRENT\tALLOWED\tALLOWED SOURCE\tLATEST\tLATEST SOURCE")
+	for _, release := range releases {
+		if release.Error != "" {
+			_, _ = fmt.Fprintf(w, "%s\t%s\terror: %s\n", release.Name, orDash(release.Current), release.Error)
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", release.Name,
+			orDash(release.Current),
+			orDash(release.Allowed), orDash(release.AllowedSource),
+			orDash(release.Latest), orDash(release.LatestSource),
+		)
+	}
+	_ = w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func (cmd *Outdated) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Prints the locked, highest allowed, and latest versions of every release in the Kilnfile. Versions are found in the release sources without downloading any releases.",
+		ShortDescription: "prints releases with newer versions available",
+		Flags:            cmd.Options,
+	}
+}
@@ -0,0 +1,141 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/internal/component/fakes"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+func TestOutdated_Execute(t *testing.T) {
+	const (
+		kilnfileContents = `---
+releases:
+- name: banana
+  version: ~1.2
+- name: lemon
+- name: mango
+  version: 3.0.0
+`
+		lockContents = `---
+releases:
+- name: banana
+  version: 1.2.0
+- name: lemon
+  version: 4.0.0
+- name: mango
+  version: 3.0.0
+stemcell_criteria:
+  os: ubuntu-jammy
+  version: "1.123"
+`
+	)
+
+	versions := map[string][]string{
+		"banana": {"1.2.0", "1.2.5", "1.3.0"},
+		"lemon":  {"4.0.0"},
+	}
+
+	setup := func(t *testing.T) (*commands.Outdated, *fakes.MultiReleaseSource, *bytes.Buffer, string) {
+		t.Helper()
+		dir := t.TempDir()
+		kilnfilePath := filepath.Join(dir, "Kilnfile")
+		if err := os.WriteFile(kilnfilePath, []byte(kilnfileContents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(kilnfilePath+".lock", []byte(lockContents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		releaseSource := new(fakes.MultiReleaseSource)
+		releaseSource.FindReleaseVersionStub = func(spec cargo.BOSHReleaseTarballSpecification, _ bool) (cargo.BOSHReleaseTarballLock, error) {
+			constraint, err := spec.VersionConstraints()
+			if err != nil {
+				return cargo.BOSHReleaseTarballLock{}, err
+			}
+			var found cargo.BOSHReleaseTarballLock
+			for _, v := range versions[spec.Name] {
+				if constraint.Check(semver.MustParse(v)) {
+					found = cargo.BOSHReleaseTarballLock{Name: spec.Name, Version: v, RemoteSource: "bosh.io"}
+				}
+			}
+			if found.Version == "" {
+				return cargo.BOSHReleaseTarballLock{}, component.ErrNotFound
+			}
+			return found, nil
+		}
+
+		var output bytes.Buffer
+		cmd := commands.NewOutdated(log.New(&output, "", 0), func(cargo.Kilnfile, bool) component.MultiReleaseSource {
+			return releaseSource
+		})
+		return cmd, releaseSource, &output, kilnfilePath
+	}
+
+	t.Run("report", func(t *testing.T) {
+		please := NewWithT(t)
+		cmd, releaseSource, _, kilnfilePath := setup(t)
+
+		report := commands.NewReport("outdated")
+		err := cmd.WithReport(report).Execute([]string{"--kilnfile", kilnfilePath})
+		please.Expect(err).NotTo(HaveOccurred())
+
+		releases, ok := report.Result.([]commands.OutdatedRelease)
+		please.Expect(ok).To(BeTrue())
+		please.Expect(releases).To(Equal([]commands.OutdatedRelease{
+			{Name: "banana", Current: "1.2.0", Allowed: "1.2.5", AllowedSource: "bosh.io", Latest: "1.3.0", LatestSource: "bosh.io"},
+			{Name: "lemon", Current: "4.0.0", Allowed: "4.0.0", AllowedSource: "bosh.io", Latest: "4.0.0", LatestSource: "bosh.io"},
+			{Name: "mango", Current: "3.0.0"},
+		}))
+		please.Expect(releases[0].IsOutdated()).To(BeTrue())
+		please.Expect(releases[1].IsOutdated()).To(BeFalse())
+
+		please.Expect(releaseSource.FindReleaseVersionCallCount()).To(Equal(6))
+		for i := 0; i < releaseSource.FindReleaseVersionCallCount(); i++ {
+			spec, noDownload := releaseSource.FindReleaseVersionArgsForCall(i)
+			please.Expect(noDownload).To(BeTrue())
+			please.Expect(spec.StemcellOS).To(Equal("ubuntu-jammy"))
+			please.Expect(spec.StemcellVersion).To(Equal("1.123"))
+		}
+	})
+
+	t.Run("table", func(t *testing.T) {
+		please := NewWithT(t)
+		cmd, _, output, kilnfilePath := setup(t)
+
+		err := cmd.Execute([]string{"--kilnfile", kilnfilePath})
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(output.String()).To(Equal(`RELEASE  CURRENT  ALLOWED  ALLOWED SOURCE  LATEST  LATEST SOURCE
+banana   1.2.0    1.2.5    bosh.io         1.3.0   bosh.io
+lemon    4.0.0    4.0.0    bosh.io         4.0.0   bosh.io
+mango    3.0.0    -        -               -       -
+`))
+	})
+
+	t.Run("a release source fails", func(t *testing.T) {
+		please := NewWithT(t)
+		cmd, releaseSource, output, kilnfilePath := setup(t)
+		releaseSource.FindReleaseVersionStub = nil
+		releaseSource.FindReleaseVersionReturns(cargo.BOSHReleaseTarballLock{}, errors.New("banana"))
+
+		report := commands.NewReport("outdated")
+		err := cmd.WithReport(report).Execute([]string{"--kilnfile", kilnfilePath})
+		please.Expect(err).To(MatchError("failed to find versions for 3 release(s)"))
+		please.Expect(output.String()).To(ContainSubstring("banana   1.2.0    error: banana"))
+
+		releases, ok := report.Result.([]commands.OutdatedRelease)
+		please.Expect(ok).To(BeTrue())
+		please.Expect(releases).To(HaveLen(3))
+		please.Expect(releases[0].Error).To(Equal("banana"))
+	})
+}
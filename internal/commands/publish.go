@@ -74,6 +74,8 @@ type Publish struct {
 	Now func() time.Time
 
 	OutLogger, ErrLogger *log.Logger
+
+	report *Report
 }
 
 func NewPublish(outLogger, errLogger *log.Logger, fs billy.Filesystem) Publish {
@@ -84,6 +86,12 @@ func NewPublish(outLogger, errLogger *log.Logger, fs billy.Filesystem) Publish {
 	}
 }
 
+// WithReport configures the Report the published release is added to.
+func (p Publish) WithReport(report *Report) Publish {
+	p.report = report
+	return p
+}
+
 func (p Publish) Execute(args []string) error {
 	defer p.recoverFromPanic()
 
@@ -94,7 +102,7 @@ func (p Publish) Execute(args []string) error {
 
 	err = p.updateReleaseOnPivnet(kilnfile, buildVersion)
 	if err != nil {
-		return fmt.Errorf("failed to publish tile: %s", err)
+		return withErrorCode(ErrorCodePublishFailed, fmt.Errorf("failed to publish tile: %s", err))
 	} else {
 		p.OutLogger.Println("Successfully published tile.")
 	}
@@ -271,9 +279,35 @@ func (p Publish) updateReleaseOnPivnet(kilnfile cargo.Kilnfile, buildVersion *se
 		return err
 	}
 
+	p.report.AddChange(ReportChange{
+		Kind: "product",
+		Name: kilnfile.Slug,
+		From: release.Version,
+		To:   versionToPublish.String(),
+	})
+	p.report.SetResult(publishOutput{
+		Slug:             kilnfile.Slug,
+		Version:          versionToPublish.String(),
+		ReleaseType:      string(releaseType),
+		ReleaseDate:      releaseDate,
+		EndOfSupportDate: endOfSupportDate,
+		Availability:     availability,
+		LicenseFiles:     licenseFileNames,
+	})
+
 	return nil
 }
 
+type publishOutput struct {
+	Slug             string   `json:"slug"`
+	Version          string   `json:"version"`
+	ReleaseType      string   `json:"release_type"`
+	ReleaseDate      string   `json:"release_date"`
+	EndOfSupportDate string   `json:"end_of_support_date,omitempty"`
+	Availability     string   `json:"availability"`
+	LicenseFiles     []string `json:"license_files,omitempty"`
+}
+
 func (p Publish) eogsDate(rv *releaseVersion, releases releaseSet) (string, error) {
 	if rv.IsGA() {
 		sameMajorAndMinor, err := rv.MajorMinorConstraint()
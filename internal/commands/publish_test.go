@@ -1,7 +1,9 @@
 package commands_test
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"strings"
@@ -147,6 +149,25 @@ pre_ga_user_groups:
 					Expect(outLoggerBuffer.String()).To(ContainSubstring("  License file: None, pre-GA release"))
 				})
 
+				It("adds the published release to the report", func() {
+					report := commands.NewReport("publish")
+					err := publish.WithReport(report).Execute(args)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(report.Changes).To(Equal([]commands.ReportChange{
+						{Kind: "product", Name: slug, From: "2.0.0-build.45", To: "2.0.0-alpha.1"},
+					}))
+					result, err := json.Marshal(report.Result)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result).To(MatchJSON(fmt.Sprintf(`{
+						"slug": %q,
+						"version": "2.0.0-alpha.1",
+						"release_type": "Alpha Release",
+						"release_date": %q,
+						"availability": "Selected User Groups Only"
+					}`, slug, now.Format("2006-01-02"))))
+				})
+
 				It("adds the pre-GA user groups to the release", func() {
 					err := publish.Execute(args)
 					Expect(err).NotTo(HaveOccurred())
@@ -848,6 +869,7 @@ pre_ga_user_groups:
 					Expect(pfs.ListCallCount()).To(Equal(1))
 					Expect(pfs.AddToReleaseCallCount()).To(Equal(0))
 					Expect(err).To(MatchError(ContainSubstring("bad stuff happened")))
+					Expect(commands.ErrorCode(err)).To(Equal(commands.ErrorCodePublishFailed))
 				})
 			})
 
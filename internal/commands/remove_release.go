@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/pivotal-cf/jhanda"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pivotal-cf/kiln/internal/commands/flags"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+type RemoveRelease struct {
+	Options struct {
+		flags.Standard
+
+		Name         string `short:"n"  long:"name"               required:"true"    description:"name of the release to remove"`
+		ReleasesDir  string `short:"rd" long:"releases-directory" default:"releases" description:"path to a directory containing downloaded releases"`
+		MetadataPath string `short:"m"  long:"metadata"                              description:"path to a metadata file; the release template expression for the release is removed from its releases list"`
+	}
+	filesystem billy.Filesystem
+	logger     *log.Logger
+	report     *Report
+}
+
+func NewRemoveRelease(logger *log.Logger, filesystem billy.Filesystem) RemoveRelease {
+	return RemoveRelease{
+		logger:     logger,
+		filesystem: filesystem,
+	}
+}
+
+// WithReport configures the Report the removed release is added to.
+func (r RemoveRelease) WithReport(report *Report) RemoveRelease {
+	r.report = report
+	return r
+}
+
+func (r RemoveRelease) Execute(args []string) error {
+	_, err := flags.LoadFlagsWithDefaults(&r.Options, args, r.filesystem.Stat)
+	if err != nil {
+		return err
+	}
+
+	kilnfile, kilnfileLock, err := r.Options.Standard.LoadKilnfiles(r.filesystem, nil)
+	if err != nil {
+		return withErrorCode(ErrorCodeKilnfile, fmt.Errorf("error loading Kilnfiles: %w", err))
+	}
+
+	name := r.Options.Name
+
+	_, specErr := kilnfile.BOSHReleaseTarballSpecification(name)
+	releaseLock, lockErr := kilnfileLock.FindBOSHReleaseWithName(name)
+	if specErr != nil && lockErr != nil {
+		return fmt.Errorf("no release named %q exists in your Kilnfile or Kilnfile.lock", name)
+	}
+
+	if specErr == nil {
+		err = editKilnfileReleases(r.filesystem, r.Options.Kilnfile, func(releases *yaml.Node) error {
+			content := releases.Content[:0]
+			for _, node := range releases.Content {
+				var spec cargo.BOSHReleaseTarballSpecification
+				if err := node.Decode(&spec); err == nil && spec.Name == name {
+					continue
+				}
+				content = append(content, node)
+			}
+			releases.Content = content
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if lockErr == nil {
+		releases := kilnfileLock.Releases[:0]
+		for _, lock := range kilnfileLock.Releases {
+			if lock.Name != name {
+				releases = append(releases, lock)
+			}
+		}
+		kilnfileLock.Releases = releases
+		if err := r.Options.Standard.SaveKilnfileLock(r.filesystem, kilnfileLock); err != nil {
+			return err
+		}
+		r.report.AddChange(ReportChange{Kind: "release", Name: name, From: releaseLock.Version})
+
+		if err := r.removeTarballs(releaseLock); err != nil {
+			return err
+		}
+	}
+
+	if r.Options.MetadataPath != "" {
+		err = editFile(r.filesystem, r.Options.MetadataPath, func(metadata []byte) ([]byte, error) {
+			return removeMetadataReleaseExpression(metadata, name), nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	r.logger.Printf("Removed %s. DON'T FORGET TO MAKE A COMMIT AND PR\n", name)
+	return nil
+}
+
+// removeTarballs deletes downloaded tarballs of the locked release version from the releases directory.
+// Compiled release tarballs include the stemcell in their name so the version is matched as a prefix.
+func (r RemoveRelease) removeTarballs(lock cargo.BOSHReleaseTarballLock) error {
+	if r.Options.ReleasesDir == "" {
+		// the default is cleared when the directory does not exist
+		return nil
+	}
+	files, err := r.filesystem.ReadDir(r.Options.ReleasesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	prefix := lock.Name + "-" + lock.Version
+	for _, file := range files {
+		fileName := file.Name()
+		if file.IsDir() || !strings.HasSuffix(fileName, ".tgz") {
+			continue
+		}
+		if fileName != prefix+".tgz" && !strings.HasPrefix(fileName, prefix+"-") {
+			continue
+		}
+		if err := r.filesystem.Remove(filepath.Join(r.Options.ReleasesDir, fileName)); err != nil {
+			return err
+		}
+		r.logger.Printf("Deleted %s\n", filepath.Join(r.Options.ReleasesDir, fileName))
+	}
+	return nil
+}
+
+func (r RemoveRelease) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Removes a BOSH release from the Kilnfile and Kilnfile.lock and deletes its downloaded tarball from the releases directory. With --metadata, the release template expression for the release is removed from the releases list of a metadata file.",
+		ShortDescription: "removes a release from the Kilnfile and Kilnfile.lock",
+		Flags:            r.Options,
+	}
+}
+
+// removeMetadataReleaseExpression removes the lines with a release template expression for the named release.
+func removeMetadataReleaseExpression(metadata []byte, name string) []byte {
+	expression := regexp.MustCompile(`^\s*-\s*\$\(\s*release\s+"` + regexp.QuoteMeta(name) + `"\s*\)\s*$`)
+	lines := strings.Split(string(metadata), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !expression.MatchString(line) {
+			kept = append(kept, line)
+		}
+	}
+	return []byte(strings.Join(kept, "\n"))
+}
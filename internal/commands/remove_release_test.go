@@ -0,0 +1,95 @@
+package commands_test
+
+import (
+	"io"
+	"log"
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+func TestRemoveRelease_Execute(t *testing.T) {
+	setup := func(t *testing.T) billy.Filesystem {
+		t.Helper()
+		fs := memfs.New()
+		if err := util.WriteFile(fs, "Kilnfile", []byte(`---
+release_sources:
+  - type: bosh.io
+releases:
+  - name: apple
+  # bananas are compiled
+  - name: banana
+    version: ~1.2
+`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := fsWriteYAML(fs, "Kilnfile.lock", cargo.KilnfileLock{
+			Stemcell: cargo.Stemcell{OS: "alpine", Version: "1.1"},
+			Releases: []cargo.BOSHReleaseTarballLock{
+				{Name: "apple", Version: "1.0.0", RemoteSource: "bosh.io"},
+				{Name: "banana", Version: "1.2.3", RemoteSource: "bosh.io"},
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"apple-1.0.0.tgz", "banana-1.2.3.tgz", "banana-1.2.3-alpine-1.1.tgz", "banana-1.2.30.tgz"} {
+			if err := util.WriteFile(fs, "releases/"+name, nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return fs
+	}
+
+	t.Run("it removes the release", func(t *testing.T) {
+		please := NewWithT(t)
+		fs := setup(t)
+		please.Expect(util.WriteFile(fs, "base.yml", []byte(`---
+releases:
+  - $( release "apple" )
+  - $( release "banana" )
+`), 0o644)).To(Succeed())
+
+		report := commands.NewReport("remove-release")
+		err := commands.NewRemoveRelease(log.New(io.Discard, "", 0), fs).WithReport(report).Execute([]string{"--name", "banana", "--metadata", "base.yml"})
+		please.Expect(err).NotTo(HaveOccurred())
+
+		var kilnfile cargo.Kilnfile
+		please.Expect(fsReadYAML(fs, "Kilnfile", &kilnfile)).To(Succeed())
+		please.Expect(kilnfile.Releases).To(Equal([]cargo.BOSHReleaseTarballSpecification{{Name: "apple"}}))
+
+		var lock cargo.KilnfileLock
+		please.Expect(fsReadYAML(fs, "Kilnfile.lock", &lock)).To(Succeed())
+		please.Expect(lock.Releases).To(Equal([]cargo.BOSHReleaseTarballLock{{Name: "apple", Version: "1.0.0", RemoteSource: "bosh.io"}}))
+
+		files, err := fs.ReadDir("releases")
+		please.Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, file := range files {
+			names = append(names, file.Name())
+		}
+		please.Expect(names).To(ConsistOf("apple-1.0.0.tgz", "banana-1.2.30.tgz"))
+
+		metadata, err := util.ReadFile(fs, "base.yml")
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(string(metadata)).To(Equal(`---
+releases:
+  - $( release "apple" )
+`))
+
+		please.Expect(report.Changes).To(Equal([]commands.ReportChange{{Kind: "release", Name: "banana", From: "1.2.3"}}))
+	})
+
+	t.Run("when the release does not exist", func(t *testing.T) {
+		please := NewWithT(t)
+		fs := setup(t)
+
+		err := commands.NewRemoveRelease(log.New(io.Discard, "", 0), fs).Execute([]string{"--name", "cherry"})
+		please.Expect(err).To(MatchError(`no release named "cherry" exists in your Kilnfile or Kilnfile.lock`))
+	})
+}
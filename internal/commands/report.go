@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// Error codes set in the errors of a Report. Errors without a more specific code
+// have ErrorCodeUnknown.
+const (
+	ErrorCodeUnknown            = "error"
+	ErrorCodeNotFound           = "not_found"
+	ErrorCodeKilnfile           = "kilnfile"
+	ErrorCodeDownloadFailed     = "download_failed"
+	ErrorCodeChecksumMismatch   = "checksum_mismatch"
+	ErrorCodeVerificationFailed = "verification_failed"
+	ErrorCodeStemcellMismatch   = "stemcell_mismatch"
+	ErrorCodeValidationFailed   = "validation_failed"
+	ErrorCodePublishFailed      = "publish_failed"
+)
+
+// Report is the machine-readable result document written when kiln is run with
+// "--output json". Commands add what they changed and downloaded while they run.
+// All methods may be called on a nil Report; they do nothing.
+type Report struct {
+	Command   string           `json:"command"`
+	Success   bool             `json:"success"`
+	Changes   []ReportChange   `json:"changes,omitempty"`
+	Downloads []ReportDownload `json:"downloads,omitempty"`
+	Result    interface{}      `json:"result,omitempty"`
+	Errors    []ReportError    `json:"errors,omitempty"`
+	mu        sync.Mutex
+}
+
+// ReportChange is a change to the Kilnfile.lock or to a published product.
+type ReportChange struct {
+	// Kind is "release", "stemcell", or "product".
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+
+	RemoteSource string `json:"remote_source,omitempty"`
+	RemotePath   string `json:"remote_path,omitempty"`
+}
+
+type ReportDownload struct {
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	SHA1         string `json:"sha1,omitempty"`
+	SHA256       string `json:"sha256,omitempty"`
+	RemoteSource string `json:"remote_source,omitempty"`
+	LocalPath    string `json:"local_path"`
+}
+
+type ReportError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func NewReport(command string) *Report {
+	return &Report{Command: command}
+}
+
+func (r *Report) AddChange(change ReportChange) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Changes = append(r.Changes, change)
+}
+
+// AddReleaseChange records a release lock that was changed from previous to next.
+func (r *Report) AddReleaseChange(previous, next cargo.BOSHReleaseTarballLock) {
+	r.AddChange(ReportChange{
+		Kind:         "release",
+		Name:         next.Name,
+		From:         previous.Version,
+		To:           next.Version,
+		RemoteSource: next.RemoteSource,
+		RemotePath:   next.RemotePath,
+	})
+}
+
+func (r *Report) AddDownload(local component.Local) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Downloads = append(r.Downloads, ReportDownload{
+		Name:         local.Lock.Name,
+		Version:      local.Lock.Version,
+		SHA1:         local.Lock.SHA1,
+		SHA256:       local.Lock.SHA256,
+		RemoteSource: local.Lock.RemoteSource,
+		LocalPath:    local.LocalPath,
+	})
+}
+
+// SetResult sets the command specific part of the document, for example the
+// version found by find-release-version.
+func (r *Report) SetResult(result interface{}) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Result = result
+}
+
+// Finish records the error returned by the command. Each error in a list of errors
+// (like those returned by validate) is recorded separately.
+func (r *Report) Finish(err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Success = err == nil
+	if err == nil {
+		return
+	}
+	var list errorList
+	if errors.As(err, &list) {
+		for _, e := range list {
+			r.Errors = append(r.Errors, ReportError{Code: ErrorCode(e), Message: e.Error()})
+		}
+		return
+	}
+	r.Errors = append(r.Errors, ReportError{Code: ErrorCode(err), Message: err.Error()})
+}
+
+func (r *Report) Write(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// ErrorCode returns the code of the first error in the chain that has one.
+func ErrorCode(err error) string {
+	var coded codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	if errors.Is(err, component.ErrNotFound) {
+		return ErrorCodeNotFound
+	}
+	return ErrorCodeUnknown
+}
+
+type codedError struct {
+	code string
+	err  error
+}
+
+// withErrorCode sets the code of err in a Report. Errors checked with
+// component.IsErrNotFound must not be wrapped.
+func withErrorCode(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return codedError{code: code, err: err}
+}
+
+func (err codedError) Error() string { return err.err.Error() }
+
+func (err codedError) Unwrap() error { return err.err }
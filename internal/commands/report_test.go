@@ -0,0 +1,145 @@
+package commands_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	"github.com/pivotal-cf/kiln/internal/commands/fakes"
+	"github.com/pivotal-cf/kiln/internal/component"
+	componentFakes "github.com/pivotal-cf/kiln/internal/component/fakes"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+func TestReport_nil(t *testing.T) {
+	please := NewWithT(t)
+
+	var report *commands.Report
+
+	please.Expect(func() {
+		report.AddChange(commands.ReportChange{Kind: "release", Name: "banana"})
+		report.AddDownload(component.Local{})
+		report.SetResult("result")
+		report.Finish(errors.New("banana"))
+	}).NotTo(Panic())
+}
+
+func TestReport_Finish(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		please := NewWithT(t)
+
+		report := commands.NewReport("fetch")
+		report.AddDownload(component.Local{
+			Lock:      cargo.BOSHReleaseTarballLock{Name: "banana", Version: "1.2.3", SHA1: "some-sha1"},
+			LocalPath: "releases/banana-1.2.3.tgz",
+		})
+		report.Finish(nil)
+
+		var buf bytes.Buffer
+		please.Expect(report.Write(&buf)).To(Succeed())
+		please.Expect(buf.String()).To(MatchJSON(`{
+			"command": "fetch",
+			"success": true,
+			"downloads": [
+				{"name": "banana", "version": "1.2.3", "sha1": "some-sha1", "local_path": "releases/banana-1.2.3.tgz"}
+			]
+		}`))
+	})
+
+	t.Run("validation errors", func(t *testing.T) {
+		please := NewWithT(t)
+
+		fs := memfs.New()
+		please.Expect(fsWriteYAML(fs, "Kilnfile", cargo.Kilnfile{})).To(Succeed())
+		please.Expect(fsWriteYAML(fs, "Kilnfile.lock", cargo.KilnfileLock{
+			Releases: []cargo.BOSHReleaseTarballLock{
+				{Name: "banana", Version: "1.2.3"},
+				{Name: "lemon", Version: "1.2.3"},
+			},
+		})).To(Succeed())
+
+		report := commands.NewReport("validate")
+		report.Finish(commands.NewValidate(fs).Execute(nil))
+
+		please.Expect(report.Success).To(BeFalse())
+		please.Expect(report.Errors).To(HaveLen(2))
+		for _, reportError := range report.Errors {
+			please.Expect(reportError.Code).To(Equal(commands.ErrorCodeValidationFailed))
+		}
+	})
+
+	t.Run("unknown errors", func(t *testing.T) {
+		please := NewWithT(t)
+
+		report := commands.NewReport("publish")
+		report.Finish(errors.New("banana"))
+
+		please.Expect(report.Errors).To(Equal([]commands.ReportError{
+			{Code: commands.ErrorCodeUnknown, Message: "banana"},
+		}))
+	})
+}
+
+func TestErrorCode(t *testing.T) {
+	please := NewWithT(t)
+
+	please.Expect(commands.ErrorCode(fmt.Errorf("finding banana: %w", component.ErrNotFound))).To(Equal(commands.ErrorCodeNotFound))
+	please.Expect(commands.ErrorCode(errors.New("banana"))).To(Equal(commands.ErrorCodeUnknown))
+}
+
+func TestUpdateStemcell_report(t *testing.T) {
+	please := NewWithT(t)
+
+	fs := memfs.New()
+	please.Expect(fsWriteYAML(fs, "Kilnfile", cargo.Kilnfile{
+		Stemcell: cargo.Stemcell{OS: "alpine", Version: "^1"},
+		Releases: []cargo.BOSHReleaseTarballSpecification{{Name: "banana"}},
+	})).To(Succeed())
+	please.Expect(fsWriteYAML(fs, "Kilnfile.lock", cargo.KilnfileLock{
+		Stemcell: cargo.Stemcell{OS: "alpine", Version: "1.1"},
+		Releases: []cargo.BOSHReleaseTarballLock{
+			{Name: "banana", Version: "1.2.3", RemoteSource: "some-source", RemotePath: "banana-1.2.3-alpine-1.1.tgz"},
+		},
+	})).To(Succeed())
+
+	releaseSource := new(componentFakes.MultiReleaseSource)
+	releaseSource.GetMatchedReleaseReturns(cargo.BOSHReleaseTarballLock{
+		Name: "banana", Version: "1.2.3", RemoteSource: "some-source", RemotePath: "banana-1.2.3-alpine-1.2.tgz",
+	}, nil)
+	releaseSource.DownloadReleaseReturns(component.Local{
+		Lock:      cargo.BOSHReleaseTarballLock{Name: "banana", Version: "1.2.3", SHA1: "some-sha1"},
+		LocalPath: "releases/banana-1.2.3.tgz",
+	}, nil)
+	provider := new(fakes.MultiReleaseSourceProvider)
+	provider.Returns(releaseSource)
+
+	report := commands.NewReport("update-stemcell")
+	err := commands.UpdateStemcell{
+		FS:                         fs,
+		MultiReleaseSourceProvider: provider.Spy,
+		Logger:                     log.New(io.Discard, "", 0),
+	}.WithReport(report).Execute([]string{"--version", "1.2", "--releases-directory", "releases"})
+	please.Expect(err).NotTo(HaveOccurred())
+
+	please.Expect(report.Changes).To(Equal([]commands.ReportChange{
+		{Kind: "release", Name: "banana", From: "1.2.3", To: "1.2.3", RemoteSource: "some-source", RemotePath: "banana-1.2.3-alpine-1.2.tgz"},
+		{Kind: "stemcell", Name: "alpine", From: "1.1", To: "1.2"},
+	}))
+	please.Expect(report.Downloads).To(Equal([]commands.ReportDownload{
+		{Name: "banana", Version: "1.2.3", SHA1: "some-sha1", LocalPath: "releases/banana-1.2.3.tgz"},
+	}))
+
+	var buf bytes.Buffer
+	please.Expect(report.Write(&buf)).To(Succeed())
+	var document map[string]interface{}
+	please.Expect(json.Unmarshal(buf.Bytes(), &document)).To(Succeed())
+	please.Expect(document).To(HaveKeyWithValue("command", "update-stemcell"))
+}
@@ -64,11 +64,20 @@ func (command SyncWithLocal) Execute(args []string) error {
 	command.logger.Printf("Found %d releases on disk\n", len(releases))
 
 	for _, rel := range releases {
+		spec, err := kilnfile.BOSHReleaseTarballSpecification(rel.Lock.Name)
+		if err != nil {
+			spec = cargo.BOSHReleaseTarballSpecification{Name: rel.Lock.Name}
+		}
+		stemcell, err := kilnfileLock.StemcellForRelease(spec)
+		if err != nil {
+			return err
+		}
+
 		remotePath, err := remotePather.RemotePath(cargo.BOSHReleaseTarballSpecification{
 			Name:            rel.Lock.Name,
 			Version:         rel.Lock.Version,
-			StemcellOS:      kilnfileLock.Stemcell.OS,
-			StemcellVersion: kilnfileLock.Stemcell.Version,
+			StemcellOS:      stemcell.OS,
+			StemcellVersion: stemcell.Version,
 		})
 		if err != nil {
 			return fmt.Errorf("couldn't generate a remote path for release %q: %w", rel.Lock.Name, err)
@@ -92,6 +101,7 @@ func (command SyncWithLocal) Execute(args []string) error {
 
 		matchingRelease.Version = rel.Lock.Version
 		matchingRelease.SHA1 = rel.Lock.SHA1
+		matchingRelease.SHA256 = rel.Lock.SHA256
 		matchingRelease.RemoteSource = command.Options.ReleaseSourceID
 		matchingRelease.RemotePath = remotePath
 
@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 
@@ -16,15 +18,18 @@ type UpdateRelease struct {
 	Options struct {
 		flags.Standard
 
-		Name                         string `short:"n" long:"name" required:"true" description:"name of release to update"`
-		Version                      string `short:"v" long:"version" required:"true" description:"desired version of release"`
-		ReleasesDir                  string `short:"rd" long:"releases-directory" default:"releases" description:"path to a directory to download releases into"`
-		AllowOnlyPublishableReleases bool   `long:"allow-only-publishable-releases" description:"include releases that would not be shipped with the tile (development builds)"`
-		WithoutDownload              bool   `long:"without-download" description:"updates releases without downloading them"`
+		Names                        []string `short:"n" long:"name" description:"name of release to update (may be repeated)"`
+		All                          bool     `long:"all" description:"update every release in the Kilnfile"`
+		Version                      string   `short:"v" long:"version" description:"desired version of release; without it releases are updated to the newest version allowed by the Kilnfile"`
+		ReleasesDir                  string   `short:"rd" long:"releases-directory" default:"releases" description:"path to a directory to download releases into"`
+		AllowOnlyPublishableReleases bool     `long:"allow-only-publishable-releases" description:"include releases that would not be shipped with the tile (development builds)"`
+		WithoutDownload              bool     `long:"without-download" description:"updates releases without downloading them"`
+		DryRun                       bool     `long:"dry-run" description:"print the version bumps without downloading releases or writing the Kilnfile.lock"`
 	}
 	multiReleaseSourceProvider MultiReleaseSourceProvider
 	filesystem                 billy.Filesystem
 	logger                     *log.Logger
+	report                     *Report
 }
 
 func NewUpdateRelease(logger *log.Logger, filesystem billy.Filesystem, multiReleaseSourceProvider MultiReleaseSourceProvider) UpdateRelease {
@@ -35,68 +40,90 @@ func NewUpdateRelease(logger *log.Logger, filesystem billy.Filesystem, multiRele
 	}
 }
 
+// WithReport configures the Report updated and downloaded releases are added to.
+func (u UpdateRelease) WithReport(report *Report) UpdateRelease {
+	u.report = report
+	return u
+}
+
 func (u UpdateRelease) Execute(args []string) error {
 	_, err := flags.LoadFlagsWithDefaults(&u.Options, args, u.filesystem.Stat)
 	if err != nil {
 		return err
 	}
 
+	switch {
+	case u.Options.All && len(u.Options.Names) > 0:
+		return errors.New("--all and --name can not be used together")
+	case !u.Options.All && len(u.Options.Names) == 0:
+		return errors.New("missing required flag \"--name\" (or \"--all\")")
+	case u.Options.Version != "" && (u.Options.All || len(u.Options.Names) > 1):
+		return errors.New("--version can only be used when updating a single release")
+	}
+
 	kilnfile, kilnfileLock, err := u.Options.Standard.LoadKilnfiles(u.filesystem, nil)
 	if err != nil {
-		return fmt.Errorf("error loading Kilnfiles: %w", err)
+		return withErrorCode(ErrorCodeKilnfile, fmt.Errorf("error loading Kilnfiles: %w", err))
 	}
 
-	releaseLock, err := kilnfileLock.FindBOSHReleaseWithName(u.Options.Name)
-	if err != nil {
-		return fmt.Errorf(
-			"no release named %q exists in your Kilnfile.lock - try removing the -release, -boshrelease, or -bosh-release suffix if present",
-			u.Options.Name,
-		)
+	releaseSource := u.multiReleaseSourceProvider(kilnfile, u.Options.AllowOnlyPublishableReleases)
+
+	if u.Options.Version == "" {
+		return u.updateToAllowedVersions(releaseSource, kilnfile, kilnfileLock)
 	}
-	releaseSpec, err := kilnfile.BOSHReleaseTarballSpecification(u.Options.Name)
+
+	name := u.Options.Names[0]
+
+	releaseLock, releaseSpec, err := findReleaseToUpdate(kilnfile, kilnfileLock, name)
 	if err != nil {
 		return err
 	}
 
-	releaseVersionConstraint := releaseSpec.Version
-	if u.Options.Version != "" {
-		releaseVersionConstraint = u.Options.Version
+	stemcell, err := kilnfileLock.StemcellForRelease(releaseSpec)
+	if err != nil {
+		return err
 	}
 
-	releaseSource := u.multiReleaseSourceProvider(kilnfile, u.Options.AllowOnlyPublishableReleases)
-
 	u.logger.Println("Searching for the release...")
 
 	var localRelease component.Local
 	var remoteRelease cargo.BOSHReleaseTarballLock
-	var newVersion, newSHA1, newSourceID, newRemotePath string
+	var signatures component.SignatureReader
+	var publicKey string
+	var newVersion, newSHA1, newSHA256, newSourceID, newRemotePath string
 	if u.Options.WithoutDownload {
 		remoteRelease, err = releaseSource.FindReleaseVersion(cargo.BOSHReleaseTarballSpecification{
-			Name:             u.Options.Name,
-			Version:          releaseVersionConstraint,
-			StemcellVersion:  kilnfileLock.Stemcell.Version,
-			StemcellOS:       kilnfileLock.Stemcell.OS,
+			Name:             name,
+			Version:          u.Options.Version,
+			StemcellVersion:  stemcell.Version,
+			StemcellOS:       stemcell.OS,
 			GitHubRepository: releaseSpec.GitHubRepository,
-		}, false)
+		}, u.Options.DryRun)
 
 		if err != nil {
 			if component.IsErrNotFound(err) {
 				return fmt.Errorf("error finding the release: %w", err)
 			}
-			return fmt.Errorf("couldn't find %q %s in any release source", u.Options.Name, u.Options.Version)
+			return fmt.Errorf("couldn't find %q %s in any release source", name, u.Options.Version)
+		}
+
+		signatures, publicKey, err = releaseSignatureReader(releaseSource, remoteRelease.RemoteSource)
+		if err != nil {
+			return err
 		}
 
 		newVersion = remoteRelease.Version
 		newSHA1 = remoteRelease.SHA1
+		newSHA256 = remoteRelease.SHA256
 		newSourceID = remoteRelease.RemoteSource
 		newRemotePath = remoteRelease.RemotePath
 
 	} else {
 		remoteRelease, err = releaseSource.GetMatchedRelease(cargo.BOSHReleaseTarballSpecification{
-			Name:             u.Options.Name,
+			Name:             name,
 			Version:          u.Options.Version,
-			StemcellOS:       kilnfileLock.Stemcell.OS,
-			StemcellVersion:  kilnfileLock.Stemcell.Version,
+			StemcellOS:       stemcell.OS,
+			StemcellVersion:  stemcell.Version,
 			GitHubRepository: releaseSpec.GitHubRepository,
 		})
 
@@ -104,15 +131,28 @@ func (u UpdateRelease) Execute(args []string) error {
 			if component.IsErrNotFound(err) {
 				return fmt.Errorf("error finding the release: %w", err)
 			}
-			return fmt.Errorf("couldn't find %q %s in any release source", u.Options.Name, u.Options.Version)
+			return fmt.Errorf("couldn't find %q %s in any release source", name, u.Options.Version)
+		}
+
+		if u.Options.DryRun {
+			u.printBumps(cargo.CalculateBumps([]cargo.BOSHReleaseTarballLock{remoteRelease}, []cargo.BOSHReleaseTarballLock{releaseLock}))
+			u.report.AddReleaseChange(releaseLock, remoteRelease)
+			return nil
+		}
+
+		signatures, publicKey, err = releaseSignatureReader(releaseSource, remoteRelease.RemoteSource)
+		if err != nil {
+			return err
 		}
 
 		localRelease, err = releaseSource.DownloadRelease(u.Options.ReleasesDir, remoteRelease)
 		if err != nil {
-			return fmt.Errorf("error downloading the release: %w", err)
+			return withErrorCode(ErrorCodeDownloadFailed, fmt.Errorf("error downloading the release: %w", err))
 		}
+		u.report.AddDownload(localRelease)
 		newVersion = localRelease.Lock.Version
 		newSHA1 = localRelease.Lock.SHA1
+		newSHA256 = localRelease.Lock.SHA256
 		newSourceID = remoteRelease.RemoteSource
 		newRemotePath = remoteRelease.RemotePath
 	}
@@ -122,25 +162,231 @@ func (u UpdateRelease) Execute(args []string) error {
 		return nil
 	}
 
-	releaseLock.Version = newVersion
-	releaseLock.SHA1 = newSHA1
-	releaseLock.RemoteSource = newSourceID
-	releaseLock.RemotePath = newRemotePath
+	updatedLock := releaseLock
+	updatedLock.Version = newVersion
+	updatedLock.SHA1 = newSHA1
+	updatedLock.SHA256 = newSHA256
+	updatedLock.RemoteSource = newSourceID
+	updatedLock.RemotePath = newRemotePath
+	updatedLock.Signature = ""
+
+	if u.Options.DryRun {
+		u.printBumps(cargo.CalculateBumps([]cargo.BOSHReleaseTarballLock{updatedLock}, []cargo.BOSHReleaseTarballLock{releaseLock}))
+		u.report.AddReleaseChange(releaseLock, updatedLock)
+		return nil
+	}
+
+	updatedLock.Signature, err = releaseSignature(signatures, publicKey, updatedLock)
+	if err != nil {
+		return err
+	}
+	updatedLock.Provenance = releaseProvenance(u.logger, releaseSource, updatedLock)
 
-	_ = kilnfileLock.UpdateBOSHReleaseTarballLockWithName(u.Options.Name, releaseLock)
+	_ = kilnfileLock.UpdateBOSHReleaseTarballLockWithName(name, updatedLock)
 
 	err = u.Options.Standard.SaveKilnfileLock(u.filesystem, kilnfileLock)
 	if err != nil {
 		return err
 	}
+	u.report.AddReleaseChange(releaseLock, updatedLock)
 
-	u.logger.Printf("Updated %s to %s. DON'T FORGET TO MAKE A COMMIT AND PR\n", u.Options.Name, u.Options.Version)
+	u.logger.Printf("Updated %s to %s. DON'T FORGET TO MAKE A COMMIT AND PR\n", name, u.Options.Version)
 	return nil
 }
 
+// updateToAllowedVersions updates each named release (or every release with --all) to the
+// newest version satisfying the version constraint in the Kilnfile. The Kilnfile.lock is
+// only written once every release has been resolved.
+func (u UpdateRelease) updateToAllowedVersions(releaseSource component.MultiReleaseSource, kilnfile cargo.Kilnfile, kilnfileLock cargo.KilnfileLock) error {
+	names := u.Options.Names
+	if u.Options.All {
+		names = nil
+		for _, spec := range kilnfile.Releases {
+			names = append(names, spec.Name)
+		}
+	}
+
+	previous := make([]cargo.BOSHReleaseTarballLock, len(kilnfileLock.Releases))
+	copy(previous, kilnfileLock.Releases)
+
+	u.logger.Println("Searching for releases...")
+
+	changed := false
+	for _, name := range names {
+		releaseLock, releaseSpec, err := findReleaseToUpdate(kilnfile, kilnfileLock, name)
+		if err != nil {
+			return err
+		}
+		stemcell, err := kilnfileLock.StemcellForRelease(releaseSpec)
+		if err != nil {
+			return err
+		}
+		releaseSpec.StemcellOS = stemcell.OS
+		releaseSpec.StemcellVersion = stemcell.Version
+
+		// the SHA1 sum is only needed from the release source when the release will not be downloaded
+		noDownload := u.Options.DryRun || !u.Options.WithoutDownload
+		remoteRelease, err := releaseSource.FindReleaseVersion(releaseSpec, noDownload)
+		if err != nil {
+			if component.IsErrNotFound(err) {
+				return fmt.Errorf("couldn't find %q %s in any release source", name, releaseSpec.Version)
+			}
+			return fmt.Errorf("error finding the release %q: %w", name, err)
+		}
+
+		if remoteRelease.Version == releaseLock.Version && remoteRelease.RemoteSource == releaseLock.RemoteSource && remoteRelease.RemotePath == releaseLock.RemotePath {
+			continue
+		}
+
+		updatedLock := releaseLock
+		updatedLock.Version = remoteRelease.Version
+		updatedLock.SHA1 = remoteRelease.SHA1
+		updatedLock.SHA256 = remoteRelease.SHA256
+		updatedLock.RemoteSource = remoteRelease.RemoteSource
+		updatedLock.RemotePath = remoteRelease.RemotePath
+		updatedLock.Signature = ""
+
+		signatures, publicKey, err := releaseSignatureReader(releaseSource, remoteRelease.RemoteSource)
+		if err != nil {
+			return err
+		}
+
+		if !u.Options.DryRun && !u.Options.WithoutDownload {
+			localRelease, err := releaseSource.DownloadRelease(u.Options.ReleasesDir, remoteRelease)
+			if err != nil {
+				return withErrorCode(ErrorCodeDownloadFailed, fmt.Errorf("error downloading the release %q: %w", name, err))
+			}
+			u.report.AddDownload(localRelease)
+			updatedLock.SHA1 = localRelease.Lock.SHA1
+			updatedLock.SHA256 = localRelease.Lock.SHA256
+		}
+		if !u.Options.DryRun {
+			updatedLock.Signature, err = releaseSignature(signatures, publicKey, updatedLock)
+			if err != nil {
+				return err
+			}
+			updatedLock.Provenance = releaseProvenance(u.logger, releaseSource, updatedLock)
+		}
+
+		_ = kilnfileLock.UpdateBOSHReleaseTarballLockWithName(name, updatedLock)
+		u.report.AddReleaseChange(releaseLock, updatedLock)
+		changed = true
+	}
+
+	bumps := cargo.CalculateBumps(kilnfileLock.Releases, previous)
+	u.printBumps(bumps)
+
+	if !changed || u.Options.DryRun {
+		return nil
+	}
+
+	err := u.Options.Standard.SaveKilnfileLock(u.filesystem, kilnfileLock)
+	if err != nil {
+		return err
+	}
+
+	u.logger.Println("DON'T FORGET TO MAKE A COMMIT AND PR")
+	return nil
+}
+
+// releaseProvenance returns the provenance reference for the release when its source supports it.
+func releaseProvenance(logger *log.Logger, releaseSource component.MultiReleaseSource, lock cargo.BOSHReleaseTarballLock) string {
+	src, err := releaseSource.FindByID(lock.RemoteSource)
+	if err != nil {
+		return ""
+	}
+	reporter, ok := src.(component.ProvenanceReporter)
+	if !ok {
+		return ""
+	}
+	provenance, err := reporter.Provenance(lock)
+	if err != nil {
+		logger.Printf("Warning: failed to get the provenance of %s %s: %s", lock.Name, lock.Version, err)
+		return ""
+	}
+	return provenance
+}
+
+// releaseSignatureReader returns the SignatureReader and signature_public_key of the release
+// source with the ID when the source has a signature_public_key. It returns an error when the
+// source can not provide signatures, so releases are not locked without one.
+func releaseSignatureReader(releaseSource component.MultiReleaseSource, sourceID string) (component.SignatureReader, string, error) {
+	src, err := releaseSource.FindByID(sourceID)
+	if err != nil || src == nil {
+		return nil, "", nil
+	}
+	config := src.Configuration()
+	if config.SignaturePublicKey == "" {
+		return nil, "", nil
+	}
+	reader, ok := src.(component.SignatureReader)
+	if !ok {
+		return nil, "", fmt.Errorf("release source %q has a signature_public_key but signatures can not be read from %s release sources", sourceID, config.Type)
+	}
+	return reader, config.SignaturePublicKey, nil
+}
+
+// releaseSignature reads the detached signature of the release and checks it against the
+// SHA256 sum in the lock. It returns an empty signature when reader is nil and an error when
+// the lock has no SHA256 sum, so an unverified signature is never recorded.
+func releaseSignature(reader component.SignatureReader, publicKey string, lock cargo.BOSHReleaseTarballLock) (string, error) {
+	if reader == nil {
+		return "", nil
+	}
+	signature, err := reader.ReleaseSignature(lock)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the signature of %s %s from %s: %w", lock.Name, lock.Version, lock.RemoteSource, err)
+	}
+	if lock.SHA256 == "" {
+		return "", fmt.Errorf("can not verify the signature of %s %s from %s without its SHA256 sum; update the release without --without-download", lock.Name, lock.Version, lock.RemoteSource)
+	}
+	key, err := component.ParseSignaturePublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("release source %q: %w", lock.RemoteSource, err)
+	}
+	digest, err := hex.DecodeString(lock.SHA256)
+	if err != nil {
+		return "", fmt.Errorf("invalid SHA256 sum for %s: %w", lock.Name, err)
+	}
+	err = component.VerifyReleaseSignature(key, digest, signature)
+	if err != nil {
+		return "", fmt.Errorf("signature of %s %s from %s: %w", lock.Name, lock.Version, lock.RemoteSource, err)
+	}
+	return signature, nil
+}
+
+func findReleaseToUpdate(kilnfile cargo.Kilnfile, kilnfileLock cargo.KilnfileLock, name string) (cargo.BOSHReleaseTarballLock, cargo.BOSHReleaseTarballSpecification, error) {
+	releaseLock, err := kilnfileLock.FindBOSHReleaseWithName(name)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, cargo.BOSHReleaseTarballSpecification{}, fmt.Errorf(
+			"no release named %q exists in your Kilnfile.lock - try removing the -release, -boshrelease, or -bosh-release suffix if present",
+			name,
+		)
+	}
+	releaseSpec, err := kilnfile.BOSHReleaseTarballSpecification(name)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, cargo.BOSHReleaseTarballSpecification{}, err
+	}
+	return releaseLock, releaseSpec, nil
+}
+
+func (u UpdateRelease) printBumps(bumps []cargo.Bump) {
+	prefix := "Updated"
+	if u.Options.DryRun {
+		prefix = "Would update"
+	}
+	if len(bumps) == 0 {
+		u.logger.Println("No release versions changed.")
+		return
+	}
+	for _, bump := range bumps {
+		u.logger.Printf("%s %s from %s to %s\n", prefix, bump.Name, bump.FromVersion, bump.ToVersion)
+	}
+}
+
 func (u UpdateRelease) Usage() jhanda.Usage {
 	return jhanda.Usage{
-		Description:      "Bumps a release to a new version in Kilnfile.lock",
+		Description:      "Bumps releases to new versions in Kilnfile.lock. With --version, a single release is updated to exactly that version. Otherwise each release named with --name (or every release with --all) is updated to the newest version allowed by its Kilnfile version constraint.",
 		ShortDescription: "bumps a release to a new version",
 		Flags:            u.Options,
 	}
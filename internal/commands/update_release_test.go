@@ -1,6 +1,13 @@
 package commands_test
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"log"
@@ -184,6 +191,32 @@ var _ = Describe("UpdateRelease", func() {
 				))
 			})
 
+			It("adds the change and the download to the report", func() {
+				report := commands.NewReport("update-release")
+				err := updateReleaseCommand.WithReport(report).Execute([]string{
+					"--kilnfile", "Kilnfile",
+					"--name", releaseName,
+					"--version", newReleaseVersion,
+					"--releases-directory", releasesDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(report.Changes).To(Equal([]commands.ReportChange{{
+					Kind:         "release",
+					Name:         releaseName,
+					From:         oldReleaseVersion,
+					To:           newReleaseVersion,
+					RemoteSource: newReleaseSourceName,
+					RemotePath:   newRemotePath,
+				}}))
+				Expect(report.Downloads).To(Equal([]commands.ReportDownload{{
+					Name:      releaseName,
+					Version:   newReleaseVersion,
+					SHA1:      newReleaseSha1,
+					LocalPath: downloadedReleasePath,
+				}}))
+			})
+
 			It("considers all release sources", func() {
 				err := updateReleaseCommand.Execute([]string{
 					"--kilnfile", "Kilnfile",
@@ -417,6 +450,335 @@ var _ = Describe("UpdateRelease", func() {
 					},
 				))
 			})
+
+			It("writes the SHA256 sum from the release source to the Kilnfile.lock", func() {
+				releaseSource.FindReleaseVersionReturns(cargo.BOSHReleaseTarballLock{
+					Name:         releaseName,
+					Version:      notDownloadedReleaseVersion,
+					RemotePath:   notDownloadedRemotePath,
+					RemoteSource: notDownloadedReleaseSourceName,
+					SHA1:         notDownloadedReleaseSha1,
+					SHA256:       "some-sha256",
+				}, nil)
+
+				err := updateReleaseCommand.Execute([]string{
+					"--kilnfile", "Kilnfile",
+					"--name", releaseName,
+					"--version", notDownloadedReleaseVersion,
+					"--releases-directory", releasesDir,
+					"--without-download",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				var updatedLockfile cargo.KilnfileLock
+				err = fsReadYAML(filesystem, kilnfileLockPath, &updatedLockfile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(updatedLockfile.Releases[1].SHA256).To(Equal("some-sha256"))
+			})
+		})
+
+		When("the release source has a signature_public_key", func() {
+			var (
+				sourceDir     string
+				privateKey    ed25519.PrivateKey
+				releaseDigest [sha256.Size]byte
+			)
+
+			writeSignature := func(remotePath string, signature []byte) {
+				signaturePath := filepath.Join(sourceDir, filepath.FromSlash(remotePath)+".sig")
+				Expect(os.MkdirAll(filepath.Dir(signaturePath), 0o755)).To(Succeed())
+				Expect(os.WriteFile(signaturePath, []byte(base64.StdEncoding.EncodeToString(signature)), 0o644)).To(Succeed())
+			}
+
+			BeforeEach(func() {
+				var (
+					publicKey ed25519.PublicKey
+					err       error
+				)
+				publicKey, privateKey, err = ed25519.GenerateKey(rand.Reader)
+				Expect(err).NotTo(HaveOccurred())
+				der, err := x509.MarshalPKIXPublicKey(publicKey)
+				Expect(err).NotTo(HaveOccurred())
+
+				sourceDir, err = os.MkdirTemp("", "signed-releases")
+				Expect(err).NotTo(HaveOccurred())
+				releaseSource.FindByIDReturns(component.NewDirectoryReleaseSource(cargo.ReleaseSourceConfig{
+					Type:               component.ReleaseSourceTypeDirectory,
+					ID:                 newReleaseSourceName,
+					Path:               sourceDir,
+					PathTemplate:       "{{.Name}}-{{.Version}}.tgz",
+					SignaturePublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+				}, logger), nil)
+
+				releaseDigest = sha256.Sum256([]byte("some release contents"))
+				expectedDownloadedRelease.Lock.SHA256 = hex.EncodeToString(releaseDigest[:])
+				releaseSource.DownloadReleaseReturns(expectedDownloadedRelease, nil)
+			})
+
+			AfterEach(func() {
+				_ = os.RemoveAll(sourceDir)
+			})
+
+			It("records the signature from the release source", func() {
+				signature := ed25519.Sign(privateKey, releaseDigest[:])
+				writeSignature(newRemotePath, signature)
+
+				err := updateReleaseCommand.Execute([]string{
+					"--kilnfile", "Kilnfile",
+					"--name", releaseName,
+					"--version", newReleaseVersion,
+					"--releases-directory", releasesDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				var updatedLockfile cargo.KilnfileLock
+				Expect(fsReadYAML(filesystem, kilnfileLockPath, &updatedLockfile)).To(Succeed())
+				Expect(updatedLockfile.Releases[1].Signature).To(Equal(base64.StdEncoding.EncodeToString(signature)))
+			})
+
+			It("records the signature when updating to the newest allowed version", func() {
+				signature := ed25519.Sign(privateKey, releaseDigest[:])
+				writeSignature(notDownloadedRemotePath, signature)
+
+				err := updateReleaseCommand.Execute([]string{
+					"--kilnfile", "Kilnfile",
+					"--name", releaseName,
+					"--releases-directory", releasesDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				var updatedLockfile cargo.KilnfileLock
+				Expect(fsReadYAML(filesystem, kilnfileLockPath, &updatedLockfile)).To(Succeed())
+				Expect(updatedLockfile.Releases[1].Signature).To(Equal(base64.StdEncoding.EncodeToString(signature)))
+			})
+
+			It("fails when the signature does not match the release", func() {
+				otherDigest := sha256.Sum256([]byte("some other release"))
+				writeSignature(newRemotePath, ed25519.Sign(privateKey, otherDigest[:]))
+
+				err := updateReleaseCommand.Execute([]string{
+					"--kilnfile", "Kilnfile",
+					"--name", releaseName,
+					"--version", newReleaseVersion,
+					"--releases-directory", releasesDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("signature verification failed")))
+
+				var updatedLockfile cargo.KilnfileLock
+				Expect(fsReadYAML(filesystem, kilnfileLockPath, &updatedLockfile)).To(Succeed())
+				Expect(updatedLockfile).To(Equal(kilnfileLock))
+			})
+
+			It("fails when the release does not have a signature", func() {
+				err := updateReleaseCommand.Execute([]string{
+					"--kilnfile", "Kilnfile",
+					"--name", releaseName,
+					"--version", newReleaseVersion,
+					"--releases-directory", releasesDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to read the signature of capi 1.8.7")))
+			})
+
+			It("fails when the release is not downloaded and the release source has no SHA256 sum", func() {
+				writeSignature(notDownloadedRemotePath, ed25519.Sign(privateKey, releaseDigest[:]))
+
+				err := updateReleaseCommand.Execute([]string{
+					"--kilnfile", "Kilnfile",
+					"--name", releaseName,
+					"--version", newReleaseVersion,
+					"--releases-directory", releasesDir,
+					"--without-download",
+				})
+				Expect(err).To(MatchError(ContainSubstring("can not verify the signature of capi 1.8.4 from compiled-releases without its SHA256 sum")))
+
+				var updatedLockfile cargo.KilnfileLock
+				Expect(fsReadYAML(filesystem, kilnfileLockPath, &updatedLockfile)).To(Succeed())
+				Expect(updatedLockfile).To(Equal(kilnfileLock))
+			})
+
+			It("fails before downloading when the release source can not read signatures", func() {
+				src := new(fetcherFakes.ReleaseSource)
+				src.ConfigurationReturns(cargo.ReleaseSourceConfig{Type: component.ReleaseSourceTypeGithub, SignaturePublicKey: "some-key"})
+				releaseSource.FindByIDReturns(src, nil)
+
+				err := updateReleaseCommand.Execute([]string{
+					"--kilnfile", "Kilnfile",
+					"--name", releaseName,
+					"--version", newReleaseVersion,
+					"--releases-directory", releasesDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("signatures can not be read from github release sources")))
+				Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(0))
+			})
+		})
+
+		When("updating releases to the newest allowed versions", func() {
+			var logBuf *gbytes.Buffer
+
+			BeforeEach(func() {
+				logBuf = gbytes.NewBuffer()
+				logger = log.New(logBuf, "", 0)
+
+				releaseSource.FindReleaseVersionStub = func(spec cargo.BOSHReleaseTarballSpecification, _ bool) (cargo.BOSHReleaseTarballLock, error) {
+					switch spec.Name {
+					case releaseName:
+						return cargo.BOSHReleaseTarballLock{
+							Name:         releaseName,
+							Version:      newReleaseVersion,
+							RemotePath:   newRemotePath,
+							RemoteSource: newReleaseSourceName,
+							SHA1:         "not-calculated",
+						}, nil
+					case "minecraft":
+						return cargo.BOSHReleaseTarballLock{
+							Name:         "minecraft",
+							Version:      "2.0.1",
+							RemotePath:   "not-used",
+							RemoteSource: "bosh.io",
+						}, nil
+					}
+					return cargo.BOSHReleaseTarballLock{}, component.ErrNotFound
+				}
+			})
+
+			It("updates every release with --all", func() {
+				err := updateReleaseCommand.Execute([]string{
+					"--kilnfile", "Kilnfile",
+					"--all",
+					"--releases-directory", releasesDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(releaseSource.FindReleaseVersionCallCount()).To(Equal(2))
+				spec, noDownload := releaseSource.FindReleaseVersionArgsForCall(1)
+				Expect(noDownload).To(BeTrue())
+				Expect(spec).To(Equal(cargo.BOSHReleaseTarballSpecification{
+					Name:             releaseName,
+					StemcellOS:       "some-os",
+					StemcellVersion:  "4.5.6",
+					GitHubRepository: githubRepo,
+				}))
+
+				Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(1), "it only downloads releases that changed")
+
+				var updatedLockfile cargo.KilnfileLock
+				err = fsReadYAML(filesystem, kilnfileLockPath, &updatedLockfile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(updatedLockfile.Releases).To(ConsistOf(
+					kilnfileLock.Releases[0],
+					cargo.BOSHReleaseTarballLock{
+						Name:         releaseName,
+						Version:      newReleaseVersion,
+						SHA1:         newReleaseSha1,
+						RemoteSource: newReleaseSourceName,
+						RemotePath:   newRemotePath,
+					},
+				))
+
+				Expect(string(logBuf.Contents())).To(ContainSubstring("Updated capi from 1.8.0 to 1.8.7"))
+				Expect(string(logBuf.Contents())).To(ContainSubstring("COMMIT"))
+			})
+
+			It("updates each release named with --name", func() {
+				err := updateReleaseCommand.Execute([]string{
+					"--kilnfile", "Kilnfile",
+					"--name", releaseName,
+					"--name", "minecraft",
+					"--releases-directory", releasesDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(releaseSource.FindReleaseVersionCallCount()).To(Equal(2))
+			})
+
+			It("uses the SHA1 sum from the release source when not downloading", func() {
+				err := updateReleaseCommand.Execute([]string{
+					"--kilnfile", "Kilnfile",
+					"--name", releaseName,
+					"--without-download",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, noDownload := releaseSource.FindReleaseVersionArgsForCall(0)
+				Expect(noDownload).To(BeFalse())
+				Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(0))
+
+				var updatedLockfile cargo.KilnfileLock
+				err = fsReadYAML(filesystem, kilnfileLockPath, &updatedLockfile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(updatedLockfile.Releases[1].SHA1).To(Equal("not-calculated"))
+			})
+
+			It("does not write anything with --dry-run", func() {
+				err := updateReleaseCommand.Execute([]string{
+					"--kilnfile", "Kilnfile",
+					"--all",
+					"--dry-run",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(releaseSource.DownloadReleaseCallCount()).To(Equal(0))
+
+				var updatedLockfile cargo.KilnfileLock
+				err = fsReadYAML(filesystem, kilnfileLockPath, &updatedLockfile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(updatedLockfile).To(Equal(kilnfileLock))
+
+				Expect(string(logBuf.Contents())).To(ContainSubstring("Would update capi from 1.8.0 to 1.8.7"))
+				Expect(string(logBuf.Contents())).NotTo(ContainSubstring("COMMIT"))
+			})
+
+			It("adds the changes to the report with --dry-run", func() {
+				report := commands.NewReport("update-release")
+				err := updateReleaseCommand.WithReport(report).Execute([]string{
+					"--kilnfile", "Kilnfile",
+					"--all",
+					"--dry-run",
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(report.Changes).To(Equal([]commands.ReportChange{{
+					Kind:         "release",
+					Name:         releaseName,
+					From:         oldReleaseVersion,
+					To:           newReleaseVersion,
+					RemoteSource: newReleaseSourceName,
+					RemotePath:   newRemotePath,
+				}}))
+				Expect(report.Downloads).To(BeEmpty())
+			})
+
+			It("does not write the Kilnfile.lock when a release can not be found", func() {
+				releaseSource.FindReleaseVersionStub = nil
+				releaseSource.FindReleaseVersionReturns(cargo.BOSHReleaseTarballLock{}, component.ErrNotFound)
+
+				err := updateReleaseCommand.Execute([]string{
+					"--kilnfile", "Kilnfile",
+					"--all",
+				})
+				Expect(err).To(MatchError(ContainSubstring("couldn't find \"minecraft\"")))
+
+				var updatedLockfile cargo.KilnfileLock
+				err = fsReadYAML(filesystem, kilnfileLockPath, &updatedLockfile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(updatedLockfile).To(Equal(kilnfileLock))
+			})
+		})
+
+		When("the flags conflict", func() {
+			It("requires --name or --all", func() {
+				err := updateReleaseCommand.Execute([]string{"--kilnfile", "Kilnfile"})
+				Expect(err).To(MatchError(ContainSubstring("missing required flag")))
+			})
+
+			It("does not allow --name with --all", func() {
+				err := updateReleaseCommand.Execute([]string{"--kilnfile", "Kilnfile", "--all", "--name", releaseName})
+				Expect(err).To(MatchError(ContainSubstring("can not be used together")))
+			})
+
+			It("does not allow --version with more than one release", func() {
+				err := updateReleaseCommand.Execute([]string{"--kilnfile", "Kilnfile", "--name", releaseName, "--name", "minecraft", "--version", newReleaseVersion})
+				Expect(err).To(MatchError(ContainSubstring("single release")))
+			})
 		})
 	})
 })
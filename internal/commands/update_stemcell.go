@@ -19,11 +19,19 @@ type UpdateStemcell struct {
 		flags.Standard
 
 		Version     string `short:"v"  long:"version"            required:"true"    description:"desired version of stemcell"`
+		OS          string `            long:"os"                                  description:"operating system of the stemcell to update (required when the Kilnfile has more than one stemcell)"`
 		ReleasesDir string `short:"rd" long:"releases-directory" default:"releases" description:"path to a directory to download releases into"`
 	}
 	FS                         billy.Filesystem
 	MultiReleaseSourceProvider MultiReleaseSourceProvider
 	Logger                     *log.Logger
+	report                     *Report
+}
+
+// WithReport configures the Report the updated stemcell and releases are added to.
+func (update UpdateStemcell) WithReport(report *Report) UpdateStemcell {
+	update.report = report
+	return update
 }
 
 func (update UpdateStemcell) Execute(args []string) error {
@@ -34,7 +42,7 @@ func (update UpdateStemcell) Execute(args []string) error {
 
 	kilnfile, kilnfileLock, err := update.Options.Standard.LoadKilnfiles(update.FS, nil)
 	if err != nil {
-		return fmt.Errorf("error loading Kilnfiles: %w", err)
+		return withErrorCode(ErrorCodeKilnfile, fmt.Errorf("error loading Kilnfiles: %w", err))
 	}
 
 	var releaseVersionConstraint *semver.Constraints
@@ -46,8 +54,16 @@ func (update UpdateStemcell) Execute(args []string) error {
 		return fmt.Errorf("invalid stemcell version (please enter a valid version): %w", err)
 	}
 
-	kilnStemcellVersion := kilnfile.Stemcell.Version
-	releaseVersionConstraint, err = semver.NewConstraint(kilnStemcellVersion)
+	kilnfileStemcell, err := kilnfile.FindStemcell(update.Options.OS)
+	if err != nil {
+		return err
+	}
+	lockStemcell, err := kilnfileLock.FindStemcell(update.Options.OS)
+	if err != nil {
+		return err
+	}
+
+	releaseVersionConstraint, err = semver.NewConstraint(kilnfileStemcell.Version)
 
 	if err != nil {
 		return fmt.Errorf("invalid stemcell constraint in kilnfile: %w", err)
@@ -58,7 +74,7 @@ func (update UpdateStemcell) Execute(args []string) error {
 		return nil
 	}
 
-	currentStemcellVersion, _ := semver.NewVersion(kilnfileLock.Stemcell.Version)
+	currentStemcellVersion, _ := semver.NewVersion(lockStemcell.Version)
 
 	if currentStemcellVersion.Equal(latestStemcellVersion) {
 		update.Logger.Println("Stemcell is up-to-date. Nothing to update for product")
@@ -68,13 +84,21 @@ func (update UpdateStemcell) Execute(args []string) error {
 	releaseSource := update.MultiReleaseSourceProvider(kilnfile, false)
 
 	for i, rel := range kilnfileLock.Releases {
-		update.Logger.Printf("Updating release %q with stemcell %s %s...", rel.Name, kilnfileLock.Stemcell.OS, trimmedInputVersion)
-
 		spec, err := kilnfile.BOSHReleaseTarballSpecification(rel.Name)
 		if err != nil {
 			return err
 		}
-		spec.StemcellOS = kilnfileLock.Stemcell.OS
+		releaseStemcell, err := kilnfileLock.StemcellForRelease(spec)
+		if err != nil {
+			return err
+		}
+		if releaseStemcell.OS != lockStemcell.OS {
+			continue
+		}
+
+		update.Logger.Printf("Updating release %q with stemcell %s %s...", rel.Name, lockStemcell.OS, trimmedInputVersion)
+
+		spec.StemcellOS = lockStemcell.OS
 		spec.StemcellVersion = trimmedInputVersion
 		spec.Version = rel.Version
 
@@ -93,22 +117,37 @@ func (update UpdateStemcell) Execute(args []string) error {
 
 		local, err := releaseSource.DownloadRelease(update.Options.ReleasesDir, remote)
 		if err != nil {
-			return fmt.Errorf("while downloading release %q, encountered error: %w", rel.Name, err)
+			return withErrorCode(ErrorCodeDownloadFailed, fmt.Errorf("while downloading release %q, encountered error: %w", rel.Name, err))
 		}
+		update.report.AddDownload(local)
 
 		lock := &kilnfileLock.Releases[i]
 		lock.SHA1 = local.Lock.SHA1
+		lock.SHA256 = local.Lock.SHA256
 		lock.RemotePath = remote.RemotePath
 		lock.RemoteSource = remote.RemoteSource
+		update.report.AddReleaseChange(rel, *lock)
 	}
 
-	kilnfileLock.Stemcell.Version = trimmedInputVersion
+	previousStemcellVersion := lockStemcell.Version
+	lockStemcell.Version = trimmedInputVersion
+	err = kilnfileLock.UpdateStemcell(lockStemcell)
+	if err != nil {
+		return err
+	}
 
 	err = update.Options.Standard.SaveKilnfileLock(update.FS, kilnfileLock)
 	if err != nil {
 		return err
 	}
 
+	update.report.AddChange(ReportChange{
+		Kind: "stemcell",
+		Name: lockStemcell.OS,
+		From: previousStemcellVersion,
+		To:   lockStemcell.Version,
+	})
+
 	update.Logger.Println("Finished updating Kilnfile.lock")
 	return nil
 }
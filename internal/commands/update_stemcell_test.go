@@ -414,6 +414,56 @@ var _ = Describe("UpdateStemcell", func() {
 			})
 		})
 
+		When("the Kilnfile has more than one stemcell", func() {
+			BeforeEach(func() {
+				kilnfile.Stemcell = cargo.Stemcell{}
+				kilnfile.Stemcells = []cargo.Stemcell{
+					{OS: "old-os", Version: "^1"},
+					{OS: "windows2019", Version: "2019.*"},
+				}
+				kilnfile.Releases[1].StemcellOS = "windows2019"
+
+				kilnfileLock.Stemcell = cargo.Stemcell{}
+				kilnfileLock.Stemcells = []cargo.Stemcell{
+					{OS: "old-os", Version: "1.1"},
+					{OS: "windows2019", Version: "2019.50"},
+				}
+			})
+
+			It("requires the operating system", func() {
+				err := update.Execute([]string{"--kilnfile", kilnfilePath, "--version", newStemcellVersion})
+				Expect(err).To(MatchError(ContainSubstring("an operating system must be specified")))
+			})
+
+			It("only updates the releases compiled against that stemcell", func() {
+				err := update.Execute([]string{"--kilnfile", kilnfilePath, "--version", "2019.51", "--os", "windows2019"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(releaseSource.GetMatchedReleaseCallCount()).To(Equal(1))
+				Expect(releaseSource.GetMatchedReleaseArgsForCall(0)).To(Equal(cargo.BOSHReleaseTarballSpecification{
+					Name: release2Name, Version: release2Version,
+					StemcellOS: "windows2019", StemcellVersion: "2019.51",
+					GitHubRepository: "https://example.com/orange",
+				}))
+
+				var updatedLockfile cargo.KilnfileLock
+				Expect(fsReadYAML(fs, kilnfileLockPath, &updatedLockfile)).NotTo(HaveOccurred())
+				Expect(updatedLockfile.Stemcells).To(Equal([]cargo.Stemcell{
+					{OS: "old-os", Version: "1.1"},
+					{OS: "windows2019", Version: "2019.51"},
+				}))
+				Expect(updatedLockfile.Releases[0]).To(Equal(kilnfileLock.Releases[0]))
+				Expect(updatedLockfile.Releases[1].SHA1).To(Equal(newRelease2SHA))
+			})
+
+			When("the operating system is not in the Kilnfile", func() {
+				It("errors", func() {
+					err := update.Execute([]string{"--kilnfile", kilnfilePath, "--version", newStemcellVersion, "--os", "banana"})
+					Expect(err).To(MatchError(ContainSubstring(`stemcell "banana" not found in Kilnfile`)))
+				})
+			})
+		})
+
 		When("downloading the release errors", func() {
 			BeforeEach(func() {
 				releaseSource.DownloadReleaseReturns(component.Local{}, errors.New("big badda boom"))
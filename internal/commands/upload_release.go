@@ -98,8 +98,8 @@ func (command UploadRelease) Execute(args []string) error {
 
 func (command UploadRelease) Usage() jhanda.Usage {
 	return jhanda.Usage{
-		Description:      "Uploads a BOSH Release to an S3 release source for use in kiln fetch",
-		ShortDescription: "uploads a BOSH release to an s3 release_source",
+		Description:      "Uploads a BOSH Release to a release source (such as S3, Artifactory, or an OCI registry) for use in kiln fetch",
+		ShortDescription: "uploads a BOSH release to a release_source",
 		Flags:            command.Options,
 	}
 }
@@ -1,7 +1,11 @@
 package commands
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"path/filepath"
 	"strings"
 
 	"github.com/go-git/go-billy/v5"
@@ -9,14 +13,20 @@ import (
 
 	"github.com/pivotal-cf/kiln/internal/commands/flags"
 	"github.com/pivotal-cf/kiln/pkg/cargo"
+	"github.com/pivotal-cf/kiln/pkg/proofing"
+	"github.com/pivotal-cf/kiln/pkg/tile"
 )
 
 type Validate struct {
 	Options struct {
 		flags.Standard
+
+		MetadataPath string `long:"metadata" description:"path to a baked metadata file or tile to validate"`
 	}
 
 	FS billy.Filesystem
+
+	report *Report
 }
 
 var _ jhanda.Command = (*Validate)(nil)
@@ -27,6 +37,20 @@ func NewValidate(fs billy.Filesystem) Validate {
 	}
 }
 
+// WithReport configures the Report the validated files are set as the result of. Each
+// validation error is a separate error in the Report.
+func (v Validate) WithReport(report *Report) Validate {
+	v.report = report
+	return v
+}
+
+// ValidateResult is the "--output json" result of validate. The JSON field names should not change.
+type ValidateResult struct {
+	Kilnfile     string `json:"kilnfile"`
+	KilnfileLock string `json:"kilnfile_lock"`
+	Metadata     string `json:"metadata,omitempty"`
+}
+
 func (v Validate) Execute(args []string) error {
 	_, err := flags.LoadFlagsWithDefaults(&v.Options, args, v.FS.Stat)
 	if err != nil {
@@ -35,17 +59,80 @@ func (v Validate) Execute(args []string) error {
 
 	kf, lock, err := v.Options.Standard.LoadKilnfiles(v.FS, nil)
 	if err != nil {
-		return fmt.Errorf("failed to load kilnfiles: %w", err)
+		return withErrorCode(ErrorCodeKilnfile, fmt.Errorf("failed to load kilnfiles: %w", err))
 	}
 
 	errs := cargo.Validate(kf, lock)
+
+	if v.Options.MetadataPath != "" {
+		metadataErrs, err := v.validateMetadata()
+		if err != nil {
+			return err
+		}
+		errs = append(errs, metadataErrs...)
+	}
+	v.report.SetResult(ValidateResult{
+		Kilnfile:     v.Options.Kilnfile,
+		KilnfileLock: v.Options.KilnfileLockPath(),
+		Metadata:     v.Options.MetadataPath,
+	})
+
 	if len(errs) > 0 {
-		return errorList(errs)
+		list := make(errorList, 0, len(errs))
+		for _, err := range errs {
+			list = append(list, withErrorCode(ErrorCodeValidationFailed, err))
+		}
+		return list
 	}
 
 	return nil
 }
 
+// validateMetadata runs proofing.ProductTemplate.Validate against a baked metadata file or the metadata in a tile.
+func (v Validate) validateMetadata() ([]error, error) {
+	f, err := v.FS.Open(v.Options.MetadataPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open metadata: %w", err)
+	}
+	defer closeAndIgnoreError(f)
+
+	var metadata []byte
+	if filepath.Ext(v.Options.MetadataPath) == ".pivotal" {
+		info, err := v.FS.Stat(v.Options.MetadataPath)
+		if err != nil {
+			return nil, err
+		}
+		metadata, err = tile.ReadMetadataFromZip(f, info.Size())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read metadata from tile: %w", err)
+		}
+	} else {
+		metadata, err = io.ReadAll(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read metadata: %w", err)
+		}
+	}
+
+	productTemplate, err := proofing.Parse(bytes.NewReader(metadata))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+
+	err = productTemplate.Validate()
+	var compoundErr *proofing.CompoundError
+	if errors.As(err, &compoundErr) {
+		errs := make([]error, 0, len(*compoundErr))
+		for _, e := range *compoundErr {
+			errs = append(errs, fmt.Errorf("metadata %w", e))
+		}
+		return errs, nil
+	}
+	if err != nil {
+		return []error{err}, nil
+	}
+	return nil, nil
+}
+
 type errorList []error
 
 func (list errorList) Error() string {
@@ -56,9 +143,11 @@ func (list errorList) Error() string {
 	return strings.Join(messages, "\n")
 }
 
+func (list errorList) Unwrap() []error { return list }
+
 func (v Validate) Usage() jhanda.Usage {
 	return jhanda.Usage{
-		Description:      "Validate checks for common Kilnfile and Kilnfile.lock mistakes",
+		Description:      "Validate checks for common Kilnfile and Kilnfile.lock mistakes. With --metadata it also checks baked tile metadata for errors Ops Manager would reject.",
 		ShortDescription: "validate Kilnfile and Kilnfile.lock",
 		Flags:            v.Options,
 	}
@@ -0,0 +1,59 @@
+package commands_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+	"github.com/pivotal-cf/kiln/pkg/proofing"
+)
+
+func TestValidate_metadata(t *testing.T) {
+	t.Run("invalid metadata", func(t *testing.T) {
+		please := NewWithT(t)
+
+		fs := memfs.New()
+		please.Expect(fsWriteYAML(fs, "Kilnfile", cargo.Kilnfile{})).To(Succeed())
+		please.Expect(fsWriteYAML(fs, "Kilnfile.lock", cargo.KilnfileLock{})).To(Succeed())
+		f, err := fs.Create("metadata.yml")
+		please.Expect(err).NotTo(HaveOccurred())
+		_, err = f.Write([]byte(`---
+name: banana
+product_version: 1.2.3
+metadata_version: "2.7"
+stemcell_criteria: {os: ubuntu-jammy, version: "1.10"}
+job_types:
+  - name: peeler
+    resource_label: Peeler
+    templates:
+      - {name: peeler, release: banana}
+`))
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(f.Close()).To(Succeed())
+
+		report := commands.NewReport("validate")
+		err = commands.NewValidate(fs).WithReport(report).Execute([]string{"--metadata", "metadata.yml"})
+		please.Expect(err).To(MatchError(`metadata job_types[0].templates[0].release: "banana" is not a declared release`))
+		please.Expect(report.Result).To(Equal(commands.ValidateResult{Kilnfile: "Kilnfile", KilnfileLock: "Kilnfile.lock", Metadata: "metadata.yml"}))
+
+		var fieldErr proofing.FieldError
+		please.Expect(errors.As(err, &fieldErr)).To(BeTrue())
+		please.Expect(fieldErr.Path).To(Equal("job_types[0].templates[0].release"))
+		please.Expect(commands.ErrorCode(err)).To(Equal(commands.ErrorCodeValidationFailed))
+	})
+
+	t.Run("missing metadata", func(t *testing.T) {
+		please := NewWithT(t)
+
+		fs := memfs.New()
+		please.Expect(fsWriteYAML(fs, "Kilnfile", cargo.Kilnfile{})).To(Succeed())
+		please.Expect(fsWriteYAML(fs, "Kilnfile.lock", cargo.KilnfileLock{})).To(Succeed())
+
+		err := commands.NewValidate(fs).Execute([]string{"--metadata", "metadata.yml"})
+		please.Expect(err).To(MatchError(ContainSubstring("failed to open metadata")))
+	})
+}
@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pivotal-cf/jhanda"
+
+	"github.com/pivotal-cf/kiln/internal/commands/flags"
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+type VerifyLock struct {
+	outLogger             *log.Logger
+	localReleaseDirectory LocalReleaseDirectory
+	mrsProvider           MultiReleaseSourceProvider
+	report                *Report
+
+	Options struct {
+		flags.Standard
+
+		ReleasesDir string `short:"rd" long:"releases-directory" default:"releases" description:"path to a directory containing downloaded releases"`
+		Remote      bool   `long:"remote" description:"compare the provenance in the Kilnfile.lock with the release sources"`
+	}
+}
+
+func NewVerifyLock(outLogger *log.Logger, localReleaseDirectory LocalReleaseDirectory, multiReleaseSourceProvider MultiReleaseSourceProvider) *VerifyLock {
+	return &VerifyLock{
+		outLogger:             outLogger,
+		localReleaseDirectory: localReleaseDirectory,
+		mrsProvider:           multiReleaseSourceProvider,
+	}
+}
+
+// WithReport configures the Report the verified releases are set as the result of.
+func (cmd *VerifyLock) WithReport(report *Report) *VerifyLock {
+	cmd.report = report
+	return cmd
+}
+
+// VerifiedRelease is a row of the verify-lock table. The JSON field names are part of
+// the "--output json" result and should not change.
+type VerifiedRelease struct {
+	Name       string   `json:"name"`
+	Version    string   `json:"version"`
+	Downloaded bool     `json:"downloaded"`
+	Problems   []string `json:"problems,omitempty"`
+}
+
+type lockVerification struct {
+	lock       cargo.BOSHReleaseTarballLock
+	downloaded bool
+	problems   []string
+}
+
+func (cmd *VerifyLock) Execute(args []string) error {
+	argsAfterFlags, err := flags.LoadFlagsWithDefaults(&cmd.Options, args, nil)
+	if err != nil {
+		return err
+	}
+	if len(argsAfterFlags) != 0 {
+		return fmt.Errorf("unexpected arguments: %v", argsAfterFlags)
+	}
+
+	kilnfile, kilnfileLock, err := cmd.Options.LoadKilnfiles(nil, nil)
+	if err != nil {
+		return err
+	}
+
+	var localReleases []component.Local
+	if cmd.Options.ReleasesDir != "" {
+		localReleases, err = cmd.localReleaseDirectory.GetLocalReleases(cmd.Options.ReleasesDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	var releaseSource component.MultiReleaseSource
+	if cmd.Options.Remote {
+		releaseSource = cmd.mrsProvider(kilnfile, false)
+	}
+
+	failed := 0
+	results := make([]lockVerification, 0, len(kilnfileLock.Releases))
+	for _, lock := range kilnfileLock.Releases {
+		result := cmd.verify(kilnfile, lock, localReleases, releaseSource)
+		if len(result.problems) > 0 {
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	cmd.printResults(results)
+	verified := make([]VerifiedRelease, 0, len(results))
+	for _, result := range results {
+		verified = append(verified, VerifiedRelease{Name: result.lock.Name, Version: result.lock.Version, Downloaded: result.downloaded, Problems: result.problems})
+	}
+	cmd.report.SetResult(verified)
+
+	if failed > 0 {
+		return withErrorCode(ErrorCodeVerificationFailed, fmt.Errorf("%d of %d releases failed verification", failed, len(results)))
+	}
+	return nil
+}
+
+func (cmd *VerifyLock) verify(kilnfile cargo.Kilnfile, lock cargo.BOSHReleaseTarballLock, localReleases []component.Local, releaseSource component.MultiReleaseSource) lockVerification {
+	result := lockVerification{lock: lock}
+
+	source, found := component.FindReleaseSourceConfig(kilnfile, lock.RemoteSource)
+	if !found {
+		result.problems = append(result.problems, fmt.Sprintf("release source %q is not in the Kilnfile", lock.RemoteSource))
+	}
+	if lock.SHA1 == "" {
+		result.problems = append(result.problems, "missing sha1")
+	}
+
+	for _, local := range localReleases {
+		if local.Lock.Name != lock.Name || local.Lock.Version != lock.Version {
+			continue
+		}
+		result.downloaded = true
+		if err := component.VerifyLocalRelease(local, lock, source); err != nil {
+			result.problems = append(result.problems, err.Error())
+		}
+		break
+	}
+	if !result.downloaded && source.SignaturePublicKey != "" && lock.Signature == "" {
+		result.problems = append(result.problems, "missing signature")
+	}
+
+	if releaseSource != nil && lock.Provenance != "" {
+		if problem := checkProvenance(releaseSource, lock); problem != "" {
+			result.problems = append(result.problems, problem)
+		}
+	}
+
+	return result
+}
+
+func checkProvenance(releaseSource component.MultiReleaseSource, lock cargo.BOSHReleaseTarballLock) string {
+	src, err := releaseSource.FindByID(lock.RemoteSource)
+	if err != nil {
+		return err.Error()
+	}
+	reporter, ok := src.(component.ProvenanceReporter)
+	if !ok {
+		return fmt.Sprintf("release source %q can not report provenance", lock.RemoteSource)
+	}
+	provenance, err := reporter.Provenance(lock)
+	if err != nil {
+		return fmt.Sprintf("failed to get provenance: %s", err)
+	}
+	if provenance != lock.Provenance {
+		return fmt.Sprintf("provenance changed - expected %q, got %q", lock.Provenance, provenance)
+	}
+	return ""
+}
+
+func (cmd *VerifyLock) printResults(results []lockVerification) {
+	w := tabwriter.NewWriter(cmd.outLogger.Writer(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "RELEASE\tVERSION\tSTATUS")
+	for _, result := range results {
+		status := "ok"
+		switch {
+		case len(result.problems) > 0:
+			status = "failed: " + strings.Join(result.problems, "; ")
+		case !result.downloaded:
+			status = "ok (not downloaded)"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", result.lock.Name, result.lock.Version, status)
+	}
+	_ = w.Flush()
+}
+
+func (cmd *VerifyLock) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Checks every release in the Kilnfile.lock. Downloaded releases are checked against the SHA1 and SHA256 sums and, when the release source has a signature_public_key, the signature. With --remote the provenance of each release is compared with the release source.",
+		ShortDescription: "verifies the releases in Kilnfile.lock",
+		Flags:            cmd.Options,
+	}
+}
@@ -0,0 +1,214 @@
+package commands_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/kiln/internal/commands"
+	commandsFakes "github.com/pivotal-cf/kiln/internal/commands/fakes"
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/internal/component/fakes"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+type provenanceReleaseSource struct {
+	*fakes.ReleaseSource
+	provenance string
+}
+
+func (source provenanceReleaseSource) Provenance(cargo.BOSHReleaseTarballLock) (string, error) {
+	return source.provenance, nil
+}
+
+func TestVerifyLock_Execute(t *testing.T) {
+	const (
+		releaseContents = "some release contents"
+		releaseSHA1     = "641c0d1e5d0578ededf8cd76f5d8399e867f6f6c"
+		releaseSHA256   = "ca6838db82f1df4b7456c468b3035aee107a19caaafb65e2b5e68f037d8bb6cc"
+	)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDER}))
+	digest := sha256.Sum256([]byte(releaseContents))
+	signatureBytes, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := base64.StdEncoding.EncodeToString(signatureBytes)
+
+	setup := func(t *testing.T, source cargo.ReleaseSourceConfig, locks []cargo.BOSHReleaseTarballLock, localReleases []component.Local) (*commands.VerifyLock, *fakes.MultiReleaseSource, *bytes.Buffer, string) {
+		t.Helper()
+		dir := t.TempDir()
+		kilnfilePath := filepath.Join(dir, "Kilnfile")
+		kilnfileBuf, err := yaml.Marshal(cargo.Kilnfile{ReleaseSources: []cargo.ReleaseSourceConfig{source}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(kilnfilePath, kilnfileBuf, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		lockBuf, err := yaml.Marshal(cargo.KilnfileLock{Releases: locks})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(kilnfilePath+".lock", lockBuf, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		for i, local := range localReleases {
+			localReleases[i].LocalPath = filepath.Join(dir, filepath.Base(local.LocalPath))
+			if err := os.WriteFile(localReleases[i].LocalPath, []byte(releaseContents), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		localReleaseDirectory := new(commandsFakes.LocalReleaseDirectory)
+		localReleaseDirectory.GetLocalReleasesReturns(localReleases, nil)
+
+		releaseSource := new(fakes.MultiReleaseSource)
+		var output bytes.Buffer
+		cmd := commands.NewVerifyLock(log.New(&output, "", 0), localReleaseDirectory, func(cargo.Kilnfile, bool) component.MultiReleaseSource {
+			return releaseSource
+		})
+		return cmd, releaseSource, &output, kilnfilePath
+	}
+
+	mangoLock := cargo.BOSHReleaseTarballLock{Name: "mango", Version: "1.2.3", RemoteSource: "artifacts", SHA1: releaseSHA1, SHA256: releaseSHA256}
+	mangoLocal := component.Local{Lock: cargo.BOSHReleaseTarballLock{Name: "mango", Version: "1.2.3"}, LocalPath: "mango-1.2.3.tgz"}
+	artifacts := cargo.ReleaseSourceConfig{Type: component.ReleaseSourceTypeArtifactory, ID: "artifacts"}
+
+	t.Run("when the downloaded releases match the lock", func(t *testing.T) {
+		please := NewWithT(t)
+
+		cmd, _, output, kilnfilePath := setup(t, artifacts, []cargo.BOSHReleaseTarballLock{
+			mangoLock,
+			{Name: "banana", Version: "2.0.0", RemoteSource: "artifacts", SHA1: "some-sha"},
+		}, []component.Local{mangoLocal})
+
+		report := commands.NewReport("verify-lock")
+		err := cmd.WithReport(report).Execute([]string{"--kilnfile", kilnfilePath, "--releases-directory", filepath.Dir(kilnfilePath)})
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(output.String()).To(MatchRegexp(`mango\s+1\.2\.3\s+ok\n`))
+		please.Expect(output.String()).To(MatchRegexp(`banana\s+2\.0\.0\s+ok \(not downloaded\)\n`))
+		please.Expect(report.Result).To(Equal([]commands.VerifiedRelease{
+			{Name: "mango", Version: "1.2.3", Downloaded: true},
+			{Name: "banana", Version: "2.0.0"},
+		}))
+	})
+
+	t.Run("when a downloaded release does not match the SHA256 sum", func(t *testing.T) {
+		please := NewWithT(t)
+
+		lock := mangoLock
+		lock.SHA256 = "0000000000000000000000000000000000000000000000000000000000000000"
+		cmd, _, output, kilnfilePath := setup(t, artifacts, []cargo.BOSHReleaseTarballLock{lock}, []component.Local{mangoLocal})
+
+		report := commands.NewReport("verify-lock")
+		err := cmd.WithReport(report).Execute([]string{"--kilnfile", kilnfilePath, "--releases-directory", filepath.Dir(kilnfilePath)})
+		please.Expect(err).To(MatchError("1 of 1 releases failed verification"))
+		please.Expect(commands.ErrorCode(err)).To(Equal(commands.ErrorCodeVerificationFailed))
+		please.Expect(output.String()).To(ContainSubstring("failed: SHA256 mismatch"))
+		verified, ok := report.Result.([]commands.VerifiedRelease)
+		please.Expect(ok).To(BeTrue())
+		please.Expect(verified).To(HaveLen(1))
+		please.Expect(verified[0].Name).To(Equal("mango"))
+		please.Expect(verified[0].Problems).To(ConsistOf(ContainSubstring("SHA256 mismatch")))
+	})
+
+	t.Run("when the release source requires signatures", func(t *testing.T) {
+		source := artifacts
+		source.SignaturePublicKey = publicKey
+
+		t.Run("and the downloaded release is signed", func(t *testing.T) {
+			please := NewWithT(t)
+
+			lock := mangoLock
+			lock.Signature = signature
+			cmd, _, _, kilnfilePath := setup(t, source, []cargo.BOSHReleaseTarballLock{lock}, []component.Local{mangoLocal})
+
+			err := cmd.Execute([]string{"--kilnfile", kilnfilePath, "--releases-directory", filepath.Dir(kilnfilePath)})
+			please.Expect(err).NotTo(HaveOccurred())
+		})
+
+		t.Run("and the signature is for another release", func(t *testing.T) {
+			please := NewWithT(t)
+
+			otherDigest := sha256.Sum256([]byte("other release contents"))
+			otherSignature, err := ecdsa.SignASN1(rand.Reader, key, otherDigest[:])
+			please.Expect(err).NotTo(HaveOccurred())
+			lock := mangoLock
+			lock.Signature = base64.StdEncoding.EncodeToString(otherSignature)
+			cmd, _, output, kilnfilePath := setup(t, source, []cargo.BOSHReleaseTarballLock{lock}, []component.Local{mangoLocal})
+
+			err = cmd.Execute([]string{"--kilnfile", kilnfilePath, "--releases-directory", filepath.Dir(kilnfilePath)})
+			please.Expect(err).To(HaveOccurred())
+			please.Expect(output.String()).To(ContainSubstring("signature verification failed"))
+		})
+
+		t.Run("and a release that is not downloaded is not signed", func(t *testing.T) {
+			please := NewWithT(t)
+
+			cmd, _, output, kilnfilePath := setup(t, source, []cargo.BOSHReleaseTarballLock{mangoLock}, nil)
+
+			err := cmd.Execute([]string{"--kilnfile", kilnfilePath, "--releases-directory", filepath.Dir(kilnfilePath)})
+			please.Expect(err).To(HaveOccurred())
+			please.Expect(output.String()).To(ContainSubstring("failed: missing signature"))
+		})
+	})
+
+	t.Run("with --remote", func(t *testing.T) {
+		lock := mangoLock
+		lock.Provenance = "s3-version-id:some-version"
+
+		t.Run("when the provenance has not changed", func(t *testing.T) {
+			please := NewWithT(t)
+
+			cmd, releaseSource, _, kilnfilePath := setup(t, artifacts, []cargo.BOSHReleaseTarballLock{lock}, nil)
+			releaseSource.FindByIDReturns(provenanceReleaseSource{ReleaseSource: new(fakes.ReleaseSource), provenance: "s3-version-id:some-version"}, nil)
+
+			err := cmd.Execute([]string{"--kilnfile", kilnfilePath, "--releases-directory", filepath.Dir(kilnfilePath), "--remote"})
+			please.Expect(err).NotTo(HaveOccurred())
+			please.Expect(releaseSource.FindByIDArgsForCall(0)).To(Equal("artifacts"))
+		})
+
+		t.Run("when the provenance has changed", func(t *testing.T) {
+			please := NewWithT(t)
+
+			cmd, releaseSource, output, kilnfilePath := setup(t, artifacts, []cargo.BOSHReleaseTarballLock{lock}, nil)
+			releaseSource.FindByIDReturns(provenanceReleaseSource{ReleaseSource: new(fakes.ReleaseSource), provenance: "s3-version-id:another-version"}, nil)
+
+			err := cmd.Execute([]string{"--kilnfile", kilnfilePath, "--releases-directory", filepath.Dir(kilnfilePath), "--remote"})
+			please.Expect(err).To(HaveOccurred())
+			please.Expect(output.String()).To(ContainSubstring(`provenance changed - expected "s3-version-id:some-version", got "s3-version-id:another-version"`))
+		})
+
+		t.Run("without --remote the release sources are not used", func(t *testing.T) {
+			please := NewWithT(t)
+
+			cmd, releaseSource, _, kilnfilePath := setup(t, artifacts, []cargo.BOSHReleaseTarballLock{lock}, nil)
+
+			err := cmd.Execute([]string{"--kilnfile", kilnfilePath, "--releases-directory", filepath.Dir(kilnfilePath)})
+			please.Expect(err).NotTo(HaveOccurred())
+			please.Expect(releaseSource.FindByIDCallCount()).To(Equal(0))
+		})
+	})
+}
@@ -2,8 +2,6 @@ package component
 
 import (
 	"bytes"
-	"crypto/sha1"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -70,41 +68,35 @@ func NewArtifactoryReleaseSource(c cargo.ReleaseSourceConfig) *ArtifactoryReleas
 func (ars *ArtifactoryReleaseSource) DownloadRelease(releaseDir string, remoteRelease cargo.BOSHReleaseTarballLock) (Local, error) {
 	downloadURL := ars.ArtifactoryHost + "/artifactory/" + ars.Repo + "/" + remoteRelease.RemotePath
 	ars.logger.Printf(logLineDownload, remoteRelease.Name, ReleaseSourceTypeArtifactory, ars.ID)
-	resp, err := ars.Client.Get(downloadURL)
-	if err != nil {
-		return Local{}, wrapVPNError(err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return Local{}, fmt.Errorf("failed to download %s release from artifactory with error code %d", remoteRelease.Name, resp.StatusCode)
-	}
-
 	filePath := filepath.Join(releaseDir, filepath.Base(remoteRelease.RemotePath))
 
-	out, err := os.Create(filePath)
+	out, err := openPartialDownload(filePath)
 	if err != nil {
 		return Local{}, err
 	}
-	defer func() { _ = out.Close() }()
+	defer closeAndIgnoreError(out)
 
-	_, err = io.Copy(out, resp.Body)
-	_ = resp.Body.Close()
+	req, err := http.NewRequest(http.MethodGet, downloadURL, nil)
 	if err != nil {
 		return Local{}, err
 	}
+	out.setRange(req)
 
-	_, err = out.Seek(0, 0)
+	resp, err := ars.Client.Do(req)
 	if err != nil {
-		return Local{}, fmt.Errorf("error reseting file cursor: %w", err) // untested
+		return Local{}, wrapVPNError(err)
 	}
 
-	hash := sha1.New()
-	_, err = io.Copy(hash, out)
+	err = out.copyResponse(resp)
+	_ = resp.Body.Close()
 	if err != nil {
-		return Local{}, fmt.Errorf("error hashing file contents: %w", err) // untested
+		return Local{}, fmt.Errorf("failed to download %s release from artifactory: %w", remoteRelease.Name, err)
 	}
 
-	remoteRelease.SHA1 = hex.EncodeToString(hash.Sum(nil))
+	remoteRelease.SHA1, remoteRelease.SHA256, err = out.complete()
+	if err != nil {
+		return Local{}, err
+	}
 
 	return Local{Lock: remoteRelease, LocalPath: filePath}, nil
 }
@@ -292,7 +284,8 @@ func (ars *ArtifactoryReleaseSource) UploadRelease(spec cargo.BOSHReleaseTarball
 
 	fullUrl := ars.ArtifactoryHost + "/artifactory/" + ars.Repo + "/" + remotePath
 
-	request, err := http.NewRequest(http.MethodPut, fullUrl, file)
+	hash := newReleaseHash()
+	request, err := http.NewRequest(http.MethodPut, fullUrl, io.TeeReader(file, hash))
 	if err != nil {
 		fmt.Println(err)
 		return cargo.BOSHReleaseTarballLock{}, err
@@ -312,12 +305,14 @@ func (ars *ArtifactoryReleaseSource) UploadRelease(spec cargo.BOSHReleaseTarball
 		return cargo.BOSHReleaseTarballLock{}, fmt.Errorf(response.Status)
 	}
 
-	return cargo.BOSHReleaseTarballLock{
+	lock := cargo.BOSHReleaseTarballLock{
 		Name:         spec.Name,
 		Version:      spec.Version,
 		RemotePath:   remotePath,
 		RemoteSource: ars.ReleaseSourceConfig.ID,
-	}, nil
+	}
+	lock.SHA1, lock.SHA256 = hash.sums()
+	return lock, nil
 }
 
 func (ars *ArtifactoryReleaseSource) RemotePath(spec cargo.BOSHReleaseTarballSpecification) (string, error) {
@@ -181,7 +181,8 @@ var _ = Describe("interacting with BOSH releases on Artifactory", func() {
 				Version: "2.3.4",
 				// StemcellOS:      "smoothie",
 				// StemcellVersion: "9.9",
-				// SHA1:         "some-sha",
+				SHA1:         "6d96f7c98610fa6d8e7f45271111221b5b8497a2",
+				SHA256:       "6ff4d9d50beaa2f73063a66c8cf0df769bf244cb2f78bd257f58275d0d6a266d",
 				RemotePath:   "bosh-releases/smoothie/9.9/mango/mango-2.3.4-smoothie-9.9.tgz",
 				RemoteSource: "some-mango-tree",
 			}))
@@ -0,0 +1,393 @@
+package component
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// AzureReleaseSource stores releases in an Azure Blob Storage container using the Blob
+// service REST API. It behaves like S3ReleaseSource: RemotePath evaluates the path_template
+// and FindReleaseVersion parses versions from the blob names under the release prefix.
+//
+// Requests are signed with the storage account key (Shared Key authorization) when
+// account_key is set, otherwise sas_token is appended to every request. The endpoint
+// defaults to https://<account_name>.blob.core.windows.net; set it to the account URL of an
+// emulator such as Azurite (for example http://127.0.0.1:10000/devstoreaccount1).
+type AzureReleaseSource struct {
+	cargo.ReleaseSourceConfig
+
+	Client *http.Client
+
+	DownloadThreads int
+
+	logger *log.Logger
+}
+
+const azureStorageAPIVersion = "2020-10-02"
+
+// NewAzureReleaseSource will provision a new AzureReleaseSource from the Kilnfile
+// (ReleaseSourceConfig). If type is incorrect or a required field is missing it will PANIC
+func NewAzureReleaseSource(c cargo.ReleaseSourceConfig, logger *log.Logger) *AzureReleaseSource {
+	if c.Type != "" && c.Type != ReleaseSourceTypeAzure {
+		panic(panicMessageWrongReleaseSourceType)
+	}
+	if c.PathTemplate == "" {
+		panic(`Missing required field "path_template" in release source config. Is your Kilnfile out of date?`)
+	}
+	if c.Container == "" {
+		panic(`Missing required field "container" in release source config. Is your Kilnfile out of date?`)
+	}
+	if c.AccountName == "" {
+		panic(`Missing required field "account_name" in release source config. Is your Kilnfile out of date?`)
+	}
+	if logger == nil {
+		logger = log.New(os.Stderr, "[Azure release source] ", log.Default().Flags())
+	}
+	return &AzureReleaseSource{
+		ReleaseSourceConfig: c,
+		Client:              http.DefaultClient,
+		logger:              logger,
+	}
+}
+
+func (src *AzureReleaseSource) ID() string { return src.ReleaseSourceConfig.ID }
+
+func (src *AzureReleaseSource) Configuration() cargo.ReleaseSourceConfig {
+	return src.ReleaseSourceConfig
+}
+
+func (src *AzureReleaseSource) RemotePath(spec cargo.BOSHReleaseTarballSpecification) (string, error) {
+	return evaluatePathTemplate(src.ReleaseSourceConfig.PathTemplate, spec)
+}
+
+func (src *AzureReleaseSource) GetMatchedRelease(spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	_, err = src.blobSize(remotePath)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	return cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      spec.Version,
+		RemotePath:   remotePath,
+		RemoteSource: src.ID(),
+	}, nil
+}
+
+func (src *AzureReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
+	keys, err := src.listBlobs(objectKeyPrefix(src.ReleaseSourceConfig.PathTemplate, spec.Name))
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	foundRelease, err := findReleaseVersionInObjectKeys(spec, keys, src.ID())
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	if noDownload {
+		foundRelease.SHA1 = "not-calculated"
+	} else {
+		releaseLocal, err := src.DownloadRelease(os.TempDir(), foundRelease)
+		if err != nil {
+			return cargo.BOSHReleaseTarballLock{}, err
+		}
+		foundRelease.SHA1 = releaseLocal.Lock.SHA1
+		foundRelease.SHA256 = releaseLocal.Lock.SHA256
+	}
+	return foundRelease, nil
+}
+
+// DownloadRelease downloads the blob in DownloadThreads parts requested in parallel.
+func (src *AzureReleaseSource) DownloadRelease(releaseDir string, lock cargo.BOSHReleaseTarballLock) (Local, error) {
+	src.logger.Printf(logLineDownload, lock.Name, ReleaseSourceTypeAzure, src.ID())
+
+	size, err := src.blobSize(lock.RemotePath)
+	if err != nil {
+		return Local{}, fmt.Errorf("failed to get %s from %s: %w", lock.RemotePath, src.ID(), err)
+	}
+
+	outputFile := filepath.Join(releaseDir, filepath.Base(lock.RemotePath))
+	file, err := openPartialDownload(outputFile)
+	if err != nil {
+		return Local{}, err
+	}
+	defer closeAndIgnoreError(file)
+
+	err = downloadParts(file, size, src.DownloadThreads, func(first, last int64) (*http.Response, error) {
+		req, err := src.newRequest(http.MethodGet, lock.RemotePath, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("x-ms-range", fmt.Sprintf("bytes=%d-%d", first, last))
+		return src.do(req)
+	})
+	if err != nil {
+		return Local{}, fmt.Errorf("failed to download file: %w", err)
+	}
+
+	lock.SHA1, lock.SHA256, err = file.complete()
+	if err != nil {
+		return Local{}, err
+	}
+
+	return Local{Lock: lock, LocalPath: outputFile}, nil
+}
+
+// ReleaseSignature downloads the signature blob next to the release blob.
+func (src *AzureReleaseSource) ReleaseSignature(lock cargo.BOSHReleaseTarballLock) (string, error) {
+	req, err := src.newRequest(http.MethodGet, lock.RemotePath+signatureFileSuffix, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := src.do(req)
+	if err != nil {
+		return "", err
+	}
+	return readSignatureResponse(res)
+}
+
+// UploadRelease uploads the release as a block blob with a single request.
+func (src *AzureReleaseSource) UploadRelease(spec cargo.BOSHReleaseTarballSpecification, file io.Reader) (cargo.BOSHReleaseTarballLock, error) {
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	src.logger.Printf("uploading release %q to %s at %q...\n", spec.Name, src.ID(), remotePath)
+
+	hash := newReleaseHash()
+	tmp, size, err := spoolUpload(io.TeeReader(file, hash))
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	defer func() {
+		closeAndIgnoreError(tmp)
+		_ = os.Remove(tmp.Name())
+	}()
+
+	req, err := src.newRequest(http.MethodPut, remotePath, nil, io.NopCloser(tmp))
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+
+	res, err := src.do(req)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	closeAndIgnoreError(res.Body)
+	if err := checkStatus(http.StatusCreated, res.StatusCode); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, fmt.Errorf("failed to upload %s: %w", remotePath, err)
+	}
+
+	lock := cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      spec.Version,
+		RemotePath:   remotePath,
+		RemoteSource: src.ID(),
+	}
+	lock.SHA1, lock.SHA256 = hash.sums()
+	return lock, nil
+}
+
+func (src *AzureReleaseSource) blobSize(name string) (int64, error) {
+	req, err := src.newRequest(http.MethodHead, name, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	res, err := src.do(req)
+	if err != nil {
+		return 0, err
+	}
+	closeAndIgnoreError(res.Body)
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return 0, ErrNotFound
+	default:
+		return 0, checkStatus(http.StatusOK, res.StatusCode)
+	}
+	return strconv.ParseInt(res.Header.Get("Content-Length"), 10, 64)
+}
+
+func (src *AzureReleaseSource) listBlobs(prefix string) ([]string, error) {
+	var (
+		names  []string
+		marker string
+	)
+	for {
+		query := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {prefix}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		req, err := src.newRequest(http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		res, err := src.do(req)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Blobs []struct {
+				Name string `xml:"Name"`
+			} `xml:"Blobs>Blob"`
+			NextMarker string `xml:"NextMarker"`
+		}
+		err = checkStatus(http.StatusOK, res.StatusCode)
+		if err == nil {
+			err = xml.NewDecoder(res.Body).Decode(&page)
+		}
+		closeAndIgnoreError(res.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list blobs in %s: %w", src.Container, err)
+		}
+		for _, blob := range page.Blobs {
+			names = append(names, blob.Name)
+		}
+		if page.NextMarker == "" {
+			return names, nil
+		}
+		marker = page.NextMarker
+	}
+}
+
+// newRequest creates a request for a blob in the container. When name is empty the
+// request is for the container itself.
+func (src *AzureReleaseSource) newRequest(method, name string, query url.Values, body io.ReadCloser) (*http.Request, error) {
+	endpoint := strings.TrimSuffix(src.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", src.AccountName)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint for %s: %w", src.ID(), err)
+	}
+	segments := []string{url.PathEscape(src.Container)}
+	if name != "" {
+		for _, segment := range strings.Split(name, "/") {
+			segments = append(segments, url.PathEscape(segment))
+		}
+	}
+	u.RawPath = u.EscapedPath() + "/" + strings.Join(segments, "/")
+	u.Path, _ = url.PathUnescape(u.RawPath)
+
+	if query == nil {
+		query = url.Values{}
+	}
+	if src.AccountKey == "" && src.SASToken != "" {
+		sas, err := url.ParseQuery(strings.TrimPrefix(src.SASToken, "?"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid sas_token for %s: %w", src.ID(), err)
+		}
+		for key, values := range sas {
+			query[key] = values
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-version", azureStorageAPIVersion)
+	return req, nil
+}
+
+func (src *AzureReleaseSource) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	if src.AccountKey != "" {
+		err := signAzureRequest(req, src.AccountName, src.AccountKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	res, err := src.Client.Do(req)
+	if err != nil {
+		return nil, wrapVPNError(err)
+	}
+	return res, nil
+}
+
+// signAzureRequest sets the Authorization header for Shared Key authorization.
+// See https://learn.microsoft.com/en-us/rest/api/storageservices/authorize-with-shared-key
+func signAzureRequest(req *http.Request, accountName, accountKey string) error {
+	key, err := base64.StdEncoding.DecodeString(accountKey)
+	if err != nil {
+		return fmt.Errorf("account_key must be base64 encoded: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	_, _ = io.WriteString(mac, azureStringToSign(req, accountName))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	req.Header.Set("Authorization", "SharedKey "+accountName+":"+signature)
+	return nil
+}
+
+func azureStringToSign(req *http.Request, accountName string) string {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	var msHeaders []string
+	for name := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-ms-") {
+			msHeaders = append(msHeaders, lower+":"+strings.TrimSpace(req.Header.Get(name)))
+		}
+	}
+	sort.Strings(msHeaders)
+
+	resource := "/" + accountName + req.URL.EscapedPath()
+	query := req.URL.Query()
+	var params []string
+	for name := range query {
+		params = append(params, name)
+	}
+	sort.Strings(params)
+	for _, name := range params {
+		values := query[name]
+		sort.Strings(values)
+		resource += "\n" + strings.ToLower(name) + ":" + strings.Join(values, ",")
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date is sent as x-ms-date
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		strings.Join(msHeaders, "\n"),
+		resource,
+	}, "\n")
+}
@@ -0,0 +1,248 @@
+package component_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+func TestAzureReleaseSource(t *testing.T) {
+	const (
+		contents  = "some release contents"
+		sum       = "641c0d1e5d0578ededf8cd76f5d8399e867f6f6c"
+		sha256Sum = "ca6838db82f1df4b7456c468b3035aee107a19caaafb65e2b5e68f037d8bb6cc"
+
+		// the well-known Azurite development account key
+		accountKey = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+	)
+
+	setup := func(t *testing.T, container *fakeAzureContainer, config cargo.ReleaseSourceConfig) *component.AzureReleaseSource {
+		t.Helper()
+		server := httptest.NewServer(container)
+		t.Cleanup(server.Close)
+		config.Type = component.ReleaseSourceTypeAzure
+		config.ID = "some-container"
+		config.Container = "some-container"
+		config.AccountName = "devstoreaccount1"
+		config.Endpoint = server.URL + "/devstoreaccount1"
+		config.PathTemplate = "{{.Name}}/{{.Name}}-{{.Version}}.tgz"
+		source := component.NewAzureReleaseSource(config, log.New(io.Discard, "", 0))
+		source.Client = server.Client()
+		return source
+	}
+
+	t.Run("interface compliance", func(t *testing.T) {
+		please := NewWithT(t)
+		var source interface{} = new(component.AzureReleaseSource)
+		_, isReleaseSource := source.(component.ReleaseSource)
+		_, isUploader := source.(component.ReleaseUploader)
+		_, isPather := source.(component.RemotePather)
+		_, isSignatureReader := source.(component.SignatureReader)
+		please.Expect(isReleaseSource).To(BeTrue())
+		please.Expect(isUploader).To(BeTrue())
+		please.Expect(isPather).To(BeTrue())
+		please.Expect(isSignatureReader).To(BeTrue())
+	})
+
+	t.Run("missing container", func(t *testing.T) {
+		please := NewWithT(t)
+		please.Expect(func() {
+			component.NewAzureReleaseSource(cargo.ReleaseSourceConfig{Type: component.ReleaseSourceTypeAzure, PathTemplate: "{{.Name}}.tgz", AccountName: "some-account"}, nil)
+		}).To(Panic())
+	})
+
+	t.Run("upload and download with an account key", func(t *testing.T) {
+		please := NewWithT(t)
+		container := newFakeAzureContainer()
+		source := setup(t, container, cargo.ReleaseSourceConfig{AccountKey: accountKey})
+		spec := cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "1.2.3"}
+
+		lock, err := source.UploadRelease(spec, strings.NewReader(contents))
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(lock.RemotePath).To(Equal("mango/mango-1.2.3.tgz"))
+		please.Expect(lock.RemoteSource).To(Equal("some-container"))
+		please.Expect(container.blobs).To(HaveKeyWithValue("mango/mango-1.2.3.tgz", []byte(contents)))
+
+		matched, err := source.GetMatchedRelease(spec)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(lock.SHA1).To(Equal(sum))
+		please.Expect(lock.SHA256).To(Equal(sha256Sum))
+		withoutSums := lock
+		withoutSums.SHA1, withoutSums.SHA256 = "", ""
+		please.Expect(matched).To(Equal(withoutSums))
+
+		releasesDir := t.TempDir()
+		local, err := source.DownloadRelease(releasesDir, lock)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(local.LocalPath).To(Equal(filepath.Join(releasesDir, "mango-1.2.3.tgz")))
+		please.Expect(local.Lock.SHA1).To(Equal(sum))
+		please.Expect(local.Lock.SHA256).To(Equal(sha256Sum))
+		please.Expect(os.ReadFile(local.LocalPath)).To(Equal([]byte(contents)))
+
+		please.Expect(container.authorizations).NotTo(BeEmpty())
+		for _, authorization := range container.authorizations {
+			please.Expect(authorization).To(HavePrefix("SharedKey devstoreaccount1:"))
+		}
+	})
+
+	t.Run("sas token", func(t *testing.T) {
+		please := NewWithT(t)
+		container := newFakeAzureContainer()
+		container.blobs["mango/mango-1.2.3.tgz"] = []byte(contents)
+		source := setup(t, container, cargo.ReleaseSourceConfig{SASToken: "?sv=2020-10-02&sig=some-signature"})
+
+		_, err := source.GetMatchedRelease(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "1.2.3"})
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(container.authorizations).To(ConsistOf(""))
+		please.Expect(container.signatures).To(ConsistOf("some-signature"))
+	})
+
+	t.Run("GetMatchedRelease when the blob does not exist", func(t *testing.T) {
+		please := NewWithT(t)
+		source := setup(t, newFakeAzureContainer(), cargo.ReleaseSourceConfig{AccountKey: accountKey})
+
+		_, err := source.GetMatchedRelease(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "1.2.3"})
+		please.Expect(component.IsErrNotFound(err)).To(BeTrue())
+	})
+
+	t.Run("ReleaseSignature", func(t *testing.T) {
+		please := NewWithT(t)
+		container := newFakeAzureContainer()
+		source := setup(t, container, cargo.ReleaseSourceConfig{AccountKey: accountKey})
+		lock := cargo.BOSHReleaseTarballLock{Name: "mango", Version: "1.2.3", RemotePath: "mango/mango-1.2.3.tgz"}
+
+		_, err := source.ReleaseSignature(lock)
+		please.Expect(component.IsErrNotFound(err)).To(BeTrue())
+
+		container.blobs["mango/mango-1.2.3.tgz.sig"] = []byte("c29tZSBzaWduYXR1cmU=\n")
+		please.Expect(source.ReleaseSignature(lock)).To(Equal("c29tZSBzaWduYXR1cmU="))
+	})
+
+	t.Run("FindReleaseVersion", func(t *testing.T) {
+		please := NewWithT(t)
+		container := newFakeAzureContainer()
+		container.pageSize = 1
+		for _, name := range []string{"mango/mango-1.2.3.tgz", "mango/mango-1.3.0.tgz", "mango/mango-2.0.0.tgz", "peach/peach-9.0.0.tgz"} {
+			container.blobs[name] = []byte(contents)
+		}
+		source := setup(t, container, cargo.ReleaseSourceConfig{AccountKey: accountKey})
+
+		lock, err := source.FindReleaseVersion(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "~1"}, false)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+			Name:         "mango",
+			Version:      "1.3.0",
+			RemotePath:   "mango/mango-1.3.0.tgz",
+			RemoteSource: "some-container",
+			SHA1:         sum,
+			SHA256:       sha256Sum,
+		}))
+	})
+}
+
+type fakeAzureContainer struct {
+	mu       sync.Mutex
+	blobs    map[string][]byte
+	pageSize int
+
+	authorizations []string
+	signatures     []string
+}
+
+func newFakeAzureContainer() *fakeAzureContainer {
+	return &fakeAzureContainer{blobs: make(map[string][]byte)}
+}
+
+func (container *fakeAzureContainer) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	container.mu.Lock()
+	defer container.mu.Unlock()
+
+	container.authorizations = append(container.authorizations, req.Header.Get("Authorization"))
+	if sig := req.URL.Query().Get("sig"); sig != "" {
+		container.signatures = append(container.signatures, sig)
+	}
+	if req.Header.Get("x-ms-version") == "" || req.Header.Get("x-ms-date") == "" {
+		http.Error(res, "missing x-ms headers", http.StatusBadRequest)
+		return
+	}
+
+	const containerPath = "/devstoreaccount1/some-container"
+	if req.URL.Path == containerPath && req.URL.Query().Get("comp") == "list" {
+		container.list(res, req)
+		return
+	}
+	if !strings.HasPrefix(req.URL.Path, containerPath+"/") {
+		http.NotFound(res, req)
+		return
+	}
+	name := strings.TrimPrefix(req.URL.Path, containerPath+"/")
+
+	switch req.Method {
+	case http.MethodPut:
+		if req.Header.Get("x-ms-blob-type") != "BlockBlob" {
+			http.Error(res, "unsupported blob type", http.StatusBadRequest)
+			return
+		}
+		buf, _ := io.ReadAll(req.Body)
+		container.blobs[name] = buf
+		res.WriteHeader(http.StatusCreated)
+	case http.MethodGet, http.MethodHead:
+		buf, ok := container.blobs[name]
+		if !ok {
+			http.NotFound(res, req)
+			return
+		}
+		if r := req.Header.Get("x-ms-range"); r != "" {
+			req.Header.Set("Range", r)
+		}
+		http.ServeContent(res, req, name, time.Time{}, bytes.NewReader(buf))
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (container *fakeAzureContainer) list(res http.ResponseWriter, req *http.Request) {
+	var names []string
+	for name := range container.blobs {
+		if strings.HasPrefix(name, req.URL.Query().Get("prefix")) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	start := sort.SearchStrings(names, req.URL.Query().Get("marker"))
+	end := len(names)
+	if container.pageSize > 0 && start+container.pageSize < end {
+		end = start + container.pageSize
+	}
+	type blob struct {
+		Name string `xml:"Name"`
+	}
+	var page struct {
+		XMLName    xml.Name `xml:"EnumerationResults"`
+		Blobs      []blob   `xml:"Blobs>Blob"`
+		NextMarker string   `xml:"NextMarker"`
+	}
+	for _, name := range names[start:end] {
+		page.Blobs = append(page.Blobs, blob{Name: name})
+	}
+	if end < len(names) {
+		page.NextMarker = names[end]
+	}
+	res.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(res).Encode(page)
+}
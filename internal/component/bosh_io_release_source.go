@@ -1,8 +1,6 @@
 package component
 
 import (
-	"crypto/sha1"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -142,40 +140,36 @@ func (src BOSHIOReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTarballS
 func (src BOSHIOReleaseSource) DownloadRelease(releaseDir string, remoteRelease cargo.BOSHReleaseTarballLock) (Local, error) {
 	src.logger.Printf(logLineDownload, remoteRelease.Name, ReleaseSourceTypeBOSHIO, src.ID())
 
-	downloadURL := remoteRelease.RemotePath
+	filePath := filepath.Join(releaseDir, fmt.Sprintf("%s-%s.tgz", remoteRelease.Name, remoteRelease.Version))
 
-	resp, err := http.Get(downloadURL)
+	out, err := openPartialDownload(filePath)
 	if err != nil {
 		return Local{}, err
 	}
+	defer closeAndIgnoreError(out)
 
-	filePath := filepath.Join(releaseDir, fmt.Sprintf("%s-%s.tgz", remoteRelease.Name, remoteRelease.Version))
-
-	out, err := os.Create(filePath)
+	req, err := http.NewRequest(http.MethodGet, remoteRelease.RemotePath, nil)
 	if err != nil {
 		return Local{}, err
 	}
-	defer closeAndIgnoreError(out)
+	out.setRange(req)
 
-	_, err = io.Copy(out, resp.Body)
-	_ = resp.Body.Close()
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return Local{}, err
 	}
 
-	_, err = out.Seek(0, 0)
+	err = out.copyResponse(resp)
+	_ = resp.Body.Close()
 	if err != nil {
-		return Local{}, fmt.Errorf("error reseting file cursor: %w", err) // untested
+		return Local{}, err
 	}
 
-	hash := sha1.New()
-	_, err = io.Copy(hash, out)
+	remoteRelease.SHA1, remoteRelease.SHA256, err = out.complete()
 	if err != nil {
-		return Local{}, fmt.Errorf("error hashing file contents: %w", err) // untested
+		return Local{}, err
 	}
 
-	remoteRelease.SHA1 = hex.EncodeToString(hash.Sum(nil))
-
 	return Local{Lock: remoteRelease, LocalPath: filePath}, nil
 }
 
@@ -2,6 +2,7 @@ package component_test
 
 import (
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -229,7 +230,7 @@ var _ = Describe("BOSHIOReleaseSource", func() {
 			release1ID cargo.BOSHReleaseTarballSpecification
 			release1   cargo.BOSHReleaseTarballLock
 
-			release1Sha1 string
+			release1Sha1, release1Sha256 string
 		)
 
 		BeforeEach(func() {
@@ -249,6 +250,8 @@ var _ = Describe("BOSHIOReleaseSource", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			release1Sha1 = hex.EncodeToString(hash.Sum(nil))
+			sum256 := sha256.Sum256([]byte(release1ServerFileContents))
+			release1Sha256 = hex.EncodeToString(sum256[:])
 
 			testServer.RouteToHandler("GET", release1ServerPath,
 				ghttp.RespondWith(http.StatusOK, release1ServerFileContents,
@@ -276,6 +279,7 @@ var _ = Describe("BOSHIOReleaseSource", func() {
 
 			lock := release1ID.Lock()
 			lock.SHA1 = release1Sha1
+			lock.SHA256 = release1Sha256
 			Expect(localRelease).To(Equal(
 				component.Local{
 					Lock: lock.WithRemote(
@@ -286,6 +290,40 @@ var _ = Describe("BOSHIOReleaseSource", func() {
 				},
 			))
 		})
+
+		When("a partial download exists", func() {
+			BeforeEach(func() {
+				partialPath := filepath.Join(releaseDir, release1Filename+component.PartialDownloadSuffix)
+				Expect(os.WriteFile(partialPath, []byte(release1ServerFileContents[:6]), 0o644)).To(Succeed())
+
+				testServer.RouteToHandler("GET", release1ServerPath, ghttp.CombineHandlers(
+					ghttp.VerifyHeaderKV("Range", "bytes=6-"),
+					ghttp.RespondWith(http.StatusPartialContent, release1ServerFileContents[6:], nil),
+				))
+			})
+
+			It("requests only the remaining bytes", func() {
+				localRelease, err := releaseSource.DownloadRelease(releaseDir, release1)
+				Expect(err).NotTo(HaveOccurred())
+
+				release1DiskContents, err := os.ReadFile(localRelease.LocalPath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(release1DiskContents).To(BeEquivalentTo(release1ServerFileContents))
+				Expect(localRelease.Lock.SHA1).To(Equal(release1Sha1))
+			})
+		})
+
+		When("the server responds with an error", func() {
+			BeforeEach(func() {
+				testServer.RouteToHandler("GET", release1ServerPath, ghttp.RespondWith(http.StatusBadGateway, ""))
+			})
+
+			It("does not create the release file", func() {
+				_, err := releaseSource.DownloadRelease(releaseDir, release1)
+				Expect(err).To(HaveOccurred())
+				Expect(filepath.Join(releaseDir, release1Filename)).NotTo(BeAnExistingFile())
+			})
+		})
 	})
 
 	Describe("FindReleaseVersion from bosh.io", func() {
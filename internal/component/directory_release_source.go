@@ -0,0 +1,273 @@
+package component
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-git/go-billy/v5/osfs"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// DirectoryReleaseSource reads and writes release tarballs in a directory on the local
+// filesystem, such as a mounted NFS share. The path_template is evaluated like it is for
+// S3ReleaseSource, except the result is a path relative to the configured path.
+type DirectoryReleaseSource struct {
+	cargo.ReleaseSourceConfig
+
+	logger *log.Logger
+}
+
+// NewDirectoryReleaseSource will provision a new DirectoryReleaseSource from the Kilnfile
+// (ReleaseSourceConfig). If type is incorrect or a required field is missing it will PANIC
+func NewDirectoryReleaseSource(c cargo.ReleaseSourceConfig, logger *log.Logger) DirectoryReleaseSource {
+	if c.Type != "" && c.Type != ReleaseSourceTypeDirectory {
+		panic(panicMessageWrongReleaseSourceType)
+	}
+	if c.PathTemplate == "" {
+		panic(`Missing required field "path_template" in release source config. Is your Kilnfile out of date?`)
+	}
+	if c.Path == "" {
+		panic(`Missing required field "path" in release source config. Is your Kilnfile out of date?`)
+	}
+	if logger == nil {
+		logger = log.New(os.Stderr, "[directory release source] ", log.Default().Flags())
+	}
+	return DirectoryReleaseSource{
+		ReleaseSourceConfig: c,
+		logger:              logger,
+	}
+}
+
+func (src DirectoryReleaseSource) ID() string { return src.ReleaseSourceConfig.ID }
+
+func (src DirectoryReleaseSource) Configuration() cargo.ReleaseSourceConfig {
+	return src.ReleaseSourceConfig
+}
+
+// GetMatchedRelease checks that the file for the release exists. It does not calculate the
+// SHA1 sum.
+func (src DirectoryReleaseSource) GetMatchedRelease(spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	_, err = os.Stat(src.filePath(remotePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+		}
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	return cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      spec.Version,
+		RemotePath:   remotePath,
+		RemoteSource: src.ID(),
+	}, nil
+}
+
+// FindReleaseVersion finds the files matching the path_template for every version and
+// returns the highest version that satisfies the specification. Since the files are local
+// the SHA1 sum is always calculated.
+func (src DirectoryReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTarballSpecification, _ bool) (cargo.BOSHReleaseTarballLock, error) {
+	constraint, err := spec.VersionConstraints()
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	pattern, walkRoot, err := src.versionPattern(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	var (
+		highest    *semver.Version
+		remotePath string
+	)
+	err = filepath.WalkDir(src.filePath(walkRoot), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(src.Path, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		version, ok := matchVersion(pattern, rel)
+		if !ok {
+			return nil
+		}
+		v, err := semver.NewVersion(version)
+		if err != nil || !constraint.Check(v) {
+			return nil
+		}
+		if highest == nil || v.GreaterThan(highest) {
+			highest, remotePath = v, rel
+		}
+		return nil
+	})
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	if highest == nil {
+		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+	}
+
+	sha1Sum, sha256Sum, err := CalculateSums(src.filePath(remotePath), osfs.New(""))
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	return cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      highest.Original(),
+		SHA1:         sha1Sum,
+		SHA256:       sha256Sum,
+		RemotePath:   remotePath,
+		RemoteSource: src.ID(),
+	}, nil
+}
+
+// DownloadRelease hardlinks or copies the release file into releaseDir.
+func (src DirectoryReleaseSource) DownloadRelease(releaseDir string, lock cargo.BOSHReleaseTarballLock) (Local, error) {
+	src.logger.Printf(logLineDownload, lock.Name, ReleaseSourceTypeDirectory, src.ID())
+
+	outputFile := filepath.Join(releaseDir, path.Base(lock.RemotePath))
+	err := linkOrCopy(src.filePath(lock.RemotePath), outputFile)
+	if err != nil {
+		return Local{}, fmt.Errorf("failed to copy release from %s: %w", src.ID(), err)
+	}
+
+	lock.SHA1, lock.SHA256, err = CalculateSums(outputFile, osfs.New(""))
+	if err != nil {
+		return Local{}, err
+	}
+
+	return Local{Lock: lock, LocalPath: outputFile}, nil
+}
+
+// ReleaseSignature reads the signature file next to the release file.
+func (src DirectoryReleaseSource) ReleaseSignature(lock cargo.BOSHReleaseTarballLock) (string, error) {
+	data, err := os.ReadFile(src.filePath(lock.RemotePath + signatureFileSuffix))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return encodeSignature(data), nil
+}
+
+// UploadRelease writes the release to the path from path_template, creating any parent
+// directories. The file is moved into place once it is complete.
+func (src DirectoryReleaseSource) UploadRelease(spec cargo.BOSHReleaseTarballSpecification, file io.Reader) (cargo.BOSHReleaseTarballLock, error) {
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	src.logger.Printf("uploading release %q to %s at %q...\n", spec.Name, src.ID(), remotePath)
+
+	outputFile := src.filePath(remotePath)
+	err = os.MkdirAll(filepath.Dir(outputFile), 0o755)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	tmp := outputFile + temporaryFileSuffix
+	defer func() { _ = os.Remove(tmp) }()
+	out, err := os.Create(tmp)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	defer closeAndIgnoreError(out)
+
+	hash := newReleaseHash()
+	_, err = io.Copy(io.MultiWriter(out, hash), file)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	if err := out.Close(); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	if err := os.Rename(tmp, outputFile); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	lock := cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      spec.Version,
+		RemotePath:   remotePath,
+		RemoteSource: src.ID(),
+	}
+	lock.SHA1, lock.SHA256 = hash.sums()
+	return lock, nil
+}
+
+func (src DirectoryReleaseSource) RemotePath(spec cargo.BOSHReleaseTarballSpecification) (string, error) {
+	return evaluatePathTemplate(src.ReleaseSourceConfig.PathTemplate, spec)
+}
+
+func (src DirectoryReleaseSource) filePath(remotePath string) string {
+	return filepath.Join(src.Path, filepath.FromSlash(remotePath))
+}
+
+// versionPlaceholder is substituted for the version when the path_template is turned into
+// a pattern. It must not be changed by regexp.QuoteMeta.
+const versionPlaceholder = "KILNVERSIONPLACEHOLDER"
+
+// versionPattern evaluates the path_template with a placeholder version and returns a
+// pattern matching the path of any version of the release. It also returns the deepest
+// directory that does not depend on the version so fewer files need to be walked.
+func (src DirectoryReleaseSource) versionPattern(spec cargo.BOSHReleaseTarballSpecification) (*regexp.Regexp, string, error) {
+	spec.Version = versionPlaceholder
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return nil, "", err
+	}
+	i := strings.Index(remotePath, versionPlaceholder)
+	if i < 0 {
+		return nil, "", fmt.Errorf("path_template for %s does not use the release version", src.ID())
+	}
+
+	exp := strings.ReplaceAll(regexp.QuoteMeta(remotePath), versionPlaceholder, `([^/]+)`)
+	pattern, err := regexp.Compile("^" + exp + "$")
+	if err != nil {
+		return nil, "", err
+	}
+	return pattern, path.Dir(remotePath[:i] + "x"), nil
+}
+
+// matchVersion returns the version from a path matching the pattern from versionPattern.
+// When the version appears more than once in the path, every occurrence must be the same.
+func matchVersion(pattern *regexp.Regexp, remotePath string) (string, bool) {
+	match := pattern.FindStringSubmatch(remotePath)
+	if match == nil {
+		return "", false
+	}
+	for _, v := range match[2:] {
+		if v != match[1] {
+			return "", false
+		}
+	}
+	return match[1], true
+}
@@ -0,0 +1,146 @@
+package component_test
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+func TestDirectoryReleaseSource(t *testing.T) {
+	const (
+		contents  = "some release contents"
+		sum       = "641c0d1e5d0578ededf8cd76f5d8399e867f6f6c"
+		sha256Sum = "ca6838db82f1df4b7456c468b3035aee107a19caaafb65e2b5e68f037d8bb6cc"
+	)
+
+	setup := func(t *testing.T) component.DirectoryReleaseSource {
+		t.Helper()
+		return component.NewDirectoryReleaseSource(cargo.ReleaseSourceConfig{
+			Type:         component.ReleaseSourceTypeDirectory,
+			ID:           "nfs",
+			Path:         t.TempDir(),
+			PathTemplate: "{{.StemcellOS}}/{{.StemcellVersion}}/{{.Name}}/{{.Name}}-{{.Version}}-{{.StemcellOS}}-{{.StemcellVersion}}.tgz",
+		}, log.New(io.Discard, "", 0))
+	}
+
+	writeRelease := func(t *testing.T, src component.DirectoryReleaseSource, remotePath, contents string) {
+		t.Helper()
+		p := filepath.Join(src.Path, filepath.FromSlash(remotePath))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spec := cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "1.2.3", StemcellOS: "smoothie", StemcellVersion: "9.9"}
+
+	t.Run("interface compliance", func(t *testing.T) {
+		please := NewWithT(t)
+		var source interface{} = component.DirectoryReleaseSource{}
+		_, isReleaseSource := source.(component.ReleaseSource)
+		_, isUploader := source.(component.ReleaseUploader)
+		_, isPather := source.(component.RemotePather)
+		_, isSignatureReader := source.(component.SignatureReader)
+		please.Expect(isReleaseSource).To(BeTrue())
+		please.Expect(isUploader).To(BeTrue())
+		please.Expect(isPather).To(BeTrue())
+		please.Expect(isSignatureReader).To(BeTrue())
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		please := NewWithT(t)
+		please.Expect(func() {
+			component.NewDirectoryReleaseSource(cargo.ReleaseSourceConfig{PathTemplate: "{{.Name}}.tgz"}, nil)
+		}).To(Panic())
+	})
+
+	t.Run("upload and download", func(t *testing.T) {
+		please := NewWithT(t)
+		src := setup(t)
+
+		lock, err := src.UploadRelease(spec, strings.NewReader(contents))
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+			Name: "mango", Version: "1.2.3", SHA1: sum, SHA256: sha256Sum,
+			RemotePath:   "smoothie/9.9/mango/mango-1.2.3-smoothie-9.9.tgz",
+			RemoteSource: "nfs",
+		}))
+		please.Expect(filepath.Join(src.Path, "smoothie", "9.9", "mango", "mango-1.2.3-smoothie-9.9.tgz")).To(BeAnExistingFile())
+
+		releasesDir := t.TempDir()
+		local, err := src.DownloadRelease(releasesDir, lock)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(local.LocalPath).To(Equal(filepath.Join(releasesDir, "mango-1.2.3-smoothie-9.9.tgz")))
+		please.Expect(local.Lock.SHA1).To(Equal(sum))
+		please.Expect(local.Lock.SHA256).To(Equal(sha256Sum))
+		please.Expect(os.ReadFile(local.LocalPath)).To(BeEquivalentTo(contents))
+	})
+
+	t.Run("ReleaseSignature", func(t *testing.T) {
+		please := NewWithT(t)
+		src := setup(t)
+		lock := cargo.BOSHReleaseTarballLock{Name: "mango", Version: "1.2.3", RemotePath: "mango/mango-1.2.3.tgz"}
+
+		_, err := src.ReleaseSignature(lock)
+		please.Expect(component.IsErrNotFound(err)).To(BeTrue())
+
+		writeRelease(t, src, "mango/mango-1.2.3.tgz.sig", "c29tZSBzaWduYXR1cmU=\n")
+		please.Expect(src.ReleaseSignature(lock)).To(Equal("c29tZSBzaWduYXR1cmU="))
+
+		writeRelease(t, src, "mango/mango-1.2.3.tgz.sig", "\x30\x45\x02\xff")
+		please.Expect(src.ReleaseSignature(lock)).To(Equal("MEUC/w=="), "binary signatures are base64 encoded")
+	})
+
+	t.Run("GetMatchedRelease", func(t *testing.T) {
+		please := NewWithT(t)
+		src := setup(t)
+
+		_, err := src.GetMatchedRelease(spec)
+		please.Expect(component.IsErrNotFound(err)).To(BeTrue())
+
+		writeRelease(t, src, "smoothie/9.9/mango/mango-1.2.3-smoothie-9.9.tgz", contents)
+
+		lock, err := src.GetMatchedRelease(spec)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+			Name: "mango", Version: "1.2.3",
+			RemotePath:   "smoothie/9.9/mango/mango-1.2.3-smoothie-9.9.tgz",
+			RemoteSource: "nfs",
+		}))
+	})
+
+	t.Run("FindReleaseVersion", func(t *testing.T) {
+		please := NewWithT(t)
+		src := setup(t)
+
+		writeRelease(t, src, "smoothie/9.9/mango/mango-1.2.3-smoothie-9.9.tgz", contents)
+		writeRelease(t, src, "smoothie/9.9/mango/mango-1.10.0-smoothie-9.9.tgz", contents)
+		writeRelease(t, src, "smoothie/9.9/mango/mango-2.0.0-smoothie-9.9.tgz", "other")
+		writeRelease(t, src, "smoothie/9.8/mango/mango-1.11.0-smoothie-9.8.tgz", "other")
+		writeRelease(t, src, "smoothie/9.9/mango/notes.txt", "other")
+
+		lock, err := src.FindReleaseVersion(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "~1", StemcellOS: "smoothie", StemcellVersion: "9.9"}, true)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+			Name: "mango", Version: "1.10.0", SHA1: sum, SHA256: sha256Sum,
+			RemotePath:   "smoothie/9.9/mango/mango-1.10.0-smoothie-9.9.tgz",
+			RemoteSource: "nfs",
+		}))
+
+		_, err = src.FindReleaseVersion(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "~3", StemcellOS: "smoothie", StemcellVersion: "9.9"}, true)
+		please.Expect(component.IsErrNotFound(err)).To(BeTrue())
+
+		_, err = src.FindReleaseVersion(cargo.BOSHReleaseTarballSpecification{Name: "banana", StemcellOS: "smoothie", StemcellVersion: "9.9"}, true)
+		please.Expect(component.IsErrNotFound(err)).To(BeTrue())
+	})
+}
@@ -0,0 +1,341 @@
+package component
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2/jwt"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// GCSReleaseSource stores releases in a Google Cloud Storage bucket using the JSON API. It
+// behaves like S3ReleaseSource: RemotePath evaluates the path_template and
+// FindReleaseVersion parses versions from the object names under the release prefix.
+//
+// Requests are authenticated with the service account key in the service_account_key
+// field. When it is empty, requests are not authenticated; this is useful for public
+// buckets and for emulators such as fake-gcs-server, whose URL can be set in the endpoint
+// field.
+type GCSReleaseSource struct {
+	cargo.ReleaseSourceConfig
+
+	// Client is used for every request. When it is nil, a client authenticated with the
+	// service account key is created on first use.
+	Client *http.Client
+
+	DownloadThreads int
+
+	logger *log.Logger
+
+	clientOnce sync.Once
+	clientErr  error
+}
+
+const (
+	gcsDefaultEndpoint = "https://storage.googleapis.com"
+	gcsReadWriteScope  = "https://www.googleapis.com/auth/devstorage.read_write"
+)
+
+type gcsObject struct {
+	Name string `json:"name"`
+	Size string `json:"size"`
+}
+
+// NewGCSReleaseSource will provision a new GCSReleaseSource from the Kilnfile
+// (ReleaseSourceConfig). If type is incorrect or a required field is missing it will PANIC
+func NewGCSReleaseSource(c cargo.ReleaseSourceConfig, logger *log.Logger) *GCSReleaseSource {
+	if c.Type != "" && c.Type != ReleaseSourceTypeGCS {
+		panic(panicMessageWrongReleaseSourceType)
+	}
+	validateConfig(c)
+	if logger == nil {
+		logger = log.New(os.Stderr, "[GCS release source] ", log.Default().Flags())
+	}
+	src := &GCSReleaseSource{
+		ReleaseSourceConfig: c,
+		logger:              logger,
+	}
+	if c.ServiceAccountKey == "" {
+		src.Client = http.DefaultClient
+	}
+	return src
+}
+
+func (src *GCSReleaseSource) ID() string { return src.ReleaseSourceConfig.ID }
+
+func (src *GCSReleaseSource) Configuration() cargo.ReleaseSourceConfig {
+	return src.ReleaseSourceConfig
+}
+
+func (src *GCSReleaseSource) RemotePath(spec cargo.BOSHReleaseTarballSpecification) (string, error) {
+	return evaluatePathTemplate(src.ReleaseSourceConfig.PathTemplate, spec)
+}
+
+func (src *GCSReleaseSource) GetMatchedRelease(spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	_, err = src.getObject(remotePath)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	return cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      spec.Version,
+		RemotePath:   remotePath,
+		RemoteSource: src.ID(),
+	}, nil
+}
+
+func (src *GCSReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
+	keys, err := src.listObjects(objectKeyPrefix(src.ReleaseSourceConfig.PathTemplate, spec.Name))
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	foundRelease, err := findReleaseVersionInObjectKeys(spec, keys, src.ID())
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	if noDownload {
+		foundRelease.SHA1 = "not-calculated"
+	} else {
+		releaseLocal, err := src.DownloadRelease(os.TempDir(), foundRelease)
+		if err != nil {
+			return cargo.BOSHReleaseTarballLock{}, err
+		}
+		foundRelease.SHA1 = releaseLocal.Lock.SHA1
+		foundRelease.SHA256 = releaseLocal.Lock.SHA256
+	}
+	return foundRelease, nil
+}
+
+// DownloadRelease downloads the object in DownloadThreads parts requested in parallel.
+func (src *GCSReleaseSource) DownloadRelease(releaseDir string, lock cargo.BOSHReleaseTarballLock) (Local, error) {
+	src.logger.Printf(logLineDownload, lock.Name, ReleaseSourceTypeGCS, src.ID())
+
+	object, err := src.getObject(lock.RemotePath)
+	if err != nil {
+		return Local{}, fmt.Errorf("failed to get %s from %s: %w", lock.RemotePath, src.ID(), err)
+	}
+	size, err := strconv.ParseInt(object.Size, 10, 64)
+	if err != nil {
+		return Local{}, fmt.Errorf("object %s has an invalid size: %w", lock.RemotePath, err)
+	}
+
+	outputFile := filepath.Join(releaseDir, filepath.Base(lock.RemotePath))
+	file, err := openPartialDownload(outputFile)
+	if err != nil {
+		return Local{}, err
+	}
+	defer closeAndIgnoreError(file)
+
+	client, err := src.client()
+	if err != nil {
+		return Local{}, err
+	}
+	err = downloadParts(file, size, src.DownloadThreads, func(first, last int64) (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, src.objectURL(lock.RemotePath)+"?alt=media", nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", first, last))
+		return client.Do(req)
+	})
+	if err != nil {
+		return Local{}, fmt.Errorf("failed to download file: %w", err)
+	}
+
+	lock.SHA1, lock.SHA256, err = file.complete()
+	if err != nil {
+		return Local{}, err
+	}
+
+	return Local{Lock: lock, LocalPath: outputFile}, nil
+}
+
+// ReleaseSignature downloads the signature object next to the release object.
+func (src *GCSReleaseSource) ReleaseSignature(lock cargo.BOSHReleaseTarballLock) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, src.objectURL(lock.RemotePath+signatureFileSuffix)+"?alt=media", nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := src.do(req)
+	if err != nil {
+		return "", err
+	}
+	return readSignatureResponse(res)
+}
+
+func (src *GCSReleaseSource) UploadRelease(spec cargo.BOSHReleaseTarballSpecification, file io.Reader) (cargo.BOSHReleaseTarballLock, error) {
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	src.logger.Printf("uploading release %q to %s at %q...\n", spec.Name, src.ID(), remotePath)
+
+	hash := newReleaseHash()
+	tmp, size, err := spoolUpload(io.TeeReader(file, hash))
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	defer func() {
+		closeAndIgnoreError(tmp)
+		_ = os.Remove(tmp.Name())
+	}()
+
+	query := url.Values{"uploadType": {"media"}, "name": {remotePath}}
+	req, err := http.NewRequest(http.MethodPost, src.endpoint()+"/upload/storage/v1/b/"+url.PathEscape(src.Bucket)+"/o?"+query.Encode(), io.NopCloser(tmp))
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+
+	res, err := src.do(req)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	closeAndIgnoreError(res.Body)
+	if err := checkStatus(http.StatusOK, res.StatusCode); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, fmt.Errorf("failed to upload %s: %w", remotePath, err)
+	}
+
+	lock := cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      spec.Version,
+		RemotePath:   remotePath,
+		RemoteSource: src.ID(),
+	}
+	lock.SHA1, lock.SHA256 = hash.sums()
+	return lock, nil
+}
+
+func (src *GCSReleaseSource) endpoint() string {
+	if src.Endpoint != "" {
+		return strings.TrimSuffix(src.Endpoint, "/")
+	}
+	return gcsDefaultEndpoint
+}
+
+func (src *GCSReleaseSource) objectURL(name string) string {
+	return src.endpoint() + "/storage/v1/b/" + url.PathEscape(src.Bucket) + "/o/" + url.PathEscape(name)
+}
+
+func (src *GCSReleaseSource) getObject(name string) (gcsObject, error) {
+	req, err := http.NewRequest(http.MethodGet, src.objectURL(name), nil)
+	if err != nil {
+		return gcsObject{}, err
+	}
+	res, err := src.do(req)
+	if err != nil {
+		return gcsObject{}, err
+	}
+	var object gcsObject
+	return object, decodeJSONResponse(res, &object)
+}
+
+func (src *GCSReleaseSource) listObjects(prefix string) ([]string, error) {
+	var (
+		names     []string
+		pageToken string
+	)
+	for {
+		query := url.Values{"prefix": {prefix}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		req, err := http.NewRequest(http.MethodGet, src.endpoint()+"/storage/v1/b/"+url.PathEscape(src.Bucket)+"/o?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		res, err := src.do(req)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items         []gcsObject `json:"items"`
+			NextPageToken string      `json:"nextPageToken"`
+		}
+		if err := decodeJSONResponse(res, &page); err != nil {
+			return nil, fmt.Errorf("failed to list objects in %s: %w", src.Bucket, err)
+		}
+		for _, item := range page.Items {
+			names = append(names, item.Name)
+		}
+		if page.NextPageToken == "" {
+			return names, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func (src *GCSReleaseSource) do(req *http.Request) (*http.Response, error) {
+	client, err := src.client()
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, wrapVPNError(err)
+	}
+	return res, nil
+}
+
+func (src *GCSReleaseSource) client() (*http.Client, error) {
+	src.clientOnce.Do(func() {
+		if src.Client != nil {
+			return
+		}
+		var key struct {
+			ClientEmail  string `json:"client_email"`
+			PrivateKey   string `json:"private_key"`
+			PrivateKeyID string `json:"private_key_id"`
+			TokenURI     string `json:"token_uri"`
+		}
+		if err := json.Unmarshal([]byte(src.ServiceAccountKey), &key); err != nil {
+			src.clientErr = fmt.Errorf("failed to parse service_account_key for %s: %w", src.ID(), err)
+			return
+		}
+		if key.TokenURI == "" {
+			key.TokenURI = "https://oauth2.googleapis.com/token"
+		}
+		conf := &jwt.Config{
+			Email:        key.ClientEmail,
+			PrivateKey:   []byte(key.PrivateKey),
+			PrivateKeyID: key.PrivateKeyID,
+			Scopes:       []string{gcsReadWriteScope},
+			TokenURL:     key.TokenURI,
+		}
+		src.Client = conf.Client(context.Background())
+	})
+	return src.Client, src.clientErr
+}
+
+func decodeJSONResponse(res *http.Response, v interface{}) error {
+	defer closeAndIgnoreError(res.Body)
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return ErrNotFound
+	default:
+		return checkStatus(http.StatusOK, res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
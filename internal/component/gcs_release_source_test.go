@@ -0,0 +1,208 @@
+package component_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+func TestGCSReleaseSource(t *testing.T) {
+	const (
+		contents  = "some release contents"
+		sum       = "641c0d1e5d0578ededf8cd76f5d8399e867f6f6c"
+		sha256Sum = "ca6838db82f1df4b7456c468b3035aee107a19caaafb65e2b5e68f037d8bb6cc"
+	)
+
+	setup := func(t *testing.T, bucket *fakeGCSBucket) *component.GCSReleaseSource {
+		t.Helper()
+		server := httptest.NewServer(bucket)
+		t.Cleanup(server.Close)
+		source := component.NewGCSReleaseSource(cargo.ReleaseSourceConfig{
+			Type:         component.ReleaseSourceTypeGCS,
+			ID:           "some-bucket",
+			Bucket:       "some-bucket",
+			Endpoint:     server.URL,
+			PathTemplate: "{{.Name}}/{{.Name}}-{{.Version}}.tgz",
+		}, log.New(io.Discard, "", 0))
+		source.Client = server.Client()
+		return source
+	}
+
+	t.Run("interface compliance", func(t *testing.T) {
+		please := NewWithT(t)
+		var source interface{} = new(component.GCSReleaseSource)
+		_, isReleaseSource := source.(component.ReleaseSource)
+		_, isUploader := source.(component.ReleaseUploader)
+		_, isPather := source.(component.RemotePather)
+		_, isSignatureReader := source.(component.SignatureReader)
+		please.Expect(isReleaseSource).To(BeTrue())
+		please.Expect(isUploader).To(BeTrue())
+		please.Expect(isPather).To(BeTrue())
+		please.Expect(isSignatureReader).To(BeTrue())
+	})
+
+	t.Run("missing bucket", func(t *testing.T) {
+		please := NewWithT(t)
+		please.Expect(func() {
+			component.NewGCSReleaseSource(cargo.ReleaseSourceConfig{Type: component.ReleaseSourceTypeGCS, PathTemplate: "{{.Name}}.tgz"}, nil)
+		}).To(Panic())
+	})
+
+	t.Run("upload and download", func(t *testing.T) {
+		please := NewWithT(t)
+		bucket := newFakeGCSBucket()
+		source := setup(t, bucket)
+		spec := cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "1.2.3"}
+
+		lock, err := source.UploadRelease(spec, strings.NewReader(contents))
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(lock.RemotePath).To(Equal("mango/mango-1.2.3.tgz"))
+		please.Expect(lock.RemoteSource).To(Equal("some-bucket"))
+		please.Expect(bucket.objects).To(HaveKeyWithValue("mango/mango-1.2.3.tgz", []byte(contents)))
+
+		matched, err := source.GetMatchedRelease(spec)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(lock.SHA1).To(Equal(sum))
+		please.Expect(lock.SHA256).To(Equal(sha256Sum))
+		withoutSums := lock
+		withoutSums.SHA1, withoutSums.SHA256 = "", ""
+		please.Expect(matched).To(Equal(withoutSums))
+
+		releasesDir := t.TempDir()
+		local, err := source.DownloadRelease(releasesDir, lock)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(local.LocalPath).To(Equal(filepath.Join(releasesDir, "mango-1.2.3.tgz")))
+		please.Expect(local.Lock.SHA1).To(Equal(sum))
+		please.Expect(local.Lock.SHA256).To(Equal(sha256Sum))
+		please.Expect(os.ReadFile(local.LocalPath)).To(Equal([]byte(contents)))
+	})
+
+	t.Run("GetMatchedRelease when the object does not exist", func(t *testing.T) {
+		please := NewWithT(t)
+		source := setup(t, newFakeGCSBucket())
+
+		_, err := source.GetMatchedRelease(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "1.2.3"})
+		please.Expect(component.IsErrNotFound(err)).To(BeTrue())
+	})
+
+	t.Run("ReleaseSignature", func(t *testing.T) {
+		please := NewWithT(t)
+		bucket := newFakeGCSBucket()
+		source := setup(t, bucket)
+		lock := cargo.BOSHReleaseTarballLock{Name: "mango", Version: "1.2.3", RemotePath: "mango/mango-1.2.3.tgz"}
+
+		_, err := source.ReleaseSignature(lock)
+		please.Expect(component.IsErrNotFound(err)).To(BeTrue())
+
+		bucket.objects["mango/mango-1.2.3.tgz.sig"] = []byte("c29tZSBzaWduYXR1cmU=\n")
+		please.Expect(source.ReleaseSignature(lock)).To(Equal("c29tZSBzaWduYXR1cmU="))
+	})
+
+	t.Run("FindReleaseVersion", func(t *testing.T) {
+		please := NewWithT(t)
+		bucket := newFakeGCSBucket()
+		bucket.pageSize = 1
+		for _, name := range []string{"mango/mango-1.2.3.tgz", "mango/mango-1.3.0.tgz", "mango/mango-2.0.0.tgz", "mango/README.md", "peach/peach-9.0.0.tgz"} {
+			bucket.objects[name] = []byte(contents)
+		}
+		source := setup(t, bucket)
+
+		lock, err := source.FindReleaseVersion(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "~1"}, true)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+			Name:         "mango",
+			Version:      "1.3.0",
+			RemotePath:   "mango/mango-1.3.0.tgz",
+			RemoteSource: "some-bucket",
+			SHA1:         "not-calculated",
+		}))
+
+		_, err = source.FindReleaseVersion(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "~3"}, true)
+		please.Expect(component.IsErrNotFound(err)).To(BeTrue())
+	})
+}
+
+type fakeGCSBucket struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	pageSize int
+}
+
+func newFakeGCSBucket() *fakeGCSBucket {
+	return &fakeGCSBucket{objects: make(map[string][]byte)}
+}
+
+func (bucket *fakeGCSBucket) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+
+	const (
+		objectsPath = "/storage/v1/b/some-bucket/o"
+		uploadPath  = "/upload/storage/v1/b/some-bucket/o"
+	)
+	switch escapedPath := req.URL.EscapedPath(); {
+	case req.Method == http.MethodPost && escapedPath == uploadPath:
+		buf, _ := io.ReadAll(req.Body)
+		bucket.objects[req.URL.Query().Get("name")] = buf
+		_ = json.NewEncoder(res).Encode(map[string]string{"name": req.URL.Query().Get("name")})
+	case req.Method == http.MethodGet && escapedPath == objectsPath:
+		bucket.list(res, req)
+	case req.Method == http.MethodGet && strings.HasPrefix(escapedPath, objectsPath+"/"):
+		name, _ := url.PathUnescape(strings.TrimPrefix(escapedPath, objectsPath+"/"))
+		buf, ok := bucket.objects[name]
+		if !ok {
+			http.NotFound(res, req)
+			return
+		}
+		if req.URL.Query().Get("alt") == "media" {
+			http.ServeContent(res, req, name, time.Time{}, bytes.NewReader(buf))
+			return
+		}
+		_ = json.NewEncoder(res).Encode(map[string]string{"name": name, "size": strconv.Itoa(len(buf))})
+	default:
+		http.NotFound(res, req)
+	}
+}
+
+func (bucket *fakeGCSBucket) list(res http.ResponseWriter, req *http.Request) {
+	var names []string
+	for name := range bucket.objects {
+		if strings.HasPrefix(name, req.URL.Query().Get("prefix")) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	start, _ := strconv.Atoi(req.URL.Query().Get("pageToken"))
+	end := len(names)
+	if bucket.pageSize > 0 && start+bucket.pageSize < end {
+		end = start + bucket.pageSize
+	}
+	var page struct {
+		Items         []map[string]string `json:"items"`
+		NextPageToken string              `json:"nextPageToken,omitempty"`
+	}
+	for _, name := range names[start:end] {
+		page.Items = append(page.Items, map[string]string{"name": name, "size": strconv.Itoa(len(bucket.objects[name]))})
+	}
+	if end < len(names) {
+		page.NextPageToken = strconv.Itoa(end)
+	}
+	_ = json.NewEncoder(res).Encode(page)
+}
@@ -3,7 +3,6 @@ package component
 import (
 	"context"
 	"crypto/sha1"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -200,10 +199,10 @@ func (grs *GithubReleaseSource) getLockFromRelease(ctx context.Context, r *githu
 			continue
 		}
 
-		sum := "not-calculated"
+		sha1Sum, sha256Sum := "not-calculated", ""
 		if !noDownload {
 			var err error
-			sum, err = grs.getReleaseSHA1(ctx, s, *asset.ID)
+			sha1Sum, sha256Sum, err = grs.getReleaseSums(ctx, s, *asset.ID)
 			if err != nil {
 				return cargo.BOSHReleaseTarballLock{}, err
 			}
@@ -214,24 +213,32 @@ func (grs *GithubReleaseSource) getLockFromRelease(ctx context.Context, r *githu
 			Version:      lockVersion,
 			RemoteSource: grs.Org,
 			RemotePath:   asset.GetBrowserDownloadURL(),
-			SHA1:         sum,
+			SHA1:         sha1Sum,
+			SHA256:       sha256Sum,
 		}, nil
 	}
 
 	return cargo.BOSHReleaseTarballLock{}, fmt.Errorf("no matching GitHub release asset file name equal to %q", expectedAssetName)
 }
 
-func (grs *GithubReleaseSource) getReleaseSHA1(ctx context.Context, s cargo.BOSHReleaseTarballSpecification, id int64) (string, error) {
+func (grs *GithubReleaseSource) getReleaseSums(ctx context.Context, s cargo.BOSHReleaseTarballSpecification, id int64) (sha1Sum, sha256Sum string, _ error) {
 	repoOwner, repoName, err := gh.OwnerAndRepoFromURI(s.GitHubRepository)
 	if err != nil {
-		return "", fmt.Errorf("could not parse repository name: %v", err)
+		return "", "", fmt.Errorf("could not parse repository name: %v", err)
 	}
 
 	rc, _, err := grs.DownloadReleaseAsset(ctx, repoOwner, repoName, id, http.DefaultClient)
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+	defer closeAndIgnoreError(rc)
+
+	hash := newReleaseHash()
+	if _, err := io.Copy(hash, rc); err != nil {
+		return "", "", err
 	}
-	return calculateSHA1(rc)
+	sha1Sum, sha256Sum = hash.sums()
+	return sha1Sum, sha256Sum, nil
 }
 
 //counterfeiter:generate -o ./fakes/releases_lister.go --fake-name ReleasesLister . ReleasesLister
@@ -255,29 +262,22 @@ type ReleaseByTagGetterAssetDownloader interface {
 	ReleaseAssetDownloader
 }
 
-func downloadRelease(ctx context.Context, releaseDir string, remoteRelease cargo.BOSHReleaseTarballLock, client ReleaseByTagGetterAssetDownloader, logger *log.Logger) (Local, error) {
-	filePath := filepath.Join(releaseDir, fmt.Sprintf("%s-%s.tgz", remoteRelease.Name, remoteRelease.Version))
-
-	remoteUrl, err := url.Parse(remoteRelease.RemotePath)
+// Provenance returns the ID of the release asset. Assets are immutable, so a different ID
+// means the asset was deleted and uploaded again.
+func (grs *GithubReleaseSource) Provenance(lock cargo.BOSHReleaseTarballLock) (string, error) {
+	_, _, assetFile, err := findReleaseAsset(context.TODO(), lock, grs, grs.Logger)
 	if err != nil {
-		return Local{}, fmt.Errorf("failed to parse remote_path as url: %w", err)
+		return "", err
 	}
-	remotePathParts := strings.Split(remoteUrl.Path, "/")
-	// TODO: add test coverage for length
-	org, repo := remotePathParts[1], remotePathParts[2]
+	return fmt.Sprintf("github-asset-id:%d", assetFile.GetID()), nil
+}
 
-	rTag, _, err := client.GetReleaseByTag(ctx, org, repo, remoteRelease.Version)
-	if err != nil {
-		logger.Println("warning: failed to find release tag of ", remoteRelease.Version)
-		rTag, _, err = client.GetReleaseByTag(ctx, org, repo, "v"+remoteRelease.Version)
-		if err != nil {
-			return Local{}, fmt.Errorf("cant find release tag: %+v", err.Error())
-		}
-	}
+func downloadRelease(ctx context.Context, releaseDir string, remoteRelease cargo.BOSHReleaseTarballLock, client ReleaseByTagGetterAssetDownloader, logger *log.Logger) (Local, error) {
+	filePath := filepath.Join(releaseDir, fmt.Sprintf("%s-%s.tgz", remoteRelease.Name, remoteRelease.Version))
 
-	assetFile, found := findAssetFile(rTag.Assets, remoteRelease)
-	if !found {
-		return Local{}, errors.New("failed to download file for release: expected release asset not found")
+	org, repo, assetFile, err := findReleaseAsset(ctx, remoteRelease, client, logger)
+	if err != nil {
+		return Local{}, err
 	}
 
 	rc, _, err := client.DownloadReleaseAsset(ctx, org, repo, assetFile.GetID(), http.DefaultClient)
@@ -287,26 +287,58 @@ func downloadRelease(ctx context.Context, releaseDir string, remoteRelease cargo
 	}
 	defer closeAndIgnoreError(rc)
 
-	file, err := os.Create(filePath)
+	file, err := openPartialDownload(filePath)
 	if err != nil {
 		fmt.Printf("failed to create file for release: %+v: ", err)
 		return Local{}, err
 	}
 	defer closeAndIgnoreError(file)
 
-	hash := sha1.New()
+	// the asset download API does not support ranges so an interrupted download starts over
+	err = file.restart()
+	if err != nil {
+		return Local{}, err
+	}
 
-	mw := io.MultiWriter(file, hash)
-	_, err = io.Copy(mw, rc)
+	_, err = io.Copy(file, rc)
 	if err != nil {
-		return Local{}, fmt.Errorf("failed to calculate checksum for downloaded file: %+v: ", err)
+		return Local{}, fmt.Errorf("failed to download file for release: %w", err)
 	}
 
-	remoteRelease.SHA1 = hex.EncodeToString(hash.Sum(nil))
+	remoteRelease.SHA1, remoteRelease.SHA256, err = file.complete()
+	if err != nil {
+		return Local{}, err
+	}
 
 	return Local{Lock: remoteRelease, LocalPath: filePath}, nil
 }
 
+// findReleaseAsset finds the asset for the release in the repository from the remote path.
+func findReleaseAsset(ctx context.Context, remoteRelease cargo.BOSHReleaseTarballLock, client ReleaseByTagGetter, logger *log.Logger) (string, string, *github.ReleaseAsset, error) {
+	remoteUrl, err := url.Parse(remoteRelease.RemotePath)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to parse remote_path as url: %w", err)
+	}
+	remotePathParts := strings.Split(remoteUrl.Path, "/")
+	// TODO: add test coverage for length
+	org, repo := remotePathParts[1], remotePathParts[2]
+
+	rTag, _, err := client.GetReleaseByTag(ctx, org, repo, remoteRelease.Version)
+	if err != nil {
+		logger.Println("warning: failed to find release tag of ", remoteRelease.Version)
+		rTag, _, err = client.GetReleaseByTag(ctx, org, repo, "v"+remoteRelease.Version)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("cant find release tag: %+v", err.Error())
+		}
+	}
+
+	assetFile, found := findAssetFile(rTag.Assets, remoteRelease)
+	if !found {
+		return "", "", nil, errors.New("failed to download file for release: expected release asset not found")
+	}
+	return org, repo, assetFile, nil
+}
+
 type ReleaseAssetDownloader interface {
 	DownloadReleaseAsset(ctx context.Context, owner, repo string, id int64, followRedirectsClient *http.Client) (rc io.ReadCloser, redirectURL string, err error)
 }
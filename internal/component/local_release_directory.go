@@ -2,8 +2,10 @@ package component
 
 import (
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"log"
 	"os"
@@ -41,9 +43,13 @@ func (l LocalReleaseDirectory) GetLocalReleases(releasesDir string) ([]Local, er
 		rm := rel.Metadata.(builder.ReleaseManifest)
 		lock := cargo.BOSHReleaseTarballLock{Name: rm.Name, Version: rm.Version, StemcellOS: rm.StemcellOS, StemcellVersion: rm.StemcellVersion}
 
-		lock.SHA1, err = CalculateSum(rel.File, osfs.New(""))
-		if err != nil {
-			return nil, fmt.Errorf("couldn't calculate SHA1 sum of %q: %w", rel.File, err) // untested
+		// the release manifest reader hashes the tarball while it reads the manifest
+		lock.SHA1, lock.SHA256 = rm.SHA1, rm.SHA256
+		if lock.SHA1 == "" || lock.SHA256 == "" {
+			lock.SHA1, lock.SHA256, err = CalculateSums(rel.File, osfs.New(""))
+			if err != nil {
+				return nil, fmt.Errorf("couldn't calculate sums of %q: %w", rel.File, err) // untested
+			}
 		}
 
 		outputReleases = append(outputReleases, Local{Lock: lock, LocalPath: rel.File})
@@ -114,3 +120,40 @@ func CalculateSum(releasePath string, fs billy.Filesystem) (string, error) {
 
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
+
+// CalculateSums returns the hex encoded SHA1 and SHA256 sums of a release tarball.
+func CalculateSums(releasePath string, fs billy.Filesystem) (sha1Sum, sha256Sum string, _ error) {
+	f, err := fs.Open(releasePath)
+	if err != nil {
+		return "", "", err
+	}
+	defer closeAndIgnoreError(f)
+
+	h := newReleaseHash()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", "", err
+	}
+
+	sha1Sum, sha256Sum = h.sums()
+	return sha1Sum, sha256Sum, nil
+}
+
+// releaseHash calculates the SHA1 and SHA256 sums of a release tarball while it is
+// downloaded or uploaded so the file does not need to be read again.
+type releaseHash struct {
+	sha1, sha256 hash.Hash
+}
+
+func newReleaseHash() *releaseHash {
+	return &releaseHash{sha1: sha1.New(), sha256: sha256.New()}
+}
+
+func (h *releaseHash) Write(p []byte) (int, error) {
+	_, _ = h.sha1.Write(p)
+	return h.sha256.Write(p)
+}
+
+func (h *releaseHash) sums() (sha1Sum, sha256Sum string) {
+	return hex.EncodeToString(h.sha1.Sum(nil)), hex.EncodeToString(h.sha256.Sum(nil))
+}
@@ -0,0 +1,224 @@
+package component
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// The functions in this file are shared by the release sources backed by object storage
+// (S3, GCS, and Azure Blob). They find releases by listing object keys starting with a
+// prefix derived from the path_template and parsing versions out of the keys.
+
+// evaluatePathTemplate executes a path_template for a release specification. The
+// template has access to the fields of cargo.BOSHReleaseTarballSpecification and the
+// trimSuffix helper.
+func evaluatePathTemplate(pathTemplate string, spec cargo.BOSHReleaseTarballSpecification) (string, error) {
+	tmp, err := template.New("remote-path").
+		Funcs(template.FuncMap{"trimSuffix": strings.TrimSuffix}).
+		Parse(pathTemplate)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse path_template: %w", err)
+	}
+
+	pathBuf := new(bytes.Buffer)
+	err = tmp.Execute(pathBuf, spec)
+	if err != nil {
+		return "", fmt.Errorf("unable to evaluate path_template: %w", err)
+	}
+
+	return pathBuf.String(), nil
+}
+
+// objectKeyPrefix returns the prefix used to list the versions of a release. When the
+// path_template starts with a TAS version (for example "2.13/{{.Name}}/...") the prefix
+// includes it.
+func objectKeyPrefix(pathTemplate, releaseName string) string {
+	tasVersion := regexp.MustCompile(`^\d+\.\d+`).FindString(pathTemplate)
+	var prefix string
+	if tasVersion != "" {
+		prefix = tasVersion + "/"
+	}
+	return prefix + releaseName + "/"
+}
+
+// findReleaseVersionInObjectKeys returns a lock for the highest release version in keys
+// that satisfies the specification. The first version-like part of a key is the release
+// version; when there is more than one, the last is the stemcell version and must match
+// the specification. It does not set the SHA1 field.
+func findReleaseVersionInObjectKeys(spec cargo.BOSHReleaseTarballSpecification, keys []string, sourceID string) (cargo.BOSHReleaseTarballLock, error) {
+	semverPattern, err := regexp.Compile(`([-v])\d+(.\d+)*`)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	foundRelease := cargo.BOSHReleaseTarballLock{}
+	constraint, err := spec.VersionConstraints()
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	for _, key := range keys {
+		versions := semverPattern.FindAllString(key, -1)
+		if len(versions) == 0 {
+			continue
+		}
+		version := versions[0]
+		stemcellVersion := versions[len(versions)-1]
+		version = strings.Replace(version, "-", "", -1)
+		version = strings.Replace(version, "v", "", -1)
+		stemcellVersion = strings.Replace(stemcellVersion, "-", "", -1)
+		if len(versions) > 1 && stemcellVersion != spec.StemcellVersion {
+			continue
+		}
+		if version != "" {
+			newVersion, err := semver.NewVersion(version)
+			if err != nil || !constraint.Check(newVersion) {
+				continue
+			}
+
+			if (foundRelease == cargo.BOSHReleaseTarballLock{}) {
+				foundRelease = cargo.BOSHReleaseTarballLock{
+					Name:         spec.Name,
+					Version:      version,
+					RemotePath:   key,
+					RemoteSource: sourceID,
+				}
+			} else {
+				foundVersion, _ := semver.NewVersion(foundRelease.Version)
+				if newVersion.GreaterThan(foundVersion) {
+					foundRelease = cargo.BOSHReleaseTarballLock{
+						Name:         spec.Name,
+						Version:      version,
+						RemotePath:   key,
+						RemoteSource: sourceID,
+					}
+				}
+			}
+		}
+	}
+	if (foundRelease == cargo.BOSHReleaseTarballLock{}) {
+		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+	}
+	return foundRelease, nil
+}
+
+const defaultDownloadParts = 5
+
+// minimumDownloadPartSize keeps small releases from being split into many tiny requests.
+var minimumDownloadPartSize int64 = 8 << 20
+
+// downloadParts downloads the bytes of an object not yet in the partial download. The
+// remaining bytes are split into at most threads ranges which are requested concurrently.
+// The request function must return the response for an inclusive byte range.
+func downloadParts(out *partialDownload, size int64, threads int, request func(first, last int64) (*http.Response, error)) error {
+	remaining := size - out.offset
+	if remaining <= 0 {
+		return nil
+	}
+	if threads <= 0 {
+		threads = defaultDownloadParts
+	}
+	partSize := remaining / int64(threads)
+	if remaining%int64(threads) != 0 {
+		partSize++
+	}
+	if partSize < minimumDownloadPartSize {
+		partSize = minimumDownloadPartSize
+	}
+
+	var starts []int64
+	for start := int64(0); start < remaining; start += partSize {
+		starts = append(starts, start)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(starts))
+	for i, start := range starts {
+		end := start + partSize
+		if end > remaining {
+			end = remaining
+		}
+		wg.Add(1)
+		go func(i int, start, end int64) {
+			defer wg.Done()
+			errs[i] = downloadPart(out, start, end, request)
+		}(i, start, end)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		// parts after a failed one may have completed, but a resumed download can only
+		// continue from the end of the bytes downloaded without gaps
+		if truncateErr := out.file.Truncate(out.offset + starts[i]); truncateErr != nil {
+			return truncateErr // untested
+		}
+		return err
+	}
+	return nil
+}
+
+// downloadPart writes the bytes in [start, end) relative to the resume offset.
+func downloadPart(out *partialDownload, start, end int64, request func(first, last int64) (*http.Response, error)) error {
+	res, err := request(out.offset+start, out.offset+end-1)
+	if err != nil {
+		return err
+	}
+	defer closeAndIgnoreError(res.Body)
+	if err := checkStatus(http.StatusPartialContent, res.StatusCode); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 32<<10)
+	for offset := start; offset < end; {
+		n, err := res.Body.Read(buf)
+		if n > 0 {
+			if offset+int64(n) > end {
+				n = int(end - offset)
+			}
+			if _, err := out.WriteAt(buf[:n], offset); err != nil {
+				return err
+			}
+			offset += int64(n)
+		}
+		if err != nil {
+			if offset < end {
+				return fmt.Errorf("download of bytes %d-%d ended early: %w", out.offset+start, out.offset+end-1, err)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// spoolUpload copies a release to a temporary file so it can be uploaded with a known
+// length. The caller must close and remove the file.
+func spoolUpload(file io.Reader) (*os.File, int64, error) {
+	tmp, err := os.CreateTemp("", "kiln-upload-*.tgz")
+	if err != nil {
+		return nil, 0, err
+	}
+	size, err := io.Copy(tmp, file)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		closeAndIgnoreError(tmp)
+		_ = os.Remove(tmp.Name())
+		return nil, 0, fmt.Errorf("failed to read release: %w", err)
+	}
+	return tmp, size, nil
+}
@@ -0,0 +1,98 @@
+package component
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_downloadParts(t *testing.T) {
+	const contents = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+	defer func(size int64) { minimumDownloadPartSize = size }(minimumDownloadPartSize)
+	minimumDownloadPartSize = 4
+
+	serveRange := func(fail func(first int64) bool) func(first, last int64) (*http.Response, error) {
+		return func(first, last int64) (*http.Response, error) {
+			if fail(first) {
+				return nil, errors.New("banana")
+			}
+			return &http.Response{
+				StatusCode: http.StatusPartialContent,
+				Body:       io.NopCloser(strings.NewReader(contents[first : last+1])),
+			}, nil
+		}
+	}
+
+	t.Run("all parts succeed", func(t *testing.T) {
+		please := NewWithT(t)
+		outputFile := filepath.Join(t.TempDir(), "release.tgz")
+		out, err := openPartialDownload(outputFile)
+		please.Expect(err).NotTo(HaveOccurred())
+		defer closeAndIgnoreError(out)
+
+		err = downloadParts(out, int64(len(contents)), 3, serveRange(func(int64) bool { return false }))
+		please.Expect(err).NotTo(HaveOccurred())
+		_, _, err = out.complete()
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(os.ReadFile(outputFile)).To(Equal([]byte(contents)))
+	})
+
+	t.Run("a part fails", func(t *testing.T) {
+		please := NewWithT(t)
+		outputFile := filepath.Join(t.TempDir(), "release.tgz")
+		out, err := openPartialDownload(outputFile)
+		please.Expect(err).NotTo(HaveOccurred())
+
+		err = downloadParts(out, int64(len(contents)), 3, serveRange(func(first int64) bool { return first == 12 }))
+		please.Expect(err).To(MatchError("banana"))
+		closeAndIgnoreError(out)
+
+		please.Expect(os.ReadFile(outputFile+PartialDownloadSuffix)).To(Equal([]byte(contents[:12])), "it keeps only the bytes before the failed part")
+
+		t.Run("resuming", func(t *testing.T) {
+			please := NewWithT(t)
+			out, err := openPartialDownload(outputFile)
+			please.Expect(err).NotTo(HaveOccurred())
+			defer closeAndIgnoreError(out)
+
+			var (
+				mu        sync.Mutex
+				requested []string
+			)
+			err = downloadParts(out, int64(len(contents)), 3, func(first, last int64) (*http.Response, error) {
+				mu.Lock()
+				requested = append(requested, fmt.Sprintf("%d-%d", first, last))
+				mu.Unlock()
+				return serveRange(func(int64) bool { return false })(first, last)
+			})
+			please.Expect(err).NotTo(HaveOccurred())
+			please.Expect(requested).To(ContainElement(HavePrefix("12-")))
+			please.Expect(requested).NotTo(ContainElement(HavePrefix("0-")))
+			_, _, err = out.complete()
+			please.Expect(err).NotTo(HaveOccurred())
+			please.Expect(os.ReadFile(outputFile)).To(Equal([]byte(contents)))
+		})
+	})
+
+	t.Run("the server ignores the range", func(t *testing.T) {
+		please := NewWithT(t)
+		out, err := openPartialDownload(filepath.Join(t.TempDir(), "release.tgz"))
+		please.Expect(err).NotTo(HaveOccurred())
+		defer closeAndIgnoreError(out)
+
+		err = downloadParts(out, int64(len(contents)), 1, func(first, last int64) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(contents)))}, nil
+		})
+		please.Expect(err).To(HaveOccurred())
+	})
+}
@@ -0,0 +1,569 @@
+package component
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// OCIReleaseSource stores BOSH release tarballs as artifacts in an OCI registry (for example
+// Harbor or registry:2) using the registry HTTP API.
+//
+// Each release version is an image manifest with a single layer containing the tarball. Built
+// releases are stored in the repository "<repo>/<name>" and compiled releases in
+// "<repo>/<stemcell-os>/<stemcell-version>/<name>". The tag is the release version with "+"
+// replaced by "_" since "+" is not allowed in tags.
+//
+// The Kilnfile configuration uses the registry, repo, username, and password fields. The
+// registry may include a scheme; "https://" is used when it does not.
+type OCIReleaseSource struct {
+	cargo.ReleaseSourceConfig
+
+	Client *http.Client
+
+	logger *log.Logger
+
+	tokensMutex sync.Mutex
+	tokens      map[string]string
+}
+
+const (
+	ociImageManifestMediaType  = "application/vnd.oci.image.manifest.v1+json"
+	ociEmptyConfigMediaType    = "application/vnd.oci.empty.v1+json"
+	ociBOSHReleaseArtifactType = "application/vnd.cloudfoundry.bosh.release.v1"
+	ociBOSHReleaseLayerType    = "application/vnd.cloudfoundry.bosh.release.layer.v1.tar+gzip"
+
+	ociAnnotationTitle       = "org.opencontainers.image.title"
+	ociAnnotationVersion     = "org.opencontainers.image.version"
+	ociAnnotationReleaseSHA1 = "org.cloudfoundry.bosh.release.sha1"
+)
+
+// ociEmptyConfig is the config blob used by artifacts that do not need one.
+var ociEmptyConfig = []byte("{}")
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+func (manifest ociManifest) releaseLayer() (ociDescriptor, error) {
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == ociBOSHReleaseLayerType {
+			return layer, nil
+		}
+	}
+	return ociDescriptor{}, fmt.Errorf("manifest does not have a layer with media type %s", ociBOSHReleaseLayerType)
+}
+
+// NewOCIReleaseSource will provision a new OCIReleaseSource from the Kilnfile
+// (ReleaseSourceConfig). If type is incorrect it will PANIC
+func NewOCIReleaseSource(c cargo.ReleaseSourceConfig, logger *log.Logger) *OCIReleaseSource {
+	if c.Type != "" && c.Type != ReleaseSourceTypeOCI {
+		panic(panicMessageWrongReleaseSourceType)
+	}
+	if logger == nil {
+		logger = log.New(os.Stderr, "[OCI release source] ", log.Default().Flags())
+	}
+	return &OCIReleaseSource{
+		ReleaseSourceConfig: c,
+		Client:              http.DefaultClient,
+		logger:              logger,
+		tokens:              make(map[string]string),
+	}
+}
+
+func (src *OCIReleaseSource) ID() string { return src.ReleaseSourceConfig.ID }
+
+func (src *OCIReleaseSource) Configuration() cargo.ReleaseSourceConfig {
+	return src.ReleaseSourceConfig
+}
+
+// RemotePath returns "<repository>:<tag>" for the release. The repository does not include
+// the registry host.
+func (src *OCIReleaseSource) RemotePath(spec cargo.BOSHReleaseTarballSpecification) (string, error) {
+	if spec.Name == "" || spec.Version == "" {
+		return "", fmt.Errorf("release name and version are required to build an OCI reference")
+	}
+	return src.repository(spec) + ":" + ociTagFromVersion(spec.Version), nil
+}
+
+// GetMatchedRelease uses the Name and Version and if supported StemcellOS and StemcellVersion
+// fields on Requirement to download a specific release.
+func (src *OCIReleaseSource) GetMatchedRelease(spec cargo.BOSHReleaseTarballSpecification) (cargo.BOSHReleaseTarballLock, error) {
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	repository, tag := splitOCIReference(remotePath)
+
+	manifest, err := src.getManifest(repository, tag)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	layer, err := manifest.releaseLayer()
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, fmt.Errorf("failed to get %s from %s: %w", remotePath, src.ID(), err)
+	}
+
+	return cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      spec.Version,
+		SHA1:         layer.Annotations[ociAnnotationReleaseSHA1],
+		SHA256:       strings.TrimPrefix(layer.Digest, "sha256:"),
+		RemotePath:   remotePath,
+		RemoteSource: src.ID(),
+	}, nil
+}
+
+// FindReleaseVersion lists the tags in the release repository and returns the highest
+// version matching the specification.
+func (src *OCIReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTarballSpecification, _ bool) (cargo.BOSHReleaseTarballLock, error) {
+	constraint, err := spec.VersionConstraints()
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	tags, err := src.listTags(src.repository(spec))
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	var highest *semver.Version
+	for _, tag := range tags {
+		v, err := semver.NewVersion(ociVersionFromTag(tag))
+		if err != nil {
+			continue
+		}
+		if !constraint.Check(v) {
+			continue
+		}
+		if highest == nil || v.GreaterThan(highest) {
+			highest = v
+		}
+	}
+	if highest == nil {
+		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
+	}
+
+	spec.Version = highest.Original()
+	return src.GetMatchedRelease(spec)
+}
+
+// DownloadRelease downloads the release layer of the artifact referenced by RemotePath.
+func (src *OCIReleaseSource) DownloadRelease(releaseDir string, remoteRelease cargo.BOSHReleaseTarballLock) (Local, error) {
+	src.logger.Printf(logLineDownload, remoteRelease.Name, ReleaseSourceTypeOCI, src.ID())
+
+	repository, tag := splitOCIReference(remoteRelease.RemotePath)
+	manifest, err := src.getManifest(repository, tag)
+	if err != nil {
+		return Local{}, fmt.Errorf("failed to get manifest for %s: %w", remoteRelease.RemotePath, err)
+	}
+	layer, err := manifest.releaseLayer()
+	if err != nil {
+		return Local{}, fmt.Errorf("failed to download %s: %w", remoteRelease.RemotePath, err)
+	}
+
+	fileName := filepath.Base(layer.Annotations[ociAnnotationTitle])
+	if fileName == "" || fileName == "." || fileName == string(filepath.Separator) {
+		fileName = fmt.Sprintf("%s-%s.tgz", remoteRelease.Name, remoteRelease.Version)
+	}
+	filePath := filepath.Join(releaseDir, fileName)
+
+	out, err := openPartialDownload(filePath)
+	if err != nil {
+		return Local{}, err
+	}
+	defer closeAndIgnoreError(out)
+
+	req, err := http.NewRequest(http.MethodGet, src.url("v2", repository, "blobs", layer.Digest), nil)
+	if err != nil {
+		return Local{}, err
+	}
+	out.setRange(req)
+
+	res, err := src.do(req, repository, false)
+	if err != nil {
+		return Local{}, err
+	}
+	err = out.copyResponse(res)
+	closeAndIgnoreError(res.Body)
+	if err != nil {
+		return Local{}, fmt.Errorf("failed to download %s release from %s: %w", remoteRelease.Name, src.ID(), err)
+	}
+
+	remoteRelease.SHA1, remoteRelease.SHA256, err = out.complete()
+	if err != nil {
+		return Local{}, err
+	}
+
+	return Local{Lock: remoteRelease, LocalPath: filePath}, nil
+}
+
+// UploadRelease pushes the tarball as an artifact tagged with the release version. The
+// tarball is buffered in a temporary file because blob uploads must declare their digest.
+func (src *OCIReleaseSource) UploadRelease(spec cargo.BOSHReleaseTarballSpecification, file io.Reader) (cargo.BOSHReleaseTarballLock, error) {
+	remotePath, err := src.RemotePath(spec)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	repository, tag := splitOCIReference(remotePath)
+
+	src.logger.Printf("uploading release %q to %s at %q...\n", spec.Name, src.ID(), remotePath)
+
+	tmp, err := os.CreateTemp("", "kiln-oci-upload-*.tgz")
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+	defer func() {
+		closeAndIgnoreError(tmp)
+		_ = os.Remove(tmp.Name())
+	}()
+
+	sha1Hash, sha256Hash := sha1.New(), sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, sha1Hash, sha256Hash), file)
+	if err != nil {
+		return cargo.BOSHReleaseTarballLock{}, fmt.Errorf("failed to read release: %w", err)
+	}
+	releaseSHA1 := hex.EncodeToString(sha1Hash.Sum(nil))
+
+	layer := ociDescriptor{
+		MediaType: ociBOSHReleaseLayerType,
+		Digest:    "sha256:" + hex.EncodeToString(sha256Hash.Sum(nil)),
+		Size:      size,
+		Annotations: map[string]string{
+			ociAnnotationTitle:       fmt.Sprintf("%s-%s.tgz", spec.Name, spec.Version),
+			ociAnnotationReleaseSHA1: releaseSHA1,
+		},
+	}
+	if spec.StemcellOS != "" {
+		layer.Annotations[ociAnnotationTitle] = fmt.Sprintf("%s-%s-%s-%s.tgz", spec.Name, spec.Version, spec.StemcellOS, spec.StemcellVersion)
+	}
+	configSum := sha256.Sum256(ociEmptyConfig)
+	config := ociDescriptor{
+		MediaType: ociEmptyConfigMediaType,
+		Digest:    "sha256:" + hex.EncodeToString(configSum[:]),
+		Size:      int64(len(ociEmptyConfig)),
+	}
+
+	if err := src.pushBlob(repository, config, func() (io.Reader, error) { return bytes.NewReader(ociEmptyConfig), nil }); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, fmt.Errorf("failed to upload config: %w", err)
+	}
+	if err := src.pushBlob(repository, layer, func() (io.Reader, error) {
+		_, err := tmp.Seek(0, io.SeekStart)
+		return tmp, err
+	}); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, fmt.Errorf("failed to upload release: %w", err)
+	}
+
+	manifest := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociImageManifestMediaType,
+		ArtifactType:  ociBOSHReleaseArtifactType,
+		Config:        config,
+		Layers:        []ociDescriptor{layer},
+		Annotations: map[string]string{
+			ociAnnotationTitle:   spec.Name,
+			ociAnnotationVersion: spec.Version,
+		},
+	}
+	if err := src.putManifest(repository, tag, manifest); err != nil {
+		return cargo.BOSHReleaseTarballLock{}, err
+	}
+
+	return cargo.BOSHReleaseTarballLock{
+		Name:         spec.Name,
+		Version:      spec.Version,
+		SHA1:         releaseSHA1,
+		SHA256:       hex.EncodeToString(sha256Hash.Sum(nil)),
+		RemotePath:   remotePath,
+		RemoteSource: src.ID(),
+	}, nil
+}
+
+func (src *OCIReleaseSource) repository(spec cargo.BOSHReleaseTarballSpecification) string {
+	return path.Join(src.Repo, spec.StemcellOS, spec.StemcellVersion, spec.Name)
+}
+
+func (src *OCIReleaseSource) url(elem ...string) string {
+	base := strings.TrimSuffix(src.Registry, "/")
+	if !strings.Contains(base, "://") {
+		base = "https://" + base
+	}
+	return base + "/" + strings.Join(elem, "/")
+}
+
+func (src *OCIReleaseSource) listTags(repository string) ([]string, error) {
+	req, err := http.NewRequest(http.MethodGet, src.url("v2", repository, "tags", "list"), nil)
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for {
+		res, err := src.do(req, repository, false)
+		if err != nil {
+			return nil, err
+		}
+		var list struct {
+			Tags []string `json:"tags"`
+		}
+		err = decodeJSONResponse(res, &list)
+		if err != nil {
+			if IsErrNotFound(err) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to list tags for %s: %w", repository, err)
+		}
+		tags = append(tags, list.Tags...)
+
+		// registries paginate long tag lists and link to the next page
+		next := ociNextPage(res.Header.Get("Link"))
+		if next == "" {
+			return tags, nil
+		}
+		nextURL, err := req.URL.Parse(next)
+		if err != nil {
+			return nil, err
+		}
+		req, err = http.NewRequest(http.MethodGet, nextURL.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (src *OCIReleaseSource) getManifest(repository, reference string) (ociManifest, error) {
+	req, err := http.NewRequest(http.MethodGet, src.url("v2", repository, "manifests", reference), nil)
+	if err != nil {
+		return ociManifest{}, err
+	}
+	req.Header.Set("Accept", ociImageManifestMediaType)
+	res, err := src.do(req, repository, false)
+	if err != nil {
+		return ociManifest{}, err
+	}
+	var manifest ociManifest
+	return manifest, decodeJSONResponse(res, &manifest)
+}
+
+func (src *OCIReleaseSource) putManifest(repository, tag string, manifest ociManifest) error {
+	buf, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, src.url("v2", repository, "manifests", tag), bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ociImageManifestMediaType)
+	res, err := src.do(req, repository, true)
+	if err != nil {
+		return err
+	}
+	closeAndIgnoreError(res.Body)
+	if err := checkStatus(http.StatusCreated, res.StatusCode); err != nil {
+		return fmt.Errorf("failed to upload manifest: %w", err)
+	}
+	return nil
+}
+
+// pushBlob uploads a blob unless the registry already has it. The upload is monolithic:
+// the session is started with a POST and the content is sent with a single PUT.
+func (src *OCIReleaseSource) pushBlob(repository string, desc ociDescriptor, content func() (io.Reader, error)) error {
+	req, err := http.NewRequest(http.MethodHead, src.url("v2", repository, "blobs", desc.Digest), nil)
+	if err != nil {
+		return err
+	}
+	res, err := src.do(req, repository, true)
+	if err != nil {
+		return err
+	}
+	closeAndIgnoreError(res.Body)
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	req, err = http.NewRequest(http.MethodPost, src.url("v2", repository, "blobs", "uploads")+"/", nil)
+	if err != nil {
+		return err
+	}
+	res, err = src.do(req, repository, true)
+	if err != nil {
+		return err
+	}
+	closeAndIgnoreError(res.Body)
+	if err := checkStatus(http.StatusAccepted, res.StatusCode); err != nil {
+		return err
+	}
+
+	location, err := req.URL.Parse(res.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("registry responded with an invalid upload location: %w", err)
+	}
+	query := location.Query()
+	query.Set("digest", desc.Digest)
+	location.RawQuery = query.Encode()
+
+	body, err := content()
+	if err != nil {
+		return err
+	}
+	req, err = http.NewRequest(http.MethodPut, location.String(), io.NopCloser(body))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = desc.Size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	res, err = src.do(req, repository, true)
+	if err != nil {
+		return err
+	}
+	closeAndIgnoreError(res.Body)
+	return checkStatus(http.StatusCreated, res.StatusCode)
+}
+
+// do sends the request with credentials for the repository. Registries that use token
+// authentication respond 401 with a challenge; the token is requested, cached per scope,
+// and the request is sent again.
+func (src *OCIReleaseSource) do(req *http.Request, repository string, push bool) (*http.Response, error) {
+	scope := "repository:" + repository + ":pull"
+	if push {
+		scope += ",push"
+	}
+
+	src.tokensMutex.Lock()
+	token, hasToken := src.tokens[scope]
+	src.tokensMutex.Unlock()
+	if hasToken {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if src.Username != "" {
+		req.SetBasicAuth(src.Username, src.Password)
+	}
+
+	res, err := src.Client.Do(req)
+	if err != nil {
+		return nil, wrapVPNError(err)
+	}
+	if res.StatusCode != http.StatusUnauthorized {
+		return res, nil
+	}
+
+	challenge := res.Header.Get("WWW-Authenticate")
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") || (req.Body != nil && req.GetBody == nil) {
+		return res, nil
+	}
+	closeAndIgnoreError(res.Body)
+
+	token, err = src.requestToken(challenge, scope)
+	if err != nil {
+		return nil, err
+	}
+	src.tokensMutex.Lock()
+	src.tokens[scope] = token
+	src.tokensMutex.Unlock()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	retry.Header.Set("Authorization", "Bearer "+token)
+	res, err = src.Client.Do(retry)
+	if err != nil {
+		return nil, wrapVPNError(err)
+	}
+	return res, nil
+}
+
+var ociChallengeParameter = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+func (src *OCIReleaseSource) requestToken(challenge, scope string) (string, error) {
+	params := make(map[string]string)
+	for _, match := range ociChallengeParameter.FindAllStringSubmatch(challenge, -1) {
+		params[match[1]] = match[2]
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("registry responded with an invalid authentication challenge: %q", challenge)
+	}
+	query := realm.Query()
+	if service, ok := params["service"]; ok {
+		query.Set("service", service)
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if src.Username != "" {
+		req.SetBasicAuth(src.Username, src.Password)
+	}
+	res, err := src.Client.Do(req)
+	if err != nil {
+		return "", wrapVPNError(err)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := decodeJSONResponse(res, &body); err != nil {
+		return "", fmt.Errorf("failed to get registry token: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+var ociLinkHeader = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+func ociNextPage(link string) string {
+	match := ociLinkHeader.FindStringSubmatch(link)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+func splitOCIReference(remotePath string) (repository, tag string) {
+	i := strings.LastIndex(remotePath, ":")
+	if i < 0 || strings.Contains(remotePath[i:], "/") {
+		return remotePath, "latest"
+	}
+	return remotePath[:i], remotePath[i+1:]
+}
+
+func ociTagFromVersion(version string) string { return strings.ReplaceAll(version, "+", "_") }
+
+func ociVersionFromTag(tag string) string { return strings.ReplaceAll(tag, "_", "+") }
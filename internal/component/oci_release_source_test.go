@@ -0,0 +1,292 @@
+package component_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+func TestOCIReleaseSource(t *testing.T) {
+	const (
+		contents  = "some release contents"
+		sum       = "641c0d1e5d0578ededf8cd76f5d8399e867f6f6c"
+		sha256Sum = "ca6838db82f1df4b7456c468b3035aee107a19caaafb65e2b5e68f037d8bb6cc"
+	)
+
+	setup := func(t *testing.T, registry *fakeOCIRegistry) *component.OCIReleaseSource {
+		t.Helper()
+		server := httptest.NewServer(registry)
+		t.Cleanup(server.Close)
+		registry.url = server.URL
+		source := component.NewOCIReleaseSource(cargo.ReleaseSourceConfig{
+			Type:     component.ReleaseSourceTypeOCI,
+			ID:       "harbor",
+			Registry: server.URL,
+			Repo:     "tas/releases",
+			Username: "kim",
+			Password: "mango_rice!",
+		}, log.New(io.Discard, "", 0))
+		source.Client = server.Client()
+		return source
+	}
+
+	t.Run("interface compliance", func(t *testing.T) {
+		please := NewWithT(t)
+		var source interface{} = new(component.OCIReleaseSource)
+		_, isReleaseSource := source.(component.ReleaseSource)
+		_, isUploader := source.(component.ReleaseUploader)
+		_, isPather := source.(component.RemotePather)
+		please.Expect(isReleaseSource).To(BeTrue())
+		please.Expect(isUploader).To(BeTrue())
+		please.Expect(isPather).To(BeTrue())
+	})
+
+	t.Run("RemotePath", func(t *testing.T) {
+		please := NewWithT(t)
+		source := setup(t, newFakeOCIRegistry())
+
+		please.Expect(source.RemotePath(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "1.2.3+dev.1"})).
+			To(Equal("tas/releases/mango:1.2.3_dev.1"))
+		please.Expect(source.RemotePath(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "1.2.3", StemcellOS: "ubuntu-jammy", StemcellVersion: "1.123"})).
+			To(Equal("tas/releases/ubuntu-jammy/1.123/mango:1.2.3"))
+
+		_, err := source.RemotePath(cargo.BOSHReleaseTarballSpecification{Name: "mango"})
+		please.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("upload and download", func(t *testing.T) {
+		please := NewWithT(t)
+		registry := newFakeOCIRegistry()
+		source := setup(t, registry)
+		spec := cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "1.2.3", StemcellOS: "ubuntu-jammy", StemcellVersion: "1.123"}
+
+		lock, err := source.UploadRelease(spec, strings.NewReader(contents))
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(lock).To(Equal(cargo.BOSHReleaseTarballLock{
+			Name: "mango", Version: "1.2.3", SHA1: sum, SHA256: sha256Sum,
+			RemotePath: "tas/releases/ubuntu-jammy/1.123/mango:1.2.3", RemoteSource: "harbor",
+		}))
+
+		matched, err := source.GetMatchedRelease(spec)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(matched).To(Equal(lock))
+
+		releasesDir := t.TempDir()
+		local, err := source.DownloadRelease(releasesDir, lock)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(local.LocalPath).To(Equal(filepath.Join(releasesDir, "mango-1.2.3-ubuntu-jammy-1.123.tgz")))
+		please.Expect(local.Lock.SHA1).To(Equal(sum))
+		please.Expect(local.Lock.SHA256).To(Equal(sha256Sum))
+		please.Expect(os.ReadFile(local.LocalPath)).To(BeEquivalentTo(contents))
+
+		_, err = source.UploadRelease(spec, strings.NewReader(contents))
+		please.Expect(err).NotTo(HaveOccurred(), "uploading the same release twice")
+	})
+
+	t.Run("resume a partial download", func(t *testing.T) {
+		please := NewWithT(t)
+		registry := newFakeOCIRegistry()
+		source := setup(t, registry)
+		lock, err := source.UploadRelease(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "1.2.3"}, strings.NewReader(contents))
+		please.Expect(err).NotTo(HaveOccurred())
+
+		releasesDir := t.TempDir()
+		partialPath := filepath.Join(releasesDir, "mango-1.2.3.tgz"+component.PartialDownloadSuffix)
+		please.Expect(os.WriteFile(partialPath, []byte(contents[:5]), 0o644)).To(Succeed())
+
+		local, err := source.DownloadRelease(releasesDir, lock)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(local.Lock.SHA1).To(Equal(sum))
+		please.Expect(local.Lock.SHA256).To(Equal(sha256Sum))
+		please.Expect(registry.lastRange).To(Equal("bytes=5-"))
+	})
+
+	t.Run("FindReleaseVersion", func(t *testing.T) {
+		please := NewWithT(t)
+		source := setup(t, newFakeOCIRegistry())
+		for _, version := range []string{"1.2.3", "1.10.0", "2.0.0"} {
+			_, err := source.UploadRelease(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: version}, strings.NewReader(contents+version))
+			please.Expect(err).NotTo(HaveOccurred())
+		}
+
+		lock, err := source.FindReleaseVersion(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "~1"}, false)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(lock.Version).To(Equal("1.10.0"))
+		please.Expect(lock.RemotePath).To(Equal("tas/releases/mango:1.10.0"))
+		please.Expect(lock.SHA1).NotTo(BeEmpty())
+
+		_, err = source.FindReleaseVersion(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "~3"}, false)
+		please.Expect(component.IsErrNotFound(err)).To(BeTrue())
+	})
+
+	t.Run("release not found", func(t *testing.T) {
+		please := NewWithT(t)
+		source := setup(t, newFakeOCIRegistry())
+
+		_, err := source.GetMatchedRelease(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "1.2.3"})
+		please.Expect(component.IsErrNotFound(err)).To(BeTrue())
+
+		_, err = source.FindReleaseVersion(cargo.BOSHReleaseTarballSpecification{Name: "mango"}, false)
+		please.Expect(component.IsErrNotFound(err)).To(BeTrue())
+	})
+
+	t.Run("token authentication", func(t *testing.T) {
+		please := NewWithT(t)
+		registry := newFakeOCIRegistry()
+		registry.requireToken = true
+		source := setup(t, registry)
+
+		lock, err := source.UploadRelease(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "1.2.3"}, strings.NewReader(contents))
+		please.Expect(err).NotTo(HaveOccurred())
+
+		_, err = source.DownloadRelease(t.TempDir(), lock)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(registry.tokenScopes).To(ContainElements(
+			"repository:tas/releases/mango:pull,push",
+			"repository:tas/releases/mango:pull",
+		))
+	})
+
+	t.Run("wrong credentials", func(t *testing.T) {
+		please := NewWithT(t)
+		registry := newFakeOCIRegistry()
+		registry.requireToken = true
+		source := setup(t, registry)
+		source.Password = "wrong"
+
+		_, err := source.UploadRelease(cargo.BOSHReleaseTarballSpecification{Name: "mango", Version: "1.2.3"}, strings.NewReader(contents))
+		please.Expect(err).To(MatchError(ContainSubstring("registry token")))
+	})
+}
+
+// fakeOCIRegistry implements the parts of the OCI distribution API used by OCIReleaseSource.
+type fakeOCIRegistry struct {
+	url          string
+	requireToken bool
+
+	mu          sync.Mutex
+	blobs       map[string][]byte
+	manifests   map[string]map[string][]byte
+	uploads     int
+	lastRange   string
+	tokenScopes []string
+}
+
+func newFakeOCIRegistry() *fakeOCIRegistry {
+	return &fakeOCIRegistry{
+		blobs:     make(map[string][]byte),
+		manifests: make(map[string]map[string][]byte),
+	}
+}
+
+func (reg *fakeOCIRegistry) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if req.URL.Path == "/token" {
+		if user, pass, _ := req.BasicAuth(); user != "kim" || pass != "mango_rice!" {
+			res.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		scope := req.URL.Query().Get("scope")
+		reg.tokenScopes = append(reg.tokenScopes, scope)
+		_ = json.NewEncoder(res).Encode(map[string]string{"token": scope})
+		return
+	}
+
+	name := strings.TrimPrefix(req.URL.Path, "/v2/")
+	var repository, route, ref string
+	for _, r := range []string{"/blobs/uploads/", "/blobs/", "/manifests/", "/tags/list"} {
+		if i := strings.LastIndex(name, r); i > 0 {
+			repository, route, ref = name[:i], r, name[i+len(r):]
+			break
+		}
+	}
+
+	if reg.requireToken {
+		scope := "repository:" + repository + ":pull"
+		if req.Method != http.MethodGet {
+			scope += ",push"
+		}
+		if req.Header.Get("Authorization") != "Bearer "+scope && req.Header.Get("Authorization") != "Bearer "+scope+",push" {
+			res.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake",scope="%s"`, reg.url, scope))
+			res.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	switch {
+	case route == "/tags/list" && req.Method == http.MethodGet:
+		tags, ok := reg.manifests[repository]
+		if !ok {
+			res.WriteHeader(http.StatusNotFound)
+			return
+		}
+		list := struct {
+			Name string   `json:"name"`
+			Tags []string `json:"tags"`
+		}{Name: repository}
+		for tag := range tags {
+			list.Tags = append(list.Tags, tag)
+		}
+		sort.Strings(list.Tags)
+		_ = json.NewEncoder(res).Encode(list)
+	case route == "/manifests/" && req.Method == http.MethodGet:
+		manifest, ok := reg.manifests[repository][ref]
+		if !ok {
+			res.WriteHeader(http.StatusNotFound)
+			return
+		}
+		res.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+		_, _ = res.Write(manifest)
+	case route == "/manifests/" && req.Method == http.MethodPut:
+		buf, _ := io.ReadAll(req.Body)
+		if reg.manifests[repository] == nil {
+			reg.manifests[repository] = make(map[string][]byte)
+		}
+		reg.manifests[repository][ref] = buf
+		res.WriteHeader(http.StatusCreated)
+	case route == "/blobs/" && (req.Method == http.MethodGet || req.Method == http.MethodHead):
+		blob, ok := reg.blobs[ref]
+		if !ok {
+			res.WriteHeader(http.StatusNotFound)
+			return
+		}
+		reg.lastRange = req.Header.Get("Range")
+		http.ServeContent(res, req, "", time.Time{}, bytes.NewReader(blob))
+	case route == "/blobs/uploads/" && req.Method == http.MethodPost:
+		reg.uploads++
+		res.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%d?state=abc", repository, reg.uploads))
+		res.WriteHeader(http.StatusAccepted)
+	case route == "/blobs/uploads/" && req.Method == http.MethodPut:
+		buf, _ := io.ReadAll(req.Body)
+		sum := sha256.Sum256(buf)
+		digest := "sha256:" + hex.EncodeToString(sum[:])
+		if req.URL.Query().Get("digest") != digest || req.URL.Query().Get("state") != "abc" {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reg.blobs[digest] = buf
+		res.WriteHeader(http.StatusCreated)
+	default:
+		res.WriteHeader(http.StatusNotFound)
+	}
+}
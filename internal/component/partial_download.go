@@ -0,0 +1,123 @@
+package component
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// PartialDownloadSuffix is appended to the file name of a release tarball while it is being
+// downloaded. When a download is interrupted the partial file is left on disk so a later
+// call to DownloadRelease can resume from where the previous one stopped.
+const PartialDownloadSuffix = ".partial"
+
+// partialDownload writes a release tarball to a ".partial" file and moves it to its final
+// path once the download is complete.
+type partialDownload struct {
+	file       *os.File
+	outputPath string
+
+	// offset is the number of bytes already on disk when the download was (re)started.
+	offset int64
+}
+
+func openPartialDownload(outputPath string) (*partialDownload, error) {
+	partialPath := outputPath + PartialDownloadSuffix
+	file, err := os.OpenFile(partialPath, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file %q: %w", partialPath, err)
+	}
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		closeAndIgnoreError(file)
+		return nil, fmt.Errorf("failed to find end of partial download %q: %w", partialPath, err) // untested
+	}
+	return &partialDownload{
+		file:       file,
+		outputPath: outputPath,
+		offset:     offset,
+	}, nil
+}
+
+// Write appends to the partial file.
+func (pd *partialDownload) Write(p []byte) (int, error) { return pd.file.Write(p) }
+
+// WriteAt writes relative to the resume offset. It is used by the S3 downloader which
+// writes ranges starting at zero.
+func (pd *partialDownload) WriteAt(p []byte, off int64) (int, error) {
+	return pd.file.WriteAt(p, pd.offset+off)
+}
+
+// Close releases the file handle. It does not remove the partial file.
+func (pd *partialDownload) Close() error { return pd.file.Close() }
+
+// restart discards any previously downloaded bytes.
+func (pd *partialDownload) restart() error {
+	if pd.offset == 0 {
+		return nil
+	}
+	if err := pd.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := pd.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	pd.offset = 0
+	return nil
+}
+
+// setRange requests only the bytes not yet on disk.
+func (pd *partialDownload) setRange(req *http.Request) {
+	if pd.offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", pd.offset))
+	}
+}
+
+// copyResponse writes the body of a response to a request made with setRange. When the
+// server ignores the Range header, the download starts over.
+func (pd *partialDownload) copyResponse(res *http.Response) error {
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		if err := pd.restart(); err != nil {
+			return fmt.Errorf("failed to restart download: %w", err) // untested
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		if pd.offset > 0 {
+			// every byte was already downloaded by a previous attempt
+			return nil
+		}
+		return checkStatus(http.StatusOK, res.StatusCode)
+	default:
+		return checkStatus(http.StatusOK, res.StatusCode)
+	}
+	_, err := io.Copy(pd.file, res.Body)
+	return err
+}
+
+// complete calculates the SHA1 and SHA256 sums of the downloaded file and moves it to the
+// output path.
+func (pd *partialDownload) complete() (sha1Sum, sha256Sum string, _ error) {
+	_, err := pd.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return "", "", fmt.Errorf("error reseting file cursor: %w", err) // untested
+	}
+
+	hash := newReleaseHash()
+	_, err = io.Copy(hash, pd.file)
+	if err != nil {
+		return "", "", fmt.Errorf("error hashing file contents: %w", err) // untested
+	}
+
+	if err := pd.file.Close(); err != nil {
+		return "", "", err
+	}
+
+	if err := os.Rename(pd.file.Name(), pd.outputPath); err != nil {
+		return "", "", fmt.Errorf("failed to move completed download into place: %w", err)
+	}
+
+	sha1Sum, sha256Sum = hash.sums()
+	return sha1Sum, sha256Sum, nil
+}
@@ -0,0 +1,249 @@
+package component
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReleaseCache is a content-addressed store of BOSH release tarballs shared by every tile
+// built on a machine. Each tarball is stored in a directory named after its SHA1 sum and
+// keeps the file name it had when it was downloaded:
+//
+//	<directory>/<sha1>/<release-file>.tgz
+//
+// Files are hardlinked into and out of the cache when possible, so a release shared by
+// several tile repositories only uses disk space once.
+type ReleaseCache struct {
+	Directory string
+}
+
+// CachedRelease describes a tarball in a ReleaseCache.
+type CachedRelease struct {
+	SHA1     string    `json:"sha1"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+func NewReleaseCache(directory string) ReleaseCache {
+	return ReleaseCache{Directory: directory}
+}
+
+// DefaultReleaseCacheDirectory returns the cache directory used when none is configured.
+func DefaultReleaseCacheDirectory(homeDirectory string) string {
+	return filepath.Join(homeDirectory, ".kiln", "cache")
+}
+
+// Lookup returns the path to the cached tarball with the given SHA1 sum. It returns
+// ErrNotFound if the tarball is not cached.
+func (cache ReleaseCache) Lookup(sha1 string) (string, error) {
+	if sha1 == "" {
+		return "", ErrNotFound
+	}
+	entries, err := os.ReadDir(filepath.Join(cache.Directory, sha1))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !isTemporaryCacheFile(entry.Name()) {
+			return filepath.Join(cache.Directory, sha1, entry.Name()), nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// Link places the cached tarball with the given SHA1 sum in releasesDir and returns its
+// path. It returns ErrNotFound if the tarball is not cached.
+func (cache ReleaseCache) Link(sha1, releasesDir string) (string, error) {
+	cachedPath, err := cache.Lookup(sha1)
+	if err != nil {
+		return "", err
+	}
+
+	localPath := filepath.Join(releasesDir, filepath.Base(cachedPath))
+	err = linkOrCopy(cachedPath, localPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to link cached release into %s: %w", releasesDir, err)
+	}
+
+	// the modification time records when the entry was last used so prune can remove stale entries
+	now := time.Now()
+	_ = os.Chtimes(cachedPath, now, now)
+
+	return localPath, nil
+}
+
+// Add stores the tarball at localPath under the given SHA1 sum. The caller must ensure
+// the sum is correct. Adding a tarball that is already cached is a no-op.
+func (cache ReleaseCache) Add(localPath, sha1 string) error {
+	if sha1 == "" {
+		return errors.New("a SHA1 sum is required to cache a release")
+	}
+	if _, err := cache.Lookup(sha1); err == nil {
+		return nil
+	}
+
+	entryDir := filepath.Join(cache.Directory, sha1)
+	err := os.MkdirAll(entryDir, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create release cache directory: %w", err)
+	}
+
+	return linkOrCopy(localPath, filepath.Join(entryDir, filepath.Base(localPath)))
+}
+
+// Remove deletes the cached tarball with the given SHA1 sum. Removing a tarball that is
+// not cached is a no-op.
+func (cache ReleaseCache) Remove(sha1 string) error {
+	if sha1 == "" {
+		return errors.New("a SHA1 sum is required to remove a cached release")
+	}
+	return os.RemoveAll(filepath.Join(cache.Directory, sha1))
+}
+
+// List returns every cached tarball sorted by SHA1 sum.
+func (cache ReleaseCache) List() ([]CachedRelease, error) {
+	entries, err := os.ReadDir(cache.Directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var releases []CachedRelease
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		cachedPath, err := cache.Lookup(entry.Name())
+		if err != nil {
+			if IsErrNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		info, err := os.Stat(cachedPath)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, CachedRelease{
+			SHA1:     entry.Name(),
+			Path:     cachedPath,
+			Size:     info.Size(),
+			LastUsed: info.ModTime(),
+		})
+	}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].SHA1 < releases[j].SHA1
+	})
+
+	return releases, nil
+}
+
+// Prune removes cached tarballs that were last used before the given time and returns
+// the removed entries.
+func (cache ReleaseCache) Prune(lastUsedBefore time.Time) ([]CachedRelease, error) {
+	releases, err := cache.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []CachedRelease
+	for _, rel := range releases {
+		if !rel.LastUsed.Before(lastUsedBefore) {
+			continue
+		}
+		err := os.RemoveAll(filepath.Join(cache.Directory, rel.SHA1))
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, rel)
+	}
+
+	return removed, nil
+}
+
+// Verify recalculates the SHA1 sum of every cached tarball and returns the entries whose
+// contents do not match their key.
+func (cache ReleaseCache) Verify() ([]CachedRelease, error) {
+	releases, err := cache.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var corrupted []CachedRelease
+	for _, rel := range releases {
+		sum, err := calculateFileSHA1(rel.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate SHA1 sum of %q: %w", rel.Path, err)
+		}
+		if sum != rel.SHA1 {
+			corrupted = append(corrupted, rel)
+		}
+	}
+
+	return corrupted, nil
+}
+
+// temporaryFileSuffix does not end in ".tgz" so releases being linked are not mistaken
+// for complete release tarballs.
+const temporaryFileSuffix = ".tmp"
+
+func isTemporaryCacheFile(name string) bool { return strings.HasSuffix(name, temporaryFileSuffix) }
+
+// linkOrCopy hardlinks src to dst falling back to a copy when src and dst are on different
+// filesystems. The new file is moved into place so readers never see a partial copy.
+func linkOrCopy(src, dst string) error {
+	tmp := dst + temporaryFileSuffix
+	_ = os.Remove(tmp)
+	// when dst is already a link to src, rename leaves tmp in place
+	defer func() { _ = os.Remove(tmp) }()
+
+	if err := os.Link(src, tmp); err != nil {
+		if err := copyFile(src, tmp); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(tmp, dst)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer closeAndIgnoreError(in)
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer closeAndIgnoreError(out)
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+func calculateFileSHA1(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	return calculateSHA1(f)
+}
@@ -0,0 +1,121 @@
+package component_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/component"
+)
+
+func TestReleaseCache(t *testing.T) {
+	const (
+		contents = "some release contents"
+		sum      = "641c0d1e5d0578ededf8cd76f5d8399e867f6f6c"
+	)
+
+	setup := func(t *testing.T) (component.ReleaseCache, string) {
+		t.Helper()
+		cache := component.NewReleaseCache(filepath.Join(t.TempDir(), "cache"))
+		releasesDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(releasesDir, "banana-1.2.3.tgz"), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return cache, releasesDir
+	}
+
+	t.Run("add and link", func(t *testing.T) {
+		please := NewWithT(t)
+		cache, releasesDir := setup(t)
+
+		please.Expect(cache.Add(filepath.Join(releasesDir, "banana-1.2.3.tgz"), sum)).To(Succeed())
+
+		otherReleasesDir := t.TempDir()
+		localPath, err := cache.Link(sum, otherReleasesDir)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(localPath).To(Equal(filepath.Join(otherReleasesDir, "banana-1.2.3.tgz")))
+		please.Expect(os.ReadFile(localPath)).To(BeEquivalentTo(contents))
+
+		dirEntries, err := os.ReadDir(otherReleasesDir)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(dirEntries).To(HaveLen(1), "it should not leave temporary files")
+	})
+
+	t.Run("link missing release", func(t *testing.T) {
+		please := NewWithT(t)
+		cache, releasesDir := setup(t)
+
+		_, err := cache.Link(sum, releasesDir)
+		please.Expect(component.IsErrNotFound(err)).To(BeTrue())
+	})
+
+	t.Run("list", func(t *testing.T) {
+		please := NewWithT(t)
+		cache, releasesDir := setup(t)
+
+		list, err := cache.List()
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(list).To(BeEmpty())
+
+		please.Expect(cache.Add(filepath.Join(releasesDir, "banana-1.2.3.tgz"), sum)).To(Succeed())
+
+		list, err = cache.List()
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(list).To(HaveLen(1))
+		please.Expect(list[0].SHA1).To(Equal(sum))
+		please.Expect(list[0].Size).To(Equal(int64(len(contents))))
+		please.Expect(list[0].Path).To(Equal(filepath.Join(cache.Directory, sum, "banana-1.2.3.tgz")))
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		please := NewWithT(t)
+		cache, releasesDir := setup(t)
+		please.Expect(cache.Add(filepath.Join(releasesDir, "banana-1.2.3.tgz"), sum)).To(Succeed())
+
+		removed, err := cache.Prune(time.Now().Add(-time.Hour))
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(removed).To(BeEmpty())
+
+		removed, err = cache.Prune(time.Now().Add(time.Hour))
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(removed).To(HaveLen(1))
+		please.Expect(filepath.Join(cache.Directory, sum)).NotTo(BeADirectory())
+		please.Expect(filepath.Join(releasesDir, "banana-1.2.3.tgz")).To(BeAnExistingFile())
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		please := NewWithT(t)
+		cache, releasesDir := setup(t)
+		please.Expect(cache.Add(filepath.Join(releasesDir, "banana-1.2.3.tgz"), sum)).To(Succeed())
+
+		please.Expect(cache.Remove(sum)).To(Succeed())
+		please.Expect(filepath.Join(cache.Directory, sum)).NotTo(BeADirectory())
+		please.Expect(filepath.Join(releasesDir, "banana-1.2.3.tgz")).To(BeAnExistingFile())
+
+		_, err := cache.Link(sum, t.TempDir())
+		please.Expect(component.IsErrNotFound(err)).To(BeTrue())
+
+		please.Expect(cache.Remove(sum)).To(Succeed(), "removing a release that is not cached should be a no-op")
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		please := NewWithT(t)
+		cache, releasesDir := setup(t)
+		please.Expect(cache.Add(filepath.Join(releasesDir, "banana-1.2.3.tgz"), sum)).To(Succeed())
+
+		corrupted, err := cache.Verify()
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(corrupted).To(BeEmpty())
+
+		const wrongSum = "0000000000000000000000000000000000000000"
+		please.Expect(cache.Add(filepath.Join(releasesDir, "banana-1.2.3.tgz"), wrongSum)).To(Succeed())
+
+		corrupted, err = cache.Verify()
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(corrupted).To(HaveLen(1))
+		please.Expect(corrupted[0].SHA1).To(Equal(wrongSum))
+	})
+}
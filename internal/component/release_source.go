@@ -17,7 +17,7 @@ type MultiReleaseSource interface {
 
 	FindByID(string) (ReleaseSource, error)
 
-	// SetDownloadThreads allows configuring the concurrency for the s3 release source.
+	// SetDownloadThreads allows configuring the concurrency for the object storage release sources (s3, gcs, and azure).
 	SetDownloadThreads(n int)
 }
 
@@ -77,6 +77,10 @@ const (
 	ReleaseSourceTypeS3          = cargo.BOSHReleaseTarballSourceTypeS3
 	ReleaseSourceTypeGithub      = cargo.BOSHReleaseTarballSourceTypeGithub
 	ReleaseSourceTypeArtifactory = cargo.BOSHReleaseTarballSourceTypeArtifactory
+	ReleaseSourceTypeOCI         = cargo.BOSHReleaseTarballSourceTypeOCI
+	ReleaseSourceTypeDirectory   = cargo.BOSHReleaseTarballSourceTypeDirectory
+	ReleaseSourceTypeGCS         = cargo.BOSHReleaseTarballSourceTypeGCS
+	ReleaseSourceTypeAzure       = cargo.BOSHReleaseTarballSourceTypeAzure
 )
 
 // ReleaseSourceFactory returns a configured ReleaseSource based on the Type field on the
@@ -92,6 +96,14 @@ func ReleaseSourceFactory(releaseConfig cargo.ReleaseSourceConfig, outLogger *lo
 		return NewGithubReleaseSource(releaseConfig)
 	case ReleaseSourceTypeArtifactory:
 		return NewArtifactoryReleaseSource(releaseConfig)
+	case ReleaseSourceTypeOCI:
+		return NewOCIReleaseSource(releaseConfig, outLogger)
+	case ReleaseSourceTypeDirectory:
+		return NewDirectoryReleaseSource(releaseConfig, outLogger)
+	case ReleaseSourceTypeGCS:
+		return NewGCSReleaseSource(releaseConfig, outLogger)
+	case ReleaseSourceTypeAzure:
+		return NewAzureReleaseSource(releaseConfig, outLogger)
 	default:
 		panic(fmt.Sprintf("unknown release config: %v", releaseConfig))
 	}
@@ -131,10 +131,14 @@ func (list ReleaseSourceList) GetMatchedRelease(requirement cargo.BOSHReleaseTar
 
 func (list ReleaseSourceList) SetDownloadThreads(n int) {
 	for i, rs := range list {
-		s3rs, ok := rs.(S3ReleaseSource)
-		if ok {
-			s3rs.DownloadThreads = n
-			list[i] = s3rs
+		switch src := rs.(type) {
+		case S3ReleaseSource:
+			src.DownloadThreads = n
+			list[i] = src
+		case *GCSReleaseSource:
+			src.DownloadThreads = n
+		case *AzureReleaseSource:
+			src.DownloadThreads = n
 		}
 	}
 }
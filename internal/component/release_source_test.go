@@ -28,13 +28,14 @@ var _ = Describe("ReleaseSourceList", func() {
 						{Type: "s3", Bucket: "built-releases", Region: "us-west-1", Publishable: false, PathTemplate: "template"},
 						{Type: "bosh.io", Publishable: false},
 						{Type: "github", Org: "cloudfoundry", GithubToken: "banana"},
+						{Type: "oci", Registry: "harbor.example.com", Repo: "tas"},
 					},
 				}
 			})
 
 			It("constructs all the release sources", func() {
 				releaseSources := component.NewReleaseSourceRepo(kilnfile, logger)
-				Expect(len(releaseSources)).To(Equal(4)) // not using HaveLen because S3 struct is so huge
+				Expect(len(releaseSources)).To(Equal(5)) // not using HaveLen because S3 struct is so huge
 			})
 
 			It("constructs the compiled release source properly", func() {
@@ -62,6 +63,13 @@ var _ = Describe("ReleaseSourceList", func() {
 				Expect(releaseSources[3]).To(BeAssignableToTypeOf(&component.GithubReleaseSource{}))
 				Expect(releaseSources[3].Configuration().ID).To(Equal(kilnfile.ReleaseSources[3].Org))
 			})
+
+			It("constructs the oci release source properly", func() {
+				releaseSources := component.NewReleaseSourceRepo(kilnfile, logger)
+
+				Expect(releaseSources[4]).To(BeAssignableToTypeOf(&component.OCIReleaseSource{}))
+				Expect(releaseSources[4].Configuration().ID).To(Equal("harbor.example.com/tas"))
+			})
 		})
 
 		Context("when bosh.io is publishable", func() {
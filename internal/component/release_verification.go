@@ -0,0 +1,176 @@
+package component
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-git/go-billy/v5/osfs"
+
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+// ProvenanceReporter is implemented by release sources that can identify the exact remote
+// object backing a release, such as a GitHub release asset ID or an S3 object version. The
+// value is recorded in the Kilnfile.lock so verify-lock can detect when the remote object
+// has been replaced.
+type ProvenanceReporter interface {
+	Provenance(lock cargo.BOSHReleaseTarballLock) (string, error)
+}
+
+// SignatureReader is implemented by release sources that store a detached signature next to
+// each release tarball, at the remote path with signatureFileSuffix appended. update-release
+// records the signature in the Kilnfile.lock when the release source has a
+// signature_public_key.
+type SignatureReader interface {
+	ReleaseSignature(lock cargo.BOSHReleaseTarballLock) (string, error)
+}
+
+// signatureFileSuffix is appended to the remote path of a release to find its signature.
+const signatureFileSuffix = ".sig"
+
+// encodeSignature returns the contents of a signature file as base64. Files written by
+// "cosign sign-blob" are already base64 encoded, binary signatures (like the output of
+// "openssl dgst -sign") are encoded.
+func encodeSignature(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 {
+		if _, err := base64.StdEncoding.DecodeString(string(trimmed)); err == nil {
+			return string(trimmed)
+		}
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// readSignatureResponse reads a signature file downloaded from an object store.
+func readSignatureResponse(res *http.Response) (string, error) {
+	defer closeAndIgnoreError(res.Body)
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", ErrNotFound
+	default:
+		return "", checkStatus(http.StatusOK, res.StatusCode)
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return encodeSignature(data), nil
+}
+
+// ReleaseNeedsVerification returns true when a release has checks beyond the SHA1 sum,
+// which is always compared when releases are downloaded.
+func ReleaseNeedsVerification(lock cargo.BOSHReleaseTarballLock, source cargo.ReleaseSourceConfig) bool {
+	return lock.SHA256 != "" || lock.Signature != "" || source.SignaturePublicKey != ""
+}
+
+// VerifyLocalRelease checks a local release against the sums and signature in the lock.
+// It uses the sums in local.Lock, which are calculated when the release is read or
+// downloaded, and only reads the tarball when one of them is missing. When the release
+// source has a signature_public_key the lock must have a signature made with the
+// matching private key.
+func VerifyLocalRelease(local Local, lock cargo.BOSHReleaseTarballLock, source cargo.ReleaseSourceConfig) error {
+	sha1Sum, sha256Sum := local.Lock.SHA1, local.Lock.SHA256
+	if sha1Sum == "" || sha256Sum == "" {
+		var err error
+		sha1Sum, sha256Sum, err = CalculateSums(local.LocalPath, osfs.New(""))
+		if err != nil {
+			return err
+		}
+	}
+
+	if lock.SHA1 != "" && lock.SHA1 != sha1Sum {
+		return fmt.Errorf("SHA1 mismatch - expected %q, got %q", lock.SHA1, sha1Sum)
+	}
+	if lock.SHA256 != "" && lock.SHA256 != sha256Sum {
+		return fmt.Errorf("SHA256 mismatch - expected %q, got %q", lock.SHA256, sha256Sum)
+	}
+
+	if source.SignaturePublicKey == "" {
+		if lock.Signature != "" {
+			return fmt.Errorf("release has a signature but release source %q has no signature_public_key", lock.RemoteSource)
+		}
+		return nil
+	}
+	if lock.Signature == "" {
+		return fmt.Errorf("release source %q requires a signature but the Kilnfile.lock does not have one", lock.RemoteSource)
+	}
+	key, err := ParseSignaturePublicKey(source.SignaturePublicKey)
+	if err != nil {
+		return fmt.Errorf("release source %q: %w", lock.RemoteSource, err)
+	}
+	digest, _ := hex.DecodeString(sha256Sum)
+	return VerifyReleaseSignature(key, digest, lock.Signature)
+}
+
+// ParseSignaturePublicKey parses a PEM encoded PKIX public key. Only ECDSA (as used by
+// "cosign generate-key-pair"), Ed25519, and RSA keys are supported.
+func ParseSignaturePublicKey(pemData string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pemData))
+	if block == nil {
+		return nil, errors.New("signature_public_key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signature_public_key: %w", err)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey, *rsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("signature_public_key has unsupported key type %T", key)
+	}
+}
+
+// VerifyReleaseSignature checks a base64 encoded signature of the SHA256 digest of a
+// release tarball. ECDSA signatures are ASN.1 encoded, like the output of "cosign sign-blob".
+// Ed25519 signatures are made over the 32 byte digest, not the tarball, like the output of
+// "openssl dgst -sha256 -binary release.tgz | openssl pkeyutl -sign -inkey key.pem -rawin".
+// RSA signatures are PKCS #1 v1.5, like the output of "openssl dgst -sha256 -sign".
+func VerifyReleaseSignature(key crypto.PublicKey, sha256Digest []byte, signature string) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("signature is not base64 encoded: %w", err)
+	}
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, sha256Digest, sig) {
+			return errors.New("signature verification failed")
+		}
+		return nil
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, sha256Digest, sig) {
+			return errors.New("signature verification failed")
+		}
+		return nil
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sha256Digest, sig); err != nil {
+			return fmt.Errorf("signature verification failed: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// FindReleaseSourceConfig returns the configuration of the release source with the ID.
+func FindReleaseSourceConfig(kilnfile cargo.Kilnfile, id string) (cargo.ReleaseSourceConfig, bool) {
+	for _, config := range kilnfile.ReleaseSources {
+		if cargo.BOSHReleaseTarballSourceID(config) == id {
+			config.ID = id
+			return config, true
+		}
+	}
+	return cargo.ReleaseSourceConfig{}, false
+}
@@ -0,0 +1,326 @@
+package component_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io"
+	mathrand "math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/go-git/go-billy/v5/osfs"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/builder"
+	"github.com/pivotal-cf/kiln/internal/component"
+	"github.com/pivotal-cf/kiln/internal/component/fakes"
+	"github.com/pivotal-cf/kiln/pkg/cargo"
+)
+
+func TestVerifyLocalRelease(t *testing.T) {
+	const (
+		contents  = "some release contents"
+		sha1Sum   = "641c0d1e5d0578ededf8cd76f5d8399e867f6f6c"
+		sha256Sum = "ca6838db82f1df4b7456c468b3035aee107a19caaafb65e2b5e68f037d8bb6cc"
+	)
+
+	releasePath := filepath.Join(t.TempDir(), "banana-1.2.3.tgz")
+	if err := os.WriteFile(releasePath, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(contents))
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaSignature, err := ecdsa.SignASN1(rand.Reader, ecdsaKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSignature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ed25519PublicKey, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed25519Signature := ed25519.Sign(ed25519Key, digest[:])
+
+	lock := cargo.BOSHReleaseTarballLock{Name: "banana", Version: "1.2.3", SHA1: sha1Sum, RemoteSource: "some-source"}
+	local := component.Local{LocalPath: releasePath}
+
+	t.Run("sums", func(t *testing.T) {
+		please := NewWithT(t)
+
+		gotSHA1, gotSHA256, err := component.CalculateSums(releasePath, osfs.New(""))
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(gotSHA1).To(Equal(sha1Sum))
+		please.Expect(gotSHA256).To(Equal(sha256Sum))
+
+		withSHA256 := lock
+		withSHA256.SHA256 = sha256Sum
+		please.Expect(component.VerifyLocalRelease(local, withSHA256, cargo.ReleaseSourceConfig{})).To(Succeed())
+
+		withSHA256.SHA256 = "bad"
+		please.Expect(component.VerifyLocalRelease(local, withSHA256, cargo.ReleaseSourceConfig{})).To(MatchError(ContainSubstring("SHA256 mismatch")))
+
+		withBadSHA1 := lock
+		withBadSHA1.SHA1 = "bad"
+		please.Expect(component.VerifyLocalRelease(local, withBadSHA1, cargo.ReleaseSourceConfig{})).To(MatchError(ContainSubstring("SHA1 mismatch")))
+	})
+
+	t.Run("known sums", func(t *testing.T) {
+		please := NewWithT(t)
+
+		// the tarball is not read when local has both sums
+		known := component.Local{Lock: cargo.BOSHReleaseTarballLock{SHA1: sha1Sum, SHA256: sha256Sum}, LocalPath: filepath.Join(t.TempDir(), "missing.tgz")}
+		source := cargo.ReleaseSourceConfig{SignaturePublicKey: publicKeyPEM(t, &ecdsaKey.PublicKey)}
+
+		signed := lock
+		signed.SHA256 = sha256Sum
+		signed.Signature = base64.StdEncoding.EncodeToString(ecdsaSignature)
+		please.Expect(component.VerifyLocalRelease(known, signed, source)).To(Succeed())
+
+		signed.SHA1 = "bad"
+		please.Expect(component.VerifyLocalRelease(known, signed, source)).To(MatchError(ContainSubstring("SHA1 mismatch")))
+
+		please.Expect(component.VerifyLocalRelease(component.Local{LocalPath: known.LocalPath}, lock, cargo.ReleaseSourceConfig{})).To(MatchError(ContainSubstring("no such file")))
+	})
+
+	t.Run("ecdsa signature", func(t *testing.T) {
+		please := NewWithT(t)
+		source := cargo.ReleaseSourceConfig{SignaturePublicKey: publicKeyPEM(t, &ecdsaKey.PublicKey)}
+
+		signed := lock
+		signed.Signature = base64.StdEncoding.EncodeToString(ecdsaSignature)
+		please.Expect(component.VerifyLocalRelease(local, signed, source)).To(Succeed())
+
+		signed.Signature = base64.StdEncoding.EncodeToString(rsaSignature)
+		please.Expect(component.VerifyLocalRelease(local, signed, source)).To(MatchError(ContainSubstring("signature verification failed")))
+
+		please.Expect(component.VerifyLocalRelease(local, lock, source)).To(MatchError(ContainSubstring("requires a signature")))
+	})
+
+	t.Run("rsa signature", func(t *testing.T) {
+		please := NewWithT(t)
+		source := cargo.ReleaseSourceConfig{SignaturePublicKey: publicKeyPEM(t, &rsaKey.PublicKey)}
+
+		signed := lock
+		signed.Signature = base64.StdEncoding.EncodeToString(rsaSignature)
+		please.Expect(component.VerifyLocalRelease(local, signed, source)).To(Succeed())
+
+		signed.Signature = "not base64!"
+		please.Expect(component.VerifyLocalRelease(local, signed, source)).To(MatchError(ContainSubstring("base64")))
+	})
+
+	t.Run("ed25519 signature", func(t *testing.T) {
+		please := NewWithT(t)
+		source := cargo.ReleaseSourceConfig{SignaturePublicKey: publicKeyPEM(t, ed25519PublicKey)}
+
+		signed := lock
+		signed.Signature = base64.StdEncoding.EncodeToString(ed25519Signature)
+		please.Expect(component.VerifyLocalRelease(local, signed, source)).To(Succeed())
+
+		signed.Signature = base64.StdEncoding.EncodeToString(ecdsaSignature)
+		please.Expect(component.VerifyLocalRelease(local, signed, source)).To(MatchError(ContainSubstring("signature verification failed")))
+	})
+
+	t.Run("signature without a key", func(t *testing.T) {
+		please := NewWithT(t)
+
+		signed := lock
+		signed.Signature = base64.StdEncoding.EncodeToString(ecdsaSignature)
+		please.Expect(component.VerifyLocalRelease(local, signed, cargo.ReleaseSourceConfig{})).To(MatchError(ContainSubstring("no signature_public_key")))
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		please := NewWithT(t)
+
+		_, err := component.ParseSignaturePublicKey("not a key")
+		please.Expect(err).To(MatchError(ContainSubstring("not PEM encoded")))
+	})
+
+	t.Run("needs verification", func(t *testing.T) {
+		please := NewWithT(t)
+
+		please.Expect(component.ReleaseNeedsVerification(lock, cargo.ReleaseSourceConfig{})).To(BeFalse())
+		please.Expect(component.ReleaseNeedsVerification(lock, cargo.ReleaseSourceConfig{SignaturePublicKey: "key"})).To(BeTrue())
+		please.Expect(component.ReleaseNeedsVerification(cargo.BOSHReleaseTarballLock{SHA256: "sum"}, cargo.ReleaseSourceConfig{})).To(BeTrue())
+	})
+}
+
+// BenchmarkVerifyLocalRelease compares reading a release and then hashing it again to
+// verify it, as fetch used to, with verifying it using the sums from the read.
+func BenchmarkVerifyLocalRelease(b *testing.B) {
+	const packageSize = 64 << 20
+	releasePath := filepath.Join(b.TempDir(), "banana-1.2.3.tgz")
+	tarball, err := os.Create(releasePath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := writeReleaseTarball(tarball, "name: banana\nversion: 1.2.3\n", packageSize); err != nil {
+		b.Fatal(err)
+	}
+	if err := tarball.Close(); err != nil {
+		b.Fatal(err)
+	}
+	info, err := os.Stat(releasePath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	sha1Sum, sha256Sum, err := component.CalculateSums(releasePath, osfs.New(""))
+	if err != nil {
+		b.Fatal(err)
+	}
+	lock := cargo.BOSHReleaseTarballLock{Name: "banana", Version: "1.2.3", SHA1: sha1Sum, SHA256: sha256Sum}
+
+	for _, bm := range []struct {
+		name      string
+		knownSums bool
+	}{
+		{name: "two passes", knownSums: false},
+		{name: "one pass", knownSums: true},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(info.Size())
+			for i := 0; i < b.N; i++ {
+				part, err := builder.ReleaseManifestReader{}.Read(releasePath)
+				if err != nil {
+					b.Fatal(err)
+				}
+				local := component.Local{LocalPath: releasePath}
+				if bm.knownSums {
+					rm := part.Metadata.(builder.ReleaseManifest)
+					local.Lock.SHA1, local.Lock.SHA256 = rm.SHA1, rm.SHA256
+				}
+				if err := component.VerifyLocalRelease(local, lock, cargo.ReleaseSourceConfig{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// writeReleaseTarball writes a release tarball with a release.MF followed by a package of random bytes.
+func writeReleaseTarball(w io.Writer, releaseManifest string, packageSize int64) error {
+	gw, err := gzip.NewWriterLevel(w, gzip.BestSpeed)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(gw)
+
+	if err := tw.WriteHeader(&tar.Header{Name: "./release.MF", Size: int64(len(releaseManifest)), Mode: 0o644}); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(tw, releaseManifest); err != nil {
+		return err
+	}
+
+	if err := tw.WriteHeader(&tar.Header{Name: "./packages/some-package.tgz", Size: packageSize, Mode: 0o644}); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(tw, mathrand.New(mathrand.NewSource(1)), packageSize); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func TestS3ReleaseSource_Provenance(t *testing.T) {
+	config := cargo.ReleaseSourceConfig{Type: component.ReleaseSourceTypeS3, Bucket: "some-bucket", PathTemplate: "{{.Name}}.tgz"}
+	lock := cargo.BOSHReleaseTarballLock{Name: "banana", Version: "1.2.3", RemotePath: "banana/banana-1.2.3.tgz"}
+
+	t.Run("versioned bucket", func(t *testing.T) {
+		please := NewWithT(t)
+		client := new(fakes.S3Client)
+		client.HeadObjectReturns(&s3.HeadObjectOutput{ETag: aws.String(`"some-etag"`), VersionId: aws.String("some-version")}, nil)
+		source := component.NewS3ReleaseSource(config, client, nil, nil, nil)
+
+		please.Expect(source.Provenance(lock)).To(Equal("s3-version-id:some-version"))
+		please.Expect(*client.HeadObjectArgsForCall(0).Key).To(Equal("banana/banana-1.2.3.tgz"))
+	})
+
+	t.Run("unversioned bucket", func(t *testing.T) {
+		please := NewWithT(t)
+		client := new(fakes.S3Client)
+		client.HeadObjectReturns(&s3.HeadObjectOutput{ETag: aws.String(`"some-etag"`)}, nil)
+		source := component.NewS3ReleaseSource(config, client, nil, nil, nil)
+
+		please.Expect(source.Provenance(lock)).To(Equal("s3-etag:some-etag"))
+	})
+
+	t.Run("request fails", func(t *testing.T) {
+		please := NewWithT(t)
+		client := new(fakes.S3Client)
+		client.HeadObjectReturns(nil, errors.New("banana"))
+		source := component.NewS3ReleaseSource(config, client, nil, nil, nil)
+
+		_, err := source.Provenance(lock)
+		please.Expect(err).To(MatchError("banana"))
+	})
+}
+
+func TestS3ReleaseSource_ReleaseSignature(t *testing.T) {
+	config := cargo.ReleaseSourceConfig{Type: component.ReleaseSourceTypeS3, Bucket: "some-bucket", PathTemplate: "{{.Name}}.tgz"}
+	lock := cargo.BOSHReleaseTarballLock{Name: "banana", Version: "1.2.3", RemotePath: "banana/banana-1.2.3.tgz"}
+
+	t.Run("signature exists", func(t *testing.T) {
+		please := NewWithT(t)
+		downloader := new(fakes.S3Downloader)
+		downloader.DownloadStub = func(w io.WriterAt, _ *s3.GetObjectInput, _ ...func(*s3manager.Downloader)) (int64, error) {
+			n, err := w.WriteAt([]byte("c29tZSBzaWduYXR1cmU=\n"), 0)
+			return int64(n), err
+		}
+		source := component.NewS3ReleaseSource(config, nil, downloader, nil, nil)
+
+		please.Expect(source.ReleaseSignature(lock)).To(Equal("c29tZSBzaWduYXR1cmU="))
+		_, input, _ := downloader.DownloadArgsForCall(0)
+		please.Expect(*input.Bucket).To(Equal("some-bucket"))
+		please.Expect(*input.Key).To(Equal("banana/banana-1.2.3.tgz.sig"))
+	})
+
+	t.Run("request fails", func(t *testing.T) {
+		please := NewWithT(t)
+		downloader := new(fakes.S3Downloader)
+		downloader.DownloadReturns(0, errors.New("banana"))
+		source := component.NewS3ReleaseSource(config, nil, downloader, nil, nil)
+
+		_, err := source.ReleaseSignature(lock)
+		please.Expect(err).To(MatchError("banana"))
+	})
+}
+
+func publicKeyPEM(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
@@ -1,19 +1,13 @@
 package component
 
 import (
-	"bytes"
-	"crypto/sha1"
-	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
-	"os"
+	"net/http"
 	"path/filepath"
-	"regexp"
 	"strings"
-	"text/template"
 
-	"github.com/Masterminds/semver/v3"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -130,14 +124,47 @@ func (src S3ReleaseSource) GetMatchedRelease(spec cargo.BOSHReleaseTarballSpecif
 	}, nil
 }
 
-func (src S3ReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
-	pathTemplatePattern, _ := regexp.Compile(`^\d+\.\d+`)
-	tasVersion := pathTemplatePattern.FindString(src.ReleaseSourceConfig.PathTemplate)
-	var prefix string
-	if tasVersion != "" {
-		prefix = tasVersion + "/"
+// Provenance returns the object version ID when the bucket has versioning enabled.
+// Otherwise it returns the ETag.
+func (src S3ReleaseSource) Provenance(lock cargo.BOSHReleaseTarballLock) (string, error) {
+	headRequest := new(s3.HeadObjectInput)
+	headRequest.SetBucket(src.ReleaseSourceConfig.Bucket)
+	headRequest.SetKey(lock.RemotePath)
+
+	head, err := src.s3Client.HeadObject(headRequest)
+	if err != nil {
+		requestFailure, ok := err.(s3.RequestFailure)
+		if ok && requestFailure.StatusCode() == 404 {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+
+	if versionID := aws.StringValue(head.VersionId); versionID != "" && versionID != "null" {
+		return "s3-version-id:" + versionID, nil
 	}
-	prefix += spec.Name + "/"
+	return "s3-etag:" + strings.Trim(aws.StringValue(head.ETag), `"`), nil
+}
+
+// ReleaseSignature downloads the signature object next to the release object.
+func (src S3ReleaseSource) ReleaseSignature(lock cargo.BOSHReleaseTarballLock) (string, error) {
+	buf := aws.NewWriteAtBuffer(nil)
+	_, err := src.s3Downloader.Download(buf, &s3.GetObjectInput{
+		Bucket: aws.String(src.ReleaseSourceConfig.Bucket),
+		Key:    aws.String(lock.RemotePath + signatureFileSuffix),
+	})
+	if err != nil {
+		requestFailure, ok := err.(s3.RequestFailure)
+		if ok && requestFailure.StatusCode() == 404 {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return encodeSignature(buf.Bytes()), nil
+}
+
+func (src S3ReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTarballSpecification, noDownload bool) (cargo.BOSHReleaseTarballLock, error) {
+	prefix := objectKeyPrefix(src.ReleaseSourceConfig.PathTemplate, spec.Name)
 
 	releaseResults, err := src.s3Client.ListObjectsV2(&s3.ListObjectsV2Input{
 		Bucket: &src.ReleaseSourceConfig.Bucket,
@@ -147,57 +174,16 @@ func (src S3ReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTarballSpeci
 		return cargo.BOSHReleaseTarballLock{}, err
 	}
 
-	semverPattern, err := regexp.Compile(`([-v])\d+(.\d+)*`)
-	if err != nil {
-		return cargo.BOSHReleaseTarballLock{}, err
+	keys := make([]string, 0, len(releaseResults.Contents))
+	for _, result := range releaseResults.Contents {
+		keys = append(keys, *result.Key)
 	}
 
-	foundRelease := cargo.BOSHReleaseTarballLock{}
-	constraint, err := spec.VersionConstraints()
+	foundRelease, err := findReleaseVersionInObjectKeys(spec, keys, src.ReleaseSourceConfig.ID)
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
 	}
 
-	for _, result := range releaseResults.Contents {
-		versions := semverPattern.FindAllString(*result.Key, -1)
-		version := versions[0]
-		stemcellVersion := versions[len(versions)-1]
-		version = strings.Replace(version, "-", "", -1)
-		version = strings.Replace(version, "v", "", -1)
-		stemcellVersion = strings.Replace(stemcellVersion, "-", "", -1)
-		if len(versions) > 1 && stemcellVersion != spec.StemcellVersion {
-			continue
-		}
-		if version != "" {
-			newVersion, _ := semver.NewVersion(version)
-			if !constraint.Check(newVersion) {
-				continue
-			}
-
-			if (foundRelease == cargo.BOSHReleaseTarballLock{}) {
-				foundRelease = cargo.BOSHReleaseTarballLock{
-					Name:         spec.Name,
-					Version:      version,
-					RemotePath:   *result.Key,
-					RemoteSource: src.ReleaseSourceConfig.ID,
-				}
-			} else {
-				foundVersion, _ := semver.NewVersion(foundRelease.Version)
-				if newVersion.GreaterThan(foundVersion) {
-					foundRelease = cargo.BOSHReleaseTarballLock{
-						Name:         spec.Name,
-						Version:      version,
-						RemotePath:   *result.Key,
-						RemoteSource: src.ReleaseSourceConfig.ID,
-					}
-				}
-			}
-		}
-	}
-	if (foundRelease == cargo.BOSHReleaseTarballLock{}) {
-		return cargo.BOSHReleaseTarballLock{}, ErrNotFound
-	}
-
 	if noDownload {
 		foundRelease.SHA1 = "not-calculated"
 	} else {
@@ -207,50 +193,60 @@ func (src S3ReleaseSource) FindReleaseVersion(spec cargo.BOSHReleaseTarballSpeci
 			return cargo.BOSHReleaseTarballLock{}, err
 		}
 		foundRelease.SHA1 = releaseLocal.Lock.SHA1
+		foundRelease.SHA256 = releaseLocal.Lock.SHA256
 	}
 	return foundRelease, nil
 }
 
 func (src S3ReleaseSource) DownloadRelease(releaseDir string, lock cargo.BOSHReleaseTarballLock) (Local, error) {
+	concurrency := s3manager.DefaultDownloadConcurrency
+	if src.DownloadThreads > 0 {
+		concurrency = src.DownloadThreads
+	}
 	setConcurrency := func(dl *s3manager.Downloader) {
-		if src.DownloadThreads > 0 {
-			dl.Concurrency = src.DownloadThreads
-		} else {
-			dl.Concurrency = s3manager.DefaultDownloadConcurrency
-		}
+		dl.Concurrency = concurrency
 	}
 
 	src.logger.Printf(logLineDownload, lock.Name, ReleaseSourceTypeS3, src.ID())
 
 	outputFile := filepath.Join(releaseDir, filepath.Base(lock.RemotePath))
 
-	file, err := os.Create(outputFile)
+	file, err := openPartialDownload(outputFile)
 	if err != nil {
-		return Local{}, fmt.Errorf("failed to create file %q: %w", outputFile, err)
+		return Local{}, err
 	}
 	defer closeAndIgnoreError(file)
 
-	_, err = src.s3Downloader.Download(file, &s3.GetObjectInput{
+	input := &s3.GetObjectInput{
 		Bucket: aws.String(src.ReleaseSourceConfig.Bucket),
 		Key:    aws.String(lock.RemotePath),
-	}, setConcurrency)
-	if err != nil {
-		return Local{}, fmt.Errorf("failed to download file: %w\n", err)
+	}
+	if file.offset > 0 {
+		// resuming a download disables part-level concurrency
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", file.offset))
 	}
 
-	_, err = file.Seek(0, 0)
+	_, err = src.s3Downloader.Download(file, input, setConcurrency)
 	if err != nil {
-		return Local{}, fmt.Errorf("error reseting file cursor: %w", err) // untested
+		requestFailure, ok := err.(s3.RequestFailure)
+		alreadyDownloaded := ok && file.offset > 0 && requestFailure.StatusCode() == http.StatusRequestedRangeNotSatisfiable
+		if !alreadyDownloaded {
+			if file.offset == 0 && concurrency > 1 {
+				// parts are written out of order so parts after a failed one may be on disk,
+				// but a resumed download can only continue from the end of the file
+				if truncateErr := file.file.Truncate(0); truncateErr != nil {
+					return Local{}, truncateErr // untested
+				}
+			}
+			return Local{}, fmt.Errorf("failed to download file: %w\n", err)
+		}
 	}
 
-	hash := sha1.New()
-	_, err = io.Copy(hash, file)
+	lock.SHA1, lock.SHA256, err = file.complete()
 	if err != nil {
-		return Local{}, fmt.Errorf("error hashing file contents: %w", err) // untested
+		return Local{}, err
 	}
 
-	lock.SHA1 = hex.EncodeToString(hash.Sum(nil))
-
 	return Local{Lock: lock, LocalPath: outputFile}, nil
 }
 
@@ -262,37 +258,26 @@ func (src S3ReleaseSource) UploadRelease(spec cargo.BOSHReleaseTarballSpecificat
 
 	src.logger.Printf("uploading release %q to %s at %q...\n", spec.Name, src.ReleaseSourceConfig.Bucket, remotePath)
 
+	hash := newReleaseHash()
 	_, err = src.s3Uploader.Upload(&s3manager.UploadInput{
 		Bucket: aws.String(src.ReleaseSourceConfig.Bucket),
 		Key:    aws.String(remotePath),
-		Body:   file,
+		Body:   io.TeeReader(file, hash),
 	})
 	if err != nil {
 		return cargo.BOSHReleaseTarballLock{}, err
 	}
 
-	return cargo.BOSHReleaseTarballLock{
+	lock := cargo.BOSHReleaseTarballLock{
 		Name:         spec.Name,
 		Version:      spec.Version,
 		RemotePath:   remotePath,
 		RemoteSource: src.ReleaseSourceConfig.Bucket,
-	}, nil
-}
-
-func (src S3ReleaseSource) RemotePath(spec cargo.BOSHReleaseTarballSpecification) (string, error) {
-	pathBuf := new(bytes.Buffer)
-
-	err := src.pathTemplate().Execute(pathBuf, spec)
-	if err != nil {
-		return "", fmt.Errorf("unable to evaluate path_template: %w", err)
 	}
-
-	return pathBuf.String(), nil
+	lock.SHA1, lock.SHA256 = hash.sums()
+	return lock, nil
 }
 
-func (src S3ReleaseSource) pathTemplate() *template.Template {
-	return template.Must(
-		template.New("remote-path").
-			Funcs(template.FuncMap{"trimSuffix": strings.TrimSuffix}).
-			Parse(src.ReleaseSourceConfig.PathTemplate))
+func (src S3ReleaseSource) RemotePath(spec cargo.BOSHReleaseTarballSpecification) (string, error) {
+	return evaluatePathTemplate(src.ReleaseSourceConfig.PathTemplate, spec)
 }
@@ -14,6 +14,7 @@ import (
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gstruct"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/go-git/go-billy/v5/osfs"
@@ -137,14 +138,14 @@ var _ = Describe("S3ReleaseSource", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(releaseContents).To(Equal([]byte("some-bucket/" + remoteRelease.RemotePath)))
 
-			sha1, err := component.CalculateSum(releasePath, osfs.New(""))
+			sha1, sha256, err := component.CalculateSums(releasePath, osfs.New(""))
 			Expect(err).NotTo(HaveOccurred())
 
 			_, _, opts := fakeS3Downloader.DownloadArgsForCall(0)
 			verifySetsConcurrency(opts, 7)
 
 			Expect(localRelease).To(Equal(component.Local{
-				Lock:      remoteRelease.WithSHA1(sha1),
+				Lock:      remoteRelease.WithSHA1(sha1).WithSHA256(sha256),
 				LocalPath: releasePath,
 			}))
 		})
@@ -161,6 +162,34 @@ var _ = Describe("S3ReleaseSource", func() {
 			})
 		})
 
+		Context("when a previous download was interrupted", func() {
+			BeforeEach(func() {
+				partialPath := filepath.Join(releaseDir, expectedLocalFilename+component.PartialDownloadSuffix)
+				Expect(os.WriteFile(partialPath, []byte("some-bucket/"), 0o644)).To(Succeed())
+
+				fakeS3Downloader.DownloadStub = func(writer io.WriterAt, objectInput *s3.GetObjectInput, setConcurrency ...func(dl *s3manager.Downloader)) (int64, error) {
+					n, err := writer.WriteAt([]byte(*objectInput.Key), 0)
+					return int64(n), err
+				}
+			})
+
+			It("resumes the download from the end of the partial file", func() {
+				localRelease, err := releaseSource.DownloadRelease(releaseDir, remoteRelease)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, input, _ := fakeS3Downloader.DownloadArgsForCall(0)
+				Expect(input.Range).To(Equal(aws.String("bytes=12-")))
+
+				releasePath := filepath.Join(releaseDir, expectedLocalFilename)
+				Expect(localRelease.LocalPath).To(Equal(releasePath))
+				releaseContents, err := os.ReadFile(releasePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(releaseContents).To(Equal([]byte("some-bucket/" + remoteRelease.RemotePath)))
+
+				Expect(releasePath + component.PartialDownloadSuffix).NotTo(BeAnExistingFile())
+			})
+		})
+
 		Context("failure cases", func() {
 			Context("when a file can't be created", func() {
 				It("returns an error", func() {
@@ -182,6 +211,48 @@ var _ = Describe("S3ReleaseSource", func() {
 					Expect(err).To(HaveOccurred())
 					Expect(err).To(MatchError("failed to download file: 503 Service Unavailable\n"))
 				})
+
+				It("leaves the partial file for the next attempt", func() {
+					_, _ = releaseSource.DownloadRelease(releaseDir, remoteRelease)
+					Expect(filepath.Join(releaseDir, expectedLocalFilename+component.PartialDownloadSuffix)).To(BeAnExistingFile())
+					Expect(filepath.Join(releaseDir, expectedLocalFilename)).NotTo(BeAnExistingFile())
+				})
+			})
+
+			Context("when a part of a concurrent download fails after a later part was written", func() {
+				BeforeEach(func() {
+					fakeS3Downloader.DownloadCalls(func(w io.WriterAt, i *s3.GetObjectInput, options ...func(*s3manager.Downloader)) (int64, error) {
+						n, err := w.WriteAt([]byte("later-part"), 1024)
+						if err != nil {
+							return int64(n), err
+						}
+						return int64(n), errors.New("503 Service Unavailable")
+					})
+				})
+
+				It("discards the partial file so the next attempt does not resume after a hole", func() {
+					releaseSource.DownloadThreads = 4
+					_, err := releaseSource.DownloadRelease(releaseDir, remoteRelease)
+					Expect(err).To(MatchError(ContainSubstring("503 Service Unavailable")))
+
+					info, err := os.Stat(filepath.Join(releaseDir, expectedLocalFilename+component.PartialDownloadSuffix))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(info.Size()).To(BeZero())
+				})
+
+				It("keeps the partial file when parts are downloaded in order", func() {
+					fakeS3Downloader.DownloadCalls(func(w io.WriterAt, i *s3.GetObjectInput, options ...func(*s3manager.Downloader)) (int64, error) {
+						n, _ := w.WriteAt([]byte("first-part"), 0)
+						return int64(n), errors.New("503 Service Unavailable")
+					})
+					releaseSource.DownloadThreads = 1
+					_, err := releaseSource.DownloadRelease(releaseDir, remoteRelease)
+					Expect(err).To(HaveOccurred())
+
+					info, err := os.Stat(filepath.Join(releaseDir, expectedLocalFilename+component.PartialDownloadSuffix))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(info.Size()).NotTo(BeZero())
+				})
 			})
 		})
 	})
@@ -349,7 +420,8 @@ var _ = Describe("S3ReleaseSource", func() {
 				Expect(remoteRelease).To(Equal(
 					releaseID.Lock().
 						WithRemote(sourceID, uaaKey).
-						WithSHA1("1a77ff749f0f2f49493eb8a517fb7eaa04df9b62"),
+						WithSHA1("1a77ff749f0f2f49493eb8a517fb7eaa04df9b62").
+						WithSHA256("572b6164ed25b6e690d5d7b2ec29f8ab4d836ee10354d36449057f6392e88448"),
 				),
 				)
 			})
@@ -414,6 +486,7 @@ var _ = Describe("S3ReleaseSource", func() {
 					RemotePath:   uaaKey,
 					RemoteSource: sourceID,
 					SHA1:         "bc7cb372ee4b9a9d6f4e8a993d46405d2c114e9c",
+					SHA256:       "031ec6d6041055c25659017b0ccbe925131c1c65e7c371e0b8790524fea5405e",
 				}))
 			})
 		})
@@ -545,7 +618,7 @@ var _ = Describe("S3ReleaseSource", func() {
 				input := fakeS3Client.ListObjectsV2ArgsForCall(0)
 				Expect(*input.Prefix).To(Equal("2.11/uaa/"))
 
-				Expect(remoteRelease).To(Equal(releaseID.Lock().WithRemote(sourceID, uaaKey).WithSHA1("78facf87f730395fb263fb5e89157c438fc1d8a9")))
+				Expect(remoteRelease).To(Equal(releaseID.Lock().WithRemote(sourceID, uaaKey).WithSHA1("78facf87f730395fb263fb5e89157c438fc1d8a9").WithSHA256("11b31a3c1b2e28c9dccaf717bf73d0fd6f9c3f09548f9f400bd1ca3d80909a79")))
 			})
 		})
 	})
@@ -555,6 +628,7 @@ var _ = Describe("S3ReleaseSource", func() {
 			s3Uploader    *fetcherFakes.S3Uploader
 			releaseSource component.S3ReleaseSource
 			file          io.Reader
+			uploaded      []byte
 		)
 
 		BeforeEach(func() {
@@ -572,6 +646,12 @@ var _ = Describe("S3ReleaseSource", func() {
 				log.New(GinkgoWriter, "", 0),
 			)
 			file = strings.NewReader("banana banana")
+			uploaded = nil
+			s3Uploader.UploadStub = func(input *s3manager.UploadInput, _ ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+				var err error
+				uploaded, err = io.ReadAll(input.Body)
+				return new(s3manager.UploadOutput), err
+			}
 		})
 
 		Context("happy path", func() {
@@ -590,7 +670,7 @@ var _ = Describe("S3ReleaseSource", func() {
 
 				Expect(opts.Bucket).To(PointTo(Equal("orange-bucket")))
 				Expect(opts.Key).To(PointTo(Equal("banana/banana-1.2.3.tgz")))
-				Expect(opts.Body).To(Equal(file))
+				Expect(uploaded).To(Equal([]byte("banana banana")))
 			})
 
 			It("returns the remote release", func() {
@@ -603,6 +683,8 @@ var _ = Describe("S3ReleaseSource", func() {
 				Expect(remoteRelease).To(Equal(cargo.BOSHReleaseTarballLock{
 					Name:         "banana",
 					Version:      "1.2.3",
+					SHA1:         "65cc9a90ec600cf961daadc13a664d6bfcd1927f",
+					SHA256:       "fd338db3bb619a50fbd68bd46899ff177be5e8d5e06943938a7df70f974dfa5a",
 					RemotePath:   "banana/banana-1.2.3.tgz",
 					RemoteSource: "orange-bucket",
 				}))
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"runtime"
 
 	"github.com/docker/docker/client"
 
@@ -26,8 +27,9 @@ func main() {
 	outLogger := log.New(os.Stdout, "", 0)
 
 	var global struct {
-		Help    bool `short:"h" long:"help"    description:"prints this usage information"   default:"false"`
-		Version bool `short:"v" long:"version" description:"prints the kiln release version" default:"false"`
+		Help    bool   `short:"h" long:"help"    description:"prints this usage information"   default:"false"`
+		Version bool   `short:"v" long:"version" description:"prints the kiln release version" default:"false"`
+		Output  string `          long:"output"  description:"output format: text (the default) or json"`
 	}
 
 	args, err := jhanda.Parse(&global, os.Args[1:])
@@ -57,10 +59,21 @@ func main() {
 		command = "help"
 	}
 
+	var report *commands.Report
+	switch global.Output {
+	case "", "text":
+	case "json":
+		// stdout is reserved for the result document
+		outLogger.SetOutput(os.Stderr)
+		report = commands.NewReport(command)
+	default:
+		log.Fatalf("unknown output format %q: expected text or json", global.Output)
+	}
+
 	fs := osfs.New("")
 
 	releaseManifestReader := builder.NewReleaseManifestReader(fs)
-	releasesService := baking.NewReleasesService(errLogger, releaseManifestReader)
+	releasesService := baking.NewReleasesService(errLogger, releaseManifestReader).WithConcurrency(runtime.NumCPU())
 	pivnetService := new(pivnet.Service)
 	localReleaseDirectory := component.NewLocalReleaseDirectory(outLogger, releasesService)
 	mrsProvider := commands.MultiReleaseSourceProvider(func(kilnfile cargo.Kilnfile, allowOnlyPublishable bool) component.MultiReleaseSource {
@@ -78,7 +91,10 @@ func main() {
 
 	commandSet := jhanda.CommandSet{}
 	fetch := commands.NewFetch(outLogger, mrsProvider, localReleaseDirectory)
-	commandSet["fetch"] = fetch
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		fetch = fetch.WithReleaseCache(component.NewReleaseCache(component.DefaultReleaseCacheDirectory(homeDir)))
+	}
+	commandSet["fetch"] = fetch.WithReport(report)
 	commandSet["bake"] = commands.NewBake(fs, releasesService, outLogger, errLogger, fetch)
 	mobyClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
@@ -92,38 +108,89 @@ func main() {
 	commandSet["test"] = commands.NewTileTest(outLogger, context.Background(), mobyClient, sshProvider)
 	commandSet["help"] = commands.NewHelp(os.Stdout, globalFlagsUsage, commandSet)
 	commandSet["version"] = commands.NewVersion(outLogger, version)
-	commandSet["update-release"] = commands.NewUpdateRelease(outLogger, fs, mrsProvider)
+	commandSet["update-release"] = commands.NewUpdateRelease(outLogger, fs, mrsProvider).WithReport(report)
+	commandSet["add-release"] = commands.NewAddRelease(outLogger, fs, mrsProvider).WithReport(report)
+	commandSet["remove-release"] = commands.NewRemoveRelease(outLogger, fs).WithReport(report)
 	commandSet["upload-release"] = commands.UploadRelease{
 		FS:                    fs,
 		Logger:                outLogger,
 		ReleaseUploaderFinder: ruFinder,
 	}
 	commandSet["sync-with-local"] = commands.NewSyncWithLocal(fs, localReleaseDirectory, rpFinder, outLogger)
-	commandSet["publish"] = commands.NewPublish(outLogger, errLogger, osfs.New(""))
+	commandSet["publish"] = commands.NewPublish(outLogger, errLogger, osfs.New("")).WithReport(report)
 
 	commandSet["update-stemcell"] = commands.UpdateStemcell{
 		Logger:                     outLogger,
 		MultiReleaseSourceProvider: mrsProvider,
 		FS:                         osfs.New(""),
-	}
+	}.WithReport(report)
 
 	// commandSet["fetch"] = commands.NewFetch(outLogger, mrsProvider, localReleaseDirectory)
 	commandSet["glaze"] = new(commands.Glaze)
 
 	commandSet["generate-osm-manifest"] = commands.NewOSM(outLogger, nil)
 
-	commandSet["find-release-version"] = commands.NewFindReleaseVersion(outLogger, mrsProvider)
+	commandSet["find-release-version"] = commands.NewFindReleaseVersion(outLogger, mrsProvider).WithReport(report)
+	commandSet["outdated"] = commands.NewOutdated(outLogger, mrsProvider).WithReport(report)
+	commandSet["init"] = commands.NewInit(osfs.New(""), outLogger).WithReport(report)
+	commandSet["diff"] = commands.NewDiff(outLogger).WithReport(report)
+	commandSet["check-upgrade"] = commands.NewCheckUpgrade(outLogger).WithReport(report)
+
+	commandSet["find-stemcell-version"] = commands.NewFindStemcellVersion(outLogger, pivnetService).WithReport(report)
 
-	commandSet["find-stemcell-version"] = commands.NewFindStemcellVersion(outLogger, pivnetService)
+	commandSet["cache"] = commands.NewCache(outLogger).WithReport(report)
 
-	commandSet["cache-compiled-releases"] = commands.NewCacheCompiledReleases().WithLogger(outLogger)
+	commandSet["cache-compiled-releases"] = commands.NewCacheCompiledReleases().WithLogger(outLogger).WithReport(report)
 
-	commandSet["validate"] = commands.NewValidate(osfs.New(""))
+	commandSet["validate"] = commands.NewValidate(osfs.New("")).WithReport(report)
+	commandSet["verify-lock"] = commands.NewVerifyLock(outLogger, localReleaseDirectory, mrsProvider).WithReport(report)
 	commandSet["release-notes"], err = commands.NewReleaseNotesCommand()
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// reportCommands are the commands that add their results to the report written with --output json
+	reportCommands := map[string]bool{
+		"add-release":             true,
+		"cache":                   true,
+		"cache-compiled-releases": true,
+		"check-upgrade":           true,
+		"diff":                    true,
+		"fetch":                   true,
+		"find-release-version":    true,
+		"find-stemcell-version":   true,
+		"init":                    true,
+		"outdated":                true,
+		"publish":                 true,
+		"remove-release":          true,
+		"update-release":          true,
+		"update-stemcell":         true,
+		"validate":                true,
+		"verify-lock":             true,
+	}
+
+	if report != nil {
+		// CommandSet.Execute formats command errors as text, which drops their error codes
+		if cmd, ok := commandSet[command]; ok {
+			if reportCommands[command] {
+				err = cmd.Execute(args)
+			} else {
+				err = fmt.Errorf("--output json is not supported by the %s command", command)
+			}
+		} else {
+			err = commandSet.Execute(command, args)
+		}
+		report.Finish(err)
+		if writeErr := report.Write(os.Stdout); writeErr != nil {
+			log.Fatal(writeErr)
+		}
+		if err != nil {
+			errLogger.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	err = commandSet.Execute(command, args)
 	if err != nil {
 		log.Fatal(err)
@@ -16,6 +16,14 @@ type Kilnfile struct {
 	Releases        []BOSHReleaseTarballSpecification `yaml:"releases,omitempty"`
 	TileNames       []string                          `yaml:"tile_names,omitempty"`
 	Stemcell        Stemcell                          `yaml:"stemcell_criteria,omitempty"`
+
+	// Stemcells is used instead of Stemcell when a tile is built for more than one
+	// stemcell operating system, for example ubuntu-jammy and windows2019.
+	Stemcells []Stemcell `yaml:"stemcells,omitempty"`
+
+	// Variants are the tiles built from the same sources, for example a full and
+	// a small footprint tile. See TileVariant.
+	Variants []TileVariant `yaml:"variants,omitempty"`
 }
 
 func (kf Kilnfile) BOSHReleaseTarballSpecification(name string) (BOSHReleaseTarballSpecification, error) {
@@ -27,9 +35,65 @@ func (kf Kilnfile) BOSHReleaseTarballSpecification(name string) (BOSHReleaseTarb
 	return BOSHReleaseTarballSpecification{}, fmt.Errorf("failed to find component specification with name %q in Kilnfile", name)
 }
 
+// AllStemcells returns the stemcells list or, when it is not set, the stemcell_criteria.
+func (kf Kilnfile) AllStemcells() []Stemcell {
+	return allStemcells(kf.Stemcell, kf.Stemcells)
+}
+
+// FindStemcell returns the stemcell with the operating system. When os is empty and
+// there is exactly one stemcell, that stemcell is returned.
+func (kf Kilnfile) FindStemcell(os string) (Stemcell, error) {
+	return findStemcell(kf.AllStemcells(), os, "Kilnfile")
+}
+
+// FindVariant returns the tile variant with the name.
+func (kf Kilnfile) FindVariant(name string) (TileVariant, error) {
+	for _, variant := range kf.Variants {
+		if variant.Name == name {
+			return variant, nil
+		}
+	}
+	return TileVariant{}, fmt.Errorf("failed to find tile variant with name %q in Kilnfile", name)
+}
+
+// TileVariant is one of the tiles baked from the sources next to the Kilnfile. Fields that are
+// not set use the bake flags. Paths are relative to the directory containing the Kilnfile.
+type TileVariant struct {
+	// Name is a required field. When the variables do not set tile_name, it is set to Name.
+	Name string `yaml:"name"`
+
+	// OutputFile defaults to tile-<name>-<version>.pivotal.
+	OutputFile string `yaml:"output_file,omitempty"`
+
+	Metadata string `yaml:"metadata,omitempty"`
+
+	// VariablesFiles are read after the --variables-file files. Variables are
+	// set last and override any variable with the same name.
+	VariablesFiles []string               `yaml:"variables_files,omitempty"`
+	Variables      map[string]interface{} `yaml:"variables,omitempty"`
+
+	BOSHVariableDirectories  []string `yaml:"bosh_variables_directories,omitempty"`
+	FormDirectories          []string `yaml:"forms_directories,omitempty"`
+	InstanceGroupDirectories []string `yaml:"instance_groups_directories,omitempty"`
+	JobDirectories           []string `yaml:"jobs_directories,omitempty"`
+	MigrationDirectories     []string `yaml:"migrations_directories,omitempty"`
+	PartialDirectories       []string `yaml:"partials_directories,omitempty"`
+	PropertyDirectories      []string `yaml:"properties_directories,omitempty"`
+	RuntimeConfigDirectories []string `yaml:"runtime_configs_directories,omitempty"`
+	EmbedPaths               []string `yaml:"embed_paths,omitempty"`
+
+	// Releases are the names of the releases in the tile. When it is empty
+	// every release in the releases directories is included.
+	Releases []string `yaml:"releases,omitempty"`
+}
+
 type KilnfileLock struct {
 	Releases []BOSHReleaseTarballLock `yaml:"releases"`
-	Stemcell Stemcell                 `yaml:"stemcell_criteria"`
+	Stemcell Stemcell                 `yaml:"stemcell_criteria,omitempty"`
+
+	// Stemcells is used instead of Stemcell when a tile is built for more than one
+	// stemcell operating system. See Kilnfile.Stemcells.
+	Stemcells []Stemcell `yaml:"stemcells,omitempty"`
 }
 
 func (k KilnfileLock) FindBOSHReleaseWithName(name string) (BOSHReleaseTarballLock, error) {
@@ -51,6 +115,51 @@ func (k KilnfileLock) UpdateBOSHReleaseTarballLockWithName(name string, lock BOS
 	return errors.New("not found")
 }
 
+// AllStemcells returns the stemcells list or, when it is not set, the stemcell_criteria.
+func (k KilnfileLock) AllStemcells() []Stemcell {
+	return allStemcells(k.Stemcell, k.Stemcells)
+}
+
+// FindStemcell returns the stemcell with the operating system. When os is empty and
+// there is exactly one stemcell, that stemcell is returned.
+func (k KilnfileLock) FindStemcell(os string) (Stemcell, error) {
+	return findStemcell(k.AllStemcells(), os, "Kilnfile.lock")
+}
+
+// StemcellForRelease returns the stemcell a release is compiled against. Releases select
+// a stemcell by setting "os" in the Kilnfile; releases that do not set it use the first
+// stemcell. The zero Stemcell is returned when the lock does not have any stemcells.
+func (k KilnfileLock) StemcellForRelease(spec BOSHReleaseTarballSpecification) (Stemcell, error) {
+	stemcells := k.AllStemcells()
+	if spec.StemcellOS == "" {
+		if len(stemcells) == 0 {
+			return Stemcell{}, nil
+		}
+		return stemcells[0], nil
+	}
+	for _, stemcell := range stemcells {
+		if stemcell.OS == spec.StemcellOS {
+			return stemcell, nil
+		}
+	}
+	return Stemcell{}, fmt.Errorf("release %q is compiled against stemcell %q which is not in the Kilnfile.lock", spec.Name, spec.StemcellOS)
+}
+
+// UpdateStemcell replaces the stemcell with the same operating system.
+func (k *KilnfileLock) UpdateStemcell(stemcell Stemcell) error {
+	for i, s := range k.Stemcells {
+		if s.OS == stemcell.OS {
+			k.Stemcells[i] = stemcell
+			return nil
+		}
+	}
+	if len(k.Stemcells) == 0 && k.Stemcell.OS == stemcell.OS {
+		k.Stemcell = stemcell
+		return nil
+	}
+	return fmt.Errorf("stemcell %q not found in Kilnfile.lock", stemcell.OS)
+}
+
 type BOSHReleaseTarballSpecification struct {
 	// Name is a required field and must be set with the bosh release name
 	Name string `yaml:"name"`
@@ -117,6 +226,18 @@ type ReleaseSourceConfig struct {
 	ArtifactoryHost string `yaml:"artifactory_host,omitempty"`
 	Username        string `yaml:"username,omitempty"`
 	Password        string `yaml:"password,omitempty"`
+	Registry        string `yaml:"registry,omitempty"`
+	Path            string `yaml:"path,omitempty"`
+
+	ServiceAccountKey string `yaml:"service_account_key,omitempty"`
+	Container         string `yaml:"container,omitempty"`
+	AccountName       string `yaml:"account_name,omitempty"`
+	AccountKey        string `yaml:"account_key,omitempty"`
+	SASToken          string `yaml:"sas_token,omitempty"`
+
+	// SignaturePublicKey is a PEM encoded ECDSA, Ed25519, or RSA public key. When it is set, every
+	// release from the source must have a valid signature in the Kilnfile.lock.
+	SignaturePublicKey string `yaml:"signature_public_key,omitempty"`
 }
 
 // BOSHReleaseTarballLock represents an exact build of a bosh release
@@ -130,11 +251,24 @@ type BOSHReleaseTarballLock struct {
 	SHA1    string `yaml:"sha1"`
 	Version string `yaml:"version,omitempty"`
 
+	// SHA256 is recorded along with SHA1 when a release is downloaded or uploaded.
+	// It is empty in Kilnfile.lock files written by older versions of kiln; when it
+	// is set, fetch checks it in addition to SHA1.
+	SHA256 string `yaml:"sha256,omitempty"`
+
 	StemcellOS      string `yaml:"-"`
 	StemcellVersion string `yaml:"-"`
 
 	RemoteSource string `yaml:"remote_source"`
 	RemotePath   string `yaml:"remote_path"`
+
+	// Provenance optionally identifies the exact remote object the release came from,
+	// for example "github-asset-id:1234" or "s3-version-id:abc". See component.ProvenanceReporter.
+	Provenance string `yaml:"provenance,omitempty"`
+
+	// Signature is a base64 encoded detached signature of the release tarball. It is
+	// required when the release source has a signature_public_key.
+	Signature string `yaml:"signature,omitempty"`
 }
 
 func (lock BOSHReleaseTarballLock) ReleaseSlug() boshdir.ReleaseSlug {
@@ -183,6 +317,11 @@ func (lock BOSHReleaseTarballLock) WithSHA1(sum string) BOSHReleaseTarballLock {
 	return lock
 }
 
+func (lock BOSHReleaseTarballLock) WithSHA256(sum string) BOSHReleaseTarballLock {
+	lock.SHA256 = sum
+	return lock
+}
+
 func (lock BOSHReleaseTarballLock) WithRemote(source, path string) BOSHReleaseTarballLock {
 	lock.RemoteSource = source
 	lock.RemotePath = path
@@ -200,6 +339,35 @@ type Stemcell struct {
 	TanzuNetSlug string `yaml:"slug,omitempty"`
 }
 
+func allStemcells(stemcell Stemcell, stemcells []Stemcell) []Stemcell {
+	if len(stemcells) > 0 {
+		return stemcells
+	}
+	if stemcell == (Stemcell{}) {
+		return nil
+	}
+	return []Stemcell{stemcell}
+}
+
+func findStemcell(stemcells []Stemcell, os, fileName string) (Stemcell, error) {
+	if os == "" {
+		switch len(stemcells) {
+		case 0:
+			return Stemcell{}, fmt.Errorf("no stemcell in %s", fileName)
+		case 1:
+			return stemcells[0], nil
+		default:
+			return Stemcell{}, fmt.Errorf("%s has more than one stemcell; an operating system must be specified", fileName)
+		}
+	}
+	for _, stemcell := range stemcells {
+		if stemcell.OS == os {
+			return stemcell, nil
+		}
+	}
+	return Stemcell{}, fmt.Errorf("stemcell %q not found in %s", os, fileName)
+}
+
 func (stemcell Stemcell) ProductSlug() (string, error) {
 	if stemcell.TanzuNetSlug != "" {
 		return stemcell.TanzuNetSlug, nil
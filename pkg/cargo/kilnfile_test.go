@@ -121,3 +121,98 @@ func TestStemcell_ProductSlug(t *testing.T) {
 		})
 	}
 }
+
+func TestKilnfileLock_stemcells(t *testing.T) {
+	jammy := Stemcell{OS: "ubuntu-jammy", Version: "1.123"}
+	windows := Stemcell{OS: "windows2019", Version: "2019.60"}
+
+	t.Run("stemcell_criteria", func(t *testing.T) {
+		please := NewWithT(t)
+		lock := KilnfileLock{Stemcell: jammy}
+
+		please.Expect(lock.AllStemcells()).To(Equal([]Stemcell{jammy}))
+		please.Expect(lock.FindStemcell("")).To(Equal(jammy))
+		please.Expect(lock.StemcellForRelease(BOSHReleaseTarballSpecification{Name: "banana"})).To(Equal(jammy))
+
+		please.Expect(lock.UpdateStemcell(Stemcell{OS: "ubuntu-jammy", Version: "1.200"})).To(Succeed())
+		please.Expect(lock.Stemcell.Version).To(Equal("1.200"))
+	})
+
+	t.Run("stemcells", func(t *testing.T) {
+		please := NewWithT(t)
+		lock := KilnfileLock{Stemcells: []Stemcell{jammy, windows}}
+
+		please.Expect(lock.AllStemcells()).To(Equal([]Stemcell{jammy, windows}))
+		please.Expect(lock.FindStemcell("windows2019")).To(Equal(windows))
+		_, err := lock.FindStemcell("")
+		please.Expect(err).To(MatchError(ContainSubstring("more than one stemcell")))
+
+		please.Expect(lock.StemcellForRelease(BOSHReleaseTarballSpecification{Name: "banana"})).To(Equal(jammy))
+		please.Expect(lock.StemcellForRelease(BOSHReleaseTarballSpecification{Name: "hwc", StemcellOS: "windows2019"})).To(Equal(windows))
+		_, err = lock.StemcellForRelease(BOSHReleaseTarballSpecification{Name: "lemon", StemcellOS: "ubuntu-xenial"})
+		please.Expect(err).To(MatchError(ContainSubstring(`stemcell "ubuntu-xenial" which is not in the Kilnfile.lock`)))
+
+		please.Expect(lock.UpdateStemcell(Stemcell{OS: "windows2019", Version: "2019.61"})).To(Succeed())
+		please.Expect(lock.Stemcells).To(Equal([]Stemcell{jammy, {OS: "windows2019", Version: "2019.61"}}))
+		please.Expect(lock.UpdateStemcell(Stemcell{OS: "ubuntu-xenial"})).NotTo(Succeed())
+	})
+
+	t.Run("without stemcells", func(t *testing.T) {
+		please := NewWithT(t)
+		lock := KilnfileLock{}
+
+		please.Expect(lock.AllStemcells()).To(BeEmpty())
+		please.Expect(lock.StemcellForRelease(BOSHReleaseTarballSpecification{Name: "banana"})).To(Equal(Stemcell{}))
+		_, err := lock.FindStemcell("")
+		please.Expect(err).To(HaveOccurred())
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		please := NewWithT(t)
+		var lock KilnfileLock
+		please.Expect(yaml.Unmarshal([]byte(`
+releases: []
+stemcells:
+- os: ubuntu-jammy
+  version: "1.123"
+- os: windows2019
+  version: "2019.60"
+`), &lock)).To(Succeed())
+		please.Expect(lock.AllStemcells()).To(Equal([]Stemcell{jammy, windows}))
+
+		buf, err := yaml.Marshal(lock)
+		please.Expect(err).NotTo(HaveOccurred())
+		please.Expect(string(buf)).NotTo(ContainSubstring("stemcell_criteria"))
+	})
+}
+
+func TestKilnfile_variants(t *testing.T) {
+	please := NewWithT(t)
+	var kilnfile Kilnfile
+	please.Expect(yaml.Unmarshal([]byte(`
+tile_names: [ert, srt]
+variants:
+- name: ert
+- name: srt
+  output_file: srt.pivotal
+  variables_files: [variables/srt.yml]
+  variables:
+    small_footprint: true
+  instance_groups_directories: [instance_groups, srt/instance_groups]
+  releases: [banana]
+`), &kilnfile)).To(Succeed())
+
+	variant, err := kilnfile.FindVariant("srt")
+	please.Expect(err).NotTo(HaveOccurred())
+	please.Expect(variant).To(Equal(TileVariant{
+		Name:                     "srt",
+		OutputFile:               "srt.pivotal",
+		VariablesFiles:           []string{"variables/srt.yml"},
+		Variables:                map[string]interface{}{"small_footprint": true},
+		InstanceGroupDirectories: []string{"instance_groups", "srt/instance_groups"},
+		Releases:                 []string{"banana"},
+	}))
+
+	_, err = kilnfile.FindVariant("banana")
+	please.Expect(err).To(MatchError(ContainSubstring(`tile variant with name "banana"`)))
+}
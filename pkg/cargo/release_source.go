@@ -1,5 +1,7 @@
 package cargo
 
+import "strings"
+
 const (
 	// BOSHReleaseTarballSourceTypeBOSHIO is the value of the Type field on cargo.ReleaseSourceConfig
 	// for fetching https://bosh.io releases.
@@ -16,6 +18,22 @@ const (
 	// BOSHReleaseTarballSourceTypeArtifactory is the value for the Type field on cargo.ReleaseSourceConfig
 	// for releases stored on Artifactory.
 	BOSHReleaseTarballSourceTypeArtifactory = "artifactory"
+
+	// BOSHReleaseTarballSourceTypeOCI is the value for the Type field on cargo.ReleaseSourceConfig
+	// for releases stored as artifacts in an OCI registry.
+	BOSHReleaseTarballSourceTypeOCI = "oci"
+
+	// BOSHReleaseTarballSourceTypeDirectory is the value for the Type field on cargo.ReleaseSourceConfig
+	// for releases stored in a local (or network mounted) directory.
+	BOSHReleaseTarballSourceTypeDirectory = "directory"
+
+	// BOSHReleaseTarballSourceTypeGCS is the value for the Type field on cargo.ReleaseSourceConfig
+	// for releases stored in a Google Cloud Storage bucket.
+	BOSHReleaseTarballSourceTypeGCS = "gcs"
+
+	// BOSHReleaseTarballSourceTypeAzure is the value for the Type field on cargo.ReleaseSourceConfig
+	// for releases stored in an Azure Blob Storage container.
+	BOSHReleaseTarballSourceTypeAzure = "azure"
 )
 
 func BOSHReleaseTarballSourceID(releaseConfig ReleaseSourceConfig) string {
@@ -31,6 +49,18 @@ func BOSHReleaseTarballSourceID(releaseConfig ReleaseSourceConfig) string {
 		return releaseConfig.Org
 	case BOSHReleaseTarballSourceTypeArtifactory:
 		return BOSHReleaseTarballSourceTypeArtifactory
+	case BOSHReleaseTarballSourceTypeOCI:
+		// sources on the same registry are told apart by their repository
+		if releaseConfig.Repo == "" {
+			return releaseConfig.Registry
+		}
+		return strings.TrimSuffix(releaseConfig.Registry, "/") + "/" + strings.Trim(releaseConfig.Repo, "/")
+	case BOSHReleaseTarballSourceTypeDirectory:
+		return releaseConfig.Path
+	case BOSHReleaseTarballSourceTypeGCS:
+		return releaseConfig.Bucket
+	case BOSHReleaseTarballSourceTypeAzure:
+		return releaseConfig.Container
 	default:
 		return ""
 	}
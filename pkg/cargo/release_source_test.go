@@ -24,6 +24,8 @@ func TestReleaseSourceID(t *testing.T) {
 		{Name: BOSHReleaseTarballSourceTypeBOSHIO + " default", ExpectedID: BOSHReleaseTarballSourceTypeBOSHIO, Configuration: ReleaseSourceConfig{ID: "", Type: BOSHReleaseTarballSourceTypeBOSHIO}},
 		{Name: BOSHReleaseTarballSourceTypeGithub + " default", ExpectedID: "identifier", Configuration: ReleaseSourceConfig{ID: "", Type: BOSHReleaseTarballSourceTypeGithub, Org: "identifier"}},
 		{Name: BOSHReleaseTarballSourceTypeS3 + " default", ExpectedID: "identifier", Configuration: ReleaseSourceConfig{ID: "", Type: BOSHReleaseTarballSourceTypeS3, Bucket: "identifier"}},
+		{Name: BOSHReleaseTarballSourceTypeOCI + " default", ExpectedID: "harbor.example.com/tas/releases", Configuration: ReleaseSourceConfig{ID: "", Type: BOSHReleaseTarballSourceTypeOCI, Registry: "harbor.example.com/", Repo: "tas/releases"}},
+		{Name: BOSHReleaseTarballSourceTypeOCI + " default without repo", ExpectedID: "harbor.example.com", Configuration: ReleaseSourceConfig{ID: "", Type: BOSHReleaseTarballSourceTypeOCI, Registry: "harbor.example.com"}},
 	} {
 		t.Run(tt.Name, func(t *testing.T) {
 			assert.Equal(t, tt.ExpectedID, BOSHReleaseTarballSourceID(tt.Configuration))
@@ -29,6 +29,7 @@ func Validate(spec Kilnfile, lock KilnfileLock) []error {
 	}
 
 	result = append(result, ensureRemoteSourceExistsForEachReleaseLock(spec, lock)...)
+	result = append(result, validateVariants(spec)...)
 
 	if len(result) > 0 {
 		return result
@@ -50,6 +51,28 @@ func ensureRemoteSourceExistsForEachReleaseLock(spec Kilnfile, lock KilnfileLock
 	return result
 }
 
+func validateVariants(spec Kilnfile) []error {
+	var result []error
+	names := make(map[string]bool, len(spec.Variants))
+	for index, variant := range spec.Variants {
+		if variant.Name == "" {
+			result = append(result, fmt.Errorf("variant at index %d missing name", index))
+			continue
+		}
+		if names[variant.Name] {
+			result = append(result, fmt.Errorf("variant %q is declared more than once", variant.Name))
+		}
+		names[variant.Name] = true
+
+		for _, releaseName := range variant.Releases {
+			if _, err := spec.BOSHReleaseTarballSpecification(releaseName); err != nil {
+				result = append(result, fmt.Errorf("variant %q includes release %q which is not in the Kilnfile", variant.Name, releaseName))
+			}
+		}
+	}
+	return result
+}
+
 func checkComponentVersionsAndConstraint(spec BOSHReleaseTarballSpecification, lock BOSHReleaseTarballLock, index int) error {
 	v, err := semver.NewVersion(lock.Version)
 	if err != nil {
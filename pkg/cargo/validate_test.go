@@ -247,3 +247,31 @@ func TestValidate_checkComponentVersionsAndConstraint(t *testing.T) {
 		))
 	})
 }
+
+func TestValidate_variants(t *testing.T) {
+	t.Parallel()
+	please := NewWithT(t)
+	results := Validate(Kilnfile{
+		ReleaseSources: []ReleaseSourceConfig{
+			{ID: someReleaseSourceID},
+		},
+		Releases: []BOSHReleaseTarballSpecification{
+			{Name: "banana"},
+		},
+		Variants: []TileVariant{
+			{Name: "full", Releases: []string{"banana"}},
+			{Name: "small", Releases: []string{"banana", "lemon"}},
+			{Name: "small"},
+			{},
+		},
+	}, KilnfileLock{
+		Releases: []BOSHReleaseTarballLock{
+			{Name: "banana", Version: "1.2.3", RemoteSource: someReleaseSourceID},
+		},
+	})
+	please.Expect(results).To(ConsistOf(
+		MatchError(`variant "small" includes release "lemon" which is not in the Kilnfile`),
+		MatchError(`variant "small" is declared more than once`),
+		MatchError("variant at index 3 missing name"),
+	))
+}
@@ -51,7 +51,29 @@ type Data struct {
 	Bumps          cargo.BumpList
 	TrainstatNotes []string
 
-	Stemcell cargo.Stemcell
+	// Stemcells lists every stemcell in the Kilnfile.lock. Stemcell is the first of them; it
+	// is kept for custom templates written before tiles could have more than one stemcell.
+	Stemcells []cargo.Stemcell
+	Stemcell  cargo.Stemcell
+}
+
+// AllStemcells returns Stemcells or, when it is not set, Stemcell.
+func (notes Data) AllStemcells() []cargo.Stemcell {
+	if len(notes.Stemcells) > 0 {
+		return notes.Stemcells
+	}
+	if notes.Stemcell.OS == "" {
+		return nil
+	}
+	return []cargo.Stemcell{notes.Stemcell}
+}
+
+// setStemcells sets Stemcells and Stemcell from the Kilnfile.lock.
+func (notes *Data) setStemcells(lock cargo.KilnfileLock) {
+	notes.Stemcells = lock.AllStemcells()
+	if len(notes.Stemcells) > 0 {
+		notes.Stemcell = notes.Stemcells[0]
+	}
 }
 
 //func (notes Data) Strings() string {
@@ -140,9 +162,9 @@ func FetchDataWithoutRepo(ctx context.Context, client *github.Client, tileRepoOw
 		releasesService: client.Repositories,
 	}
 	data := Data{
-		Bumps:    cargo.CalculateBumps(kilnfileLockFinal.Releases, kilnfileLockInitial.Releases),
-		Stemcell: kilnfileLockFinal.Stemcell,
+		Bumps: cargo.CalculateBumps(kilnfileLockFinal.Releases, kilnfileLockInitial.Releases),
 	}
+	data.setStemcells(kilnfileLockFinal)
 	var err error
 	data.Issues, data.Bumps, err = r.fetchIssuesAndReleaseNotes(ctx, kilnfile, kilnfile, data.Bumps, issuesQuery)
 	if err != nil {
@@ -213,10 +235,10 @@ func (r fetchNotesData) fetch(ctx context.Context) (Data, error) {
 	}
 
 	data := Data{
-		Version:  finalVersion,
-		Bumps:    cargo.CalculateBumps(finalKilnfileLock.Releases, initialKilnfileLock.Releases),
-		Stemcell: finalKilnfileLock.Stemcell,
+		Version: finalVersion,
+		Bumps:   cargo.CalculateBumps(finalKilnfileLock.Releases, initialKilnfileLock.Releases),
 	}
+	data.setStemcells(finalKilnfileLock)
 
 	wtKilnfile, err := r.kilnfileFromWorktree(r.kilnfilePath)
 	if err != nil {
@@ -473,6 +473,26 @@ func TestData_WriteVersionNotes(t *testing.T) {
 
 		please.Expect(releaseNotes.Notes).To(ContainSubstring("### <a id='4.0.0'></a> 4.0.0+LTS-T"))
 	})
+
+	t.Run("every stemcell is listed", func(t *testing.T) {
+		please := NewWithT(t)
+		data := Data{
+			Version: semver.MustParse("4.0.0"),
+		}
+		data.setStemcells(cargo.KilnfileLock{
+			Stemcells: []cargo.Stemcell{
+				{OS: "ubuntu-jammy", Version: "1.100"},
+				{OS: "windows2019", Version: "2019.60"},
+			},
+		})
+		please.Expect(data.Stemcell).To(Equal(cargo.Stemcell{OS: "ubuntu-jammy", Version: "1.100"}))
+
+		releaseNotes, err := data.WriteVersionNotes()
+		please.Expect(err).NotTo(HaveOccurred())
+
+		please.Expect(releaseNotes.Notes).To(ContainSubstring("<tr><td>ubuntu-jammy stemcell</td><td>1.100</td></tr>"))
+		please.Expect(releaseNotes.Notes).To(ContainSubstring("<tr><td>windows2019 stemcell</td><td>2019.60</td></tr>"))
+	})
 }
 
 func Test_trainstatURLFieldName_shouldNotChange(t *testing.T) {
@@ -6,3 +6,8 @@ type CollectionPropertyBlueprint struct {
 	PropertyBlueprints []SimplePropertyBlueprint `yaml:"property_blueprints"`
 	NamedManifests     []NamedManifest           `yaml:"named_manifests"`
 }
+
+func (blueprint CollectionPropertyBlueprint) validate(errs *CompoundError, path string) {
+	blueprint.SimplePropertyBlueprint.validate(errs, path)
+	validateSimplePropertyBlueprints(errs, path+".property_blueprints", blueprint.PropertyBlueprints)
+}
@@ -1,5 +1,12 @@
 package proofing
 
+import (
+	"fmt"
+	"regexp"
+
+	"golang.org/x/exp/slices"
+)
+
 type JobType struct {
 	Name          string `yaml:"name"`
 	ResourceLabel string `yaml:"resource_label"`
@@ -27,3 +34,39 @@ type JobType struct {
 	// TODO: find_object: https://github.com/pivotal-cf/installation/blob/039a2ef3f751ef5915c425da8150a29af4b764dd/web/app/models/persistence/metadata/job_type.rb#L57-L58
 	// TODO: max_in_flight can be int or percentage
 }
+
+var maxInFlightPercentage = regexp.MustCompile(`^\d+%$`)
+
+func (jobType JobType) validate(errs *CompoundError, path string, releaseNames []string) {
+	errs.validatePresence(path+".name", jobType.Name)
+	errs.validatePresence(path+".resource_label", jobType.ResourceLabel)
+
+	switch maxInFlight := jobType.MaxInFlight.(type) {
+	case nil, int:
+	case string:
+		if !maxInFlightPercentage.MatchString(maxInFlight) {
+			errs.addFieldError(path+".max_in_flight", "must be an integer or a percentage")
+		}
+	default:
+		errs.addFieldError(path+".max_in_flight", "must be an integer or a percentage")
+	}
+
+	templateNames := make([]string, 0, len(jobType.Templates))
+	for i, template := range jobType.Templates {
+		templatePath := fmt.Sprintf("%s.templates[%d]", path, i)
+		errs.validatePresence(templatePath+".name", template.Name)
+		errs.validatePresence(templatePath+".release", template.Release)
+		if template.Release != "" && !slices.Contains(releaseNames, template.Release) {
+			errs.addFieldError(templatePath+".release", "%q is not a declared release", template.Release)
+		}
+		templateNames = append(templateNames, template.Name)
+	}
+	errs.validateUniqueNames(path+".templates", templateNames)
+
+	if constraints := jobType.InstanceDefinition.Constraints; constraints != nil &&
+		constraints.Min != nil && constraints.Max != nil && *constraints.Min > *constraints.Max {
+		errs.addFieldError(path+".instance_definition.constraints", "min %d is greater than max %d", *constraints.Min, *constraints.Max)
+	}
+
+	validatePropertyBlueprints(errs, path+".property_blueprints", jobType.PropertyBlueprints)
+}
@@ -0,0 +1,185 @@
+package proofing
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// manifestExpression matches Ops Manager expressions like "(( .properties.some_property.value ))".
+	manifestExpression = regexp.MustCompile(`\(\(\s*(.*?)\s*\)\)`)
+
+	// manifestReference matches references like ".properties.some_property.value", ".some_job.some_property.value",
+	// and "..some_product.some_job.some_property.value" in a manifest expression.
+	manifestReference = regexp.MustCompile(`(?:^|[^\w.$])(\.\.?[\w-]+(?:\.[\w-]+)+)`)
+)
+
+// Lint checks the property references in job type manifests, job template manifests, runtime configs, and
+// named manifests of interpolated metadata. It reports references to property blueprints or job types that
+// do not exist, configurable product properties no manifest references, and references to releases the
+// metadata does not declare. The returned error is a *CompoundError of FieldError values.
+func (productTemplate *ProductTemplate) Lint() error {
+	var (
+		errs       CompoundError
+		referenced = make(map[string]bool)
+	)
+
+	for _, manifest := range productTemplate.manifests() {
+		for _, expression := range manifestExpression.FindAllStringSubmatch(manifest.text, -1) {
+			for _, match := range manifestReference.FindAllStringSubmatch(expression[1], -1) {
+				reference := match[1]
+				propertyName, err := productTemplate.checkManifestReference(reference)
+				if err != nil {
+					errs.addFieldError(manifest.path, "%q %s", reference, err)
+					continue
+				}
+				if propertyName != "" {
+					referenced[propertyName] = true
+				}
+			}
+		}
+	}
+
+	for i, blueprint := range productTemplate.PropertyBlueprints {
+		if blueprint.IsConfigurable() && !referenced[blueprint.PropertyName()] {
+			errs.addFieldError(fmt.Sprintf("property_blueprints[%d]", i), "configurable property %q is not referenced by any manifest", blueprint.PropertyName())
+		}
+	}
+
+	releaseNames := make([]string, 0, len(productTemplate.Releases))
+	for _, release := range productTemplate.Releases {
+		releaseNames = append(releaseNames, release.Name)
+	}
+	for i, jobType := range productTemplate.JobTypes {
+		for j, template := range jobType.Templates {
+			if template.Release != "" && !slices.Contains(releaseNames, template.Release) {
+				errs.addFieldError(fmt.Sprintf("job_types[%d].templates[%d].release", i, j), "%q is not a declared release", template.Release)
+			}
+		}
+	}
+	for i, runtimeConfig := range productTemplate.RuntimeConfigs {
+		path := fmt.Sprintf("runtime_configs[%d].runtime_config", i)
+		for _, name := range runtimeConfigReleaseNames(runtimeConfig.RuntimeConfig) {
+			if !slices.Contains(releaseNames, name) {
+				errs.addFieldError(path, "%q is not a declared release", name)
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return &errs
+}
+
+type manifestSource struct {
+	path, text string
+}
+
+// manifests lists the manifest strings that may contain property references.
+func (productTemplate *ProductTemplate) manifests() []manifestSource {
+	var manifests []manifestSource
+	for i, jobType := range productTemplate.JobTypes {
+		path := fmt.Sprintf("job_types[%d]", i)
+		manifests = append(manifests, manifestSource{path: path + ".manifest", text: jobType.Manifest})
+		for j, template := range jobType.Templates {
+			manifests = append(manifests, manifestSource{path: fmt.Sprintf("%s.templates[%d].manifest", path, j), text: template.Manifest})
+		}
+		if ref := jobType.InstanceDefinition.ZeroIf.PropertyReference; ref != "" {
+			manifests = append(manifests, manifestSource{path: path + ".instance_definition.zero_if.property_reference", text: "(( " + ref + " ))"})
+		}
+		manifests = append(manifests, namedManifests(path+".property_blueprints", jobType.PropertyBlueprints)...)
+	}
+	for i, runtimeConfig := range productTemplate.RuntimeConfigs {
+		manifests = append(manifests, manifestSource{path: fmt.Sprintf("runtime_configs[%d].runtime_config", i), text: runtimeConfig.RuntimeConfig})
+	}
+	manifests = append(manifests, namedManifests("property_blueprints", productTemplate.PropertyBlueprints)...)
+	return manifests
+}
+
+func namedManifests(path string, blueprints PropertyBlueprints) []manifestSource {
+	var manifests []manifestSource
+	add := func(blueprintPath string, named []NamedManifest) {
+		for k, namedManifest := range named {
+			manifests = append(manifests, manifestSource{path: fmt.Sprintf("%s.named_manifests[%d].manifest", blueprintPath, k), text: namedManifest.Manifest})
+		}
+	}
+	for i, blueprint := range blueprints {
+		blueprintPath := fmt.Sprintf("%s[%d]", path, i)
+		switch b := blueprint.(type) {
+		case *SelectorPropertyBlueprint:
+			for j, option := range b.OptionTemplates {
+				add(fmt.Sprintf("%s.option_templates[%d]", blueprintPath, j), option.NamedManifests)
+			}
+		case *CollectionPropertyBlueprint:
+			add(blueprintPath, b.NamedManifests)
+		}
+	}
+	return manifests
+}
+
+// checkManifestReference returns the name of the product property a reference uses or an error when the
+// reference can not be resolved. References to other products are not checked.
+func (productTemplate *ProductTemplate) checkManifestReference(reference string) (string, error) {
+	segments := strings.Split(strings.TrimPrefix(reference, "."), ".")
+	if strings.HasPrefix(reference, "..") {
+		if segments[1] != productTemplate.Name {
+			return "", nil
+		}
+		segments = segments[2:]
+	}
+	if len(segments) < 2 {
+		return "", nil
+	}
+	if segments[0] == "properties" {
+		if _, _, err := productTemplate.FindPropertyBlueprintWithName(segments[1]); err != nil {
+			return "", fmt.Errorf("references undeclared property blueprint %q", segments[1])
+		}
+		return segments[1], nil
+	}
+	jobType, _, err := productTemplate.FindJobTypeWithName(segments[0])
+	if err != nil {
+		return "", fmt.Errorf("references undeclared job type %q", segments[0])
+	}
+	// references with two segments like ".some_job.ips" are job accessors, not properties
+	if len(segments) > 2 && slices.IndexFunc(jobType.PropertyBlueprints, func(blueprint PropertyBlueprint) bool {
+		return blueprint.PropertyName() == segments[1]
+	}) < 0 {
+		return "", fmt.Errorf("references undeclared property blueprint %q on job type %q", segments[1], segments[0])
+	}
+	return "", nil
+}
+
+func runtimeConfigReleaseNames(runtimeConfig string) []string {
+	var manifest struct {
+		Releases []struct {
+			Name string `yaml:"name"`
+		} `yaml:"releases"`
+		Addons []struct {
+			Jobs []struct {
+				Release string `yaml:"release"`
+			} `yaml:"jobs"`
+		} `yaml:"addons"`
+	}
+	if err := yaml.Unmarshal([]byte(runtimeConfig), &manifest); err != nil {
+		return nil
+	}
+	var names []string
+	for _, release := range manifest.Releases {
+		if release.Name != "" && !slices.Contains(names, release.Name) {
+			names = append(names, release.Name)
+		}
+	}
+	for _, addon := range manifest.Addons {
+		for _, job := range addon.Jobs {
+			if job.Release != "" && !slices.Contains(names, job.Release) {
+				names = append(names, job.Release)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,88 @@
+package proofing_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/pkg/proofing"
+)
+
+var _ = Describe("Lint", func() {
+	const metadata = `---
+name: cf
+releases:
+  - {name: routing, version: 1.0.0, file: routing-1.0.0.tgz}
+  - {name: dns, version: 1.0.0, file: dns-1.0.0.tgz}
+property_blueprints:
+  - {name: router_timeout, type: integer, configurable: true, default: 900}
+  - name: networking_mode
+    type: selector
+    configurable: true
+    default: internal
+    option_templates:
+      - name: internal_option
+        select_value: internal
+        named_manifests:
+          - name: router_config
+            manifest: |
+              skip_cert_verify: (( ..cf.ha_proxy.skip_cert_verify.value ))
+  - {name: generated_secret, type: secret}
+job_types:
+  - name: ha_proxy
+    resource_label: HAProxy
+    templates:
+      - name: haproxy
+        release: routing
+        manifest: |
+          request_timeout: (( .properties.router_timeout.value ))
+          ips: (( .ha_proxy.ips ))
+          uuid: (( $self.uuid ))
+          uaa_url: (( ..pivotal-container-service.uaa.url.value ))
+    property_blueprints:
+      - {name: skip_cert_verify, type: boolean, configurable: true, default: false}
+    manifest: |
+      networking: (( .properties.networking_mode.selected_option.parsed_manifest(router_config) ))
+runtime_configs:
+  - name: dns
+    runtime_config: |
+      releases:
+        - {name: dns, version: 1.0.0}
+      addons:
+        - name: dns
+          jobs:
+            - {name: bosh-dns, release: dns}
+`
+
+	var productTemplate proofing.ProductTemplate
+
+	BeforeEach(func() {
+		var err error
+		productTemplate, err = proofing.Parse(strings.NewReader(metadata))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("accepts resolvable references", func() {
+		Expect(productTemplate.Lint()).To(Succeed())
+	})
+
+	It("reports undeclared property blueprints and job types", func() {
+		productTemplate.JobTypes[0].Templates[0].Manifest = `request_timeout: (( .properties.router_timeuot.value ))
+skip_cert_verify: (( .ha_proxy.skip_cert_verfiy.value ))
+ips: (( .router.ips ))
+`
+		Expect(productTemplate.Lint()).To(MatchError(`- job_types[0].templates[0].manifest: ".properties.router_timeuot.value" references undeclared property blueprint "router_timeuot"
+- job_types[0].templates[0].manifest: ".ha_proxy.skip_cert_verfiy.value" references undeclared property blueprint "skip_cert_verfiy" on job type "ha_proxy"
+- job_types[0].templates[0].manifest: ".router.ips" references undeclared job type "router"
+- property_blueprints[0]: configurable property "router_timeout" is not referenced by any manifest`))
+	})
+
+	It("reports undeclared releases", func() {
+		productTemplate.Releases = productTemplate.Releases[:1]
+		Expect(productTemplate.Lint()).To(MatchError(`- runtime_configs[0].runtime_config: "dns" is not a declared release`))
+
+		productTemplate.JobTypes[0].Templates[0].Release = "router"
+		Expect(productTemplate.Lint()).To(MatchError(ContainSubstring(`job_types[0].templates[0].release: "router" is not a declared release`)))
+	})
+})
@@ -2,6 +2,7 @@ package proofing
 
 import (
 	"fmt"
+	"strings"
 
 	"golang.org/x/exp/slices"
 )
@@ -81,3 +82,121 @@ func (productTemplate *ProductTemplate) FindJobTypeWithName(name string) (*JobTy
 	}
 	return &productTemplate.JobTypes[index], index, nil
 }
+
+// Validate checks the presence, type, uniqueness, and cross-reference rules Ops Manager enforces when it
+// imports a tile. The returned error is a *CompoundError of FieldError values.
+func (productTemplate *ProductTemplate) Validate() error {
+	var errs CompoundError
+
+	errs.validatePresence("name", productTemplate.Name)
+	errs.validatePresence("product_version", productTemplate.ProductVersion)
+	errs.validateVersion("product_version", productTemplate.ProductVersion)
+	errs.validateVersion("minimum_version_for_upgrade", productTemplate.MinimumVersionForUpgrade)
+	errs.validatePresence("metadata_version", productTemplate.MetadataVersion)
+	errs.validatePresence("stemcell_criteria.os", productTemplate.StemcellCriteria.OS)
+	errs.validatePresence("stemcell_criteria.version", productTemplate.StemcellCriteria.Version)
+
+	releaseNames := make([]string, 0, len(productTemplate.Releases))
+	for i, release := range productTemplate.Releases {
+		path := fmt.Sprintf("releases[%d]", i)
+		errs.validatePresence(path+".name", release.Name)
+		errs.validatePresence(path+".version", release.Version)
+		errs.validatePresence(path+".file", release.File)
+		releaseNames = append(releaseNames, release.Name)
+	}
+	errs.validateUniqueNames("releases", releaseNames)
+
+	validatePropertyBlueprints(&errs, "property_blueprints", productTemplate.PropertyBlueprints)
+
+	jobTypeNames := make([]string, 0, len(productTemplate.JobTypes))
+	for i, jobType := range productTemplate.JobTypes {
+		jobType.validate(&errs, fmt.Sprintf("job_types[%d]", i), releaseNames)
+		jobTypeNames = append(jobTypeNames, jobType.Name)
+	}
+	errs.validateUniqueNames("job_types", jobTypeNames)
+
+	formTypeNames := make([]string, 0, len(productTemplate.FormTypes))
+	for i, formType := range productTemplate.FormTypes {
+		path := fmt.Sprintf("form_types[%d]", i)
+		errs.validatePresence(path+".name", formType.Name)
+		errs.validatePresence(path+".label", formType.Label)
+		for j, input := range formType.PropertyInputs {
+			inputPath := fmt.Sprintf("%s.property_inputs[%d].reference", path, j)
+			if input.Ref() == "" {
+				errs.addFieldError(inputPath, "must be present")
+				continue
+			}
+			if !productTemplate.hasPropertyReference(input.Ref()) {
+				errs.addFieldError(inputPath, "%q does not reference a property blueprint", input.Ref())
+			}
+		}
+		formTypeNames = append(formTypeNames, formType.Name)
+	}
+	errs.validateUniqueNames("form_types", formTypeNames)
+
+	productTemplate.validateErrands(&errs, "post_deploy_errands", productTemplate.PostDeployErrands)
+	productTemplate.validateErrands(&errs, "pre_delete_errands", productTemplate.PreDeleteErrands)
+
+	runtimeConfigNames := make([]string, 0, len(productTemplate.RuntimeConfigs))
+	for i, runtimeConfig := range productTemplate.RuntimeConfigs {
+		errs.validatePresence(fmt.Sprintf("runtime_configs[%d].name", i), runtimeConfig.Name)
+		runtimeConfigNames = append(runtimeConfigNames, runtimeConfig.Name)
+	}
+	errs.validateUniqueNames("runtime_configs", runtimeConfigNames)
+
+	variableNames := make([]string, 0, len(productTemplate.Variables))
+	for i, variable := range productTemplate.Variables {
+		path := fmt.Sprintf("variables[%d]", i)
+		errs.validatePresence(path+".name", variable.Name)
+		errs.validatePresence(path+".type", variable.Type)
+		variableNames = append(variableNames, variable.Name)
+	}
+	errs.validateUniqueNames("variables", variableNames)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return &errs
+}
+
+// hasPropertyReference checks references like ".properties.some_property" (product properties) and
+// ".some_job_type.some_property" (job type properties).
+func (productTemplate *ProductTemplate) hasPropertyReference(reference string) bool {
+	jobTypeName, propertyName, found := strings.Cut(strings.TrimPrefix(reference, "."), ".")
+	if !strings.HasPrefix(reference, ".") || !found {
+		return false
+	}
+	propertyName, _, _ = strings.Cut(propertyName, ".")
+	if jobTypeName == "properties" {
+		_, _, err := productTemplate.FindPropertyBlueprintWithName(propertyName)
+		return err == nil
+	}
+	jobType, _, err := productTemplate.FindJobTypeWithName(jobTypeName)
+	if err != nil {
+		return false
+	}
+	return slices.IndexFunc(jobType.PropertyBlueprints, func(blueprint PropertyBlueprint) bool {
+		return blueprint.PropertyName() == propertyName
+	}) >= 0
+}
+
+func (productTemplate *ProductTemplate) validateErrands(errs *CompoundError, path string, errands []ErrandTemplate) {
+	names := make([]string, 0, len(errands))
+	for i, errand := range errands {
+		namePath := fmt.Sprintf("%s[%d].name", path, i)
+		errs.validatePresence(namePath, errand.Name)
+		names = append(names, errand.Name)
+		if errand.Name == "" || errand.Colocated {
+			continue
+		}
+		jobType, _, err := productTemplate.FindJobTypeWithName(errand.Name)
+		if err != nil {
+			errs.addFieldError(namePath, "%q does not reference a job type", errand.Name)
+			continue
+		}
+		if !jobType.Errand {
+			errs.addFieldError(namePath, "job type %q is not an errand", errand.Name)
+		}
+	}
+	errs.validateUniqueNames(path, names)
+}
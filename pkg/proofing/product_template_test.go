@@ -2,6 +2,7 @@ package proofing_test
 
 import (
 	"os"
+	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -49,4 +50,107 @@ var _ = Describe("ProductTemplate", func() {
 		Expect(productTemplate.StemcellCriteria).To(BeAssignableToTypeOf(proofing.StemcellCriteria{}))
 		Expect(productTemplate.Variables).To(HaveLen(1))
 	})
+
+	Describe("Validate", func() {
+		const validMetadata = `---
+name: banana
+product_version: 1.2.3
+minimum_version_for_upgrade: 1.0.0
+metadata_version: "2.7"
+stemcell_criteria: {os: ubuntu-jammy, version: "1.10"}
+releases:
+  - {name: banana, version: 1.0.0, file: banana-1.0.0.tgz}
+property_blueprints:
+  - {name: color, type: string, configurable: true, default: yellow}
+  - name: ripeness
+    type: selector
+    configurable: true
+    default: ripe
+    option_templates:
+      - {name: ripe_option, select_value: ripe}
+      - {name: green_option, select_value: green, property_blueprints: [{name: days, type: integer}]}
+form_types:
+  - name: appearance
+    label: Appearance
+    property_inputs:
+      - {reference: .properties.color, label: Color}
+      - {reference: .peeler.sharpness, label: Sharpness}
+job_types:
+  - name: peeler
+    resource_label: Peeler
+    max_in_flight: 50%
+    templates:
+      - {name: peeler, release: banana}
+    instance_definition: {default: 1, constraints: {min: 1, max: 3}}
+    property_blueprints:
+      - {name: sharpness, type: integer, configurable: true, default: 3}
+  - name: smoke_tests
+    resource_label: Smoke tests
+    errand: true
+    templates:
+      - {name: smoke_tests, release: banana}
+post_deploy_errands:
+  - name: smoke_tests
+`
+
+		BeforeEach(func() {
+			var err error
+			productTemplate, err = proofing.Parse(strings.NewReader(validMetadata))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("is valid", func() {
+			Expect(productTemplate.Validate()).To(Succeed())
+		})
+
+		It("validates the presence of fields", func() {
+			productTemplate.Name = ""
+			productTemplate.StemcellCriteria.OS = ""
+			productTemplate.Releases[0].File = ""
+			Expect(productTemplate.Validate()).To(MatchError(`- name: must be present
+- stemcell_criteria.os: must be present
+- releases[0].file: must be present`))
+		})
+
+		It("validates versions", func() {
+			productTemplate.ProductVersion = "latest"
+			Expect(productTemplate.Validate()).To(MatchError(ContainSubstring("product_version: must be a semantic version")))
+		})
+
+		It("validates unique names", func() {
+			productTemplate.PropertyBlueprints = append(productTemplate.PropertyBlueprints, &proofing.SimplePropertyBlueprint{Name: "color", Type: "string"})
+			Expect(productTemplate.Validate()).To(MatchError(`- property_blueprints[2].name: duplicate name "color"`))
+		})
+
+		It("validates nested property blueprints", func() {
+			selector := productTemplate.PropertyBlueprints[1].(*proofing.SelectorPropertyBlueprint)
+			selector.OptionTemplates[1].PropertyBlueprints[0].Type = ""
+			Expect(productTemplate.Validate()).To(MatchError(`- property_blueprints[1].option_templates[1].property_blueprints[0].type: must be present`))
+		})
+
+		It("validates form type property references", func() {
+			productTemplate.FormTypes[0].PropertyInputs = append(productTemplate.FormTypes[0].PropertyInputs,
+				proofing.SimplePropertyInput{Reference: ".properties.size"},
+				proofing.SimplePropertyInput{Reference: ".juicer.speed"},
+			)
+			Expect(productTemplate.Validate()).To(MatchError(`- form_types[0].property_inputs[2].reference: ".properties.size" does not reference a property blueprint
+- form_types[0].property_inputs[3].reference: ".juicer.speed" does not reference a property blueprint`))
+		})
+
+		It("validates job types", func() {
+			productTemplate.JobTypes[0].Templates[0].Release = "lemon"
+			productTemplate.JobTypes[0].MaxInFlight = "many"
+			min, max := 3, 1
+			productTemplate.JobTypes[0].InstanceDefinition.Constraints = &proofing.IntegerConstraints{Min: &min, Max: &max}
+			Expect(productTemplate.Validate()).To(MatchError(`- job_types[0].max_in_flight: must be an integer or a percentage
+- job_types[0].templates[0].release: "lemon" is not a declared release
+- job_types[0].instance_definition.constraints: min 3 is greater than max 1`))
+		})
+
+		It("validates errands reference errand job types", func() {
+			productTemplate.PostDeployErrands = append(productTemplate.PostDeployErrands, proofing.ErrandTemplate{Name: "peeler"}, proofing.ErrandTemplate{Name: "deploy-all"})
+			Expect(productTemplate.Validate()).To(MatchError(`- post_deploy_errands[1].name: job type "peeler" is not an errand
+- post_deploy_errands[2].name: "deploy-all" does not reference a job type`))
+		})
+	})
 })
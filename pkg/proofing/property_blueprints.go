@@ -57,3 +57,35 @@ func unmarshalPropertyBlueprint(node *yaml.Node) (PropertyBlueprint, error) {
 	}
 	return &simplePropertyBlueprint, nil
 }
+
+func validatePropertyBlueprints(errs *CompoundError, path string, blueprints PropertyBlueprints) {
+	names := make([]string, 0, len(blueprints))
+	for i, blueprint := range blueprints {
+		blueprintPath := fmt.Sprintf("%s[%d]", path, i)
+		switch b := blueprint.(type) {
+		case *SelectorPropertyBlueprint:
+			b.validate(errs, blueprintPath)
+		case SelectorPropertyBlueprint:
+			b.validate(errs, blueprintPath)
+		case *CollectionPropertyBlueprint:
+			b.validate(errs, blueprintPath)
+		case CollectionPropertyBlueprint:
+			b.validate(errs, blueprintPath)
+		case *SimplePropertyBlueprint:
+			b.validate(errs, blueprintPath)
+		case SimplePropertyBlueprint:
+			b.validate(errs, blueprintPath)
+		}
+		names = append(names, blueprint.PropertyName())
+	}
+	errs.validateUniqueNames(path, names)
+}
+
+func validateSimplePropertyBlueprints(errs *CompoundError, path string, blueprints []SimplePropertyBlueprint) {
+	names := make([]string, 0, len(blueprints))
+	for i, blueprint := range blueprints {
+		blueprint.validate(errs, fmt.Sprintf("%s[%d]", path, i))
+		names = append(names, blueprint.Name)
+	}
+	errs.validateUniqueNames(path, names)
+}
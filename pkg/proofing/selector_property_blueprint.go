@@ -1,5 +1,7 @@
 package proofing
 
+import "fmt"
+
 type SelectorPropertyBlueprint struct {
 	SimplePropertyBlueprint `yaml:",inline"`
 
@@ -8,3 +10,16 @@ type SelectorPropertyBlueprint struct {
 	// TODO: validations: https://github.com/pivotal-cf/installation/blob/039a2ef3f751ef5915c425da8150a29af4b764dd/web/app/models/persistence/metadata/selector_property_blueprint.rb#L10
 	// TODO: find_object: https://github.com/pivotal-cf/installation/blob/039a2ef3f751ef5915c425da8150a29af4b764dd/web/app/models/persistence/metadata/selector_property_blueprint.rb#L8
 }
+
+func (blueprint SelectorPropertyBlueprint) validate(errs *CompoundError, path string) {
+	blueprint.SimplePropertyBlueprint.validate(errs, path)
+	names := make([]string, 0, len(blueprint.OptionTemplates))
+	for i, option := range blueprint.OptionTemplates {
+		optionPath := fmt.Sprintf("%s.option_templates[%d]", path, i)
+		errs.validatePresence(optionPath+".name", option.Name)
+		errs.validatePresence(optionPath+".select_value", option.SelectValue)
+		validateSimplePropertyBlueprints(errs, optionPath+".property_blueprints", option.PropertyBlueprints)
+		names = append(names, option.Name)
+	}
+	errs.validateUniqueNames(path+".option_templates", names)
+}
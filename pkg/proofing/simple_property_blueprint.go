@@ -26,3 +26,8 @@ type PropertyBlueprintOption struct {
 	Label string `yaml:"label"`
 	Name  string `yaml:"name"`
 }
+
+func (blueprint SimplePropertyBlueprint) validate(errs *CompoundError, path string) {
+	errs.validatePresence(path+".name", blueprint.Name)
+	errs.validatePresence(path+".type", blueprint.Type)
+}
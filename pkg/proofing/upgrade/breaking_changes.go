@@ -1,9 +1,13 @@
 package upgrade
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+
 	"github.com/pivotal-cf/kiln/pkg/proofing"
 )
 
@@ -13,14 +17,32 @@ const (
 	BreakingChangedConfigurablePropertyType                = "changed configurable property type"
 	BreakingRemovedOrRenamedConfigurableProperty           = "removed or renamed configurable property"
 	BreakingRemovedRemovedConfigurablePropertyDefault      = "removed configurable property default"
+	BreakingRemovedSelectorOption                          = "removed selector option"
+	BreakingRemovedOrRenamedCollectionSubProperty          = "removed or renamed collection sub-property"
+	BreakingChangedCollectionSubPropertyType               = "changed collection sub-property type"
+	BreakingTightenedConfigurablePropertyConstraints       = "tightened configurable property constraints"
+	BreakingChangedFreezeOnDeploy                          = "changed freeze_on_deploy"
+)
+
+// Categories of breaking changes returned by Category.
+const (
+	CategoryProductVersion    = "product_version"
+	CategoryProductName       = "product_name"
+	CategoryPropertyBlueprint = "property_blueprint"
+	CategoryErrand            = "errand"
+	CategoryInstanceGroup     = "instance_group"
+	CategoryFormType          = "form_type"
+	CategoryOther             = "other"
 )
 
 func ListBreakingChanges(stable, candidate proofing.ProductTemplate) []error {
 	var breakingChanges []error
 	breakingChanges = append(breakingChanges, detectProductVersionErrors(stable, candidate)...)
+	breakingChanges = append(breakingChanges, detectMinimumVersionForUpgradeErrors(stable, candidate)...)
 	breakingChanges = append(breakingChanges, detectProductNameChange(stable, candidate)...)
 	breakingChanges = append(breakingChanges, listPropertyBlueprintBreakingChanges(stable, candidate)...)
 	breakingChanges = append(breakingChanges, detectRemovedErrand(stable, candidate)...)
+	breakingChanges = append(breakingChanges, detectRemovedFormTypes(stable, candidate)...)
 	breakingChanges = append(breakingChanges, listJobDefinitionBreakingChanges(stable, candidate)...)
 
 	return breakingChanges
@@ -30,25 +52,52 @@ func detectProductVersionErrors(stable, candidate proofing.ProductTemplate) []er
 	var errList []error
 	sv, err := semver.NewVersion(stable.ProductVersion)
 	if err != nil {
-		errList = append(errList, fmt.Errorf("failed to parse stable product_version: %w", err))
+		errList = append(errList, categorized(CategoryProductVersion, fmt.Errorf("failed to parse stable product_version: %w", err)))
 		return errList
 	}
 
 	cv, err := semver.NewVersion(candidate.ProductVersion)
 	if err != nil {
-		errList = append(errList, fmt.Errorf("failed to parse candidate product_version: %w", err))
+		errList = append(errList, categorized(CategoryProductVersion, fmt.Errorf("failed to parse candidate product_version: %w", err)))
 		return errList
 	}
 	if sv.Patch() != 0 {
-		errList = append(errList, fmt.Errorf("stable metadata product_version patch number must be zero"))
+		errList = append(errList, categorized(CategoryProductVersion, fmt.Errorf("stable metadata product_version patch number must be zero")))
 	}
 	if cv.LessThan(sv) {
-		errList = append(errList, fmt.Errorf("stable metadata product_version must be less than candidate metadata product_version"))
+		errList = append(errList, categorized(CategoryProductVersion, fmt.Errorf("stable metadata product_version must be less than candidate metadata product_version")))
 	}
 
 	return errList
 }
 
+// detectMinimumVersionForUpgradeErrors checks that the stable tile may be upgraded to the candidate and
+// that the candidate does not allow upgrades from versions the stable tile did not allow.
+func detectMinimumVersionForUpgradeErrors(stable, candidate proofing.ProductTemplate) []error {
+	if candidate.MinimumVersionForUpgrade == "" {
+		return nil
+	}
+	cmv, err := semver.NewVersion(candidate.MinimumVersionForUpgrade)
+	if err != nil {
+		return []error{categorized(CategoryProductVersion, fmt.Errorf("failed to parse candidate minimum_version_for_upgrade: %w", err))}
+	}
+	var errList []error
+	if sv, err := semver.NewVersion(stable.ProductVersion); err == nil && sv.LessThan(cmv) {
+		errList = append(errList, categorized(CategoryProductVersion, fmt.Errorf("candidate metadata minimum_version_for_upgrade %s is greater than stable metadata product_version %s", cmv, sv)))
+	}
+	if stable.MinimumVersionForUpgrade == "" {
+		return errList
+	}
+	smv, err := semver.NewVersion(stable.MinimumVersionForUpgrade)
+	if err != nil {
+		return append(errList, categorized(CategoryProductVersion, fmt.Errorf("failed to parse stable minimum_version_for_upgrade: %w", err)))
+	}
+	if cmv.LessThan(smv) {
+		errList = append(errList, categorized(CategoryProductVersion, fmt.Errorf("candidate metadata minimum_version_for_upgrade %s is lower than stable metadata minimum_version_for_upgrade %s", cmv, smv)))
+	}
+	return errList
+}
+
 func listPropertyBlueprintBreakingChanges(stable, candidate proofing.ProductTemplate) []error {
 	var breakingChanges []error
 	for _, check := range []func(stable, candidate proofing.ProductTemplate) []PropertyBlueprintBreakingChange{
@@ -57,6 +106,10 @@ func listPropertyBlueprintBreakingChanges(stable, candidate proofing.ProductTemp
 		detectConfigurablePropertyTypeChanged,
 		detectRemovedConfigurableProperty,
 		detectRemovedConfigurablePropertyDefault,
+		detectRemovedSelectorOptions,
+		detectCollectionSubPropertyChanges,
+		detectTightenedPropertyConstraints,
+		detectChangedFreezeOnDeploy,
 	} {
 		breakingChanges = appendStaticErrorType(breakingChanges, check(stable, candidate)...)
 	}
@@ -66,7 +119,7 @@ func listPropertyBlueprintBreakingChanges(stable, candidate proofing.ProductTemp
 func detectProductNameChange(stable, candidate proofing.ProductTemplate) []error {
 	if stable.Name != candidate.Name {
 		return []error{
-			fmt.Errorf("breaking change tile names are not the same (%q != %q)", stable.Name, candidate.Name),
+			categorized(CategoryProductName, fmt.Errorf("breaking change tile names are not the same (%q != %q)", stable.Name, candidate.Name)),
 		}
 	}
 	return nil
@@ -172,12 +225,160 @@ func detectRemovedConfigurablePropertyDefault(stable, candidate proofing.Product
 	return breakingChanges
 }
 
+func detectRemovedSelectorOptions(stable, candidate proofing.ProductTemplate) []PropertyBlueprintBreakingChange {
+	var breakingChanges []PropertyBlueprintBreakingChange
+	for _, candidateProperty := range candidate.PropertyBlueprints {
+		stableProperty, _, findErr := stable.FindPropertyBlueprintWithName(candidateProperty.PropertyName())
+		if findErr != nil || !candidateProperty.IsConfigurable() || !stableProperty.IsConfigurable() {
+			continue
+		}
+		stableSelector, isSelector := selectorPropertyBlueprint(stableProperty)
+		if !isSelector {
+			continue
+		}
+		candidateSelector, isSelector := selectorPropertyBlueprint(candidateProperty)
+		if !isSelector {
+			continue
+		}
+		for _, stableOption := range stableSelector.OptionTemplates {
+			if !hasSelectorOptionWithSelectValue(candidateSelector.OptionTemplates, stableOption.SelectValue) {
+				breakingChanges = append(breakingChanges, PropertyBlueprintBreakingChange{
+					Type:         BreakingRemovedSelectorOption,
+					PropertyName: stableProperty.PropertyName(),
+					Detail:       fmt.Sprintf("option %q", stableOption.SelectValue),
+				})
+			}
+		}
+	}
+	return breakingChanges
+}
+
+func detectCollectionSubPropertyChanges(stable, candidate proofing.ProductTemplate) []PropertyBlueprintBreakingChange {
+	var breakingChanges []PropertyBlueprintBreakingChange
+	for _, candidateProperty := range candidate.PropertyBlueprints {
+		stableProperty, _, findErr := stable.FindPropertyBlueprintWithName(candidateProperty.PropertyName())
+		if findErr != nil || !candidateProperty.IsConfigurable() || !stableProperty.IsConfigurable() {
+			continue
+		}
+		stableCollection, isCollection := collectionPropertyBlueprint(stableProperty)
+		if !isCollection {
+			continue
+		}
+		candidateCollection, isCollection := collectionPropertyBlueprint(candidateProperty)
+		if !isCollection {
+			continue
+		}
+		for _, stableSubProperty := range stableCollection.PropertyBlueprints {
+			if !stableSubProperty.IsConfigurable() {
+				continue
+			}
+			candidateSubProperty, found := findSimplePropertyBlueprint(candidateCollection.PropertyBlueprints, stableSubProperty.Name)
+			switch {
+			case !found:
+				breakingChanges = append(breakingChanges, PropertyBlueprintBreakingChange{
+					Type:         BreakingRemovedOrRenamedCollectionSubProperty,
+					PropertyName: stableProperty.PropertyName(),
+					Detail:       fmt.Sprintf("sub-property %q", stableSubProperty.Name),
+				})
+			case candidateSubProperty.Type != stableSubProperty.Type:
+				breakingChanges = append(breakingChanges, PropertyBlueprintBreakingChange{
+					Type:         BreakingChangedCollectionSubPropertyType,
+					PropertyName: stableProperty.PropertyName(),
+					Detail:       fmt.Sprintf("sub-property %q type changed from %q to %q", stableSubProperty.Name, stableSubProperty.Type, candidateSubProperty.Type),
+				})
+			}
+		}
+	}
+	return breakingChanges
+}
+
+func detectTightenedPropertyConstraints(stable, candidate proofing.ProductTemplate) []PropertyBlueprintBreakingChange {
+	var breakingChanges []PropertyBlueprintBreakingChange
+	for _, candidateProperty := range candidate.PropertyBlueprints {
+		stableProperty, _, findErr := stable.FindPropertyBlueprintWithName(candidateProperty.PropertyName())
+		if findErr != nil || !candidateProperty.IsConfigurable() || !stableProperty.IsConfigurable() {
+			continue
+		}
+		stableConstraints, ok := integerConstraints(stableProperty)
+		if !ok {
+			continue
+		}
+		candidateConstraints, ok := integerConstraints(candidateProperty)
+		if !ok {
+			continue
+		}
+		for _, constraintErr := range checkTighterConstraints(stableConstraints, &candidateConstraints) {
+			breakingChanges = append(breakingChanges, PropertyBlueprintBreakingChange{
+				Type:         BreakingTightenedConfigurablePropertyConstraints,
+				PropertyName: candidateProperty.PropertyName(),
+				Detail:       constraintErr.Error(),
+			})
+		}
+	}
+	return breakingChanges
+}
+
+func detectChangedFreezeOnDeploy(stable, candidate proofing.ProductTemplate) []PropertyBlueprintBreakingChange {
+	var breakingChanges []PropertyBlueprintBreakingChange
+	for _, candidateProperty := range candidate.PropertyBlueprints {
+		stableProperty, _, findErr := stable.FindPropertyBlueprintWithName(candidateProperty.PropertyName())
+		if findErr != nil || !stableProperty.IsConfigurable() {
+			continue
+		}
+		stableSimple, ok := simplePropertyBlueprint(stableProperty)
+		if !ok {
+			continue
+		}
+		candidateSimple, ok := simplePropertyBlueprint(candidateProperty)
+		if !ok {
+			continue
+		}
+		if stableSimple.FreezeOnDeploy != candidateSimple.FreezeOnDeploy {
+			breakingChanges = append(breakingChanges, PropertyBlueprintBreakingChange{
+				Type:         BreakingChangedFreezeOnDeploy,
+				PropertyName: candidateProperty.PropertyName(),
+				Detail:       fmt.Sprintf("changed from %t to %t", stableSimple.FreezeOnDeploy, candidateSimple.FreezeOnDeploy),
+			})
+		}
+	}
+	return breakingChanges
+}
+
 func detectRemovedErrand(stable, candidate proofing.ProductTemplate) []error {
 	var breakingChanges []error
 	for _, stableErrand := range stable.PostDeployErrands {
 		found := candidate.HasPostDeployErrandWithName(stableErrand.Name)
 		if !found {
-			breakingChanges = append(breakingChanges, fmt.Errorf("breaking change for errand with name %q: removed", stableErrand.Name))
+			breakingChanges = append(breakingChanges, categorized(CategoryErrand, fmt.Errorf("breaking change for errand with name %q: removed", stableErrand.Name)))
+		}
+	}
+	return breakingChanges
+}
+
+// detectRemovedFormTypes finds removed form types with inputs for properties that are still configurable but no
+// longer have an input on any form, so operators can not change them after upgrading.
+func detectRemovedFormTypes(stable, candidate proofing.ProductTemplate) []error {
+	candidateReferences := make(map[string]bool)
+	for _, formType := range candidate.FormTypes {
+		for _, input := range formType.PropertyInputs {
+			candidateReferences[propertyInputName(input)] = true
+		}
+	}
+	var breakingChanges []error
+	for _, stableFormType := range stable.FormTypes {
+		if hasFormTypeWithName(candidate.FormTypes, stableFormType.Name) {
+			continue
+		}
+		for _, input := range stableFormType.PropertyInputs {
+			name := propertyInputName(input)
+			if name == "" || candidateReferences[name] {
+				continue
+			}
+			candidateProperty, _, findErr := candidate.FindPropertyBlueprintWithName(name)
+			if findErr != nil || !candidateProperty.IsConfigurable() {
+				continue
+			}
+			breakingChanges = append(breakingChanges, categorized(CategoryFormType, fmt.Errorf("breaking change for form type with name %q: removed while property %q is still configurable", stableFormType.Name, name)))
 		}
 	}
 	return breakingChanges
@@ -200,7 +401,7 @@ func detectRemovedConfigurableInstanceGroup(stable, candidate proofing.ProductTe
 	for _, stableIG := range stable.JobTypes {
 		found := candidate.HasJobTypeWithName(stableIG.Name)
 		if !found && stableIG.InstanceDefinition.Configurable {
-			breakingChanges = append(breakingChanges, fmt.Errorf("breaking change for configurable instance group with name %q: removed", stableIG.Name))
+			breakingChanges = append(breakingChanges, categorized(CategoryInstanceGroup, fmt.Errorf("breaking change for configurable instance group with name %q: removed", stableIG.Name)))
 		}
 	}
 	return breakingChanges
@@ -211,7 +412,7 @@ func detectConfigurabilityChangeToFalse(stable, candidate proofing.ProductTempla
 	for _, stableJob := range stable.JobTypes {
 		candidateJob, _, findErr := candidate.FindJobTypeWithName(stableJob.Name)
 		if findErr == nil && stableJob.InstanceDefinition.Configurable && !candidateJob.InstanceDefinition.Configurable {
-			breakingChanges = append(breakingChanges, fmt.Errorf("breaking change for configurable instance group with name %q: configurable changed to false", stableJob.Name))
+			breakingChanges = append(breakingChanges, categorized(CategoryInstanceGroup, fmt.Errorf("breaking change for configurable instance group with name %q: configurable changed to false", stableJob.Name)))
 		}
 	}
 	return breakingChanges
@@ -224,26 +425,34 @@ func detectTightenedInstanceGroupConstraints(stable, candidate proofing.ProductT
 		if err != nil {
 			continue
 		}
-		for _, constraintErr := range checkTighterConstraints(stableJob.InstanceDefinition, candidateJob.InstanceDefinition) {
-			breakingChanges = append(breakingChanges, fmt.Errorf("breaking change for instance definition constraint with name %q: %w", stableJob.Name, constraintErr))
+		if stableJob.InstanceDefinition.Constraints == nil {
+			continue
+		}
+		for _, constraintErr := range checkTighterConstraints(*stableJob.InstanceDefinition.Constraints, candidateJob.InstanceDefinition.Constraints) {
+			breakingChanges = append(breakingChanges, categorized(CategoryInstanceGroup, fmt.Errorf("breaking change for instance definition constraint with name %q: %w", stableJob.Name, constraintErr)))
 		}
 	}
 	return breakingChanges
 }
 
-// checkTighterConstraints only check proofing.IntegerConstraints.Min and proofing.IntegerConstraints.Max
+// checkTighterConstraints only checks proofing.IntegerConstraints.Min, proofing.IntegerConstraints.Max, and
+// proofing.IntegerConstraints.Modulo
 // TODO: implement checks for other fields
-func checkTighterConstraints(stableJob, candidateJob proofing.InstanceDefinition) []error {
-	if candidateJob.Constraints == nil {
+func checkTighterConstraints(stable proofing.IntegerConstraints, candidate *proofing.IntegerConstraints) []error {
+	if candidate == nil {
 		return nil
 	}
 	var errList []error
-	if stableJob.Constraints != nil && candidateJob.Constraints.Max != nil && ((stableJob.Constraints.Max == nil) || (*candidateJob.Constraints.Max < *stableJob.Constraints.Max)) {
+	if candidate.Max != nil && ((stable.Max == nil) || (*candidate.Max < *stable.Max)) {
 		errList = append(errList, fmt.Errorf("reduced max constraint"))
 	}
-	if stableJob.Constraints != nil && candidateJob.Constraints.Min != nil && ((stableJob.Constraints.Min == nil) || (*candidateJob.Constraints.Min > *stableJob.Constraints.Min)) {
+	if candidate.Min != nil && ((stable.Min == nil) || (*candidate.Min > *stable.Min)) {
 		errList = append(errList, fmt.Errorf("increased min constraint"))
 	}
+	// values matching the stable modulo still match the candidate modulo only when the candidate modulo divides it
+	if candidate.Modulo != nil && *candidate.Modulo != 0 && ((stable.Modulo == nil) || (*stable.Modulo%*candidate.Modulo != 0)) {
+		errList = append(errList, fmt.Errorf("changed modulo constraint"))
+	}
 	return errList
 }
 
@@ -258,9 +467,133 @@ func listNewPropertyBlueprints(stable, candidate proofing.ProductTemplate) []pro
 	return newProperties
 }
 
+func simplePropertyBlueprint(pb proofing.PropertyBlueprint) (proofing.SimplePropertyBlueprint, bool) {
+	switch blueprint := pb.(type) {
+	case proofing.SimplePropertyBlueprint:
+		return blueprint, true
+	case *proofing.SimplePropertyBlueprint:
+		return *blueprint, true
+	case proofing.SelectorPropertyBlueprint:
+		return blueprint.SimplePropertyBlueprint, true
+	case *proofing.SelectorPropertyBlueprint:
+		return blueprint.SimplePropertyBlueprint, true
+	case proofing.CollectionPropertyBlueprint:
+		return blueprint.SimplePropertyBlueprint, true
+	case *proofing.CollectionPropertyBlueprint:
+		return blueprint.SimplePropertyBlueprint, true
+	default:
+		return proofing.SimplePropertyBlueprint{}, false
+	}
+}
+
+func selectorPropertyBlueprint(pb proofing.PropertyBlueprint) (proofing.SelectorPropertyBlueprint, bool) {
+	switch blueprint := pb.(type) {
+	case proofing.SelectorPropertyBlueprint:
+		return blueprint, true
+	case *proofing.SelectorPropertyBlueprint:
+		return *blueprint, true
+	default:
+		return proofing.SelectorPropertyBlueprint{}, false
+	}
+}
+
+func collectionPropertyBlueprint(pb proofing.PropertyBlueprint) (proofing.CollectionPropertyBlueprint, bool) {
+	switch blueprint := pb.(type) {
+	case proofing.CollectionPropertyBlueprint:
+		return blueprint, true
+	case *proofing.CollectionPropertyBlueprint:
+		return *blueprint, true
+	default:
+		return proofing.CollectionPropertyBlueprint{}, false
+	}
+}
+
+// integerConstraints decodes the untyped constraints of a property blueprint. It returns false when the
+// property does not have constraints or when they are not integer constraints.
+func integerConstraints(pb proofing.PropertyBlueprint) (proofing.IntegerConstraints, bool) {
+	blueprint, ok := simplePropertyBlueprint(pb)
+	if !ok || blueprint.Constraints == nil {
+		return proofing.IntegerConstraints{}, false
+	}
+	buf, err := yaml.Marshal(blueprint.Constraints)
+	if err != nil {
+		return proofing.IntegerConstraints{}, false
+	}
+	var constraints proofing.IntegerConstraints
+	if err := yaml.Unmarshal(buf, &constraints); err != nil {
+		return proofing.IntegerConstraints{}, false
+	}
+	return constraints, true
+}
+
+func hasSelectorOptionWithSelectValue(options []proofing.SelectorPropertyOptionTemplate, selectValue string) bool {
+	for _, option := range options {
+		if option.SelectValue == selectValue {
+			return true
+		}
+	}
+	return false
+}
+
+func findSimplePropertyBlueprint(blueprints []proofing.SimplePropertyBlueprint, name string) (proofing.SimplePropertyBlueprint, bool) {
+	for _, blueprint := range blueprints {
+		if blueprint.Name == name {
+			return blueprint, true
+		}
+	}
+	return proofing.SimplePropertyBlueprint{}, false
+}
+
+func hasFormTypeWithName(formTypes []proofing.FormType, name string) bool {
+	for _, formType := range formTypes {
+		if formType.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// propertyInputName returns the name of the product property referenced by a form input. It returns an empty
+// string for references to job properties.
+func propertyInputName(input proofing.PropertyInput) string {
+	const prefix = ".properties."
+	ref := input.Ref()
+	if !strings.HasPrefix(ref, prefix) {
+		return ""
+	}
+	name, _, _ := strings.Cut(strings.TrimPrefix(ref, prefix), ".")
+	return name
+}
+
 func appendStaticErrorType[T error](errList []error, list ...T) []error {
 	for _, c := range list {
 		errList = append(errList, c)
 	}
 	return errList
 }
+
+// Category returns the category of a breaking change returned by ListBreakingChanges.
+func Category(breakingChange error) string {
+	var propertyChange PropertyBlueprintBreakingChange
+	if errors.As(breakingChange, &propertyChange) {
+		return CategoryPropertyBlueprint
+	}
+	var categorizedChange categorizedError
+	if errors.As(breakingChange, &categorizedChange) {
+		return categorizedChange.category
+	}
+	return CategoryOther
+}
+
+type categorizedError struct {
+	category string
+	err      error
+}
+
+func categorized(category string, err error) error {
+	return categorizedError{category: category, err: err}
+}
+
+func (err categorizedError) Error() string { return err.err.Error() }
+
+func (err categorizedError) Unwrap() error { return err.err }
@@ -71,6 +71,37 @@ func TestListBreakingChanges(t *testing.T) {
 		})
 	})
 
+	t.Run("minimum_version_for_upgrade", func(t *testing.T) {
+		t.Run("candidate minimum_version_for_upgrade is greater than stable product_version", func(t *testing.T) {
+			initialMetadata := proofing.ProductTemplate{ProductVersion: "3.0.0", MinimumVersionForUpgrade: "2.0.0"}
+			patchMetadata := proofing.ProductTemplate{ProductVersion: "3.1.0", MinimumVersionForUpgrade: "3.1.0"}
+
+			breakingChanges := upgrade.ListBreakingChanges(initialMetadata, patchMetadata)
+
+			assert.Len(t, breakingChanges, 1)
+			assert.EqualError(t, breakingChanges[0], `candidate metadata minimum_version_for_upgrade 3.1.0 is greater than stable metadata product_version 3.0.0`)
+		})
+
+		t.Run("candidate minimum_version_for_upgrade is lowered", func(t *testing.T) {
+			initialMetadata := proofing.ProductTemplate{ProductVersion: "3.0.0", MinimumVersionForUpgrade: "2.5.0"}
+			patchMetadata := proofing.ProductTemplate{ProductVersion: "3.1.0", MinimumVersionForUpgrade: "2.0.0"}
+
+			breakingChanges := upgrade.ListBreakingChanges(initialMetadata, patchMetadata)
+
+			assert.Len(t, breakingChanges, 1)
+			assert.EqualError(t, breakingChanges[0], `candidate metadata minimum_version_for_upgrade 2.0.0 is lower than stable metadata minimum_version_for_upgrade 2.5.0`)
+		})
+
+		t.Run("candidate minimum_version_for_upgrade is raised to stable product_version", func(t *testing.T) {
+			initialMetadata := proofing.ProductTemplate{ProductVersion: "3.0.0", MinimumVersionForUpgrade: "2.5.0"}
+			patchMetadata := proofing.ProductTemplate{ProductVersion: "3.1.0", MinimumVersionForUpgrade: "3.0.0"}
+
+			breakingChanges := upgrade.ListBreakingChanges(initialMetadata, patchMetadata)
+
+			assert.Empty(t, breakingChanges)
+		})
+	})
+
 	t.Run("contrived scenarios", func(t *testing.T) {
 		t.Run("product name changed", func(t *testing.T) {
 			initialMetadata, patchMetadata := loadMetadataProperties(t)
@@ -184,9 +215,98 @@ func TestListBreakingChanges(t *testing.T) {
 			assert.EqualError(t, breakingChanges[0], `breaking change for instance definition constraint with name "uaa": increased min constraint`)
 			assert.EqualError(t, breakingChanges[1], `breaking change for instance definition constraint with name "ha_proxy": reduced max constraint`)
 		})
+		t.Run("removed selector option", func(t *testing.T) {
+			initialMetadata, patchMetadata := loadMetadataProperties(t)
+
+			breakingChanges := upgrade.ListBreakingChanges(initialMetadata, patchMetadata)
+
+			assert.Len(t, breakingChanges, 1)
+			assert.EqualError(t, breakingChanges[0], `breaking change for property with name "database": removed selector option: option "external"`)
+		})
+		t.Run("removed collection sub-property", func(t *testing.T) {
+			initialMetadata, patchMetadata := loadMetadataProperties(t)
+
+			breakingChanges := upgrade.ListBreakingChanges(initialMetadata, patchMetadata)
+
+			assert.Len(t, breakingChanges, 1)
+			assert.EqualError(t, breakingChanges[0], `breaking change for property with name "users": removed or renamed collection sub-property: sub-property "password"`)
+		})
+		t.Run("changed collection sub-property type", func(t *testing.T) {
+			initialMetadata, patchMetadata := loadMetadataProperties(t)
+
+			breakingChanges := upgrade.ListBreakingChanges(initialMetadata, patchMetadata)
+
+			assert.Len(t, breakingChanges, 1)
+			assert.EqualError(t, breakingChanges[0], `breaking change for property with name "users": changed collection sub-property type: sub-property "port" type changed from "integer" to "port"`)
+		})
+		t.Run("property constraints tightened", func(t *testing.T) {
+			initialMetadata, patchMetadata := loadMetadataProperties(t)
+
+			breakingChanges := upgrade.ListBreakingChanges(initialMetadata, patchMetadata)
+
+			assert.Len(t, breakingChanges, 2)
+			assert.EqualError(t, breakingChanges[0], `breaking change for property with name "workers": tightened configurable property constraints: increased min constraint`)
+			assert.EqualError(t, breakingChanges[1], `breaking change for property with name "threads": tightened configurable property constraints: changed modulo constraint`)
+		})
+		t.Run("changed freeze_on_deploy", func(t *testing.T) {
+			initialMetadata, patchMetadata := loadMetadataProperties(t)
+
+			breakingChanges := upgrade.ListBreakingChanges(initialMetadata, patchMetadata)
+
+			assert.Len(t, breakingChanges, 1)
+			assert.EqualError(t, breakingChanges[0], `breaking change for property with name "network_name": changed freeze_on_deploy: changed from false to true`)
+		})
+		t.Run("removed form type with configurable property", func(t *testing.T) {
+			initialMetadata, patchMetadata := loadMetadataProperties(t)
+
+			breakingChanges := upgrade.ListBreakingChanges(initialMetadata, patchMetadata)
+
+			assert.Len(t, breakingChanges, 1)
+			assert.EqualError(t, breakingChanges[0], `breaking change for form type with name "dimensions": removed while property "size" is still configurable`)
+			assert.Equal(t, upgrade.CategoryFormType, upgrade.Category(breakingChanges[0]))
+		})
 	})
 }
 
+func TestCategory(t *testing.T) {
+	for _, tt := range []struct {
+		Name               string
+		Stable, Candidate  proofing.ProductTemplate
+		ExpectedCategories []string
+	}{
+		{
+			Name:               "product version",
+			Stable:             proofing.ProductTemplate{ProductVersion: "3.1.0"},
+			Candidate:          proofing.ProductTemplate{ProductVersion: "3.0.0"},
+			ExpectedCategories: []string{upgrade.CategoryProductVersion},
+		},
+		{
+			Name:               "product name",
+			Stable:             proofing.ProductTemplate{Name: "banana", ProductVersion: "3.0.0"},
+			Candidate:          proofing.ProductTemplate{Name: "lemon", ProductVersion: "3.0.0"},
+			ExpectedCategories: []string{upgrade.CategoryProductName},
+		},
+		{
+			Name: "property blueprint and errand",
+			Stable: proofing.ProductTemplate{
+				ProductVersion:     "3.0.0",
+				PropertyBlueprints: proofing.PropertyBlueprints{proofing.SimplePropertyBlueprint{Name: "color", Type: "string", Configurable: true}},
+				PostDeployErrands:  []proofing.ErrandTemplate{{Name: "smoke_tests"}},
+			},
+			Candidate:          proofing.ProductTemplate{ProductVersion: "3.0.0"},
+			ExpectedCategories: []string{upgrade.CategoryPropertyBlueprint, upgrade.CategoryErrand},
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var categories []string
+			for _, breakingChange := range upgrade.ListBreakingChanges(tt.Stable, tt.Candidate) {
+				categories = append(categories, upgrade.Category(breakingChange))
+			}
+			assert.Equal(t, tt.ExpectedCategories, categories)
+		})
+	}
+}
+
 func loadMetadataProperties(t *testing.T) (initial, patch proofing.ProductTemplate) {
 	t.Helper()
 	readYAMLFile(t, filepath.Join("testdata", "breaking_changes", path.Base(t.Name()), "initial.yml"), &initial)
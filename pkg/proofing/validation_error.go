@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+
+	"github.com/Masterminds/semver/v3"
 )
 
 type ValidationError struct {
@@ -39,3 +41,47 @@ func ValidatePresence(err error, v interface{}, field string) error {
 
 	return err
 }
+
+// FieldError is a validation error for the value at Path, a YAML path such as
+// "job_types[0].templates[1].release".
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (fe FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", fe.Path, fe.Message)
+}
+
+func (ce *CompoundError) addFieldError(path, format string, a ...interface{}) {
+	ce.Add(FieldError{Path: path, Message: fmt.Sprintf(format, a...)})
+}
+
+func (ce *CompoundError) validatePresence(path, value string) {
+	if value == "" {
+		ce.addFieldError(path, "must be present")
+	}
+}
+
+func (ce *CompoundError) validateVersion(path, value string) {
+	if value == "" {
+		return
+	}
+	if _, err := semver.NewVersion(value); err != nil {
+		ce.addFieldError(path, "must be a semantic version: %s", err)
+	}
+}
+
+// validateUniqueNames reports each name that is already used by a previous element of the list at path.
+func (ce *CompoundError) validateUniqueNames(path string, names []string) {
+	seen := make(map[string]bool, len(names))
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		if seen[name] {
+			ce.addFieldError(fmt.Sprintf("%s[%d].name", path, i), "duplicate name %q", name)
+		}
+		seen[name] = true
+	}
+}